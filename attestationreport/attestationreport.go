@@ -20,8 +20,11 @@ import (
 	"crypto"
 	"crypto/sha256"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/hex"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/Fraunhofer-AISEC/cmc/internal"
 	"github.com/sirupsen/logrus"
@@ -47,6 +50,8 @@ type Measurer interface {
 // such as a TPM or other hardware interface
 type Signing interface{}
 
+// GetSigningKeys and GetCertChain must be called while holding the lock and the returned
+// keys must only be used until the lock is released, as signers might renew their keys
 type Signer interface {
 	Lock()
 	Unlock()
@@ -93,21 +98,59 @@ type Validity struct {
 const timeLayout = "20060102150405"
 
 // HashChainElem represents the attestation report
-// element of type 'Hash Chain' embedded in 'TPM Measurement'
+// element of type 'Hash Chain' embedded in 'TPM Measurement'. The digests
+// are tagged with the PCR bank they were taken from. Only the banks which were
+// quoted must be present
 type HashChainElem struct {
-	Type   string    `json:"type" cbor:"0,keyasint"`
-	Pcr    int32     `json:"pcr" cbor:"1,keyasint"`
-	Sha256 []HexByte `json:"sha256" cbor:"2,keyasint"`
+	Type   string      `json:"type" cbor:"0,keyasint"`
+	Pcr    int32       `json:"pcr" cbor:"1,keyasint"`
+	Sha256 []HexByte   `json:"sha256,omitempty" cbor:"2,keyasint,omitempty"`
+	Events []EventInfo `json:"events,omitempty" cbor:"3,keyasint,omitempty"`
+	Sha1   []HexByte   `json:"sha1,omitempty" cbor:"4,keyasint,omitempty"`
+	Sha384 []HexByte   `json:"sha384,omitempty" cbor:"5,keyasint,omitempty"`
+	Sm3    []HexByte   `json:"sm3,omitempty" cbor:"6,keyasint,omitempty"`
+	// StartupLocality is the locality the TPM was started from as recorded in the event
+	// log. It determines the initial value of PCR0 and is only valid for PCR0
+	StartupLocality uint8 `json:"startupLocality,omitempty" cbor:"7,keyasint,omitempty"`
+}
+
+// EventInfo contains optional information about an individual measurement
+// of a 'Hash Chain', such as the event type and description from the TPM
+// event log. If present, the Events of a HashChainElem correspond one by one
+// to its digests of each PCR bank. For IMA measurements, the event type is the IMA
+// template name and the description is the file path. Signed IMA measurements
+// additionally contain the file digest and signature for signature appraisal
+type EventInfo struct {
+	EventType     string  `json:"eventType,omitempty" cbor:"0,keyasint,omitempty"`
+	Description   string  `json:"description,omitempty" cbor:"1,keyasint,omitempty"`
+	FileDigestAlg string  `json:"fileDigestAlg,omitempty" cbor:"2,keyasint,omitempty"`
+	FileDigest    HexByte `json:"fileDigest,omitempty" cbor:"3,keyasint,omitempty"`
+	Signature     HexByte `json:"signature,omitempty" cbor:"4,keyasint,omitempty"`
+	// Remaining IMA template fields, required to recalculate the template hash
+	// of signed ima-buf and ima-modsig entries
+	Buf             HexByte `json:"buf,omitempty" cbor:"5,keyasint,omitempty"`
+	ModSigDigestAlg string  `json:"modSigDigestAlg,omitempty" cbor:"6,keyasint,omitempty"`
+	ModSigDigest    HexByte `json:"modSigDigest,omitempty" cbor:"7,keyasint,omitempty"`
+	ModSig          HexByte `json:"modSig,omitempty" cbor:"8,keyasint,omitempty"`
 }
 
 // TpmMeasurement represents the attestation report
-// element of type 'TPM Measurement'
+// element of type 'TPM Measurement'. Message and Signature contain the quote
+// over the first quoted PCR bank. As a quote covers a single PCR bank, the
+// quotes over further PCR banks are contained in Quotes
 type TpmMeasurement struct {
 	Type      string           `json:"type" cbor:"0,keyasint"`
 	Message   HexByte          `json:"message" cbor:"1,keyasint"`
 	Signature HexByte          `json:"signature" cbor:"2,keyasint"`
 	Certs     [][]byte         `json:"certs" cbor:"3,keyasint"`
 	HashChain []*HashChainElem `json:"hashChain" cbor:"4,keyasint"`
+	Quotes    []TpmQuote       `json:"quotes,omitempty" cbor:"5,keyasint,omitempty"`
+}
+
+// TpmQuote contains a TPM quote over an additional PCR bank and its signature
+type TpmQuote struct {
+	Message   HexByte `json:"message" cbor:"0,keyasint"`
+	Signature HexByte `json:"signature" cbor:"1,keyasint"`
 }
 
 // SnpMeasurement represents the attestation report
@@ -126,6 +169,14 @@ type IasMeasurement struct {
 	Certs  [][]byte `json:"certs" cbor:"2,keyasint"`
 }
 
+// TdxMeasurement represents the attestation report
+// element of type 'TDX Measurement' signed by the device
+type TdxMeasurement struct {
+	Type   string   `json:"type" cbor:"0,keyasint"`
+	Report []byte   `json:"blob" cbor:"1,keyasint"`
+	Certs  [][]byte `json:"certs,omitempty" cbor:"2,keyasint,omitempty"`
+}
+
 // SwMeasurement represents the attestation report
 // element of type 'Software Measurement'
 type SwMeasurement struct {
@@ -163,18 +214,67 @@ type SnpDetails struct {
 	Policy  SnpPolicy `json:"policy" cbor:"2,keyasint"`
 	Fw      SnpFw     `json:"fw" cbor:"3,keyasint"`
 	Tcb     SnpTcb    `json:"tcb" cbor:"4,keyasint"`
+	Product string    `json:"product,omitempty" cbor:"5,keyasint,omitempty"` // Milan, Genoa, Bergamo
+
+	// Optional expected values, only checked if present
+	HostData        HexByte          `json:"hostData,omitempty" cbor:"6,keyasint,omitempty"`
+	IdKeyDigest     HexByte          `json:"idKeyDigest,omitempty" cbor:"7,keyasint,omitempty"`
+	AuthorKeyDigest HexByte          `json:"authorKeyDigest,omitempty" cbor:"8,keyasint,omitempty"`
+	FamilyId        HexByte          `json:"familyId,omitempty" cbor:"9,keyasint,omitempty"`
+	ImageId         HexByte          `json:"imageId,omitempty" cbor:"10,keyasint,omitempty"`
+	GuestSvn        *uint32          `json:"guestSvn,omitempty" cbor:"11,keyasint,omitempty"` // Minimum guest SVN
+	ReportId        HexByte          `json:"reportId,omitempty" cbor:"12,keyasint,omitempty"`
+	PlatformInfo    *SnpPlatformInfo `json:"platformInfo,omitempty" cbor:"13,keyasint,omitempty"`
+}
+
+// SnpPlatformInfo contains the expected values of the PLATFORM_INFO field of
+// an SNP attestation report
+type SnpPlatformInfo struct {
+	Smt  bool `json:"smt" cbor:"0,keyasint"`
+	Tsme bool `json:"tsme" cbor:"1,keyasint"`
+}
+
+// TdxDetails contains the expected values of a TDX quote apart from the
+// MRTD, which is specified as the Sha384 of the 'TDX Reference Value'
+type TdxDetails struct {
+	Version uint16    `json:"version" cbor:"0,keyasint"`
+	Rtmrs   []HexByte `json:"rtmrs" cbor:"1,keyasint"` // RTMR0-3
+	Debug   bool      `json:"debug" cbor:"2,keyasint"`
+	Cas     [][]byte  `json:"cas" cbor:"3,keyasint"` // Intel SGX Root CA
+
+	// Optional expected identity of the quoting enclave. If not present, the
+	// identity of the Intel TDX QE is expected
+	QeIdentity *TdxQeIdentity `json:"qeIdentity,omitempty" cbor:"4,keyasint,omitempty"`
+}
+
+// TdxQeIdentity contains the expected identity of the TDX quoting enclave (QE),
+// which signs the quotes with its attestation key
+type TdxQeIdentity struct {
+	MrSigner  HexByte `json:"mrSigner" cbor:"0,keyasint"`
+	IsvProdId uint16  `json:"isvProdId" cbor:"1,keyasint"`
+	IsvSvn    uint16  `json:"isvSvn" cbor:"2,keyasint"` // Minimum QE SVN
 }
 
 // ReferenceValue represents the attestation report
-// element of types 'SNP Reference Value', 'TPM Reference Value'
-// and 'SW Reference Value'
+// element of types 'SNP Reference Value', 'TDX Reference Value',
+// 'TPM Reference Value' and 'SW Reference Value'. TPM Reference Values
+// contain the digest for each PCR bank that shall be verified.
+// TPM, SW and IAS Reference Values can be marked as optional, i.e. they
+// are not required to be measured. Reference Values of the same alternative
+// group are alternatives of which exactly one is expected to be measured. The
+// TPM Reference Values of an alternative group must belong to the same PCR
 type ReferenceValue struct {
-	Type   string      `json:"type" cbor:"0,keyasint"`
-	Sha256 HexByte     `json:"sha256,omitempty" cbor:"1,keyasint,omitempty"`
-	Sha384 HexByte     `json:"sha384,omitempty" cbor:"2,keyasint,omitempty"`
-	Name   string      `json:"name,omitempty" cbor:"3,keyasint,omitempty"`
-	Pcr    *int        `json:"pcr,omitempty" cbor:"4,keyasint,omitempty"`
-	Snp    *SnpDetails `json:"snp,omitempty" cbor:"5,keyasint,omitempty"`
+	Type             string      `json:"type" cbor:"0,keyasint"`
+	Sha256           HexByte     `json:"sha256,omitempty" cbor:"1,keyasint,omitempty"`
+	Sha384           HexByte     `json:"sha384,omitempty" cbor:"2,keyasint,omitempty"`
+	Name             string      `json:"name,omitempty" cbor:"3,keyasint,omitempty"`
+	Pcr              *int        `json:"pcr,omitempty" cbor:"4,keyasint,omitempty"`
+	Snp              *SnpDetails `json:"snp,omitempty" cbor:"5,keyasint,omitempty"`
+	Tdx              *TdxDetails `json:"tdx,omitempty" cbor:"6,keyasint,omitempty"`
+	Sha1             HexByte     `json:"sha1,omitempty" cbor:"7,keyasint,omitempty"`
+	Sm3              HexByte     `json:"sm3,omitempty" cbor:"8,keyasint,omitempty"`
+	Optional         bool        `json:"optional,omitempty" cbor:"9,keyasint,omitempty"`
+	AlternativeGroup string      `json:"alternativeGroup,omitempty" cbor:"10,keyasint,omitempty"`
 }
 
 // AppDescription represents the attestation report
@@ -187,22 +287,26 @@ type AppDescription struct {
 }
 
 // InternalConnection represents the attestation report
-// element of type 'Internal Connection'
+// element of type 'Internal Connection'. It connects an endpoint
+// of app A with an endpoint of app B
 type InternalConnection struct {
 	Type         string `json:"type" cbor:"0,keyasint"`
 	NameAppA     string `json:"nameAppA" cbor:"1,keyasint"`     // Links to AppDescription.Name
-	EndpointAppA string `json:"endpointAppA" cbor:"2,keyasint"` // Links to AppManifest.Endpoint
+	EndpointAppA string `json:"endpointAppA" cbor:"2,keyasint"` // Links to AppManifest.Endpoints
 	NameAppB     string `json:"nameAppB" cbor:"3,keyasint"`     // Links to AppDescription.Name
-	EndpointAppB string `json:"endpointAppB" cbor:"4,keyasint"` // Links to AppManifest.Endpoint
+	EndpointAppB string `json:"endpointAppB" cbor:"4,keyasint"` // Links to AppManifest.Endpoints
 }
 
 // ExternalInterface represents the attestation report
-// element of type 'External Interface'
+// element of type 'External Interface'. Within the Device Description, it
+// declares a network interface and port (0 for any port) the device may
+// expose. Within an App Description, it exposes an endpoint of the app
+// on a declared interface and port
 type ExternalInterface struct {
 	Type        string `json:"type" cbor:"0,keyasint"`
-	AppEndpoint string `json:"appEndpoint" cbor:"1,keyasint"` // Links to AppManifest.Endpoint
-	Interface   string `json:"interface" cbor:"2,keyasint"`   // Links to AppDescription.Name
-	Port        int    `json:"port" cbor:"3,keyasint"`        // Links to App Manifest.Endpoint
+	AppEndpoint string `json:"appEndpoint" cbor:"1,keyasint"` // Links to AppManifest.Endpoints
+	Interface   string `json:"interface" cbor:"2,keyasint"`   // Links to DeviceDescription.External.Interface
+	Port        int    `json:"port" cbor:"3,keyasint"`
 }
 
 // AppManifest represents the attestation report
@@ -217,6 +321,7 @@ type AppManifest struct {
 	CertificationLevel int              `json:"certificationLevel" cbor:"6,keyasint"`
 	Validity           Validity         `json:"validity" cbor:"7,keyasint"`
 	ReferenceValues    []ReferenceValue `json:"referenceValues" cbor:"8,keyasint"`
+	Endpoints          []string         `json:"endpoints,omitempty" cbor:"9,keyasint,omitempty"` // Endpoints provided by the app
 }
 
 // OsManifest represents the attestation report
@@ -231,6 +336,8 @@ type OsManifest struct {
 	CertificationLevel int              `json:"certificationLevel" cbor:"6,keyasint"`
 	Validity           Validity         `json:"validity" cbor:"7,keyasint"`
 	ReferenceValues    []ReferenceValue `json:"referenceValues" cbor:"8,keyasint"`
+	ImaSigningCerts    [][]byte         `json:"imaSigningCerts,omitempty" cbor:"9,keyasint,omitempty"`
+	UnorderedPcrs      []int            `json:"unorderedPcrs,omitempty" cbor:"10,keyasint,omitempty"` // PCRs whose measurement order is not deterministic, e.g. IMA
 }
 
 // RtmManifest represents the attestation report
@@ -258,6 +365,15 @@ type DeviceDescription struct {
 	AppDescriptions []AppDescription     `json:"appDescriptions" cbor:"6,keyasint"`
 	Internal        []InternalConnection `json:"internalConnections" cbor:"7,keyasint"`
 	External        []ExternalInterface  `json:"externalEndpoints" cbor:"8,keyasint"`
+	Binding         *DeviceBinding       `json:"deviceBinding,omitempty" cbor:"9,keyasint,omitempty"`
+}
+
+// DeviceBinding optionally binds the Device Description to the device which signs the
+// attestation report. All specified attributes must match the report-signing certificate
+type DeviceBinding struct {
+	Fqdn            bool     `json:"fqdn,omitempty" cbor:"0,keyasint,omitempty"`            // DeviceDescription.Fqdn must be the subject CN or a DNS SAN
+	SerialNumber    string   `json:"serialNumber,omitempty" cbor:"1,keyasint,omitempty"`    // Must match the subject serial number
+	SubjectAltNames []string `json:"subjectAltNames,omitempty" cbor:"2,keyasint,omitempty"` // Must each be a DNS, IP, email or URI SAN
 }
 
 // CompanyDescription represents the attestation report
@@ -270,6 +386,26 @@ type CompanyDescription struct {
 	Validity           Validity `json:"validity" cbor:"4,keyasint"`
 }
 
+// RevocationList represents the element of type 'Revocation List'. It is signed like
+// the manifests and descriptions and lists revoked manifests and descriptions, so that
+// these can be revoked before the end of their validity
+type RevocationList struct {
+	Type     string            `json:"type" cbor:"0,keyasint"`
+	Name     string            `json:"name" cbor:"1,keyasint"`
+	Version  string            `json:"version" cbor:"2,keyasint"`
+	Validity Validity          `json:"validity" cbor:"3,keyasint"`
+	Revoked  []RevokedArtifact `json:"revoked" cbor:"4,keyasint"`
+}
+
+// RevokedArtifact identifies a revoked manifest or description either by its name and
+// version or by the SHA256 hash of the signed token
+type RevokedArtifact struct {
+	Name    string  `json:"name,omitempty" cbor:"0,keyasint,omitempty"`    // Name of the manifest or description
+	Version string  `json:"version,omitempty" cbor:"1,keyasint,omitempty"` // Version of the manifest, all versions if empty
+	Sha256  HexByte `json:"sha256,omitempty" cbor:"2,keyasint,omitempty"`  // Hash of the signed manifest or description
+	Reason  string  `json:"reason,omitempty" cbor:"3,keyasint,omitempty"`  // Reason for the revocation
+}
+
 // DeviceConfig contains the local device configuration parameters
 type DeviceConfig struct {
 	Type  string    `json:"type" cbor:"0,keyasint"`
@@ -303,6 +439,7 @@ type ArPlain struct {
 	TpmM               *TpmMeasurement     `json:"tpmMeasurement,omitempty" cbor:"1,keyasint,omitempty"`
 	SnpM               *SnpMeasurement     `json:"snpMeasurement,omitempty" cbor:"2,keyasint,omitempty"`
 	IasM               *IasMeasurement     `cbor:"10,keyasint,omitempty"`
+	TdxM               *TdxMeasurement     `json:"tdxMeasurement,omitempty" cbor:"11,keyasint,omitempty"`
 	SWM                []SwMeasurement     `json:"swMeasurements,omitempty" cbor:"3,keyasint,omitempty"`
 	RtmManifest        RtmManifest         `json:"rtmManifest" cbor:"4,keyasint"`
 	OsManifest         OsManifest          `json:"osManifest" cbor:"5,keyasint"`
@@ -318,6 +455,7 @@ type ArPacked struct {
 	Type               string          `json:"type" cbor:"0,keyasint"`
 	TpmM               *TpmMeasurement `json:"tpmMeasurement,omitempty" cbor:"1,keyasint,omitempty"`
 	SnpM               *SnpMeasurement `json:"snpMeasurement,omitempty" cbor:"2,keyasint,omitempty"`
+	TdxM               *TdxMeasurement `json:"tdxMeasurement,omitempty" cbor:"11,keyasint,omitempty"`
 	SWM                []SwMeasurement `json:"swMeasurements,omitempty" cbor:"3,keyasint,omitempty"`
 	RtmManifest        []byte          `json:"rtmManifests" cbor:"4,keyasint"`
 	OsManifest         []byte          `json:"osManifest" cbor:"5,keyasint"`
@@ -332,8 +470,10 @@ type ArPacked struct {
 // descriptions must be either raw JWS tokens in the JWS JSON full serialization
 // format or CBOR COSE tokens. Takes a list of 'measurements' implementing the
 // attestation report 'Measurer' interface providing a method for collecting
-// the measurements from a hardware or software interface
-func Generate(nonce []byte, metadata [][]byte, measurements []Measurement, s Serializer) ([]byte, error) {
+// the measurements from a hardware or software interface. In strict mode,
+// unparseable metadata, unknown metadata types and duplicate RTM Manifests,
+// OS Manifests, Device Descriptions or Company Descriptions are rejected
+func Generate(nonce []byte, metadata [][]byte, measurements []Measurement, strict bool, s Serializer) ([]byte, error) {
 	// Create attestation report object which will be filled with the attestation
 	// data or sent back incomplete in case errors occur
 	ar := ArPacked{
@@ -355,6 +495,9 @@ func Generate(nonce []byte, metadata [][]byte, measurements []Measurement, s Ser
 		// Extract plain payload (i.e. the manifest/description itself)
 		data, err := s.GetPayload(metadata[i])
 		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("failed to parse metadata object %v: %w", i, err)
+			}
 			log.Warnf("Failed to parse metadata object %v: %v", i, err)
 			continue
 		}
@@ -364,10 +507,16 @@ func Generate(nonce []byte, metadata [][]byte, measurements []Measurement, s Ser
 		t := new(Type)
 		err = s.Unmarshal(data, t)
 		if err != nil {
+			if strict {
+				return nil, fmt.Errorf("failed to unmarshal data from metadata object %v: %w", i, err)
+			}
 			log.Warnf("Failed to unmarshal data from metadata object %v: %v", i, err)
 			continue
 		}
 
+		// Singletons must only be present once, otherwise the previous object
+		// is overwritten
+		var slot *[]byte
 		switch t.Type {
 		case "App Manifest":
 			log.Debug("Adding App Manifest")
@@ -375,18 +524,33 @@ func Generate(nonce []byte, metadata [][]byte, measurements []Measurement, s Ser
 			numManifests++
 		case "OS Manifest":
 			log.Debug("Adding OS Manifest")
-			ar.OsManifest = metadata[i]
+			slot = &ar.OsManifest
 			numManifests++
 		case "RTM Manifest":
 			log.Debug("Adding RTM Manifest")
-			ar.RtmManifest = metadata[i]
+			slot = &ar.RtmManifest
 			numManifests++
 		case "Device Description":
 			log.Debug("Adding Device Description")
-			ar.DeviceDescription = metadata[i]
+			slot = &ar.DeviceDescription
 		case "Company Description":
 			log.Debug("Adding Company Description")
-			ar.CompanyDescription = metadata[i]
+			slot = &ar.CompanyDescription
+		default:
+			if strict {
+				return nil, fmt.Errorf("metadata object %v has unknown type '%v'", i, t.Type)
+			}
+			log.Warnf("Ignoring metadata object %v of unknown type '%v'", i, t.Type)
+		}
+
+		if slot != nil {
+			if *slot != nil {
+				if strict {
+					return nil, fmt.Errorf("metadata object %v: duplicate %v", i, t.Type)
+				}
+				log.Warnf("Metadata object %v: duplicate %v overwrites previous %v", i, t.Type, t.Type)
+			}
+			*slot = metadata[i]
 		}
 	}
 
@@ -422,6 +586,9 @@ func Generate(nonce []byte, metadata [][]byte, measurements []Measurement, s Ser
 		} else if snpData, ok := data.(SnpMeasurement); ok {
 			log.Tracef("Added %v to attestation report", snpData.Type)
 			ar.SnpM = &snpData
+		} else if tdxData, ok := data.(TdxMeasurement); ok {
+			log.Tracef("Added %v to attestation report", tdxData.Type)
+			ar.TdxM = &tdxData
 		} else {
 			log.Error("Error: Unsupported measurement interface type")
 		}
@@ -446,15 +613,23 @@ func Sign(report []byte, signer Signer, s Serializer) ([]byte, error) {
 // Verify verifies an attestation report in full serialized JWS
 // format against the supplied nonce and CA certificate. Verifies the certificate
 // chains of all attestation report elements as well as the measurements against
-// the reference values and the compatibility of software artefacts.
-func Verify(arRaw string, nonce, casPem []byte, policies []byte, polEng PolicyEngineSelect, s Serializer) VerificationResult {
+// the reference values and the compatibility of software artefacts. The optional
+// CRLs are used to check the revocation status of the hardware certificate chains
+// (currently AMD SEV-SNP). The optional signed revocation list is used to check the
+// revocation status of the manifests and descriptions. In strict mode, the types of
+// the attestation report and of all unpacked manifests and descriptions must match
+// their slot in the attestation report, App Manifests must not be duplicated and
+// expired CRLs are rejected.
+func Verify(arRaw string, nonce, casPem []byte, policies []byte, polEng PolicyEngineSelect,
+	crls []*x509.RevocationList, revocationList []byte, strict bool, s Serializer,
+) VerificationResult {
 	result := VerificationResult{
 		Type:        "Verification Result",
 		Success:     true,
 		SwCertLevel: 0}
 
 	// Verify ALL signatures and unpack plain AttestationReport
-	ok, ar := verifyAndUnpackAttestationReport(arRaw, &result, casPem, s)
+	ok, ar := verifyAndUnpackAttestationReport(arRaw, &result, casPem, revocationList, strict, s)
 	if ar == nil {
 		result.InternalError = true
 	}
@@ -480,14 +655,21 @@ func Verify(arRaw string, nonce, casPem []byte, policies []byte, polEng PolicyEn
 
 	// If present, verify TPM measurements against provided TPM reference values
 	result.MeasResult.TpmMeasResult, ok = verifyTpmMeasurements(ar.TpmM, nonce,
-		referenceValues["TPM Reference Value"], casPem)
+		referenceValues["TPM Reference Value"], ar.OsManifest.ImaSigningCerts, ar.OsManifest.UnorderedPcrs, casPem)
 	if !ok {
 		result.Success = false
 	}
 
 	// If present, verify AMD SEV SNP measurements against provided SNP reference values
 	result.MeasResult.SnpMeasResult, ok = verifySnpMeasurements(ar.SnpM, nonce,
-		referenceValues["SNP Reference Value"])
+		referenceValues["SNP Reference Value"], crls, strict)
+	if !ok {
+		result.Success = false
+	}
+
+	// If present, verify Intel TDX measurements against provided TDX reference values
+	result.MeasResult.TdxMeasResult, ok = verifyTdxMeasurements(ar.TdxM, nonce,
+		referenceValues["TDX Reference Value"])
 	if !ok {
 		result.Success = false
 	}
@@ -523,13 +705,35 @@ func Verify(arRaw string, nonce, casPem []byte, policies []byte, polEng PolicyEn
 	// If no hardware trust anchor is present, the maximum certification level is 1
 	// If there are referenceValues with a higher trust level present, the remote attestation
 	// must fail
-	if ar.TpmM == nil && ar.SnpM == nil && aggCertLevel > 1 {
+	if ar.TpmM == nil && ar.SnpM == nil && ar.TdxM == nil && aggCertLevel > 1 {
 		msg := fmt.Sprintf("No hardware trust anchor measurements present but claimed certification level is %v, which requires a hardware trust anchor", aggCertLevel)
 		result.ProcessingError = append(result.ProcessingError, msg)
 		result.Success = false
 	}
 	result.SwCertLevel = aggCertLevel
 
+	// If present, verify the mapping of the Company Description to the device: The company
+	// operating the device must be certified for the certification level of the software
+	// stack and the device certificate must have been issued to the company
+	if ar.CompanyDescription != nil && result.CompDescResult != nil {
+		ok = verifyCompanyDescription(ar.CompanyDescription, aggCertLevel,
+			getDeviceCert(result.ReportSignature), result.CompDescResult)
+		if !ok {
+			result.Success = false
+		}
+	}
+
+	// If specified, verify that the Device Description is bound to the device which
+	// signed the attestation report
+	if ar.DeviceDescription.Binding != nil {
+		result.DevDescResult.DeviceBindingCheck = verifyDeviceBinding(&ar.DeviceDescription,
+			getDeviceCert(result.ReportSignature))
+		if !result.DevDescResult.DeviceBindingCheck.Success {
+			result.DevDescResult.Summary.Success = false
+			result.Success = false
+		}
+	}
+
 	// Verify the compatibility of the attestation report through verifying the
 	// compatibility of all components
 	appDescriptions := make([]string, 0)
@@ -587,6 +791,14 @@ func Verify(arRaw string, nonce, casPem []byte, policies []byte, polEng PolicyEn
 		}
 	}
 
+	// Check that the internal connections and external interfaces of the Device
+	// Description only reference existing apps, endpoints and interfaces
+	result.DevDescResult.ConnectionGraph = verifyConnectionGraph(&ar.DeviceDescription, ar.AppManifests)
+	if !result.DevDescResult.ConnectionGraph.Summary.Success {
+		result.DevDescResult.Summary.Success = false
+		result.Success = false
+	}
+
 	// Validate policies if specified
 	if policies != nil {
 		result.PolicySuccess = true
@@ -615,15 +827,9 @@ func Verify(arRaw string, nonce, casPem []byte, policies []byte, polEng PolicyEn
 	return result
 }
 
-func extendHash(hash []byte, data []byte) []byte {
-	concat := append(hash, data...)
-	h := sha256.Sum256(concat)
-	ret := make([]byte, 32)
-	copy(ret, h[:])
-	return ret
-}
-
-func verifyAndUnpackAttestationReport(attestationReport string, result *VerificationResult, casPem []byte, s Serializer) (bool, *ArPlain) {
+func verifyAndUnpackAttestationReport(attestationReport string, result *VerificationResult, casPem []byte,
+	revocationList []byte, strict bool, s Serializer,
+) (bool, *ArPlain) {
 	if result == nil {
 		log.Warn("Provided Validation Result was nil")
 		return false, nil
@@ -638,6 +844,17 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 		return false, &ar
 	}
 
+	// Validate and unpack the Revocation List if present. An invalid revocation list must
+	// not be ignored, as this would allow to use revoked manifests
+	var rl *RevocationList
+	if revocationList != nil {
+		result.RevocationListResult, rl = verifyRevocationList(revocationList, roots, s)
+		if rl == nil {
+			log.Trace("Validation of Revocation List failed")
+			result.Success = false
+		}
+	}
+
 	//Validate Attestation Report signature
 	tokenRes, payload, ok := s.VerifyToken([]byte(attestationReport), roots)
 	result.ReportSignature = tokenRes.SignatureCheck
@@ -657,9 +874,18 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 		return false, &ar
 	}
 
+	if strict && arPacked.Type != "Attestation Report" {
+		msg := fmt.Sprintf("Attestation Report has invalid type '%v'", arPacked.Type)
+		result.ProcessingError = append(result.ProcessingError, msg)
+		log.Trace(msg)
+		result.Success = false
+		return false, &ar
+	}
+
 	ar.Type = "ArPlain"
 	ar.TpmM = arPacked.TpmM
 	ar.SnpM = arPacked.SnpM
+	ar.TdxM = arPacked.TdxM
 	ar.SWM = arPacked.SWM
 	ar.Nonce = arPacked.Nonce
 
@@ -677,12 +903,21 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 			result.RtmResult.Summary.setFalseMulti(&msg)
 			result.Success = false
 		} else {
+			if strict {
+				checkMetadataType(ar.RtmManifest.Type, "RTM Manifest", &result.RtmResult.Summary, result)
+			}
 			result.RtmResult.Name = ar.RtmManifest.Name
 			result.RtmResult.ValidityCheck = checkValidity(ar.RtmManifest.Validity)
 			if !result.RtmResult.ValidityCheck.Success {
 				result.RtmResult.Summary.Success = false
 				result.Success = false
 			}
+			result.RtmResult.RevocationCheck, ok = checkRevocation(rl, ar.RtmManifest.Name,
+				ar.RtmManifest.Version, arPacked.RtmManifest)
+			if !ok {
+				result.RtmResult.Summary.Success = false
+				result.Success = false
+			}
 		}
 	}
 
@@ -700,6 +935,9 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 			result.OsResult.Summary.setFalseMulti(&msg)
 			result.Success = false
 		} else {
+			if strict {
+				checkMetadataType(ar.OsManifest.Type, "OS Manifest", &result.OsResult.Summary, result)
+			}
 			result.OsResult.Name = ar.OsManifest.Name
 			result.RtmResult.ValidityCheck = checkValidity(ar.OsManifest.Validity)
 			result.OsResult.ValidityCheck = checkValidity(ar.OsManifest.Validity)
@@ -707,6 +945,12 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 				result.OsResult.Summary.Success = false
 				result.Success = false
 			}
+			result.OsResult.RevocationCheck, ok = checkRevocation(rl, ar.OsManifest.Name,
+				ar.OsManifest.Version, arPacked.OsManifest)
+			if !ok {
+				result.OsResult.Summary.Success = false
+				result.Success = false
+			}
 		}
 	}
 
@@ -728,6 +972,17 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 				result.AppResults[i].Summary.setFalseMulti(&msg)
 				result.Success = false
 			} else {
+				if strict {
+					checkMetadataType(am.Type, "App Manifest", &result.AppResults[i].Summary, result)
+					for _, prev := range ar.AppManifests {
+						if prev.Name == am.Name {
+							msg := fmt.Sprintf("Duplicate App Manifest %v", am.Name)
+							result.ProcessingError = append(result.ProcessingError, msg)
+							result.AppResults[i].Summary.setFalseMulti(&msg)
+							result.Success = false
+						}
+					}
+				}
 				ar.AppManifests = append(ar.AppManifests, am)
 				result.AppResults[i].Name = am.Name
 				result.AppResults[i].ValidityCheck = checkValidity(am.Validity)
@@ -737,6 +992,12 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 					result.Success = false
 
 				}
+				result.AppResults[i].RevocationCheck, ok = checkRevocation(rl, am.Name, am.Version, amSigned)
+				if !ok {
+					log.Trace("App Manifest revoked - " + am.Name)
+					result.AppResults[i].Summary.Success = false
+					result.Success = false
+				}
 			}
 		}
 	}
@@ -757,6 +1018,10 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 				result.CompDescResult.Summary.setFalseMulti(&msg)
 				result.Success = false
 			} else {
+				if strict {
+					checkMetadataType(ar.CompanyDescription.Type, "Company Description",
+						&result.CompDescResult.Summary, result)
+				}
 				result.CompDescResult.Name = ar.CompanyDescription.DN
 				result.CompDescResult.CompCertLevel = ar.CompanyDescription.CertificationLevel
 
@@ -766,6 +1031,13 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 					result.CompDescResult.Summary.Success = false
 					result.Success = false
 				}
+				result.CompDescResult.RevocationCheck, ok = checkRevocation(rl, ar.CompanyDescription.DN,
+					"", arPacked.CompanyDescription)
+				if !ok {
+					log.Trace("Company Description revoked")
+					result.CompDescResult.Summary.Success = false
+					result.Success = false
+				}
 			}
 		}
 	}
@@ -782,12 +1054,396 @@ func verifyAndUnpackAttestationReport(attestationReport string, result *Verifica
 		if err != nil {
 			msg := fmt.Sprintf("Unpacking of Device Description failed: %v", err)
 			result.DevDescResult.Summary.setFalseMulti(&msg)
+		} else {
+			if strict {
+				checkMetadataType(ar.DeviceDescription.Type, "Device Description",
+					&result.DevDescResult.Summary, result)
+			}
+			result.DevDescResult.RevocationCheck, ok = checkRevocation(rl, ar.DeviceDescription.Fqdn,
+				"", arPacked.DeviceDescription)
+			if !ok {
+				log.Trace("Device Description revoked")
+				result.DevDescResult.Summary.Success = false
+				result.Success = false
+			}
 		}
 	}
 
 	return true, &ar
 }
 
+// metadataTypes contains the types of the manifests and descriptions which can be
+// part of an attestation report
+var metadataTypes = []string{
+	"RTM Manifest", "OS Manifest", "App Manifest", "Company Description", "Device Description",
+}
+
+// checkMetadataType checks that the type of an unpacked manifest or description matches
+// its slot in the attestation report. Unknown and mismatching types are recorded as
+// processing errors and fail the verification
+func checkMetadataType(got, want string, summary *ResultMulti, result *VerificationResult) {
+	if got == want {
+		return
+	}
+	var msg string
+	if contains(got, metadataTypes) {
+		msg = fmt.Sprintf("%v placed in %v slot of Attestation Report", got, want)
+	} else {
+		msg = fmt.Sprintf("Unknown type '%v' in %v slot of Attestation Report", got, want)
+	}
+	result.ProcessingError = append(result.ProcessingError, msg)
+	summary.setFalseMulti(&msg)
+	result.Success = false
+}
+
+// verifyCompanyDescription verifies that the company operating the device is certified
+// for the certification level of the software stack and that the subject of the device
+// certificate matches the distinguished name of the Company Description
+func verifyCompanyDescription(cd *CompanyDescription, swCertLevel int, deviceCert *X509CertExtracted,
+	result *CompDescResult,
+) bool {
+	ok := true
+
+	if cd.CertificationLevel >= swCertLevel {
+		result.CertLevelCheck.Success = true
+	} else {
+		msg := fmt.Sprintf("Company %v is certified for level %v, but the software stack claims level %v",
+			cd.DN, cd.CertificationLevel, swCertLevel)
+		result.CertLevelCheck.setFalse(&msg)
+		ok = false
+	}
+
+	if deviceCert == nil {
+		msg := "Device certificate not available"
+		result.DeviceCertCheck.setFalse(&msg)
+		ok = false
+	} else if err := matchDn(cd.DN, deviceCert.Subject); err != nil {
+		msg := fmt.Sprintf("Device certificate %v not issued to company %v: %v",
+			deviceCert.Subject.CommonName, cd.DN, err)
+		result.DeviceCertCheck.setFalse(&msg)
+		ok = false
+	} else {
+		result.DeviceCertCheck.Success = true
+	}
+
+	if !ok {
+		result.Summary.Success = false
+	}
+
+	return ok
+}
+
+// verifyDeviceBinding verifies that the attributes specified in the device binding of
+// the Device Description match the subject or the subject alternative names of the
+// certificate which signed the attestation report
+func verifyDeviceBinding(dd *DeviceDescription, deviceCert *X509CertExtracted) *Result {
+	result := &Result{Success: true}
+	b := dd.Binding
+
+	if deviceCert == nil {
+		msg := "Device certificate not available"
+		result.setFalse(&msg)
+		return result
+	}
+
+	if !b.Fqdn && b.SerialNumber == "" && len(b.SubjectAltNames) == 0 {
+		msg := "Device binding of Device Description does not specify any attribute"
+		result.setFalse(&msg)
+		return result
+	}
+
+	mismatches := make([]string, 0)
+	if b.Fqdn {
+		if dd.Fqdn == "" {
+			mismatches = append(mismatches, "FQDN not specified")
+		} else if !strings.EqualFold(dd.Fqdn, deviceCert.Subject.CommonName) &&
+			!containsFold(dd.Fqdn, deviceCert.DNSNames) {
+			mismatches = append(mismatches, fmt.Sprintf("FQDN %v", dd.Fqdn))
+		}
+	}
+	if b.SerialNumber != "" && b.SerialNumber != deviceCert.Subject.SerialNumber {
+		mismatches = append(mismatches, fmt.Sprintf("serial number %v", b.SerialNumber))
+	}
+	for _, san := range b.SubjectAltNames {
+		if !containsFold(san, deviceCert.DNSNames) && !contains(san, deviceCert.IPAddresses) &&
+			!contains(san, deviceCert.EmailAddresses) && !contains(san, deviceCert.URIs) {
+			mismatches = append(mismatches, fmt.Sprintf("SAN %v", san))
+		}
+	}
+
+	if len(mismatches) > 0 {
+		msg := fmt.Sprintf("Device Description %v not bound to device certificate %v: %v",
+			dd.Fqdn, deviceCert.Subject.CommonName, strings.Join(mismatches, ", "))
+		result.setFalse(&msg)
+	}
+
+	return result
+}
+
+// getDeviceCert returns the leaf certificate of the first validated certificate chain
+// of the attestation report signature, i.e. the device certificate
+func getDeviceCert(sigs []SignatureResult) *X509CertExtracted {
+	for _, sig := range sigs {
+		if len(sig.ValidatedCerts) > 0 && len(sig.ValidatedCerts[0]) > 0 {
+			return &sig.ValidatedCerts[0][0]
+		}
+	}
+	return nil
+}
+
+// matchDn checks that the certificate subject matches the distinguished name. The
+// distinguished name can either be a string representation according to RFC 4514 (e.g.
+// "CN=device0,O=Test Organization"), of which all attributes must match, or the plain
+// name of the organization
+func matchDn(dn string, subject X509Name) error {
+	if !strings.Contains(dn, "=") {
+		if !containsFold(dn, subject.Organization) {
+			return fmt.Errorf("organization %v does not match", subject.Organization)
+		}
+		return nil
+	}
+
+	rdns, err := parseDn(dn)
+	if err != nil {
+		return fmt.Errorf("failed to parse distinguished name: %w", err)
+	}
+
+	// All attributes of all (multi-valued) RDNs must be present in the subject
+	for _, rdn := range rdns {
+		for _, attr := range rdn {
+			var values []string
+			switch strings.ToUpper(attr.Type) {
+			case "CN", "2.5.4.3":
+				values = []string{subject.CommonName}
+			case "O", "2.5.4.10":
+				values = subject.Organization
+			case "OU", "2.5.4.11":
+				values = subject.OrganizationalUnit
+			case "C", "2.5.4.6":
+				values = subject.Country
+			case "L", "2.5.4.7":
+				values = subject.Locality
+			case "ST", "2.5.4.8":
+				values = subject.Province
+			case "STREET", "2.5.4.9":
+				values = subject.StreetAddress
+			case "POSTALCODE", "2.5.4.17":
+				values = subject.PostalCode
+			case "SERIALNUMBER", "2.5.4.5":
+				values = []string{subject.SerialNumber}
+			default:
+				return fmt.Errorf("attribute %v not supported", attr.Type)
+			}
+			if !containsFold(attr.Value, values) {
+				return fmt.Errorf("attribute %v=%v does not match %v", attr.Type, attr.Value, values)
+			}
+		}
+	}
+	return nil
+}
+
+// dnAttribute is an attribute type and value pair of a relative distinguished name
+type dnAttribute struct {
+	Type  string
+	Value string
+}
+
+// parseDn parses the string representation of a distinguished name according to
+// RFC 4514 into its relative distinguished names (RDNs). Multi-valued RDNs are joined
+// by '+'. Special characters within values can be escaped with a backslash, either
+// directly or as hex pair. Hex-encoded (#) values are not supported
+func parseDn(dn string) ([][]dnAttribute, error) {
+	rdns := make([][]dnAttribute, 0)
+	rdn := make([]dnAttribute, 0)
+	buf := make([]byte, 0, len(dn))
+	attrType := ""
+	inType := true
+	// Length of the value without trailing unescaped spaces
+	end := 0
+
+	for i := 0; i < len(dn); i++ {
+		c := dn[i]
+
+		if inType {
+			switch c {
+			case '=':
+				attrType = strings.TrimSpace(string(buf))
+				if !isDnAttributeType(attrType) {
+					return nil, fmt.Errorf("invalid attribute type '%v'", attrType)
+				}
+				buf = buf[:0]
+				end = 0
+				inType = false
+				// Skip leading spaces of the value
+				for i+1 < len(dn) && dn[i+1] == ' ' {
+					i++
+				}
+				if i+1 < len(dn) && dn[i+1] == '#' {
+					return nil, fmt.Errorf("hex-encoded value of attribute %v not supported", attrType)
+				}
+			case ',', ';', '+':
+				return nil, fmt.Errorf("attribute '%v' without value", strings.TrimSpace(string(buf)))
+			default:
+				buf = append(buf, c)
+			}
+			continue
+		}
+
+		switch c {
+		case '\\':
+			if i+1 >= len(dn) {
+				return nil, fmt.Errorf("incomplete escape sequence in attribute %v", attrType)
+			}
+			if i+2 < len(dn) && isHexDigit(dn[i+1]) && isHexDigit(dn[i+2]) {
+				b, _ := hex.DecodeString(dn[i+1 : i+3])
+				buf = append(buf, b...)
+				i += 2
+			} else if strings.IndexByte("\\\",+;<>= #", dn[i+1]) >= 0 {
+				buf = append(buf, dn[i+1])
+				i++
+			} else {
+				return nil, fmt.Errorf("invalid escape sequence '\\%c' in attribute %v", dn[i+1], attrType)
+			}
+			end = len(buf)
+		case ',', ';', '+':
+			value := string(buf[:end])
+			if !utf8.ValidString(value) {
+				return nil, fmt.Errorf("value of attribute %v is not valid UTF-8", attrType)
+			}
+			rdn = append(rdn, dnAttribute{Type: attrType, Value: value})
+			if c != '+' {
+				rdns = append(rdns, rdn)
+				rdn = make([]dnAttribute, 0)
+			}
+			buf = buf[:0]
+			inType = true
+		case '"', '<', '>':
+			return nil, fmt.Errorf("unescaped character '%c' in attribute %v", c, attrType)
+		default:
+			buf = append(buf, c)
+			if c != ' ' {
+				end = len(buf)
+			}
+		}
+	}
+
+	if inType {
+		return nil, fmt.Errorf("attribute '%v' without value", strings.TrimSpace(string(buf)))
+	}
+	value := string(buf[:end])
+	if !utf8.ValidString(value) {
+		return nil, fmt.Errorf("value of attribute %v is not valid UTF-8", attrType)
+	}
+	rdn = append(rdn, dnAttribute{Type: attrType, Value: value})
+	rdns = append(rdns, rdn)
+
+	return rdns, nil
+}
+
+// isDnAttributeType checks whether the attribute type is a descriptor (e.g. "CN") or a
+// numeric OID (e.g. "2.5.4.3")
+func isDnAttributeType(t string) bool {
+	if t == "" {
+		return false
+	}
+	if t[0] >= '0' && t[0] <= '9' {
+		for _, c := range strings.Split(t, ".") {
+			if c == "" || strings.Trim(c, "0123456789") != "" {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		isAlpha := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isAlpha && (i == 0 || ((c < '0' || c > '9') && c != '-')) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// containsFold returns true if the list contains the element under case-folding
+func containsFold(elem string, list []string) bool {
+	for _, s := range list {
+		if strings.EqualFold(s, elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyRevocationList verifies the signature and validity of the signed revocation list.
+// The revocation list is only returned if it is valid
+func verifyRevocationList(data []byte, roots []*x509.Certificate, s Serializer) (*ManifestResult, *RevocationList) {
+	result := &ManifestResult{}
+
+	tokenRes, payload, ok := s.VerifyToken(data, roots)
+	result.Summary = tokenRes.Summary
+	result.SignatureCheck = tokenRes.SignatureCheck
+	if !ok {
+		return result, nil
+	}
+
+	rl := &RevocationList{}
+	err := s.Unmarshal(payload, rl)
+	if err != nil {
+		msg := fmt.Sprintf("Unpacking of Revocation List failed: %v", err)
+		result.Summary.setFalseMulti(&msg)
+		return result, nil
+	}
+	result.Name = rl.Name
+
+	if rl.Type != "Revocation List" {
+		msg := fmt.Sprintf("Revocation List has invalid type %v", rl.Type)
+		result.Summary.setFalseMulti(&msg)
+		return result, nil
+	}
+
+	result.ValidityCheck = checkValidity(rl.Validity)
+	if !result.ValidityCheck.Success {
+		result.Summary.Success = false
+		return result, nil
+	}
+
+	return result, rl
+}
+
+// checkRevocation checks whether the manifest or description is revoked by the revocation
+// list, either by its name and version or by the hash of its signed token. If no revocation
+// list is present, no result is returned
+func checkRevocation(rl *RevocationList, name, version string, token []byte) (*Result, bool) {
+	if rl == nil {
+		return nil, true
+	}
+
+	result := &Result{}
+	result.Success = true
+
+	hash := sha256.Sum256(token)
+	for _, r := range rl.Revoked {
+		nameMatch := r.Name != "" && r.Name == name && (r.Version == "" || r.Version == version)
+		hashMatch := len(r.Sha256) > 0 && bytes.Equal(r.Sha256, hash[:])
+		if !nameMatch && !hashMatch {
+			continue
+		}
+		msg := fmt.Sprintf("Revocation check failed: %v (version %v) revoked by Revocation List %v (version %v)",
+			name, version, rl.Name, rl.Version)
+		if r.Reason != "" {
+			msg = fmt.Sprintf("%v: %v", msg, r.Reason)
+		}
+		result.setFalse(&msg)
+		return result, false
+	}
+
+	return result, true
+}
+
 func checkValidity(val Validity) Result {
 	result := Result{}
 	result.Success = true
@@ -819,41 +1475,44 @@ func checkValidity(val Validity) Result {
 	return result
 }
 
-// Searches for a specific hash value in the reference values for RTM and OS
-func getReferenceValue(hash []byte, referenceValues []ReferenceValue) *ReferenceValue {
-	for _, ver := range referenceValues {
-		if bytes.Equal(ver.Sha256, hash) {
-			return &ver
-		}
-	}
-	return nil
-}
-
 func verifySwMeasurements(swMeasurements []SwMeasurement, referenceValues []ReferenceValue) ([]SwMeasurementResult, bool) {
 
 	swMeasurementResults := make([]SwMeasurementResult, 0)
 	ok := true
 
-	// Check that every reference value is reflected by a measurement
-	for _, v := range referenceValues {
+	// Check that every reference value is reflected by a measurement. Optional reference
+	// values are not required to be measured, of each alternative group one reference
+	// value must be measured
+	selected, _, missing := selectReferenceValues(referenceValues, func(v *ReferenceValue) bool {
+		return getSwMeasurement(v, swMeasurements) != nil
+	})
+	for _, v := range selected {
+		swm := getSwMeasurement(&v, swMeasurements)
+		if swm == nil {
+			continue
+		}
 		swRes := SwMeasurementResult{}
-		swRes.Validation.Success = false
 		swRes.VerName = v.Name
-		found := false
-		for _, swm := range swMeasurements {
-			if bytes.Equal(swm.Sha256, v.Sha256) {
-				swRes.MeasName = swm.Name
-				swRes.Validation.Success = true
-				found = true
-				break
-			}
-		}
-		if !found {
-			msg := fmt.Sprintf("no SW Measurement found for SW Reference Value %v (hash: %v)", v.Name, v.Sha256)
-			swRes.Validation.setFalse(&msg)
-			ok = false
+		swRes.MeasName = swm.Name
+		swRes.AlternativeGroup = v.AlternativeGroup
+		swRes.Validation.Success = true
+		swMeasurementResults = append(swMeasurementResults, swRes)
+	}
+	for _, set := range missing {
+		swRes := SwMeasurementResult{}
+		swRes.Validation.Success = false
+		var msg string
+		if set.group == "" {
+			v := referenceValues[set.indices[0]]
+			swRes.VerName = v.Name
+			msg = fmt.Sprintf("no SW Measurement found for SW Reference Value %v (hash: %v)", v.Name, v.Sha256)
+		} else {
+			swRes.AlternativeGroup = set.group
+			msg = fmt.Sprintf("no SW Measurement found for any SW Reference Value of %v", set.String(referenceValues))
 		}
+		swRes.Validation.setFalse(&msg)
 		swMeasurementResults = append(swMeasurementResults, swRes)
+		ok = false
 	}
 
 	// Check that every measurement is reflected by a reference value
@@ -878,6 +1537,17 @@ func verifySwMeasurements(swMeasurements []SwMeasurement, referenceValues []Refe
 	return swMeasurementResults, ok
 }
 
+// getSwMeasurement returns the SW measurement matching the reference value or nil
+// if the reference value was not measured
+func getSwMeasurement(v *ReferenceValue, swMeasurements []SwMeasurement) *SwMeasurement {
+	for i := range swMeasurements {
+		if bytes.Equal(swMeasurements[i].Sha256, v.Sha256) {
+			return &swMeasurements[i]
+		}
+	}
+	return nil
+}
+
 func collectReferenceValues(ar *ArPlain) (map[string][]ReferenceValue, error) {
 	// Gather a list of all reference values independent of the type
 	verList := append(ar.RtmManifest.ReferenceValues, ar.OsManifest.ReferenceValues...)
@@ -889,11 +1559,18 @@ func collectReferenceValues(ar *ArPlain) (map[string][]ReferenceValue, error) {
 
 	// Iterate through the reference values and sort them into the different types
 	for _, v := range verList {
-		if v.Type != "SNP Reference Value" && v.Type != "SW Reference Value" && v.Type != "TPM Reference Value" {
+		if v.Type != "SNP Reference Value" && v.Type != "TDX Reference Value" &&
+			v.Type != "SW Reference Value" && v.Type != "TPM Reference Value" {
 			return nil, fmt.Errorf("reference value of type %v is not supported", v.Type)
 		}
 		verMap[v.Type] = append(verMap[v.Type], v)
 	}
+
+	err := checkAlternativeGroups(verMap["TPM Reference Value"])
+	if err != nil {
+		return nil, err
+	}
+
 	return verMap, nil
 }
 
@@ -929,6 +1606,26 @@ func checkExtensionUint8(cert *x509.Certificate, oid string, value uint8) error
 	return fmt.Errorf("extension %v not present in certificate", oid)
 }
 
+func getExtensionString(cert *x509.Certificate, oid string) (string, error) {
+
+	for _, ext := range cert.Extensions {
+
+		if ext.Id.String() == oid {
+			var value string
+			rest, err := asn1.UnmarshalWithParams(ext.Value, &value, "ia5")
+			if err != nil {
+				return "", fmt.Errorf("failed to unmarshal extension %v: %w", oid, err)
+			}
+			if len(rest) != 0 {
+				return "", fmt.Errorf("extension %v contains trailing data", oid)
+			}
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("extension %v not present in certificate", oid)
+}
+
 func checkExtensionBuf(cert *x509.Certificate, oid string, buf []byte) error {
 
 	for _, ext := range cert.Extensions {
@@ -961,6 +1658,17 @@ func (r *Result) setFalse(msg *string) {
 	}
 }
 
+// appendFalse sets the result to false and appends the message to the details
+// of previous failures, e.g. if the same check is performed multiple times
+func (r *Result) appendFalse(msg *string) {
+	if r.Success || r.Details == "" || msg == nil {
+		r.setFalse(msg)
+		return
+	}
+	details := r.Details + "; " + *msg
+	r.setFalse(&details)
+}
+
 func (r *ResultMulti) setFalseMulti(msg *string) {
 	r.Success = false
 	if msg != nil {
@@ -20,6 +20,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"fmt"
@@ -83,6 +84,32 @@ func (s *SwSigner) GetCertChain() []*x509.Certificate {
 	return s.certChain
 }
 
+// lockingSigner records whether the keys and the certificate chain were retrieved
+// without holding the lock
+type lockingSigner struct {
+	SwSigner
+	locked   bool
+	unlocked bool
+}
+
+func (s *lockingSigner) Lock() {
+	s.locked = true
+}
+
+func (s *lockingSigner) Unlock() {
+	s.locked = false
+}
+
+func (s *lockingSigner) GetSigningKeys() (crypto.PrivateKey, crypto.PublicKey, error) {
+	s.unlocked = s.unlocked || !s.locked
+	return s.SwSigner.GetSigningKeys()
+}
+
+func (s *lockingSigner) GetCertChain() []*x509.Certificate {
+	s.unlocked = s.unlocked || !s.locked
+	return s.SwSigner.GetCertChain()
+}
+
 func createCertsAndKeys() (*ecdsa.PrivateKey, []*x509.Certificate, error) {
 
 	// Generate private key and public key for test CA
@@ -197,7 +224,7 @@ func TestVerify(t *testing.T) {
 
 			// Preparation: Generate and sign attestation report
 			var metadata [][]byte
-			a, err := Generate(nonce, metadata, []Measurement{}, tt.args.serializer)
+			a, err := Generate(nonce, metadata, []Measurement{}, false, tt.args.serializer)
 			if err != nil {
 				t.Errorf("Preparation failed: %v", err)
 			}
@@ -210,7 +237,7 @@ func TestVerify(t *testing.T) {
 			got := Verify(
 				string(ar), nonce,
 				internal.WriteCertPem(certchain[len(certchain)-1]),
-				nil, 0, tt.args.serializer)
+				nil, 0, nil, nil, false, tt.args.serializer)
 			if got.Success != tt.want.Success {
 				t.Errorf("Result.Success = %v, want %v", got.Success, tt.want.Success)
 			}
@@ -218,6 +245,556 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+func TestGenerateStrict(t *testing.T) {
+	key, certchain, err := createCertsAndKeys()
+	if err != nil {
+		t.Fatalf("Failed to create testing certs and keys: %v", err)
+	}
+	swSigner := &SwSigner{
+		priv:      key,
+		certChain: certchain,
+	}
+	s := JsonSerializer{}
+
+	sign := func(v any) []byte {
+		data, err := s.Marshal(v)
+		if err != nil {
+			t.Fatalf("Failed to marshal metadata: %v", err)
+		}
+		signed, err := s.Sign(data, swSigner)
+		if err != nil {
+			t.Fatalf("Failed to sign metadata: %v", err)
+		}
+		return signed
+	}
+
+	rtm := sign(RtmManifest{Type: "RTM Manifest", Name: "rtm"})
+	osm := sign(OsManifest{Type: "OS Manifest", Name: "os"})
+	app := sign(AppManifest{Type: "App Manifest", Name: "app"})
+	dd := sign(DeviceDescription{Type: "Device Description", Fqdn: "device"})
+	unknown := sign(Type{Type: "Unknown Manifest"})
+
+	tests := []struct {
+		name      string
+		metadata  [][]byte
+		strict    bool
+		wantError bool
+	}{
+		{"Valid Strict", [][]byte{rtm, osm, app, app, dd}, true, false},
+		{"Duplicate OS Manifest", [][]byte{rtm, osm, osm, dd}, false, false},
+		{"Duplicate OS Manifest Strict", [][]byte{rtm, osm, osm, dd}, true, true},
+		{"Duplicate Device Description Strict", [][]byte{rtm, osm, dd, dd}, true, true},
+		{"Unknown Type", [][]byte{rtm, osm, dd, unknown}, false, false},
+		{"Unknown Type Strict", [][]byte{rtm, osm, dd, unknown}, true, true},
+		{"Unparseable", [][]byte{rtm, osm, dd, []byte("invalid")}, false, false},
+		{"Unparseable Strict", [][]byte{rtm, osm, dd, []byte("invalid")}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate(make([]byte, 8), tt.metadata, []Measurement{}, tt.strict, s)
+			if (err != nil) != tt.wantError {
+				t.Errorf("Generate() error = %v, wantError %v", err, tt.wantError)
+			}
+		})
+	}
+}
+
+func TestVerifyStrict(t *testing.T) {
+	key, certchain, err := createCertsAndKeys()
+	if err != nil {
+		t.Fatalf("Failed to create testing certs and keys: %v", err)
+	}
+	swSigner := &SwSigner{
+		priv:      key,
+		certChain: certchain,
+	}
+	ca := internal.WriteCertPem(certchain[len(certchain)-1])
+	s := JsonSerializer{}
+
+	sign := func(v any) []byte {
+		data, err := s.Marshal(v)
+		if err != nil {
+			t.Fatalf("Failed to marshal data: %v", err)
+		}
+		signed, err := s.Sign(data, swSigner)
+		if err != nil {
+			t.Fatalf("Failed to sign data: %v", err)
+		}
+		return signed
+	}
+
+	rtm := sign(RtmManifest{Type: "RTM Manifest", Name: "rtm"})
+	osm := sign(OsManifest{Type: "OS Manifest", Name: "os"})
+	app := sign(AppManifest{Type: "App Manifest", Name: "app"})
+	dd := sign(DeviceDescription{Type: "Device Description", Fqdn: "device"})
+	unknown := sign(Type{Type: "Unknown Manifest"})
+	nonce := make([]byte, 8)
+
+	tests := []struct {
+		name      string
+		ar        ArPacked
+		wantError string
+	}{
+		{
+			name: "Valid",
+			ar:   ArPacked{Type: "Attestation Report", RtmManifest: rtm, OsManifest: osm, DeviceDescription: dd, Nonce: nonce},
+		},
+		{
+			name:      "Invalid Report Type",
+			ar:        ArPacked{Type: "Other Report", RtmManifest: rtm, OsManifest: osm, DeviceDescription: dd, Nonce: nonce},
+			wantError: "Attestation Report has invalid type 'Other Report'",
+		},
+		{
+			name:      "Mismatching Type",
+			ar:        ArPacked{Type: "Attestation Report", RtmManifest: osm, OsManifest: osm, DeviceDescription: dd, Nonce: nonce},
+			wantError: "OS Manifest placed in RTM Manifest slot of Attestation Report",
+		},
+		{
+			name:      "Unknown Type",
+			ar:        ArPacked{Type: "Attestation Report", RtmManifest: rtm, OsManifest: unknown, DeviceDescription: dd, Nonce: nonce},
+			wantError: "Unknown type 'Unknown Manifest' in OS Manifest slot of Attestation Report",
+		},
+		{
+			name: "Duplicate App Manifest",
+			ar: ArPacked{Type: "Attestation Report", RtmManifest: rtm, OsManifest: osm,
+				AppManifests: [][]byte{app, app}, DeviceDescription: dd, Nonce: nonce},
+			wantError: "Duplicate App Manifest app",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := sign(tt.ar)
+
+			got := Verify(string(report), nonce, ca, nil, 0, nil, nil, true, s)
+			if tt.wantError == "" {
+				if len(got.ProcessingError) != 0 {
+					t.Errorf("Verify() processing errors = %v, want none", got.ProcessingError)
+				}
+				return
+			}
+			if got.Success {
+				t.Errorf("Verify() succeeded, want failure")
+			}
+			if !contains(tt.wantError, got.ProcessingError) {
+				t.Errorf("Verify() processing errors = %v, want %v", got.ProcessingError, tt.wantError)
+			}
+
+			// Without strict mode, the type checks are skipped
+			got = Verify(string(report), nonce, ca, nil, 0, nil, nil, false, s)
+			if contains(tt.wantError, got.ProcessingError) {
+				t.Errorf("Verify() non-strict processing errors = %v", got.ProcessingError)
+			}
+		})
+	}
+}
+
+func Test_verifyRevocationList(t *testing.T) {
+	key, certchain, err := createCertsAndKeys()
+	if err != nil {
+		t.Fatalf("Failed to create testing certs and keys: %v", err)
+	}
+	swSigner := &SwSigner{
+		priv:      key,
+		certChain: certchain,
+	}
+	ca := certchain[len(certchain)-1]
+
+	_, otherChain, err := createCertsAndKeys()
+	if err != nil {
+		t.Fatalf("Failed to create testing certs and keys: %v", err)
+	}
+
+	now := time.Now()
+	validity := Validity{
+		NotBefore: now.Add(-time.Hour).Format(timeLayout),
+		NotAfter:  now.Add(time.Hour).Format(timeLayout),
+	}
+	expired := Validity{
+		NotBefore: now.Add(-2 * time.Hour).Format(timeLayout),
+		NotAfter:  now.Add(-time.Hour).Format(timeLayout),
+	}
+
+	tests := []struct {
+		name  string
+		rl    RevocationList
+		roots []*x509.Certificate
+		want  bool
+	}{
+		{
+			name:  "Valid Revocation List",
+			rl:    RevocationList{Type: "Revocation List", Name: "rl", Validity: validity},
+			roots: []*x509.Certificate{ca},
+			want:  true,
+		},
+		{
+			name:  "Expired Revocation List",
+			rl:    RevocationList{Type: "Revocation List", Name: "rl", Validity: expired},
+			roots: []*x509.Certificate{ca},
+			want:  false,
+		},
+		{
+			name:  "Invalid Type",
+			rl:    RevocationList{Type: "OS Manifest", Name: "rl", Validity: validity},
+			roots: []*x509.Certificate{ca},
+			want:  false,
+		},
+		{
+			name:  "Untrusted Signer",
+			rl:    RevocationList{Type: "Revocation List", Name: "rl", Validity: validity},
+			roots: []*x509.Certificate{otherChain[len(otherChain)-1]},
+			want:  false,
+		},
+	}
+
+	s := JsonSerializer{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := s.Marshal(tt.rl)
+			if err != nil {
+				t.Fatalf("Failed to marshal revocation list: %v", err)
+			}
+			signed, err := Sign(data, swSigner, s)
+			if err != nil {
+				t.Fatalf("Failed to sign revocation list: %v", err)
+			}
+
+			result, rl := verifyRevocationList(signed, tt.roots, s)
+			if (rl != nil) != tt.want {
+				t.Errorf("verifyRevocationList() = %v, want %v: %v", rl != nil, tt.want, result)
+			}
+		})
+	}
+}
+
+func Test_checkRevocation(t *testing.T) {
+	token := []byte("signed os manifest")
+	hash := sha256.Sum256(token)
+
+	tests := []struct {
+		name    string
+		rl      *RevocationList
+		version string
+		want    bool
+	}{
+		{
+			name:    "No Revocation List",
+			rl:      nil,
+			version: "1.0",
+			want:    true,
+		},
+		{
+			name: "Not Revoked",
+			rl: &RevocationList{Revoked: []RevokedArtifact{
+				{Name: "de.fraunhofer.aisec.os", Version: "0.9"},
+				{Name: "de.fraunhofer.aisec.other"},
+			}},
+			version: "1.0",
+			want:    true,
+		},
+		{
+			name: "Revoked Version",
+			rl: &RevocationList{Revoked: []RevokedArtifact{
+				{Name: "de.fraunhofer.aisec.os", Version: "1.0", Reason: "vulnerable kernel"},
+			}},
+			version: "1.0",
+			want:    false,
+		},
+		{
+			name: "Revoked All Versions",
+			rl: &RevocationList{Revoked: []RevokedArtifact{
+				{Name: "de.fraunhofer.aisec.os"},
+			}},
+			version: "1.0",
+			want:    false,
+		},
+		{
+			name: "Revoked Hash",
+			rl: &RevocationList{Revoked: []RevokedArtifact{
+				{Sha256: hash[:]},
+			}},
+			version: "1.0",
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, got := checkRevocation(tt.rl, "de.fraunhofer.aisec.os", tt.version, token)
+			if got != tt.want {
+				t.Errorf("checkRevocation() = %v, want %v: %v", got, tt.want, result)
+			}
+			if (result == nil) != (tt.rl == nil) {
+				t.Errorf("checkRevocation() result = %v", result)
+			}
+		})
+	}
+}
+
+func Test_verifyCompanyDescription(t *testing.T) {
+	deviceCert := &X509CertExtracted{
+		Subject: X509Name{
+			CommonName:   "de.test.ik.device0",
+			Organization: []string{"Test Organization"},
+			Country:      []string{"DE"},
+		},
+	}
+	incDeviceCert := &X509CertExtracted{
+		Subject: X509Name{
+			CommonName:         "de.test.ik.device0",
+			Organization:       []string{"Test Organization, Inc."},
+			OrganizationalUnit: []string{"Device"},
+			Country:            []string{"DE"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		cd          *CompanyDescription
+		swCertLevel int
+		deviceCert  *X509CertExtracted
+		want        bool
+	}{
+		{
+			name:        "Matching Organization",
+			cd:          &CompanyDescription{DN: "Test Organization", CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  deviceCert,
+			want:        true,
+		},
+		{
+			name:        "Matching Distinguished Name",
+			cd:          &CompanyDescription{DN: "O=test organization, C=DE", CertificationLevel: 3},
+			swCertLevel: 1,
+			deviceCert:  deviceCert,
+			want:        true,
+		},
+		{
+			name:        "Insufficient Certification Level",
+			cd:          &CompanyDescription{DN: "Test Organization", CertificationLevel: 1},
+			swCertLevel: 2,
+			deviceCert:  deviceCert,
+			want:        false,
+		},
+		{
+			name:        "Other Organization",
+			cd:          &CompanyDescription{DN: "Other Organization", CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  deviceCert,
+			want:        false,
+		},
+		{
+			name:        "Other Country",
+			cd:          &CompanyDescription{DN: "O=Test Organization,C=US", CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  deviceCert,
+			want:        false,
+		},
+		{
+			name:        "Unsupported Attribute",
+			cd:          &CompanyDescription{DN: "O=Test Organization,UID=1", CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  deviceCert,
+			want:        false,
+		},
+		{
+			name:        "Escaped Comma",
+			cd:          &CompanyDescription{DN: `O=Test Organization\, Inc.,C=DE`, CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  incDeviceCert,
+			want:        true,
+		},
+		{
+			name:        "Unescaped Comma",
+			cd:          &CompanyDescription{DN: "O=Test Organization, Inc.,C=DE", CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  incDeviceCert,
+			want:        false,
+		},
+		{
+			name:        "Multi-Valued RDN",
+			cd:          &CompanyDescription{DN: `OU=Device+CN=de.test.ik.device0,O=Test Organization\2C Inc.`, CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  incDeviceCert,
+			want:        true,
+		},
+		{
+			name:        "Missing Device Certificate",
+			cd:          &CompanyDescription{DN: "Test Organization", CertificationLevel: 3},
+			swCertLevel: 3,
+			deviceCert:  nil,
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &CompDescResult{}
+			if got := verifyCompanyDescription(tt.cd, tt.swCertLevel, tt.deviceCert, result); got != tt.want {
+				t.Errorf("verifyCompanyDescription() = %v, want %v: %v", got, tt.want, result)
+			}
+		})
+	}
+}
+
+func Test_parseDn(t *testing.T) {
+	tests := []struct {
+		name    string
+		dn      string
+		want    [][]dnAttribute
+		wantErr bool
+	}{
+		{
+			name: "Simple",
+			dn:   "CN=device0,O=Test Organization",
+			want: [][]dnAttribute{{{"CN", "device0"}}, {{"O", "Test Organization"}}},
+		},
+		{
+			name: "Spaces",
+			dn:   " CN = device0 , O= Test Organization ",
+			want: [][]dnAttribute{{{"CN", "device0"}}, {{"O", "Test Organization"}}},
+		},
+		{
+			name: "Escaped Characters",
+			dn:   `O=Foo\, Inc.,CN=\ a\+b\\c\ `,
+			want: [][]dnAttribute{{{"O", "Foo, Inc."}}, {{"CN", ` a+b\c `}}},
+		},
+		{
+			name: "Hex Pairs",
+			dn:   `O=Foo\2C Inc.,L=M\C3\BCnchen`,
+			want: [][]dnAttribute{{{"O", "Foo, Inc."}}, {{"L", "München"}}},
+		},
+		{
+			name: "Multi-Valued RDN",
+			dn:   "OU=Device+CN=device0,2.5.4.10=Test",
+			want: [][]dnAttribute{{{"OU", "Device"}, {"CN", "device0"}}, {{"2.5.4.10", "Test"}}},
+		},
+		{
+			name:    "Missing Value",
+			dn:      "CN=device0,O",
+			wantErr: true,
+		},
+		{
+			name:    "Trailing Separator",
+			dn:      "CN=device0,",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Escape",
+			dn:      `CN=dev\ice0`,
+			wantErr: true,
+		},
+		{
+			name:    "Unescaped Quote",
+			dn:      `CN="device0"`,
+			wantErr: true,
+		},
+		{
+			name:    "Hex Value",
+			dn:      "CN=#04024869",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDn(tt.dn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDn() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseDn() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_verifyDeviceBinding(t *testing.T) {
+	deviceCert := &X509CertExtracted{
+		Subject: X509Name{
+			CommonName:   "de.test.ik.device0",
+			SerialNumber: "4711",
+		},
+		DNSNames:    []string{"device0.test.de"},
+		IPAddresses: []string{"10.0.0.1"},
+	}
+
+	tests := []struct {
+		name       string
+		fqdn       string
+		binding    DeviceBinding
+		deviceCert *X509CertExtracted
+		want       bool
+	}{
+		{
+			name:       "FQDN Matches Common Name",
+			fqdn:       "de.test.ik.device0",
+			binding:    DeviceBinding{Fqdn: true},
+			deviceCert: deviceCert,
+			want:       true,
+		},
+		{
+			name:       "FQDN Matches DNS SAN",
+			fqdn:       "Device0.Test.De",
+			binding:    DeviceBinding{Fqdn: true},
+			deviceCert: deviceCert,
+			want:       true,
+		},
+		{
+			name:       "All Attributes Match",
+			fqdn:       "device0.test.de",
+			binding:    DeviceBinding{Fqdn: true, SerialNumber: "4711", SubjectAltNames: []string{"10.0.0.1"}},
+			deviceCert: deviceCert,
+			want:       true,
+		},
+		{
+			name:       "FQDN Mismatch",
+			fqdn:       "device1.test.de",
+			binding:    DeviceBinding{Fqdn: true},
+			deviceCert: deviceCert,
+			want:       false,
+		},
+		{
+			name:       "Missing FQDN",
+			binding:    DeviceBinding{Fqdn: true},
+			deviceCert: deviceCert,
+			want:       false,
+		},
+		{
+			name:       "Serial Number Mismatch",
+			fqdn:       "device0.test.de",
+			binding:    DeviceBinding{SerialNumber: "0815"},
+			deviceCert: deviceCert,
+			want:       false,
+		},
+		{
+			name:       "SAN Mismatch",
+			fqdn:       "device0.test.de",
+			binding:    DeviceBinding{SubjectAltNames: []string{"10.0.0.2"}},
+			deviceCert: deviceCert,
+			want:       false,
+		},
+		{
+			name:       "Empty Binding",
+			fqdn:       "device0.test.de",
+			binding:    DeviceBinding{},
+			deviceCert: deviceCert,
+			want:       false,
+		},
+		{
+			name:       "Missing Device Certificate",
+			fqdn:       "device0.test.de",
+			binding:    DeviceBinding{Fqdn: true},
+			deviceCert: nil,
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dd := &DeviceDescription{Fqdn: tt.fqdn, Binding: &tt.binding}
+			if got := verifyDeviceBinding(dd, tt.deviceCert); got.Success != tt.want {
+				t.Errorf("verifyDeviceBinding() = %v, want %v (%v)", got.Success, tt.want, got.Details)
+			}
+		})
+	}
+}
+
 func Test_collectReferenceValues(t *testing.T) {
 	type args struct {
 		ar *ArPlain
@@ -240,6 +817,40 @@ func Test_collectReferenceValues(t *testing.T) {
 			want:    verMap,
 			wantErr: false,
 		},
+		{
+			name: "Alternative Group Same PCR",
+			args: args{
+				ar: &ArPlain{
+					OsManifest: OsManifest{
+						ReferenceValues: []ReferenceValue{
+							{Type: "TPM Reference Value", Name: "boot v1", Pcr: &pcrs[4], AlternativeGroup: "boot"},
+							{Type: "TPM Reference Value", Name: "boot v2", Pcr: &pcrs[4], AlternativeGroup: "boot"},
+						},
+					},
+				},
+			},
+			want: map[string][]ReferenceValue{
+				"TPM Reference Value": {
+					{Type: "TPM Reference Value", Name: "boot v1", Pcr: &pcrs[4], AlternativeGroup: "boot"},
+					{Type: "TPM Reference Value", Name: "boot v2", Pcr: &pcrs[4], AlternativeGroup: "boot"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Alternative Group Spanning PCRs",
+			args: args{
+				ar: &ArPlain{
+					OsManifest: OsManifest{
+						ReferenceValues: []ReferenceValue{
+							{Type: "TPM Reference Value", Name: "boot v1", Pcr: &pcrs[4], AlternativeGroup: "boot"},
+							{Type: "TPM Reference Value", Name: "boot v2", Pcr: &pcrs[5], AlternativeGroup: "boot"},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -254,3 +865,60 @@ func Test_collectReferenceValues(t *testing.T) {
 		})
 	}
 }
+
+func TestSignLocking(t *testing.T) {
+	key, certchain, err := createCertsAndKeys()
+	if err != nil {
+		t.Fatalf("Failed to create testing certs and keys: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		sign func(signer Signer) error
+	}{
+		{
+			name: "JSON",
+			sign: func(signer Signer) error {
+				_, err := JsonSerializer{}.Sign([]byte("{}"), signer)
+				return err
+			},
+		},
+		{
+			name: "CBOR",
+			sign: func(signer Signer) error {
+				_, err := CborSerializer{}.Sign([]byte{0xa0}, signer)
+				return err
+			},
+		},
+		{
+			name: "EAR JWT",
+			sign: func(signer Signer) error {
+				_, err := SignEar(&Ear{Profile: EarProfile}, ResultFormat_EarJwt, signer)
+				return err
+			},
+		},
+		{
+			name: "EAR CWT",
+			sign: func(signer Signer) error {
+				_, err := SignEar(&Ear{Profile: EarProfile}, ResultFormat_EarCwt, signer)
+				return err
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := &lockingSigner{
+				SwSigner: SwSigner{priv: key, certChain: certchain},
+			}
+			if err := tt.sign(signer); err != nil {
+				t.Fatalf("Sign() error = %v", err)
+			}
+			if signer.unlocked {
+				t.Errorf("Sign() retrieved signing keys without holding the lock")
+			}
+			if signer.locked {
+				t.Errorf("Sign() did not release the lock")
+			}
+		})
+	}
+}
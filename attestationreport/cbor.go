@@ -56,6 +56,12 @@ func (s CborSerializer) Unmarshal(data []byte, v any) error {
 
 func (s CborSerializer) Sign(report []byte, signer Signer) ([]byte, error) {
 
+	// This allows the signer to ensure mutual access for signing, if required. The keys
+	// and the certificate chain must be retrieved while holding the lock, as the signer
+	// might renew them in the meantime
+	signer.Lock()
+	defer signer.Unlock()
+
 	private, _, err := signer.GetSigningKeys()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get signing keys: %w", err)
@@ -88,10 +94,6 @@ func (s CborSerializer) Sign(report []byte, signer Signer) ([]byte, error) {
 	msgToSign.Payload = report
 	msgToSign.Signatures = append(msgToSign.Signatures, sigHolder)
 
-	// This allows the signer to ensure mutual access for signing, if required
-	signer.Lock()
-	defer signer.Unlock()
-
 	err = msgToSign.Sign(rand.Reader, nil, coseSigner)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign cbor object: %w", err)
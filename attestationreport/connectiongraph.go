@@ -0,0 +1,155 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"fmt"
+)
+
+// maxPort is the highest valid TCP/UDP port
+const maxPort = 65535
+
+// verifyConnectionGraph verifies that the internal connections and external interfaces
+// of the Device Description only reference existing App Descriptions, endpoints provided
+// by the linked App Manifests and network interfaces declared by the Device Description.
+// The app endpoints the device may expose are returned as part of the result
+func verifyConnectionGraph(dd *DeviceDescription, appManifests []AppManifest) ConnectionGraphResult {
+	result := ConnectionGraphResult{
+		Summary:       ResultMulti{Success: true},
+		InternalCheck: ResultMulti{Success: true},
+		ExternalCheck: ResultMulti{Success: true},
+	}
+
+	// Resolve the App Manifest of each App Description. Apps whose App Manifest is
+	// not present are recorded as well, so that references to them can be reported
+	apps := make(map[string]*AppManifest)
+	for _, a := range dd.AppDescriptions {
+		if _, ok := apps[a.Name]; ok {
+			msg := fmt.Sprintf("Device Description lists App Description %v multiple times", a.Name)
+			result.Summary.setFalseMulti(&msg)
+			continue
+		}
+		apps[a.Name] = nil
+		for i := range appManifests {
+			if appManifests[i].Name == a.AppManifest {
+				apps[a.Name] = &appManifests[i]
+				break
+			}
+		}
+	}
+
+	// Check that both sides of each internal connection exist
+	for _, c := range dd.Internal {
+		for _, side := range [][2]string{{c.NameAppA, c.EndpointAppA}, {c.NameAppB, c.EndpointAppB}} {
+			if err := checkEndpoint(apps, side[0], side[1]); err != nil {
+				msg := fmt.Sprintf("Internal connection %v:%v <-> %v:%v invalid: %v",
+					c.NameAppA, c.EndpointAppA, c.NameAppB, c.EndpointAppB, err)
+				result.InternalCheck.setFalseMulti(&msg)
+			}
+		}
+	}
+
+	// Check the network interfaces declared by the Device Description
+	for _, d := range dd.External {
+		if d.Interface == "" {
+			msg := "External interface of Device Description does not specify a network interface"
+			result.ExternalCheck.setFalseMulti(&msg)
+		}
+		if d.Port < 0 || d.Port > maxPort {
+			msg := fmt.Sprintf("External interface %v of Device Description specifies invalid port %v",
+				d.Interface, d.Port)
+			result.ExternalCheck.setFalseMulti(&msg)
+		}
+		if d.AppEndpoint != "" && !isProvidedEndpoint(apps, d.AppEndpoint) {
+			msg := fmt.Sprintf("External interface %v of Device Description references endpoint %v which is not provided by any app",
+				d.Interface, d.AppEndpoint)
+			result.ExternalCheck.setFalseMulti(&msg)
+		}
+	}
+
+	// Check that the apps only expose existing endpoints on declared interfaces and ports
+	for _, a := range dd.AppDescriptions {
+		for _, e := range a.External {
+			if err := checkEndpoint(apps, a.Name, e.AppEndpoint); err != nil {
+				msg := fmt.Sprintf("External interface %v:%v of app %v invalid: %v",
+					e.Interface, e.Port, a.Name, err)
+				result.ExternalCheck.setFalseMulti(&msg)
+				continue
+			}
+			if e.Port <= 0 || e.Port > maxPort {
+				msg := fmt.Sprintf("External interface %v of app %v specifies invalid port %v",
+					e.Interface, a.Name, e.Port)
+				result.ExternalCheck.setFalseMulti(&msg)
+				continue
+			}
+			if !isDeclaredInterface(dd.External, e.Interface, e.Port) {
+				msg := fmt.Sprintf("App %v exposes endpoint %v on %v:%v which is not declared by the Device Description",
+					a.Name, e.AppEndpoint, e.Interface, e.Port)
+				result.ExternalCheck.setFalseMulti(&msg)
+				continue
+			}
+			result.ExposedEndpoints = append(result.ExposedEndpoints, ExposedEndpoint{
+				App:         a.Name,
+				AppEndpoint: e.AppEndpoint,
+				Interface:   e.Interface,
+				Port:        e.Port,
+			})
+		}
+	}
+
+	if !result.InternalCheck.Success || !result.ExternalCheck.Success {
+		result.Summary.Success = false
+	}
+
+	return result
+}
+
+// checkEndpoint checks that the app is specified in the Device Description and that
+// its App Manifest provides the endpoint
+func checkEndpoint(apps map[string]*AppManifest, app, endpoint string) error {
+	m, ok := apps[app]
+	if !ok {
+		return fmt.Errorf("app %v is not specified in Device Description", app)
+	}
+	if m == nil {
+		return fmt.Errorf("App Manifest of app %v not present", app)
+	}
+	if !contains(endpoint, m.Endpoints) {
+		return fmt.Errorf("endpoint %v is not provided by App Manifest %v", endpoint, m.Name)
+	}
+	return nil
+}
+
+// isProvidedEndpoint returns whether any of the apps provides the endpoint
+func isProvidedEndpoint(apps map[string]*AppManifest, endpoint string) bool {
+	for _, m := range apps {
+		if m != nil && contains(endpoint, m.Endpoints) {
+			return true
+		}
+	}
+	return false
+}
+
+// isDeclaredInterface returns whether the network interface and port are declared by
+// the external interfaces of the Device Description. Port 0 declares any port
+func isDeclaredInterface(declared []ExternalInterface, iface string, port int) bool {
+	for _, d := range declared {
+		if d.Interface == iface && (d.Port == 0 || d.Port == port) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_verifyConnectionGraph(t *testing.T) {
+	appManifests := []AppManifest{
+		{Type: "App Manifest", Name: "web.manifest", Endpoints: []string{"https", "db-client"}},
+		{Type: "App Manifest", Name: "db.manifest", Endpoints: []string{"db"}},
+	}
+	web := AppDescription{Type: "App Description", Name: "web", AppManifest: "web.manifest"}
+	db := AppDescription{Type: "App Description", Name: "db", AppManifest: "db.manifest"}
+	internal := InternalConnection{Type: "Internal Connection", NameAppA: "web",
+		EndpointAppA: "db-client", NameAppB: "db", EndpointAppB: "db"}
+	eth0 := ExternalInterface{Type: "External Interface", Interface: "eth0", Port: 443}
+	webHttps := ExternalInterface{Type: "External Interface", AppEndpoint: "https", Interface: "eth0", Port: 443}
+
+	withExternal := func(a AppDescription, e ...ExternalInterface) AppDescription {
+		a.External = e
+		return a
+	}
+
+	tests := []struct {
+		name        string
+		dd          DeviceDescription
+		want        bool
+		wantExposed []ExposedEndpoint
+	}{
+		{
+			name: "Empty",
+			dd:   DeviceDescription{},
+			want: true,
+		},
+		{
+			name: "Valid",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{withExternal(web, webHttps), db},
+				Internal:        []InternalConnection{internal},
+				External:        []ExternalInterface{eth0},
+			},
+			want:        true,
+			wantExposed: []ExposedEndpoint{{App: "web", AppEndpoint: "https", Interface: "eth0", Port: 443}},
+		},
+		{
+			name: "Any Port Declared",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{
+					withExternal(web, ExternalInterface{AppEndpoint: "https", Interface: "eth0", Port: 8443}), db},
+				External: []ExternalInterface{{Interface: "eth0"}},
+			},
+			want:        true,
+			wantExposed: []ExposedEndpoint{{App: "web", AppEndpoint: "https", Interface: "eth0", Port: 8443}},
+		},
+		{
+			name: "Unknown App",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{web},
+				Internal:        []InternalConnection{internal},
+			},
+			want: false,
+		},
+		{
+			name: "Unknown Endpoint",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{web, db},
+				Internal: []InternalConnection{{NameAppA: "web", EndpointAppA: "db-client",
+					NameAppB: "db", EndpointAppB: "admin"}},
+			},
+			want: false,
+		},
+		{
+			name: "Missing App Manifest",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{web,
+					{Type: "App Description", Name: "db", AppManifest: "other.manifest"}},
+				Internal: []InternalConnection{internal},
+			},
+			want: false,
+		},
+		{
+			name: "Duplicate App Description",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{web, web},
+			},
+			want: false,
+		},
+		{
+			name: "Undeclared Interface",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{
+					withExternal(web, ExternalInterface{AppEndpoint: "https", Interface: "eth1", Port: 443})},
+				External: []ExternalInterface{eth0},
+			},
+			want: false,
+		},
+		{
+			name: "Undeclared Port",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{
+					withExternal(web, ExternalInterface{AppEndpoint: "https", Interface: "eth0", Port: 8443})},
+				External: []ExternalInterface{eth0},
+			},
+			want: false,
+		},
+		{
+			name: "Invalid Port",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{
+					withExternal(web, ExternalInterface{AppEndpoint: "https", Interface: "eth0", Port: 70000})},
+				External: []ExternalInterface{{Interface: "eth0"}},
+			},
+			want: false,
+		},
+		{
+			name: "Unknown Exposed Endpoint",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{
+					withExternal(web, ExternalInterface{AppEndpoint: "ssh", Interface: "eth0", Port: 443})},
+				External: []ExternalInterface{eth0},
+			},
+			want: false,
+		},
+		{
+			name: "Device Interface References Unknown Endpoint",
+			dd: DeviceDescription{
+				AppDescriptions: []AppDescription{web},
+				External:        []ExternalInterface{{AppEndpoint: "ssh", Interface: "eth0", Port: 22}},
+			},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := verifyConnectionGraph(&tt.dd, appManifests)
+			if got.Summary.Success != tt.want {
+				t.Errorf("verifyConnectionGraph() success = %v, want %v (%v, %v, %v)", got.Summary.Success,
+					tt.want, got.Summary.Details, got.InternalCheck.Details, got.ExternalCheck.Details)
+			}
+			if !reflect.DeepEqual(got.ExposedEndpoints, tt.wantExposed) {
+				t.Errorf("verifyConnectionGraph() exposed = %v, want %v", got.ExposedEndpoints, tt.wantExposed)
+			}
+		})
+	}
+}
+
+func TestConnectionGraphResult_ExposesPort(t *testing.T) {
+	r := ConnectionGraphResult{
+		ExposedEndpoints: []ExposedEndpoint{{App: "web", AppEndpoint: "https", Interface: "eth0", Port: 443}},
+	}
+
+	tests := []struct {
+		name  string
+		iface string
+		port  int
+		want  bool
+	}{
+		{"Any Interface", "", 443, true},
+		{"Matching Interface", "eth0", 443, true},
+		{"Other Interface", "eth1", 443, false},
+		{"Other Port", "", 22, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.ExposesPort(tt.iface, tt.port); got != tt.want {
+				t.Errorf("ExposesPort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,479 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/veraison/go-cose"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// ResultFormat selects the format in which the verifier returns the verification result
+type ResultFormat uint32
+
+const (
+	ResultFormat_CmcJson ResultFormat = 0 // VerificationResult in JSON
+	ResultFormat_EarJwt  ResultFormat = 1 // EAT Attestation Result (EAR) as signed JWT
+	ResultFormat_EarCwt  ResultFormat = 2 // EAT Attestation Result (EAR) as signed CWT
+)
+
+// COSE algorithms RSASSA-PKCS1-v1_5 with SHA-256 and SHA-512 (RFC 8812)
+const (
+	coseAlgorithmRS256 cose.Algorithm = -257
+	coseAlgorithmRS512 cose.Algorithm = -259
+)
+
+// EarProfile is the EAT profile of EAT Attestation Results (EAR)
+const EarProfile = "tag:github.com,2023:veraison/ear"
+
+// EarSubmod is the name of the EAR submodule containing the appraisal of the attester
+const EarSubmod = "cmc"
+
+// EarStatus is the overall appraisal status of an EAR submodule, i.e., the worst
+// tier of the claims of its trustworthiness vector
+type EarStatus int8
+
+const (
+	EarStatusNone            EarStatus = 0
+	EarStatusAffirming       EarStatus = 2
+	EarStatusWarning         EarStatus = 32
+	EarStatusContraindicated EarStatus = 96
+)
+
+var earStatusNames = map[EarStatus]string{
+	EarStatusNone:            "none",
+	EarStatusAffirming:       "affirming",
+	EarStatusWarning:         "warning",
+	EarStatusContraindicated: "contraindicated",
+}
+
+// TrustClaim is a trustworthiness claim as specified by the RATS Attestation Results for
+// Secure Interactions (AR4SI). Values from 2 to 31 are affirming, values from 32 to 95
+// warning and values from 96 to 127 contraindicated. The verifier reports the generic
+// value of each tier, except for unrecognized instances
+type TrustClaim int8
+
+const (
+	TrustClaimNone                 TrustClaim = 0
+	TrustClaimAffirming            TrustClaim = 2
+	TrustClaimWarning              TrustClaim = 32
+	TrustClaimContraindicated      TrustClaim = 96
+	TrustClaimUnrecognizedInstance TrustClaim = 97
+)
+
+// TrustVector is the AR4SI trustworthiness vector of an EAR submodule
+type TrustVector struct {
+	InstanceIdentity TrustClaim `json:"instance-identity,omitempty" cbor:"0,keyasint,omitempty"`
+	Configuration    TrustClaim `json:"configuration,omitempty" cbor:"1,keyasint,omitempty"`
+	Executables      TrustClaim `json:"executables,omitempty" cbor:"2,keyasint,omitempty"`
+	FileSystem       TrustClaim `json:"file-system,omitempty" cbor:"3,keyasint,omitempty"`
+	Hardware         TrustClaim `json:"hardware,omitempty" cbor:"4,keyasint,omitempty"`
+	RuntimeOpaque    TrustClaim `json:"runtime-opaque,omitempty" cbor:"5,keyasint,omitempty"`
+	StorageOpaque    TrustClaim `json:"storage-opaque,omitempty" cbor:"6,keyasint,omitempty"`
+	SourcedData      TrustClaim `json:"sourced-data,omitempty" cbor:"7,keyasint,omitempty"`
+}
+
+// EarVerifierId identifies the verifier which produced the EAR
+type EarVerifierId struct {
+	Developer string `json:"developer" cbor:"0,keyasint"`
+	Build     string `json:"build" cbor:"1,keyasint"`
+}
+
+// EarAppraisal is the appraisal of an EAR submodule
+type EarAppraisal struct {
+	Status      EarStatus    `json:"ear.status" cbor:"1000,keyasint"`
+	TrustVector *TrustVector `json:"ear.trustworthiness-vector,omitempty" cbor:"1001,keyasint,omitempty"`
+}
+
+// EarNonce is the nonce of the EAR, which is base64url encoded in JSON
+type EarNonce []byte
+
+// Ear represents an EAT Attestation Result (EAR) as specified by
+// draft-fv-rats-ear. The JSON tags are used for JWT, the CBOR keys for CWT claims
+type Ear struct {
+	Profile    string                  `json:"eat_profile" cbor:"265,keyasint"`
+	IssuedAt   int64                   `json:"iat" cbor:"6,keyasint"`
+	VerifierId EarVerifierId           `json:"ear.verifier-id" cbor:"1004,keyasint"`
+	Nonce      EarNonce                `json:"eat_nonce,omitempty" cbor:"10,keyasint,omitempty"`
+	Submods    map[string]EarAppraisal `json:"submods" cbor:"266,keyasint"`
+}
+
+// MarshalJSON encodes the status as its name
+func (s EarStatus) MarshalJSON() ([]byte, error) {
+	name, ok := earStatusNames[s]
+	if !ok {
+		return nil, fmt.Errorf("unknown EAR status %v", int8(s))
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes the status from its name
+func (s *EarStatus) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for k, v := range earStatusNames {
+		if v == name {
+			*s = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown EAR status %v", name)
+}
+
+// MarshalJSON encodes the nonce in base64url encoding without padding
+func (n EarNonce) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(n))
+}
+
+// UnmarshalJSON decodes the base64url encoded nonce
+func (n *EarNonce) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	d, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("failed to decode EAR nonce: %w", err)
+	}
+	*n = d
+	return nil
+}
+
+// NewEar maps the verification result to an EAT Attestation Result (EAR). The sub-results
+// are mapped to the claims of the AR4SI trustworthiness vector:
+//   - instance-identity: the attestation report signature and the device binding
+//   - configuration: the manifests and descriptions
+//   - executables: the measurements compared against the reference values
+//   - hardware: the signatures of the hardware trust anchors
+//   - runtime-opaque: the SNP and TDX measurements, i.e. encrypted memory
+//   - sourced-data: the revocation list
+//
+// Claims are omitted if the corresponding sub-results are not present. A failed
+// verification is always contraindicated
+func NewEar(result *VerificationResult, nonce []byte, verifier EarVerifierId) *Ear {
+	tv := &TrustVector{}
+
+	// The instance is identified by the attestation report signature and, if specified,
+	// the binding of the Device Description to the report-signing certificate
+	tv.InstanceIdentity = TrustClaimAffirming
+	if len(result.ReportSignature) == 0 {
+		tv.InstanceIdentity = TrustClaimUnrecognizedInstance
+	}
+	for _, sig := range result.ReportSignature {
+		if !sig.SignCheck.Success || !sig.CertChainCheck.Success {
+			tv.InstanceIdentity = TrustClaimUnrecognizedInstance
+		}
+	}
+	if tv.InstanceIdentity == TrustClaimAffirming && result.DevDescResult.DeviceBindingCheck != nil &&
+		!result.DevDescResult.DeviceBindingCheck.Success {
+		tv.InstanceIdentity = TrustClaimContraindicated
+	}
+
+	configuration := []bool{
+		result.RtmResult.Summary.Success,
+		result.OsResult.Summary.Success,
+		result.DevDescResult.Summary.Success,
+	}
+	for _, a := range result.AppResults {
+		configuration = append(configuration, a.Summary.Success)
+	}
+	if result.CompDescResult != nil {
+		configuration = append(configuration, result.CompDescResult.Summary.Success)
+	}
+	tv.Configuration = toTrustClaim(configuration)
+
+	executables := make([]bool, 0)
+	hardware := make([]bool, 0)
+	runtime := make([]bool, 0)
+	m := result.MeasResult
+	if m.TpmMeasResult != nil {
+		executables = append(executables, m.TpmMeasResult.Summary.Success)
+		hardware = append(hardware, signatureSuccess(&m.TpmMeasResult.QuoteSignature))
+	}
+	if m.SnpMeasResult != nil {
+		executables = append(executables, m.SnpMeasResult.Summary.Success)
+		hardware = append(hardware, signatureSuccess(&m.SnpMeasResult.Signature))
+		runtime = append(runtime, m.SnpMeasResult.Summary.Success)
+	}
+	if m.TdxMeasResult != nil {
+		executables = append(executables, m.TdxMeasResult.Summary.Success)
+		hardware = append(hardware, signatureSuccess(&m.TdxMeasResult.Signature))
+		runtime = append(runtime, m.TdxMeasResult.Summary.Success)
+	}
+	if m.IasMeasResult != nil {
+		executables = append(executables, m.IasMeasResult.Summary.Success)
+		hardware = append(hardware, signatureSuccess(&m.IasMeasResult.IasSignature))
+	}
+	for _, sw := range m.SwMeasResult {
+		executables = append(executables, sw.Validation.Success)
+	}
+	tv.Executables = toTrustClaim(executables)
+	tv.Hardware = toTrustClaim(hardware)
+	tv.RuntimeOpaque = toTrustClaim(runtime)
+
+	if result.RevocationListResult != nil {
+		tv.SourcedData = toTrustClaim([]bool{result.RevocationListResult.Summary.Success})
+	}
+
+	status := earStatus(tv)
+	if !result.Success {
+		status = EarStatusContraindicated
+	}
+
+	return &Ear{
+		Profile:    EarProfile,
+		IssuedAt:   time.Now().Unix(),
+		VerifierId: verifier,
+		Nonce:      nonce,
+		Submods: map[string]EarAppraisal{
+			EarSubmod: {
+				Status:      status,
+				TrustVector: tv,
+			},
+		},
+	}
+}
+
+// SignEar signs the EAR with the specified signer as JWT or CWT, depending on the format
+func SignEar(ear *Ear, format ResultFormat, signer Signer) ([]byte, error) {
+	switch format {
+	case ResultFormat_EarJwt:
+		return signEarJwt(ear, signer)
+	case ResultFormat_EarCwt:
+		return signEarCwt(ear, signer)
+	default:
+		return nil, fmt.Errorf("result format %v is not an EAR format", format)
+	}
+}
+
+// signEarJwt signs the EAR as JWT in JWS compact serialization
+func signEarJwt(ear *Ear, signer Signer) ([]byte, error) {
+
+	claims, err := json.Marshal(ear)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal EAR: %w", err)
+	}
+
+	// This allows the signer to ensure mutual access for signing, if required. The keys
+	// and the certificate chain must be retrieved while holding the lock, as the signer
+	// might renew them in the meantime
+	signer.Lock()
+	defer signer.Unlock()
+
+	certsb64 := make([]string, 0)
+	for _, cert := range signer.GetCertChain() {
+		certsb64 = append(certsb64, base64.StdEncoding.EncodeToString(cert.Raw))
+	}
+
+	priv, pub, err := signer.GetSigningKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing keys: %w", err)
+	}
+
+	alg, err := algFromKeyType(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alg from key type: %w", err)
+	}
+
+	hws := &hwSigner{
+		pk:     &jose.JSONWebKey{Key: pub},
+		signer: priv,
+		alg:    alg,
+	}
+
+	var opt jose.SignerOptions
+	joseSigner, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: jose.OpaqueSigner(hws)},
+		opt.WithType("JWT").WithHeader("x5c", certsb64))
+	if err != nil {
+		return nil, fmt.Errorf("failed to setup signer for the EAR: %w", err)
+	}
+
+	obj, err := joseSigner.Sign(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the EAR: %w", err)
+	}
+
+	msg, err := obj.CompactSerialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize the EAR: %w", err)
+	}
+
+	return []byte(msg), nil
+}
+
+// signEarCwt signs the EAR as CWT in a COSE_Sign1 message
+func signEarCwt(ear *Ear, signer Signer) ([]byte, error) {
+
+	claims, err := CborSerializer{}.Marshal(ear)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal EAR: %w", err)
+	}
+
+	// This allows the signer to ensure mutual access for signing, if required. The keys
+	// and the certificate chain must be retrieved while holding the lock, as the signer
+	// might renew them in the meantime
+	signer.Lock()
+	defer signer.Unlock()
+
+	priv, pub, err := signer.GetSigningKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signing keys: %w", err)
+	}
+
+	alg, err := coseAlgFromKeyType(pub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alg from key type: %w", err)
+	}
+
+	cs, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("failed to convert signing key of type %T", priv)
+	}
+	coseSigner, err := newCoseSigner(alg, cs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signer: %w", err)
+	}
+
+	certChain := make([][]byte, 0)
+	for _, cert := range signer.GetCertChain() {
+		certChain = append(certChain, cert.Raw)
+	}
+
+	msg := cose.NewSign1Message()
+	msg.Headers.Protected.SetAlgorithm(alg)
+	msg.Headers.Unprotected[cose.HeaderLabelX5Chain] = certChain
+	msg.Payload = claims
+
+	err = msg.Sign(rand.Reader, nil, coseSigner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the EAR: %w", err)
+	}
+
+	data, err := msg.MarshalCBOR()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the EAR: %w", err)
+	}
+
+	return data, nil
+}
+
+// coseAlgFromKeyType returns the COSE signature algorithm for the public key. As for
+// the JWT, RSA keys use RSASSA-PKCS1-v1_5, as supported by the TPM signing keys
+func coseAlgFromKeyType(pub crypto.PublicKey) (cose.Algorithm, error) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		switch key.Size() {
+		case 256:
+			return coseAlgorithmRS256, nil
+		case 512:
+			return coseAlgorithmRS512, nil
+		default:
+			return 0, fmt.Errorf("unknown RSA key size: %v", key.Size())
+		}
+	case *ecdsa.PublicKey:
+		switch key.Curve {
+		case elliptic.P256():
+			return cose.AlgorithmES256, nil
+		case elliptic.P384():
+			return cose.AlgorithmES384, nil
+		case elliptic.P521():
+			return cose.AlgorithmES512, nil
+		default:
+			return 0, errors.New("unknown elliptic curve")
+		}
+	default:
+		return 0, fmt.Errorf("unknown key type %T", pub)
+	}
+}
+
+// newCoseSigner returns a COSE signer for the algorithm. go-cose only implements
+// RSASSA-PSS, therefore RSASSA-PKCS1-v1_5 is implemented by coseRsaSigner
+func newCoseSigner(alg cose.Algorithm, key crypto.Signer) (cose.Signer, error) {
+	switch alg {
+	case coseAlgorithmRS256:
+		return &coseRsaSigner{alg: alg, hash: crypto.SHA256, key: key}, nil
+	case coseAlgorithmRS512:
+		return &coseRsaSigner{alg: alg, hash: crypto.SHA512, key: key}, nil
+	default:
+		return cose.NewSigner(alg, key)
+	}
+}
+
+// coseRsaSigner implements the COSE Signer interface for RSASSA-PKCS1-v1_5 (RFC 8812)
+type coseRsaSigner struct {
+	alg  cose.Algorithm
+	hash crypto.Hash
+	key  crypto.Signer
+}
+
+func (s *coseRsaSigner) Algorithm() cose.Algorithm {
+	return s.alg
+}
+
+func (s *coseRsaSigner) Sign(rand io.Reader, content []byte) ([]byte, error) {
+	h := s.hash.New()
+	h.Write(content)
+	return s.key.Sign(rand, h.Sum(nil), s.hash)
+}
+
+// toTrustClaim returns an affirming claim if all results are successful, a
+// contraindicated claim if a result failed and no claim if there are no results
+func toTrustClaim(results []bool) TrustClaim {
+	if len(results) == 0 {
+		return TrustClaimNone
+	}
+	for _, r := range results {
+		if !r {
+			return TrustClaimContraindicated
+		}
+	}
+	return TrustClaimAffirming
+}
+
+// signatureSuccess returns whether the signature and the certificate chain are valid
+func signatureSuccess(sig *SignatureResult) bool {
+	return sig.SignCheck.Success && sig.CertChainCheck.Success
+}
+
+// earStatus returns the status of the worst tier of the claims of the trustworthiness vector
+func earStatus(tv *TrustVector) EarStatus {
+	status := EarStatusNone
+	for _, c := range []TrustClaim{tv.InstanceIdentity, tv.Configuration, tv.Executables,
+		tv.FileSystem, tv.Hardware, tv.RuntimeOpaque, tv.StorageOpaque, tv.SourcedData} {
+		switch {
+		case c >= TrustClaimContraindicated:
+			return EarStatusContraindicated
+		case c >= TrustClaimWarning:
+			status = EarStatusWarning
+		case c >= TrustClaimAffirming && status == EarStatusNone:
+			status = EarStatusAffirming
+		}
+	}
+	return status
+}
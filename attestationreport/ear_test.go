@@ -0,0 +1,342 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/veraison/go-cose"
+	"gopkg.in/square/go-jose.v2"
+)
+
+func Test_NewEar(t *testing.T) {
+	validSig := SignatureResult{
+		SignCheck:      Result{Success: true},
+		CertChainCheck: Result{Success: true},
+	}
+	invalidSig := SignatureResult{
+		SignCheck:      Result{Success: false},
+		CertChainCheck: Result{Success: true},
+	}
+
+	newResult := func() *VerificationResult {
+		return &VerificationResult{
+			Success:         true,
+			ReportSignature: []SignatureResult{validSig},
+			RtmResult:       ManifestResult{Summary: ResultMulti{Success: true}},
+			OsResult:        ManifestResult{Summary: ResultMulti{Success: true}},
+			DevDescResult:   DevDescResult{Summary: ResultMulti{Success: true}},
+			MeasResult: MeasurementResult{
+				TpmMeasResult: &TpmMeasurementResult{
+					Summary:        Result{Success: true},
+					QuoteSignature: validSig,
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		modify     func(r *VerificationResult)
+		wantStatus EarStatus
+		wantTv     TrustVector
+	}{
+		{
+			name:       "Affirming",
+			modify:     func(r *VerificationResult) {},
+			wantStatus: EarStatusAffirming,
+			wantTv: TrustVector{
+				InstanceIdentity: TrustClaimAffirming,
+				Configuration:    TrustClaimAffirming,
+				Executables:      TrustClaimAffirming,
+				Hardware:         TrustClaimAffirming,
+			},
+		},
+		{
+			name: "Affirming SNP With Revocation List",
+			modify: func(r *VerificationResult) {
+				r.MeasResult.TpmMeasResult = nil
+				r.MeasResult.SnpMeasResult = &SnpMeasurementResult{
+					Summary:   Result{Success: true},
+					Signature: validSig,
+				}
+				r.RevocationListResult = &ManifestResult{Summary: ResultMulti{Success: true}}
+			},
+			wantStatus: EarStatusAffirming,
+			wantTv: TrustVector{
+				InstanceIdentity: TrustClaimAffirming,
+				Configuration:    TrustClaimAffirming,
+				Executables:      TrustClaimAffirming,
+				Hardware:         TrustClaimAffirming,
+				RuntimeOpaque:    TrustClaimAffirming,
+				SourcedData:      TrustClaimAffirming,
+			},
+		},
+		{
+			name: "Invalid Report Signature",
+			modify: func(r *VerificationResult) {
+				r.Success = false
+				r.ReportSignature = []SignatureResult{invalidSig}
+			},
+			wantStatus: EarStatusContraindicated,
+			wantTv: TrustVector{
+				InstanceIdentity: TrustClaimUnrecognizedInstance,
+				Configuration:    TrustClaimAffirming,
+				Executables:      TrustClaimAffirming,
+				Hardware:         TrustClaimAffirming,
+			},
+		},
+		{
+			name: "Device Binding Failed",
+			modify: func(r *VerificationResult) {
+				r.Success = false
+				r.DevDescResult.Summary.Success = false
+				r.DevDescResult.DeviceBindingCheck = &Result{Success: false}
+			},
+			wantStatus: EarStatusContraindicated,
+			wantTv: TrustVector{
+				InstanceIdentity: TrustClaimContraindicated,
+				Configuration:    TrustClaimContraindicated,
+				Executables:      TrustClaimAffirming,
+				Hardware:         TrustClaimAffirming,
+			},
+		},
+		{
+			name: "Measurement Mismatch",
+			modify: func(r *VerificationResult) {
+				r.Success = false
+				r.MeasResult.TpmMeasResult.Summary.Success = false
+			},
+			wantStatus: EarStatusContraindicated,
+			wantTv: TrustVector{
+				InstanceIdentity: TrustClaimAffirming,
+				Configuration:    TrustClaimAffirming,
+				Executables:      TrustClaimContraindicated,
+				Hardware:         TrustClaimAffirming,
+			},
+		},
+		{
+			name: "Other Failure",
+			modify: func(r *VerificationResult) {
+				r.Success = false
+				r.FreshnessCheck.Success = false
+			},
+			wantStatus: EarStatusContraindicated,
+			wantTv: TrustVector{
+				InstanceIdentity: TrustClaimAffirming,
+				Configuration:    TrustClaimAffirming,
+				Executables:      TrustClaimAffirming,
+				Hardware:         TrustClaimAffirming,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newResult()
+			tt.modify(r)
+
+			ear := NewEar(r, []byte{0x01, 0x02}, EarVerifierId{Developer: "test", Build: "1"})
+
+			if ear.Profile != EarProfile {
+				t.Errorf("NewEar() profile = %v, want %v", ear.Profile, EarProfile)
+			}
+			appraisal, ok := ear.Submods[EarSubmod]
+			if !ok {
+				t.Fatalf("NewEar() submod %v missing", EarSubmod)
+			}
+			if appraisal.Status != tt.wantStatus {
+				t.Errorf("NewEar() status = %v, want %v", appraisal.Status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(*appraisal.TrustVector, tt.wantTv) {
+				t.Errorf("NewEar() trust vector = %+v, want %+v", *appraisal.TrustVector, tt.wantTv)
+			}
+		})
+	}
+}
+
+func TestSignEar(t *testing.T) {
+	key, certchain, err := createCertsAndKeys()
+	if err != nil {
+		t.Fatalf("Failed to create testing certs and keys: %v", err)
+	}
+	swSigner := &SwSigner{
+		priv:      key,
+		certChain: certchain,
+	}
+	pub := &key.PublicKey
+
+	ear := &Ear{
+		Profile:    EarProfile,
+		IssuedAt:   1700000000,
+		VerifierId: EarVerifierId{Developer: "test", Build: "1"},
+		Nonce:      []byte{0xde, 0xad, 0xbe, 0xef},
+		Submods: map[string]EarAppraisal{
+			EarSubmod: {
+				Status:      EarStatusAffirming,
+				TrustVector: &TrustVector{InstanceIdentity: TrustClaimAffirming},
+			},
+		},
+	}
+
+	t.Run("JWT", func(t *testing.T) {
+		token, err := SignEar(ear, ResultFormat_EarJwt, swSigner)
+		if err != nil {
+			t.Fatalf("SignEar() error = %v", err)
+		}
+		jws, err := jose.ParseSigned(string(token))
+		if err != nil {
+			t.Fatalf("Failed to parse JWT: %v", err)
+		}
+		if typ := jws.Signatures[0].Header.ExtraHeaders[jose.HeaderType]; typ != "JWT" {
+			t.Errorf("JWT type = %v, want JWT", typ)
+		}
+		payload, err := jws.Verify(pub)
+		if err != nil {
+			t.Fatalf("Failed to verify JWT: %v", err)
+		}
+		var claims map[string]any
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Fatalf("Failed to unmarshal claims: %v", err)
+		}
+		if claims["eat_nonce"] != "3q2-7w" {
+			t.Errorf("eat_nonce = %v, want 3q2-7w", claims["eat_nonce"])
+		}
+		status := claims["submods"].(map[string]any)[EarSubmod].(map[string]any)["ear.status"]
+		if status != "affirming" {
+			t.Errorf("ear.status = %v, want affirming", status)
+		}
+		var got Ear
+		if err := json.Unmarshal(payload, &got); err != nil {
+			t.Fatalf("Failed to unmarshal EAR: %v", err)
+		}
+		if !reflect.DeepEqual(&got, ear) {
+			t.Errorf("EAR = %+v, want %+v", got, *ear)
+		}
+	})
+
+	t.Run("CWT", func(t *testing.T) {
+		token, err := SignEar(ear, ResultFormat_EarCwt, swSigner)
+		if err != nil {
+			t.Fatalf("SignEar() error = %v", err)
+		}
+		var msg cose.Sign1Message
+		if err := msg.UnmarshalCBOR(token); err != nil {
+			t.Fatalf("Failed to parse CWT: %v", err)
+		}
+		verifier, err := cose.NewVerifier(cose.AlgorithmES256, pub)
+		if err != nil {
+			t.Fatalf("Failed to create verifier: %v", err)
+		}
+		if err := msg.Verify(nil, verifier); err != nil {
+			t.Fatalf("Failed to verify CWT: %v", err)
+		}
+		var got Ear
+		if err := (CborSerializer{}).Unmarshal(msg.Payload, &got); err != nil {
+			t.Fatalf("Failed to unmarshal EAR: %v", err)
+		}
+		if !reflect.DeepEqual(&got, ear) {
+			t.Errorf("EAR = %+v, want %+v", got, *ear)
+		}
+	})
+
+	t.Run("CWT RSA", func(t *testing.T) {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate RSA key: %v", err)
+		}
+		rsaSigner := &pkcs1Signer{key: rsaKey}
+
+		token, err := SignEar(ear, ResultFormat_EarCwt, rsaSigner)
+		if err != nil {
+			t.Fatalf("SignEar() error = %v", err)
+		}
+		var msg cose.Sign1Message
+		if err := msg.UnmarshalCBOR(token); err != nil {
+			t.Fatalf("Failed to parse CWT: %v", err)
+		}
+		alg, err := msg.Headers.Protected.Algorithm()
+		if err != nil {
+			t.Fatalf("Failed to get algorithm: %v", err)
+		}
+		if alg != coseAlgorithmRS256 {
+			t.Errorf("CWT algorithm = %v, want RS256", alg)
+		}
+		verifier := &pkcs1Verifier{alg: coseAlgorithmRS256, hash: crypto.SHA256, key: &rsaKey.PublicKey}
+		if err := msg.Verify(nil, verifier); err != nil {
+			t.Fatalf("Failed to verify CWT: %v", err)
+		}
+	})
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		if _, err := SignEar(ear, ResultFormat_CmcJson, swSigner); err == nil {
+			t.Errorf("SignEar() succeeded for non-EAR format")
+		}
+	})
+}
+
+// pkcs1Signer provides an RSA key which, like the TPM signing keys, only supports
+// RSASSA-PKCS1-v1_5 signatures
+type pkcs1Signer struct {
+	key *rsa.PrivateKey
+}
+
+func (s *pkcs1Signer) Lock() {}
+
+func (s *pkcs1Signer) Unlock() {}
+
+func (s *pkcs1Signer) GetSigningKeys() (crypto.PrivateKey, crypto.PublicKey, error) {
+	return s, &s.key.PublicKey, nil
+}
+
+func (s *pkcs1Signer) GetCertChain() []*x509.Certificate {
+	return nil
+}
+
+func (s *pkcs1Signer) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s *pkcs1Signer) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		return nil, errors.New("RSA PSS not supported")
+	}
+	return s.key.Sign(rand, digest, opts)
+}
+
+// pkcs1Verifier verifies RSASSA-PKCS1-v1_5 COSE signatures
+type pkcs1Verifier struct {
+	alg  cose.Algorithm
+	hash crypto.Hash
+	key  *rsa.PublicKey
+}
+
+func (v *pkcs1Verifier) Algorithm() cose.Algorithm {
+	return v.alg
+}
+
+func (v *pkcs1Verifier) Verify(content, signature []byte) error {
+	h := v.hash.New()
+	h.Write(content)
+	return rsa.VerifyPKCS1v15(v.key, v.hash, h.Sum(nil), signature)
+}
@@ -58,11 +58,22 @@ func verifyIasMeasurements(iasM *IasMeasurement, nonce []byte, referenceValues [
 		return nil, true
 	}
 
-	// If the attestationreport contains IAS Reference Values, but no IAS measurement, the
-	// attestation must fail
+	// If the attestationreport contains mandatory IAS Reference Values, but no IAS measurement,
+	// the attestation must fail
 	if iasM == nil {
-		for _, v := range referenceValues {
-			msg := fmt.Sprintf("IAS Measurement not present. Cannot verify IAS Reference Value (hash: %v)", v.Sha256)
+		_, _, missing := selectReferenceValues(referenceValues, func(*ReferenceValue) bool { return false })
+		if len(missing) == 0 {
+			return nil, true
+		}
+		for _, set := range missing {
+			var msg string
+			if set.group == "" {
+				msg = fmt.Sprintf("IAS Measurement not present. Cannot verify IAS Reference Value (hash: %v)",
+					referenceValues[set.indices[0]].Sha256)
+			} else {
+				msg = fmt.Sprintf("IAS Measurement not present. Cannot verify IAS Reference Values of %v",
+					set.String(referenceValues))
+			}
 			result.ReferenceValueCheck.setFalseMulti(&msg)
 		}
 		result.Summary.Success = false
@@ -159,7 +170,8 @@ func verifyIasMeasurements(iasM *IasMeasurement, nonce []byte, referenceValues [
 
 	log.Trace("Verifying measurements")
 
-	// Verify measurements
+	// Verify measurements. Optional reference values are not required to be measured,
+	// of each alternative group one reference value must be measured
 	for _, ver := range referenceValues {
 		log.Tracef("Found reference value %v: %v", ver.Name, hex.EncodeToString(ver.Sha256))
 		if ver.Type != "IAS Reference Value" {
@@ -167,18 +179,28 @@ func verifyIasMeasurements(iasM *IasMeasurement, nonce []byte, referenceValues [
 			result.Summary.setFalse(&msg)
 			return result, false
 		}
-		found := false
+	}
+	_, alternatives, missing := selectReferenceValues(referenceValues, func(v *ReferenceValue) bool {
 		for _, swc := range iat.SwComponents {
-			if bytes.Equal(ver.Sha256, swc.MeasurementValue) {
-				found = true
+			if bytes.Equal(v.Sha256, swc.MeasurementValue) {
+				return true
 			}
 		}
-		if !found {
-			ok = false
-			msg := fmt.Sprintf("IAS Measurement for reference value %v: %v not present",
+		return false
+	})
+	result.Alternatives = alternatives
+	for _, set := range missing {
+		ok = false
+		var msg string
+		if set.group == "" {
+			ver := referenceValues[set.indices[0]]
+			msg = fmt.Sprintf("IAS Measurement for reference value %v: %v not present",
 				ver.Name, hex.EncodeToString(ver.Sha256))
-			result.ReferenceValueCheck.setFalseMulti(&msg)
+		} else {
+			msg = fmt.Sprintf("IAS Measurement for any reference value of %v not present",
+				set.String(referenceValues))
 		}
+		result.ReferenceValueCheck.setFalseMulti(&msg)
 	}
 	for _, swc := range iat.SwComponents {
 		log.Tracef("Found measurement %v: %v", swc.MeasurementDescription,
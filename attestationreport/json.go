@@ -88,6 +88,12 @@ func (s JsonSerializer) Sign(report []byte, signer Signer) ([]byte, error) {
 
 	log.Trace("Signing attestation report")
 
+	// This allows the signer to ensure mutual access for signing, if required. The keys
+	// and the certificate chain must be retrieved while holding the lock, as the signer
+	// might renew them in the meantime
+	signer.Lock()
+	defer signer.Unlock()
+
 	// create list of all certificates in the correct order
 	certs := signer.GetCertChain()
 
@@ -131,10 +137,6 @@ func (s JsonSerializer) Sign(report []byte, signer Signer) ([]byte, error) {
 		return nil, fmt.Errorf("failed to setup signer for the Attestation Report: %w", err)
 	}
 
-	// This allows the signer to ensure mutual access for signing, if required
-	signer.Lock()
-	defer signer.Unlock()
-
 	// sign
 	log.Trace("Performing Sign operation")
 	obj, err := joseSigner.Sign(report)
@@ -0,0 +1,249 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxReferenceValueCombinations limits the number of combinations of optional and
+// alternative reference values which are evaluated if the reference values cannot be
+// matched against individual measurements, e.g. 16 optional reference values of a PCR
+const maxReferenceValueCombinations = 1 << 16
+
+// referenceValueSet is either a single reference value or all reference values of an
+// alternative group. Of each set, one reference value is expected to be measured,
+// unless the set is optional
+type referenceValueSet struct {
+	group    string
+	indices  []int
+	optional bool
+}
+
+// String returns a human-readable description of the reference value set
+func (s *referenceValueSet) String(referenceValues []ReferenceValue) string {
+	names := make([]string, 0, len(s.indices))
+	for _, i := range s.indices {
+		names = append(names, referenceValues[i].Name)
+	}
+	if s.group == "" {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("alternative group %v (%v)", s.group, strings.Join(names, ", "))
+}
+
+// groupReferenceValues groups the reference values into sets based on their alternative
+// group. The sets are returned in the order of their first reference value. A set
+// is optional if all of its reference values are optional
+func groupReferenceValues(referenceValues []ReferenceValue) []referenceValueSet {
+	sets := make([]referenceValueSet, 0, len(referenceValues))
+	groups := make(map[string]int)
+	for i, v := range referenceValues {
+		if v.AlternativeGroup == "" {
+			sets = append(sets, referenceValueSet{
+				indices:  []int{i},
+				optional: v.Optional,
+			})
+			continue
+		}
+		if j, ok := groups[v.AlternativeGroup]; ok {
+			sets[j].indices = append(sets[j].indices, i)
+			sets[j].optional = sets[j].optional && v.Optional
+			continue
+		}
+		groups[v.AlternativeGroup] = len(sets)
+		sets = append(sets, referenceValueSet{
+			group:    v.AlternativeGroup,
+			indices:  []int{i},
+			optional: v.Optional,
+		})
+	}
+	return sets
+}
+
+// checkAlternativeGroups checks that the TPM reference values of each alternative group
+// belong to the same PCR. The reference values are selected per PCR, therefore a group
+// spanning multiple PCRs would be evaluated as a separate group for each PCR
+func checkAlternativeGroups(referenceValues []ReferenceValue) error {
+	pcrs := make(map[string]*int)
+	for _, v := range referenceValues {
+		if v.AlternativeGroup == "" {
+			continue
+		}
+		pcr, ok := pcrs[v.AlternativeGroup]
+		if !ok {
+			pcrs[v.AlternativeGroup] = v.Pcr
+			continue
+		}
+		if pcr == nil || v.Pcr == nil || *pcr != *v.Pcr {
+			return fmt.Errorf("alternative group %v contains reference values of different PCRs",
+				v.AlternativeGroup)
+		}
+	}
+	return nil
+}
+
+// applySelection returns the reference values selected from each set in their original
+// order as well as the selected alternative of each alternative group. The selection
+// contains the index of the selected reference value per set or -1 if none is selected
+func applySelection(referenceValues []ReferenceValue, sets []referenceValueSet, selection []int) ([]ReferenceValue, []AlternativeResult) {
+	selected := make([]bool, len(referenceValues))
+	var alternatives []AlternativeResult
+	for i, s := range sets {
+		if selection[i] >= 0 {
+			selected[selection[i]] = true
+		}
+		if s.group == "" {
+			continue
+		}
+		alt := AlternativeResult{
+			Group:   s.group,
+			Success: selection[i] >= 0 || s.optional,
+		}
+		if selection[i] >= 0 {
+			alt.Name = referenceValues[selection[i]].Name
+		}
+		alternatives = append(alternatives, alt)
+	}
+
+	values := make([]ReferenceValue, 0, len(referenceValues))
+	for i, v := range referenceValues {
+		if selected[i] {
+			values = append(values, v)
+		}
+	}
+	return values, alternatives
+}
+
+// selectReferenceValues selects the reference values which are expected based on
+// the individual measurements. Optional reference values are only selected if they were
+// measured, of each alternative group the first measured reference value is selected.
+// Mandatory sets without any measured reference value are returned as missing, their
+// first reference value is selected nevertheless
+func selectReferenceValues(referenceValues []ReferenceValue, measured func(v *ReferenceValue) bool) ([]ReferenceValue, []AlternativeResult, []referenceValueSet) {
+	sets := groupReferenceValues(referenceValues)
+	selection := make([]int, len(sets))
+	missing := make([]referenceValueSet, 0)
+	for i, s := range sets {
+		selection[i] = -1
+		for _, j := range s.indices {
+			if measured(&referenceValues[j]) {
+				selection[i] = j
+				break
+			}
+		}
+		if selection[i] == -1 && !s.optional {
+			missing = append(missing, s)
+			selection[i] = s.indices[0]
+		}
+	}
+
+	values, alternatives := applySelection(referenceValues, sets, selection)
+	for i := range alternatives {
+		for _, s := range missing {
+			if s.group == alternatives[i].Group {
+				alternatives[i].Name = ""
+				alternatives[i].Success = false
+			}
+		}
+	}
+
+	return values, alternatives, missing
+}
+
+// combineReferenceValues searches the combinations of the reference values which are valid
+// with respect to their optional and alternative semantics for a combination accepted by
+// the match function. This is required if the reference values cannot be matched against
+// individual measurements, e.g. if only the final PCR value was measured. The combinations
+// are built incrementally in the order of the reference values: extend returns the state
+// after appending a reference value to a combination, starting with init. Through
+// backtracking, combinations with a common prefix share its evaluation. If no combination
+// matches, the combination containing all reference values and the first reference value
+// of each alternative group is returned together with false. If the reference values allow
+// more than maxReferenceValueCombinations combinations, an error is returned without
+// evaluating any combination
+func combineReferenceValues(referenceValues []ReferenceValue, init []byte,
+	extend func(state []byte, v *ReferenceValue) []byte, match func(state []byte) bool,
+) ([]ReferenceValue, []AlternativeResult, bool, error) {
+	sets := groupReferenceValues(referenceValues)
+
+	num := 1
+	for _, s := range sets {
+		n := len(s.indices)
+		if s.optional {
+			n++
+		}
+		num *= n
+		if num > maxReferenceValueCombinations {
+			values, alternatives := applySelection(referenceValues, sets, firstSelection(sets))
+			return values, alternatives, false, fmt.Errorf("optional and alternative reference values allow more than %v combinations",
+				maxReferenceValueCombinations)
+		}
+	}
+
+	// The set of each reference value and the last reference value of each set, which
+	// must be selected if no other reference value of a mandatory set was selected
+	setOf := make([]int, len(referenceValues))
+	last := make([]int, len(sets))
+	for i, s := range sets {
+		for _, j := range s.indices {
+			setOf[j] = i
+		}
+		last[i] = s.indices[len(s.indices)-1]
+	}
+
+	selection := make([]int, len(sets))
+	for i := range selection {
+		selection[i] = -1
+	}
+
+	var search func(pos int, state []byte) bool
+	search = func(pos int, state []byte) bool {
+		if pos == len(referenceValues) {
+			return match(state)
+		}
+		set := setOf[pos]
+		if selection[set] == -1 {
+			selection[set] = pos
+			if search(pos+1, extend(state, &referenceValues[pos])) {
+				return true
+			}
+			selection[set] = -1
+			if !sets[set].optional && pos == last[set] {
+				return false
+			}
+		}
+		return search(pos+1, state)
+	}
+
+	if search(0, init) {
+		values, alternatives := applySelection(referenceValues, sets, selection)
+		return values, alternatives, true, nil
+	}
+
+	values, alternatives := applySelection(referenceValues, sets, firstSelection(sets))
+	return values, alternatives, false, nil
+}
+
+// firstSelection returns the selection of the first reference value of each set
+func firstSelection(sets []referenceValueSet) []int {
+	selection := make([]int, len(sets))
+	for i, s := range sets {
+		selection[i] = s.indices[0]
+	}
+	return selection
+}
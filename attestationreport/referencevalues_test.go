@@ -0,0 +1,193 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_selectReferenceValues(t *testing.T) {
+	kernel := ReferenceValue{Type: "SW Reference Value", Name: "kernel", Sha256: []byte{0x01}}
+	module := ReferenceValue{Type: "SW Reference Value", Name: "module", Sha256: []byte{0x02}, Optional: true}
+	bootV1 := ReferenceValue{Type: "SW Reference Value", Name: "bootloader-v1", Sha256: []byte{0x03}, AlternativeGroup: "bootloader"}
+	bootV2 := ReferenceValue{Type: "SW Reference Value", Name: "bootloader-v2", Sha256: []byte{0x04}, AlternativeGroup: "bootloader"}
+	referenceValues := []ReferenceValue{kernel, module, bootV1, bootV2}
+
+	tests := []struct {
+		name             string
+		measured         []string
+		wantSelected     []string
+		wantAlternatives []AlternativeResult
+		wantMissing      int
+	}{
+		{
+			name:             "All Measured",
+			measured:         []string{"kernel", "module", "bootloader-v1"},
+			wantSelected:     []string{"kernel", "module", "bootloader-v1"},
+			wantAlternatives: []AlternativeResult{{Group: "bootloader", Name: "bootloader-v1", Success: true}},
+		},
+		{
+			name:             "Optional Not Measured",
+			measured:         []string{"kernel", "bootloader-v2"},
+			wantSelected:     []string{"kernel", "bootloader-v2"},
+			wantAlternatives: []AlternativeResult{{Group: "bootloader", Name: "bootloader-v2", Success: true}},
+		},
+		{
+			name:             "Alternative Not Measured",
+			measured:         []string{"kernel"},
+			wantSelected:     []string{"kernel", "bootloader-v1"},
+			wantAlternatives: []AlternativeResult{{Group: "bootloader", Success: false}},
+			wantMissing:      1,
+		},
+		{
+			name:             "Mandatory Not Measured",
+			measured:         []string{"bootloader-v1"},
+			wantSelected:     []string{"kernel", "bootloader-v1"},
+			wantAlternatives: []AlternativeResult{{Group: "bootloader", Name: "bootloader-v1", Success: true}},
+			wantMissing:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, alternatives, missing := selectReferenceValues(referenceValues, func(v *ReferenceValue) bool {
+				for _, m := range tt.measured {
+					if v.Name == m {
+						return true
+					}
+				}
+				return false
+			})
+			if got := referenceValueNames(selected); !reflect.DeepEqual(got, tt.wantSelected) {
+				t.Errorf("selectReferenceValues() selected = %v, want %v", got, tt.wantSelected)
+			}
+			if !reflect.DeepEqual(alternatives, tt.wantAlternatives) {
+				t.Errorf("selectReferenceValues() alternatives = %v, want %v", alternatives, tt.wantAlternatives)
+			}
+			if len(missing) != tt.wantMissing {
+				t.Errorf("selectReferenceValues() missing = %v, want %v", len(missing), tt.wantMissing)
+			}
+		})
+	}
+}
+
+func Test_combineReferenceValues(t *testing.T) {
+	kernel := ReferenceValue{Type: "TPM Reference Value", Name: "kernel"}
+	module := ReferenceValue{Type: "TPM Reference Value", Name: "module", Optional: true}
+	bootV1 := ReferenceValue{Type: "TPM Reference Value", Name: "bootloader-v1", AlternativeGroup: "bootloader"}
+	bootV2 := ReferenceValue{Type: "TPM Reference Value", Name: "bootloader-v2", AlternativeGroup: "bootloader"}
+	referenceValues := []ReferenceValue{bootV1, bootV2, kernel, module}
+
+	// 13 optional reference values result in 8192 combinations
+	optionalValues := make([]ReferenceValue, 0, 13)
+	for i := 0; i < 13; i++ {
+		optionalValues = append(optionalValues, ReferenceValue{
+			Type:     "TPM Reference Value",
+			Name:     fmt.Sprintf("module-%v", i),
+			Optional: true,
+		})
+	}
+	tooManyValues := append(append([]ReferenceValue{}, optionalValues...), optionalValues[:4]...)
+
+	tests := []struct {
+		name            string
+		referenceValues []ReferenceValue
+		expected        []string
+		wantSelected    []string
+		wantMatch       bool
+		wantErr         bool
+	}{
+		{
+			name:            "Default Combination",
+			referenceValues: referenceValues,
+			expected:        []string{"bootloader-v1", "kernel", "module"},
+			wantSelected:    []string{"bootloader-v1", "kernel", "module"},
+			wantMatch:       true,
+		},
+		{
+			name:            "Alternative Without Optional",
+			referenceValues: referenceValues,
+			expected:        []string{"bootloader-v2", "kernel"},
+			wantSelected:    []string{"bootloader-v2", "kernel"},
+			wantMatch:       true,
+		},
+		{
+			name:            "Mandatory Missing",
+			referenceValues: referenceValues,
+			expected:        []string{"bootloader-v2"},
+			wantSelected:    []string{"bootloader-v1", "kernel", "module"},
+			wantMatch:       false,
+		},
+		{
+			name:            "Interleaved Alternatives",
+			referenceValues: []ReferenceValue{bootV1, kernel, bootV2},
+			expected:        []string{"kernel", "bootloader-v2"},
+			wantSelected:    []string{"kernel", "bootloader-v2"},
+			wantMatch:       true,
+		},
+		{
+			name:            "Many Optional Values",
+			referenceValues: optionalValues,
+			expected:        []string{"module-1", "module-7", "module-12"},
+			wantSelected:    []string{"module-1", "module-7", "module-12"},
+			wantMatch:       true,
+		},
+		{
+			name:            "Too Many Combinations",
+			referenceValues: tooManyValues,
+			expected:        []string{"module-1"},
+			wantMatch:       false,
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := strings.Join(tt.expected, ",")
+			selected, _, match, err := combineReferenceValues(tt.referenceValues, nil,
+				func(state []byte, v *ReferenceValue) []byte {
+					if len(state) == 0 {
+						return []byte(v.Name)
+					}
+					return []byte(string(state) + "," + v.Name)
+				},
+				func(state []byte) bool {
+					return string(state) == expected
+				})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("combineReferenceValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if match != tt.wantMatch {
+				t.Errorf("combineReferenceValues() match = %v, want %v", match, tt.wantMatch)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := referenceValueNames(selected); !reflect.DeepEqual(got, tt.wantSelected) {
+				t.Errorf("combineReferenceValues() selected = %v, want %v", got, tt.wantSelected)
+			}
+		})
+	}
+}
+
+func referenceValueNames(referenceValues []ReferenceValue) []string {
+	names := make([]string, 0, len(referenceValues))
+	for _, v := range referenceValues {
+		names = append(names, v.Name)
+	}
+	return names
+}
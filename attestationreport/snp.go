@@ -24,6 +24,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"math/big"
+	"strings"
 
 	"github.com/Fraunhofer-AISEC/cmc/internal"
 )
@@ -43,7 +44,7 @@ type snpreport struct {
 	SignatureAlgo   uint32
 	CurrentTcb      uint64 // platform_version
 	PlatformInfo    uint64
-	AuthorKeyEn     uint32
+	KeyInfo         uint32 // AUTHOR_KEY_EN (bit 0), MASK_CHIP_KEY (bit 1), SIGNING_KEY (bits 4:2)
 	Reserved1       uint32
 	ReportData      [64]byte
 	Measurement     [48]byte
@@ -53,7 +54,10 @@ type snpreport struct {
 	ReportId        [32]byte
 	ReportIdMa      [32]byte
 	ReportedTcb     uint64
-	Reserved2       [24]byte
+	CpuidFamId      uint8 // Version 3 and higher
+	CpuidModId      uint8 // Version 3 and higher
+	CpuidStep       uint8 // Version 3 and higher
+	Reserved2       [21]byte
 	ChipId          [64]byte
 	//Reserved3 [192]byte
 	CommittedTcb   uint64
@@ -82,7 +86,25 @@ const (
 	signature_offset = 0x2A0
 )
 
+// Signing key selectors (SIGNING_KEY field of the report)
+const (
+	snpSigningKeyVcek = 0
+	snpSigningKeyVlek = 1
+)
+
+// AMD SEV-SNP certificate extension OIDs
+const (
+	oidSnpProductName = "1.3.6.1.4.1.3704.1.2"
+	oidSnpBlSpl       = "1.3.6.1.4.1.3704.1.3.1"
+	oidSnpTeeSpl      = "1.3.6.1.4.1.3704.1.3.2"
+	oidSnpSnpSpl      = "1.3.6.1.4.1.3704.1.3.3"
+	oidSnpUcodeSpl    = "1.3.6.1.4.1.3704.1.3.8"
+	oidSnpHwid        = "1.3.6.1.4.1.3704.1.4"
+	oidSnpCspId       = "1.3.6.1.4.1.3704.1.5"
+)
+
 func verifySnpMeasurements(snpM *SnpMeasurement, nonce []byte, referenceValues []ReferenceValue,
+	crls []*x509.RevocationList, strict bool,
 ) (*SnpMeasurementResult, bool) {
 	result := &SnpMeasurementResult{}
 	ok := true
@@ -162,8 +184,16 @@ func verifySnpMeasurements(snpM *SnpMeasurement, nonce []byte, referenceValues [
 		return result, false
 	}
 
-	// Verify Signature, created with SNP VCEK private key
-	sig, ret := verifySnpSignature(snpM.Report, s, certs, cas)
+	// Determine the product line from the report or the reference value
+	product, err := getSnpProduct(s, snpReferenceValue.Snp.Product)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to determine SNP product line: %v", err)
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+
+	// Verify Signature, created with SNP VCEK or VLEK private key
+	sig, ret := verifySnpSignature(snpM.Report, s, certs, cas, product, crls, strict)
 	if !ret {
 		ok = false
 	}
@@ -199,6 +229,9 @@ func verifySnpMeasurements(snpM *SnpMeasurement, nonce []byte, referenceValues [
 	if !ret {
 		ok = false
 	}
+	if !verifySnpOptionalDetails(result, s, snpReferenceValue.Snp) {
+		ok = false
+	}
 
 	result.Summary.Success = ok
 
@@ -215,6 +248,40 @@ func DecodeSnpReport(report []byte) (snpreport, error) {
 	return s, nil
 }
 
+// signingKey returns the signing key selector of the report (0: VCEK, 1: VLEK, 7: none)
+func (s *snpreport) signingKey() uint32 {
+	return (s.KeyInfo >> 2) & 0x7
+}
+
+// getSnpProduct returns the product line of the report. Reports of version 3 and higher
+// contain the CPUID family and model, from which the product line can be derived. If
+// the reference value specifies a product line, it must match the product line derived
+// from the report. If neither is present, an empty string is returned
+func getSnpProduct(s snpreport, refProduct string) (string, error) {
+
+	var reportProduct string
+	if s.Version >= 3 {
+		reportProduct = internal.GetSnpProduct(s.CpuidFamId, s.CpuidModId)
+		if reportProduct == "" {
+			log.Debugf("Unknown SNP product line (CPUID family 0x%x, model 0x%x)",
+				s.CpuidFamId, s.CpuidModId)
+		}
+	}
+
+	if refProduct == "" {
+		return reportProduct, nil
+	}
+	if _, err := internal.GetSnpKdsProduct(refProduct); err != nil {
+		return "", err
+	}
+	if reportProduct != "" && !strings.EqualFold(reportProduct, refProduct) {
+		return "", fmt.Errorf("report product line %v does not match reference value product line %v",
+			reportProduct, refProduct)
+	}
+
+	return refProduct, nil
+}
+
 func verifySnpVersion(s snpreport, version uint32) (Result, bool) {
 	r := Result{}
 	ok := s.Version == version
@@ -356,9 +423,84 @@ func verifySnpTcb(s snpreport, v SnpTcb) (TcbCheck, bool) {
 	return r, ok
 }
 
+// verifySnpOptionalDetails compares the optional expected values of the SNP Reference
+// Value with the report. Results are only set for values present in the reference value
+func verifySnpOptionalDetails(result *SnpMeasurementResult, s snpreport, v *SnpDetails) bool {
+	ok := true
+
+	byteChecks := []struct {
+		name     string
+		claimed  HexByte
+		measured []byte
+		result   **ByteMatch
+	}{
+		{"HOST_DATA", v.HostData, s.HostData[:], &result.HostDataMatch},
+		{"ID_KEY_DIGEST", v.IdKeyDigest, s.IdKeyDigest[:], &result.IdKeyDigestMatch},
+		{"AUTHOR_KEY_DIGEST", v.AuthorKeyDigest, s.AuthorKeyDigest[:], &result.AuthorKeyDigestMatch},
+		{"FAMILY_ID", v.FamilyId, s.FamilyId[:], &result.FamilyIdMatch},
+		{"IMAGE_ID", v.ImageId, s.ImageId[:], &result.ImageIdMatch},
+		{"REPORT_ID", v.ReportId, s.ReportId[:], &result.ReportIdMatch},
+	}
+	for _, c := range byteChecks {
+		if c.claimed == nil {
+			continue
+		}
+		m := &ByteMatch{
+			Success:  bytes.Equal(c.claimed, c.measured),
+			Claimed:  c.claimed,
+			Measured: c.measured,
+		}
+		if !m.Success {
+			log.Tracef("SNP %v mismatch: Expected %v, got %v", c.name,
+				hex.EncodeToString(c.claimed), hex.EncodeToString(c.measured))
+			ok = false
+		}
+		*c.result = m
+	}
+
+	if v.GuestSvn != nil {
+		// Convert to int, as json.Marshal otherwise interprets the values as strings
+		result.GuestSvnCheck = &VersionCheck{
+			Success:  s.GuestSvn >= *v.GuestSvn,
+			Claimed:  []int{int(*v.GuestSvn)},
+			Measured: []int{int(s.GuestSvn)},
+		}
+		if !result.GuestSvnCheck.Success {
+			log.Tracef("SNP guest SVN check failed. Expected: %v, got %v", *v.GuestSvn, s.GuestSvn)
+			ok = false
+		}
+	}
+
+	if v.PlatformInfo != nil {
+		smt := (s.PlatformInfo & (1 << 0)) != 0
+		tsme := (s.PlatformInfo & (1 << 1)) != 0
+		r := &PlatformInfoCheck{
+			Smt: BooleanMatch{
+				Success:  smt == v.PlatformInfo.Smt,
+				Claimed:  v.PlatformInfo.Smt,
+				Measured: smt,
+			},
+			Tsme: BooleanMatch{
+				Success:  tsme == v.PlatformInfo.Tsme,
+				Claimed:  v.PlatformInfo.Tsme,
+				Measured: tsme,
+			},
+		}
+		r.Summary.Success = r.Smt.Success && r.Tsme.Success
+		if !r.Summary.Success {
+			log.Tracef("SNP platform info does not match: Smt: %v, Tsme: %v", r.Smt.Success, r.Tsme.Success)
+			ok = false
+		}
+		result.PlatformInfoCheck = r
+	}
+
+	return ok
+}
+
 func verifySnpSignature(
 	reportRaw []byte, report snpreport,
 	certs []*x509.Certificate, cas []*x509.Certificate,
+	product string, crls []*x509.RevocationList, strict bool,
 ) (SignatureResult, bool) {
 
 	result := SignatureResult{}
@@ -389,8 +531,15 @@ func verifySnpSignature(
 	s := new(big.Int)
 	s.SetBytes(sRaw)
 
+	// The signing key selector specifies whether the report was signed with the VCEK or the VLEK
+	if k := report.signingKey(); k != snpSigningKeyVcek && k != snpSigningKeyVlek {
+		msg := fmt.Sprintf("Signing key %v not supported", k)
+		result.SignCheck.setFalse(&msg)
+		return result, false
+	}
+
 	// Examine SNP x509 extensions
-	extensionResult, ok := verifySnpExtensions(certs[0], &report)
+	extensionResult, ok := verifySnpExtensions(certs[0], &report, product)
 	result.ExtensionsCheck = &extensionResult
 	if !ok {
 		return result, false
@@ -428,7 +577,30 @@ func verifySnpSignature(
 		result.CertChainCheck.setFalse(&msg)
 		return result, false
 	}
+
+	// Check that neither the VCEK/VLEK nor the ASK/ASVK were revoked
+	unchecked := make([]string, 0)
+	for _, chain := range x509Chains {
+		certs, err := internal.CheckRevocation(chain, crls, strict)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to verify certificate chain: %v", err)
+			result.CertChainCheck.setFalse(&msg)
+			return result, false
+		}
+		for _, c := range certs {
+			if !internal.Contains(c.Subject.CommonName, unchecked) {
+				unchecked = append(unchecked, c.Subject.CommonName)
+			}
+		}
+	}
 	result.CertChainCheck.Success = true
+	// Distinguish certificates that are not revoked from certificates whose revocation
+	// status could not be checked
+	if len(unchecked) > 0 {
+		result.CertChainCheck.Details = fmt.Sprintf("Revocation status not checked, no CRL of the issuer present: %v",
+			strings.Join(unchecked, ", "))
+		log.Debug(result.CertChainCheck.Details)
+	}
 
 	//Store details from (all) validated certificate chain(s) in the report
 	for _, chain := range x509Chains {
@@ -442,46 +614,86 @@ func verifySnpSignature(
 	return result, true
 }
 
-func verifySnpExtensions(cert *x509.Certificate, report *snpreport) (ResultMulti, bool) {
+func verifySnpExtensions(cert *x509.Certificate, report *snpreport, product string) (ResultMulti, bool) {
 	result := ResultMulti{}
 	ok := true
 	tcb := report.CurrentTcb
 
-	if err := checkExtensionUint8(cert, "1.3.6.1.4.1.3704.1.3.1", uint8(tcb)); err != nil {
+	if err := checkExtensionUint8(cert, oidSnpBlSpl, uint8(tcb)); err != nil {
 		msg := fmt.Sprintf("SEV BL Extension Check failed: %v", err)
 		result.setFalseMulti(&msg)
 		ok = false
 	}
 
-	if err := checkExtensionUint8(cert, "1.3.6.1.4.1.3704.1.3.2", uint8(tcb>>8)); err != nil {
+	if err := checkExtensionUint8(cert, oidSnpTeeSpl, uint8(tcb>>8)); err != nil {
 		msg := fmt.Sprintf("SEV TEE Extension Check failed: %v", err)
 		result.setFalseMulti(&msg)
 		ok = false
 	}
 
-	if err := checkExtensionUint8(cert, "1.3.6.1.4.1.3704.1.3.3", uint8(tcb>>48)); err != nil {
+	if err := checkExtensionUint8(cert, oidSnpSnpSpl, uint8(tcb>>48)); err != nil {
 		msg := fmt.Sprintf("SEV SNP Extension Check failed: %v", err)
 		result.setFalseMulti(&msg)
 		ok = false
 	}
 
-	if err := checkExtensionUint8(cert, "1.3.6.1.4.1.3704.1.3.8", uint8(tcb>>56)); err != nil {
+	if err := checkExtensionUint8(cert, oidSnpUcodeSpl, uint8(tcb>>56)); err != nil {
 		msg := fmt.Sprintf("SEV UCODE Extension Check failed: %v", err)
 		result.setFalseMulti(&msg)
 		ok = false
 	}
 
-	if err := checkExtensionBuf(cert, "1.3.6.1.4.1.3704.1.4", report.ChipId[:]); err != nil {
-		msg := fmt.Sprintf("Chip ID Extension Check failed: %v", err)
+	switch report.signingKey() {
+	case snpSigningKeyVcek:
+		// The VCEK is bound to the chip ID
+		if err := checkExtensionBuf(cert, oidSnpHwid, report.ChipId[:]); err != nil {
+			msg := fmt.Sprintf("Chip ID Extension Check failed: %v", err)
+			result.setFalseMulti(&msg)
+			ok = false
+		}
+	case snpSigningKeyVlek:
+		// The VLEK is not bound to a chip, but to the cloud service provider
+		if _, err := getExtensionString(cert, oidSnpCspId); err != nil {
+			msg := fmt.Sprintf("CSP ID Extension Check failed: %v", err)
+			result.setFalseMulti(&msg)
+			ok = false
+		}
+	default:
+		msg := fmt.Sprintf("Signing key %v not supported", report.signingKey())
 		result.setFalseMulti(&msg)
 		ok = false
 	}
 
+	if product != "" {
+		if err := checkSnpProductName(cert, product); err != nil {
+			msg := fmt.Sprintf("Product Name Extension Check failed: %v", err)
+			result.setFalseMulti(&msg)
+			ok = false
+		}
+	}
+
 	result.Success = ok
 
 	return result, ok
 }
 
+// checkSnpProductName checks that the product name extension of the VCEK or VLEK
+// (e.g. Milan-B0) matches the specified product line
+func checkSnpProductName(cert *x509.Certificate, product string) error {
+	kdsProduct, err := internal.GetSnpKdsProduct(product)
+	if err != nil {
+		return err
+	}
+	name, err := getExtensionString(cert, oidSnpProductName)
+	if err != nil {
+		return err
+	}
+	if name != kdsProduct && !strings.HasPrefix(name, kdsProduct+"-") {
+		return fmt.Errorf("product name %v does not match product line %v", name, product)
+	}
+	return nil
+}
+
 func min(v []uint8) uint8 {
 	if len(v) == 0 {
 		return 0
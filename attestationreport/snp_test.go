@@ -16,8 +16,22 @@
 package attestationreport
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
 	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/Fraunhofer-AISEC/cmc/internal"
 )
 
 var (
@@ -1359,7 +1373,7 @@ func Test_verifySnpMeasurements(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if _, got := verifySnpMeasurements(tt.args.snpM, tt.args.nonce, tt.args.snpV); got != tt.want {
+			if _, got := verifySnpMeasurements(tt.args.snpM, tt.args.nonce, tt.args.snpV, nil, false); got != tt.want {
 				t.Errorf("verifySnpMeasurements() = %v, want %v", got, tt.want)
 			}
 		})
@@ -1393,3 +1407,348 @@ func Test_checkMinVersion(t *testing.T) {
 		})
 	}
 }
+
+func Test_verifySnpOptionalDetails(t *testing.T) {
+
+	s := snpreport{
+		GuestSvn:     3,
+		PlatformInfo: 0x3,
+	}
+	s.HostData[0] = 0x1
+	s.IdKeyDigest[0] = 0x2
+	s.AuthorKeyDigest[0] = 0x3
+	s.FamilyId[0] = 0x4
+	s.ImageId[0] = 0x5
+	s.ReportId[0] = 0x6
+
+	svn := func(v uint32) *uint32 { return &v }
+
+	tests := []struct {
+		name    string
+		details SnpDetails
+		want    bool
+	}{
+		{"No Details", SnpDetails{}, true},
+		{
+			name: "All Details Match",
+			details: SnpDetails{
+				HostData:        s.HostData[:],
+				IdKeyDigest:     s.IdKeyDigest[:],
+				AuthorKeyDigest: s.AuthorKeyDigest[:],
+				FamilyId:        s.FamilyId[:],
+				ImageId:         s.ImageId[:],
+				GuestSvn:        svn(2),
+				ReportId:        s.ReportId[:],
+				PlatformInfo:    &SnpPlatformInfo{Smt: true, Tsme: true},
+			},
+			want: true,
+		},
+		{"Host Data Mismatch", SnpDetails{HostData: make([]byte, 32)}, false},
+		{"Host Data Length Mismatch", SnpDetails{HostData: s.HostData[:16]}, false},
+		{"ID Key Digest Mismatch", SnpDetails{IdKeyDigest: make([]byte, 48)}, false},
+		{"Author Key Digest Mismatch", SnpDetails{AuthorKeyDigest: make([]byte, 48)}, false},
+		{"Family ID Mismatch", SnpDetails{FamilyId: make([]byte, 16)}, false},
+		{"Image ID Mismatch", SnpDetails{ImageId: make([]byte, 16)}, false},
+		{"Guest SVN Too Low", SnpDetails{GuestSvn: svn(4)}, false},
+		{"Report ID Mismatch", SnpDetails{ReportId: make([]byte, 32)}, false},
+		{"Platform Info Mismatch", SnpDetails{PlatformInfo: &SnpPlatformInfo{Smt: false, Tsme: true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &SnpMeasurementResult{}
+			if got := verifySnpOptionalDetails(result, s, &tt.details); got != tt.want {
+				t.Errorf("verifySnpOptionalDetails() = %v, want %v", got, tt.want)
+			}
+			if (result.HostDataMatch != nil) != (tt.details.HostData != nil) {
+				t.Errorf("HostDataMatch present = %v, want %v", result.HostDataMatch != nil, tt.details.HostData != nil)
+			}
+			if (result.GuestSvnCheck != nil) != (tt.details.GuestSvn != nil) {
+				t.Errorf("GuestSvnCheck present = %v, want %v", result.GuestSvnCheck != nil, tt.details.GuestSvn != nil)
+			}
+		})
+	}
+}
+
+func Test_verifySnpProducts(t *testing.T) {
+	type args struct {
+		family      uint8
+		model       uint8
+		signingKey  uint32
+		productName string
+		vlekCert    bool
+		refProduct  string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"Milan VCEK", args{0x19, 0x01, snpSigningKeyVcek, "Milan-B0", false, ""}, true},
+		{"Genoa VCEK", args{0x19, 0x11, snpSigningKeyVcek, "Genoa-B1", false, ""}, true},
+		{"Bergamo VCEK", args{0x19, 0xA0, snpSigningKeyVcek, "Genoa-B0", false, "Bergamo"}, true},
+		{"Milan VLEK", args{0x19, 0x01, snpSigningKeyVlek, "Milan-B0", true, "Milan"}, true},
+		{"Genoa VLEK", args{0x19, 0x11, snpSigningKeyVlek, "Genoa-B1", true, ""}, true},
+		{"Bergamo VLEK", args{0x19, 0xA0, snpSigningKeyVlek, "Genoa-B0", true, ""}, true},
+		{"VLEK Report With VCEK", args{0x19, 0x11, snpSigningKeyVlek, "Genoa-B1", false, ""}, false},
+		{"VCEK Report With VLEK", args{0x19, 0x11, snpSigningKeyVcek, "Genoa-B1", true, ""}, false},
+		{"Product Name Mismatch", args{0x19, 0x11, snpSigningKeyVcek, "Milan-B0", false, ""}, false},
+		{"Reference Product Mismatch", args{0x19, 0x11, snpSigningKeyVcek, "Genoa-B1", false, "Milan"}, false},
+		{"Unknown Reference Product", args{0x19, 0x11, snpSigningKeyVcek, "Genoa-B1", false, "Rome"}, false},
+		{"Unsupported Signing Key", args{0x19, 0x11, 7, "Genoa-B1", false, ""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := createSnpTestVector(t, tt.args.family, tt.args.model,
+				tt.args.signingKey, tt.args.productName, tt.args.vlekCert)
+
+			snpM, snpV := v.measurement(tt.args.refProduct)
+
+			if _, got := verifySnpMeasurements(snpM, validNonce, snpV, nil, false); got != tt.want {
+				t.Errorf("verifySnpMeasurements() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_verifySnpRevocation(t *testing.T) {
+
+	v := createSnpTestVector(t, 0x19, 0x11, snpSigningKeyVcek, "Genoa-B1", false)
+	other := createSnpTestVector(t, 0x19, 0x11, snpSigningKeyVcek, "Genoa-B1", false)
+
+	valid := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name          string
+		crls          []*x509.RevocationList
+		strict        bool
+		want          bool
+		wantUnchecked bool
+	}{
+		{"No CRLs", nil, false, true, true},
+		{"Empty CRLs", []*x509.RevocationList{
+			createSnpTestCrl(t, v.ark, v.arkKey, nil, valid),
+			createSnpTestCrl(t, v.ask, v.askKey, nil, valid),
+		}, false, true, false},
+		{"ASK Revoked", []*x509.RevocationList{
+			createSnpTestCrl(t, v.ark, v.arkKey, v.ask, valid),
+		}, false, false, false},
+		{"VCEK Revoked", []*x509.RevocationList{
+			createSnpTestCrl(t, v.ask, v.askKey, v.leaf, valid),
+		}, false, false, false},
+		{"CRL From Other Issuer", []*x509.RevocationList{
+			createSnpTestCrl(t, other.ark, other.arkKey, v.ask, valid),
+		}, false, true, true},
+		{"Expired CRL", []*x509.RevocationList{
+			createSnpTestCrl(t, v.ark, v.arkKey, nil, valid),
+			createSnpTestCrl(t, v.ask, v.askKey, nil, expired),
+		}, false, true, false},
+		{"Expired CRL Strict", []*x509.RevocationList{
+			createSnpTestCrl(t, v.ark, v.arkKey, nil, valid),
+			createSnpTestCrl(t, v.ask, v.askKey, nil, expired),
+		}, true, false, false},
+		{"Empty CRLs Strict", []*x509.RevocationList{
+			createSnpTestCrl(t, v.ark, v.arkKey, nil, valid),
+			createSnpTestCrl(t, v.ask, v.askKey, nil, valid),
+		}, true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snpM, snpV := v.measurement("")
+			result, got := verifySnpMeasurements(snpM, validNonce, snpV, tt.crls, tt.strict)
+			if got != tt.want {
+				t.Errorf("verifySnpMeasurements() got = %v, want %v", got, tt.want)
+			}
+			if result.Signature.CertChainCheck.Success != tt.want {
+				t.Errorf("CertChainCheck = %v, want %v", result.Signature.CertChainCheck.Success, tt.want)
+			}
+			unchecked := strings.Contains(result.Signature.CertChainCheck.Details, "not checked")
+			if tt.want && unchecked != tt.wantUnchecked {
+				t.Errorf("CertChainCheck details = %q, want unchecked %v",
+					result.Signature.CertChainCheck.Details, tt.wantUnchecked)
+			}
+		})
+	}
+}
+
+type snpTestVector struct {
+	report []byte
+	leaf   *x509.Certificate
+	ask    *x509.Certificate
+	ark    *x509.Certificate
+	askKey *ecdsa.PrivateKey
+	arkKey *ecdsa.PrivateKey
+}
+
+func (v *snpTestVector) measurement(product string) (*SnpMeasurement, []ReferenceValue) {
+	snpM := &SnpMeasurement{
+		Type:   "SNP Measurement",
+		Report: v.report,
+		Certs:  internal.WriteCertsPem([]*x509.Certificate{v.leaf, v.ask, v.ark}),
+	}
+	snpV := []ReferenceValue{
+		{
+			Type:   "SNP Reference Value",
+			Sha384: validMeasurement,
+			Snp: &SnpDetails{
+				Version: 3,
+				Cas:     [][]byte{internal.WriteCertPem(v.ark)},
+				Policy:  validSnpPolicy,
+				Fw:      validFw,
+				Tcb:     validTcb,
+				Product: product,
+			},
+		},
+	}
+	return snpM, snpV
+}
+
+// createSnpTestVector creates a version 3 report based on the valid test report with
+// the specified CPUID family, model and signing key, signed by a newly generated
+// VCEK or VLEK
+func createSnpTestVector(t *testing.T, family, model uint8, signingKey uint32, productName string, vlek bool,
+) *snpTestVector {
+
+	report := make([]byte, len(validReport))
+	copy(report, validReport)
+
+	s, err := DecodeSnpReport(report)
+	if err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+
+	binary.LittleEndian.PutUint32(report[header_offset:], 3)
+	binary.LittleEndian.PutUint32(report[header_offset+0x48:], (s.KeyInfo&^(0x7<<2))|(signingKey<<2))
+	report[header_offset+0x188] = family
+	report[header_offset+0x189] = model
+	report[header_offset+0x18A] = 0
+
+	arkKey, ark := createSnpTestCert(t, "ARK-Test", nil, nil, nil)
+	askKey, ask := createSnpTestCert(t, "ASK-Test", nil, ark, arkKey)
+
+	ia5 := func(s string) []byte {
+		b, err := asn1.MarshalWithParams(s, "ia5")
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %v", s, err)
+		}
+		return b
+	}
+	integer := func(v uint8) []byte {
+		b, err := asn1.Marshal(int(v))
+		if err != nil {
+			t.Fatalf("failed to marshal %v: %v", v, err)
+		}
+		return b
+	}
+	oid := func(s string) asn1.ObjectIdentifier {
+		var o asn1.ObjectIdentifier
+		for _, v := range strings.Split(s, ".") {
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				t.Fatalf("failed to parse OID %v: %v", s, err)
+			}
+			o = append(o, i)
+		}
+		return o
+	}
+
+	tcb := s.CurrentTcb
+	exts := []pkix.Extension{
+		{Id: oid(oidSnpProductName), Value: ia5(productName)},
+		{Id: oid(oidSnpBlSpl), Value: integer(uint8(tcb))},
+		{Id: oid(oidSnpTeeSpl), Value: integer(uint8(tcb >> 8))},
+		{Id: oid(oidSnpSnpSpl), Value: integer(uint8(tcb >> 48))},
+		{Id: oid(oidSnpUcodeSpl), Value: integer(uint8(tcb >> 56))},
+	}
+	if vlek {
+		exts = append(exts, pkix.Extension{Id: oid(oidSnpCspId), Value: ia5("Test-CSP")})
+	} else {
+		exts = append(exts, pkix.Extension{Id: oid(oidSnpHwid), Value: s.ChipId[:]})
+	}
+	leafKey, leaf := createSnpTestCert(t, "SEV-Test", exts, ask, askKey)
+
+	// Sign the report and store r and s little endian
+	digest := sha512.Sum384(report[header_offset : header_offset+signature_offset])
+	r, sig, err := ecdsa.Sign(rand.Reader, leafKey, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign report: %v", err)
+	}
+	sigOffset := header_offset + signature_offset
+	for i := range report[sigOffset : sigOffset+144] {
+		report[sigOffset+i] = 0
+	}
+	rBuf := r.FillBytes(make([]byte, 72))
+	sBuf := sig.FillBytes(make([]byte, 72))
+	for i := 0; i < 72; i++ {
+		report[sigOffset+i] = rBuf[71-i]
+		report[sigOffset+72+i] = sBuf[71-i]
+	}
+
+	return &snpTestVector{
+		report: report,
+		leaf:   leaf,
+		ask:    ask,
+		ark:    ark,
+		askKey: askKey,
+		arkKey: arkKey,
+	}
+}
+
+func createSnpTestCrl(t *testing.T, issuer *x509.Certificate, key *ecdsa.PrivateKey, revoked *x509.Certificate,
+	nextUpdate time.Time,
+) *x509.RevocationList {
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	if revoked != nil {
+		tmpl.RevokedCertificates = []pkix.RevokedCertificate{
+			{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()},
+		}
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, issuer, key)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	crls, err := internal.ParseCrls([][]byte{der})
+	if err != nil {
+		t.Fatalf("failed to parse CRL: %v", err)
+	}
+	return crls[0]
+}
+
+func createSnpTestCert(t *testing.T, cn string, exts []pkix.Extension, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*ecdsa.PrivateKey, *x509.Certificate) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		ExtraExtensions:       exts,
+		BasicConstraintsValid: true,
+		IsCA:                  exts == nil, // Only the ARK and ASK are created without extensions
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	if parent == nil {
+		parent = tmpl
+		parentKey = key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return key, cert
+}
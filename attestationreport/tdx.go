@@ -0,0 +1,561 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/Fraunhofer-AISEC/cmc/internal"
+)
+
+// Intel TDX DCAP Quote Generation Library and Quote Verification Library, Appendix A.3
+// @ https://download.01.org/intel-sgx/latest/dcap-latest/linux/docs/Intel_TDX_DCAP_Quoting_Library_API.pdf
+type tdxQuoteHeader struct {
+	Version            uint16
+	AttestationKeyType uint16
+	TeeType            uint32
+	Reserved1          uint16
+	Reserved2          uint16
+	QeVendorId         [16]byte
+	UserData           [20]byte
+}
+
+type tdxReportBody struct {
+	TeeTcbSvn      [16]byte
+	MrSeam         [48]byte
+	MrSignerSeam   [48]byte
+	SeamAttributes uint64
+	TdAttributes   uint64
+	Xfam           uint64
+	MrTd           [48]byte
+	MrConfigId     [48]byte
+	MrOwner        [48]byte
+	MrOwnerConfig  [48]byte
+	Rtmrs          [4][48]byte
+	ReportData     [64]byte
+}
+
+type sgxReportBody struct {
+	CpuSvn       [16]byte
+	MiscSelect   uint32
+	Reserved1    [12]byte
+	IsvExtProdId [16]byte
+	Attributes   [16]byte
+	MrEnclave    [32]byte
+	Reserved2    [32]byte
+	MrSigner     [32]byte
+	Reserved3    [32]byte
+	ConfigId     [64]byte
+	IsvProdId    uint16
+	IsvSvn       uint16
+	ConfigSvn    uint16
+	Reserved4    [42]byte
+	IsvFamilyId  [16]byte
+	ReportData   [64]byte
+}
+
+type tdxQuote struct {
+	Header            tdxQuoteHeader
+	Body              tdxReportBody
+	Signature         [64]byte
+	AttestationKey    [64]byte
+	QeReportRaw       []byte
+	QeReport          sgxReportBody
+	QeReportSignature [64]byte
+	QeAuthData        []byte
+	QeCertDataType    uint16
+	QeCertData        []byte
+}
+
+const (
+	tdxQuoteVersion4        = 4
+	tdxTeeType              = 0x81
+	tdxAttKeyTypeEcdsaP256  = 2
+	tdxCertDataTypePckChain = 5
+	tdxCertDataTypeQeReport = 6
+
+	tdxHeaderSize   = 48
+	tdxBodySize     = 584
+	sgxReportSize   = 384
+	tdxNumRtmrs     = 4
+	tdxDebugAttrBit = 1 << 0
+
+	// Intel SGX PCK certificate extension, see Intel SGX PCK Certificate and
+	// Certificate Revocation List Profile Specification, 1.3.1
+	oidSgxPckExtension = "1.2.840.113741.1.13.1"
+
+	// Identity of the Intel TDX quoting enclave as published by the Intel PCS
+	tdxQeMrSigner  = "dc9e2a7c6f948f17474e34a7fc43ed030f7c1563f1babddf6340c82e0e54a8c5"
+	tdxQeIsvProdId = 2
+)
+
+// SHA256 fingerprints of the accepted roots of PCK certificate chains. The CAs of the TDX
+// Reference Value must be one of these roots, by default only the Intel SGX Root CA
+var tdxRootCaFingerprints = []string{
+	// Intel SGX Root CA, https://certificates.trustedservices.intel.com/Intel_SGX_Provisioning_Certification_RootCA.pem
+	"44a0196b2b99f889b8e149e95b807a350e7424964399e885a7cbb8ccfab674d3",
+}
+
+func verifyTdxMeasurements(tdxM *TdxMeasurement, nonce []byte, referenceValues []ReferenceValue,
+) (*TdxMeasurementResult, bool) {
+	result := &TdxMeasurementResult{}
+	ok := true
+
+	// If the attestationreport does contain neither TDX measurements, nor TDX Reference Values
+	// there is nothing to to
+	if tdxM == nil && len(referenceValues) == 0 {
+		return nil, true
+	}
+
+	// If the attestationreport contains TDX Reference Values, but no TDX measurement, the
+	// attestation must fail
+	if tdxM == nil {
+		for _, v := range referenceValues {
+			msg := fmt.Sprintf("TDX Measurement not present. Cannot verify TDX Reference Value (hash: %v)",
+				hex.EncodeToString(v.Sha384))
+			result.ReferenceValueCheck.setFalseMulti(&msg)
+		}
+		result.Summary.Success = false
+		return result, false
+	}
+
+	if len(referenceValues) == 0 {
+		msg := "Could not find TDX Reference Value"
+		result.Summary.setFalse(&msg)
+		return result, false
+	} else if len(referenceValues) > 1 {
+		msg := fmt.Sprintf("Report contains %v reference values. Currently, only 1 TDX Reference Value is supported",
+			len(referenceValues))
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+	tdxReferenceValue := referenceValues[0]
+
+	if tdxReferenceValue.Type != "TDX Reference Value" {
+		msg := fmt.Sprintf("TDX Reference Value invalid type %v", tdxReferenceValue.Type)
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+	if tdxReferenceValue.Tdx == nil {
+		msg := "TDX Reference Value does not contain TDX details"
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+
+	// Extract the TDX quote data structure
+	q, err := decodeTdxQuote(tdxM.Report)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to decode TDX quote: %v", err)
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+
+	// Compare Nonce for Freshness (contained in REPORTDATA in the TD Quote Body)
+	nonce64 := make([]byte, 64)
+	copy(nonce64, nonce)
+	if cmp := bytes.Compare(q.Body.ReportData[:], nonce64); cmp != 0 {
+		msg := fmt.Sprintf("Nonces mismatch: Supplied Nonce = %v, Nonce in TDX Quote = %v)",
+			hex.EncodeToString(nonce), hex.EncodeToString(q.Body.ReportData[:]))
+		result.Freshness.setFalse(&msg)
+		ok = false
+	} else {
+		result.Freshness.Success = true
+	}
+
+	// The PCK certificate chain is usually contained in the quote. Otherwise, it
+	// must be provided with the measurement
+	var certs []*x509.Certificate
+	if q.QeCertDataType == tdxCertDataTypePckChain {
+		certs, err = internal.ParseCerts(q.QeCertData)
+	} else {
+		certs, err = internal.ParseCerts(tdxM.Certs)
+	}
+	if err != nil {
+		msg := fmt.Sprintf("Failed to parse PCK certificates: %v", err)
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+
+	// The PCK certificate chain must chain up to the Intel SGX Root CA specified in the
+	// reference value and not to the CAs of the device certificates. As the reference
+	// values are provided by the operator, the CA must match the pinned Intel SGX Root CA
+	cas, err := internal.ParseCerts(tdxReferenceValue.Tdx.Cas)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to parse ca: %v", err)
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+	if err := checkTdxRootCas(cas); err != nil {
+		msg := fmt.Sprintf("Failed to verify ca: %v", err)
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+
+	qeIdentity, err := getTdxQeIdentity(tdxReferenceValue.Tdx.QeIdentity)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to get QE identity: %v", err)
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+
+	// Verify the QE report signed by the PCK and the quote signature created with
+	// the attestation key, which is bound to the QE report
+	sig, qeReportCheck, ret := verifyTdxSignature(tdxM.Report, q, certs, cas, qeIdentity)
+	if !ret {
+		ok = false
+	}
+	result.Signature = sig
+	result.QeReportCheck = qeReportCheck
+
+	// Compare Measurements
+	if cmp := bytes.Compare(q.Body.MrTd[:], tdxReferenceValue.Sha384); cmp != 0 {
+		msg := fmt.Sprintf("TDX MRTD mismatch: Supplied measurement = %v, TDX quote measurement = %v",
+			hex.EncodeToString(tdxReferenceValue.Sha384), hex.EncodeToString(q.Body.MrTd[:]))
+		result.MeasurementMatch.setFalse(&msg)
+		ok = false
+	} else {
+		result.MeasurementMatch.Success = true
+		// As we previously checked, that the attestation report contains exactly one
+		// TDX Reference Value, we can set this here:
+		result.ReferenceValueCheck.Success = true
+	}
+
+	result.RtmrMatch, ret = verifyTdxRtmrs(q, tdxReferenceValue.Tdx.Rtmrs)
+	if !ret {
+		ok = false
+	}
+
+	// Compare TDX parameters
+	if q.Header.Version == tdxReferenceValue.Tdx.Version {
+		result.VersionMatch.Success = true
+	} else {
+		msg := fmt.Sprintf("TDX quote version mismatch: Quote = %v, supplied = %v",
+			q.Header.Version, tdxReferenceValue.Tdx.Version)
+		result.VersionMatch.setFalse(&msg)
+		ok = false
+	}
+
+	debug := (q.Body.TdAttributes & tdxDebugAttrBit) != 0
+	result.DebugCheck = BooleanMatch{
+		Success:  debug == tdxReferenceValue.Tdx.Debug,
+		Claimed:  tdxReferenceValue.Tdx.Debug,
+		Measured: debug,
+	}
+	if !result.DebugCheck.Success {
+		ok = false
+	}
+
+	result.Summary.Success = ok
+
+	return result, ok
+}
+
+func decodeTdxQuote(quote []byte) (tdxQuote, error) {
+	var q tdxQuote
+	b := bytes.NewBuffer(quote)
+
+	err := binary.Read(b, binary.LittleEndian, &q.Header)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode header: %w", err)
+	}
+	if q.Header.Version != tdxQuoteVersion4 {
+		return tdxQuote{}, fmt.Errorf("quote version %v not supported", q.Header.Version)
+	}
+	if q.Header.TeeType != tdxTeeType {
+		return tdxQuote{}, fmt.Errorf("TEE type 0x%x is not TDX", q.Header.TeeType)
+	}
+	if q.Header.AttestationKeyType != tdxAttKeyTypeEcdsaP256 {
+		return tdxQuote{}, fmt.Errorf("attestation key type %v not supported", q.Header.AttestationKeyType)
+	}
+
+	err = binary.Read(b, binary.LittleEndian, &q.Body)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode TD quote body: %w", err)
+	}
+
+	var sigDataLen uint32
+	err = binary.Read(b, binary.LittleEndian, &sigDataLen)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode signature data length: %w", err)
+	}
+	if int(sigDataLen) > b.Len() {
+		return tdxQuote{}, fmt.Errorf("signature data length %v exceeds remaining length %v",
+			sigDataLen, b.Len())
+	}
+	// Quote buffers retrieved from the kernel or the QGS might be padded, any data
+	// beyond the signature data is ignored
+	b = bytes.NewBuffer(b.Next(int(sigDataLen)))
+
+	err = binary.Read(b, binary.LittleEndian, &q.Signature)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode quote signature: %w", err)
+	}
+	err = binary.Read(b, binary.LittleEndian, &q.AttestationKey)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode attestation key: %w", err)
+	}
+
+	certDataType, certData, err := readTdxCertData(b)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode certification data: %w", err)
+	}
+	if certDataType != tdxCertDataTypeQeReport {
+		return tdxQuote{}, fmt.Errorf("certification data type %v not supported", certDataType)
+	}
+
+	// QE Report Certification Data
+	c := bytes.NewBuffer(certData)
+	q.QeReportRaw = c.Next(sgxReportSize)
+	err = binary.Read(bytes.NewBuffer(q.QeReportRaw), binary.LittleEndian, &q.QeReport)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode QE report: %w", err)
+	}
+	err = binary.Read(c, binary.LittleEndian, &q.QeReportSignature)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode QE report signature: %w", err)
+	}
+	var authDataLen uint16
+	err = binary.Read(c, binary.LittleEndian, &authDataLen)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode QE authentication data length: %w", err)
+	}
+	if int(authDataLen) > c.Len() {
+		return tdxQuote{}, fmt.Errorf("QE authentication data length %v exceeds remaining length %v",
+			authDataLen, c.Len())
+	}
+	q.QeAuthData = c.Next(int(authDataLen))
+
+	q.QeCertDataType, q.QeCertData, err = readTdxCertData(c)
+	if err != nil {
+		return tdxQuote{}, fmt.Errorf("failed to decode QE certification data: %w", err)
+	}
+
+	return q, nil
+}
+
+func readTdxCertData(b *bytes.Buffer) (uint16, []byte, error) {
+	var certDataType uint16
+	err := binary.Read(b, binary.LittleEndian, &certDataType)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read type: %w", err)
+	}
+	var size uint32
+	err = binary.Read(b, binary.LittleEndian, &size)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to read size: %w", err)
+	}
+	if int(size) > b.Len() {
+		return 0, nil, fmt.Errorf("size %v exceeds remaining length %v", size, b.Len())
+	}
+	return certDataType, b.Next(int(size)), nil
+}
+
+func verifyTdxRtmrs(q tdxQuote, rtmrs []HexByte) (ResultMulti, bool) {
+	r := ResultMulti{Success: true}
+
+	if len(rtmrs) != tdxNumRtmrs {
+		msg := fmt.Sprintf("TDX Reference Value contains %v RTMRs, expected %v", len(rtmrs), tdxNumRtmrs)
+		r.setFalseMulti(&msg)
+		return r, false
+	}
+
+	for i := range q.Body.Rtmrs {
+		if !bytes.Equal(q.Body.Rtmrs[i][:], rtmrs[i]) {
+			msg := fmt.Sprintf("TDX RTMR%v mismatch: Supplied measurement = %v, TDX quote measurement = %v",
+				i, hex.EncodeToString(rtmrs[i]), hex.EncodeToString(q.Body.Rtmrs[i][:]))
+			r.setFalseMulti(&msg)
+		}
+	}
+
+	return r, r.Success
+}
+
+func verifyTdxSignature(quoteRaw []byte, q tdxQuote, certs []*x509.Certificate, cas []*x509.Certificate,
+	qeIdentity TdxQeIdentity,
+) (SignatureResult, Result, bool) {
+
+	result := SignatureResult{}
+	qeResult := Result{}
+
+	if len(certs) == 0 {
+		msg := "No PCK certificate present"
+		result.CertChainCheck.setFalse(&msg)
+		return result, qeResult, false
+	}
+
+	// Verify the PCK certificate chain up to the Intel root CA
+	x509Chains, err := internal.VerifyCertChain(certs, cas)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to verify PCK certificate chain: %v", err)
+		result.CertChainCheck.setFalse(&msg)
+		return result, qeResult, false
+	}
+	result.CertChainCheck.Success = true
+
+	//Store details from (all) validated certificate chain(s) in the report
+	for _, chain := range x509Chains {
+		chainExtracted := []X509CertExtracted{}
+		for _, cert := range chain {
+			chainExtracted = append(chainExtracted, ExtractX509Infos(cert))
+		}
+		result.ValidatedCerts = append(result.ValidatedCerts, chainExtracted)
+	}
+
+	// Only PCK certificates are allowed to sign QE reports
+	extensionResult := ResultMulti{Success: true}
+	if !hasExtension(certs[0], oidSgxPckExtension) {
+		msg := fmt.Sprintf("Certificate %v does not contain the SGX PCK extension %v",
+			certs[0].Subject.CommonName, oidSgxPckExtension)
+		extensionResult.setFalseMulti(&msg)
+	}
+	result.ExtensionsCheck = &extensionResult
+	if !extensionResult.Success {
+		return result, qeResult, false
+	}
+
+	// Verify the QE report signature, created with the PCK private key
+	pck, isEcdsa := certs[0].PublicKey.(*ecdsa.PublicKey)
+	if !isEcdsa {
+		msg := "Failed to extract ECDSA public key from PCK certificate"
+		qeResult.setFalse(&msg)
+		return result, qeResult, false
+	}
+	digest := sha256.Sum256(q.QeReportRaw)
+	if !ecdsa.Verify(pck, digest[:], tdxSigR(q.QeReportSignature), tdxSigS(q.QeReportSignature)) {
+		msg := "Failed to verify QE report signature"
+		qeResult.setFalse(&msg)
+		return result, qeResult, false
+	}
+
+	// Only accept attestation keys of the expected quoting enclave
+	if err := verifyTdxQeIdentity(q.QeReport, qeIdentity); err != nil {
+		msg := fmt.Sprintf("Failed to verify QE identity: %v", err)
+		qeResult.setFalse(&msg)
+		return result, qeResult, false
+	}
+
+	// The QE report data binds the attestation key to the QE report:
+	// SHA256(attestation key || QE authentication data) || 32 zero bytes
+	binding := sha256.Sum256(append(q.AttestationKey[:], q.QeAuthData...))
+	expected := make([]byte, 64)
+	copy(expected, binding[:])
+	if !bytes.Equal(q.QeReport.ReportData[:], expected) {
+		msg := fmt.Sprintf("QE report data %v does not match attestation key hash %v",
+			hex.EncodeToString(q.QeReport.ReportData[:]), hex.EncodeToString(binding[:]))
+		qeResult.setFalse(&msg)
+		return result, qeResult, false
+	}
+	qeResult.Success = true
+
+	// Verify the quote signature over the header and the TD quote body, created with
+	// the attestation key
+	attKey := &ecdsa.PublicKey{
+		Curve: elliptic.P256(),
+		X:     new(big.Int).SetBytes(q.AttestationKey[:32]),
+		Y:     new(big.Int).SetBytes(q.AttestationKey[32:]),
+	}
+	if !attKey.Curve.IsOnCurve(attKey.X, attKey.Y) {
+		msg := "Attestation key is not a valid P-256 public key"
+		result.SignCheck.setFalse(&msg)
+		return result, qeResult, false
+	}
+	digest = sha256.Sum256(quoteRaw[:tdxHeaderSize+tdxBodySize])
+	if !ecdsa.Verify(attKey, digest[:], tdxSigR(q.Signature), tdxSigS(q.Signature)) {
+		msg := "Failed to verify TDX quote signature"
+		result.SignCheck.setFalse(&msg)
+		return result, qeResult, false
+	}
+	result.SignCheck.Success = true
+
+	return result, qeResult, true
+}
+
+// checkTdxRootCas checks that all CAs are pinned roots of PCK certificate chains
+func checkTdxRootCas(cas []*x509.Certificate) error {
+	if len(cas) == 0 {
+		return fmt.Errorf("no CA specified")
+	}
+	for _, ca := range cas {
+		fingerprint := sha256.Sum256(ca.Raw)
+		if !internal.Contains(hex.EncodeToString(fingerprint[:]), tdxRootCaFingerprints) {
+			return fmt.Errorf("CA %v (SHA256 fingerprint %v) is not the Intel SGX Root CA",
+				ca.Subject.CommonName, hex.EncodeToString(fingerprint[:]))
+		}
+	}
+	return nil
+}
+
+// getTdxQeIdentity returns the expected QE identity from the reference value or
+// the identity of the Intel TDX QE if not specified
+func getTdxQeIdentity(ref *TdxQeIdentity) (TdxQeIdentity, error) {
+	if ref != nil {
+		if len(ref.MrSigner) != 32 {
+			return TdxQeIdentity{}, fmt.Errorf("invalid MRSIGNER length %v", len(ref.MrSigner))
+		}
+		return *ref, nil
+	}
+	mrSigner, err := hex.DecodeString(tdxQeMrSigner)
+	if err != nil {
+		return TdxQeIdentity{}, fmt.Errorf("failed to decode MRSIGNER: %w", err)
+	}
+	return TdxQeIdentity{
+		MrSigner:  mrSigner,
+		IsvProdId: tdxQeIsvProdId,
+	}, nil
+}
+
+func verifyTdxQeIdentity(qeReport sgxReportBody, qeIdentity TdxQeIdentity) error {
+	if !bytes.Equal(qeReport.MrSigner[:], qeIdentity.MrSigner) {
+		return fmt.Errorf("MRSIGNER mismatch: expected %v, got %v",
+			hex.EncodeToString(qeIdentity.MrSigner), hex.EncodeToString(qeReport.MrSigner[:]))
+	}
+	if qeReport.IsvProdId != qeIdentity.IsvProdId {
+		return fmt.Errorf("ISVPRODID mismatch: expected %v, got %v",
+			qeIdentity.IsvProdId, qeReport.IsvProdId)
+	}
+	if qeReport.IsvSvn < qeIdentity.IsvSvn {
+		return fmt.Errorf("ISVSVN %v is lower than minimum ISVSVN %v",
+			qeReport.IsvSvn, qeIdentity.IsvSvn)
+	}
+	return nil
+}
+
+func hasExtension(cert *x509.Certificate, oid string) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.String() == oid {
+			return true
+		}
+	}
+	return false
+}
+
+// tdxSigR and tdxSigS return the r and s values of the raw big-endian ECDSA
+// signatures contained in TDX quotes
+func tdxSigR(sig [64]byte) *big.Int {
+	return new(big.Int).SetBytes(sig[:32])
+}
+
+func tdxSigS(sig [64]byte) *big.Int {
+	return new(big.Int).SetBytes(sig[32:])
+}
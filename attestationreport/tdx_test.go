@@ -0,0 +1,948 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package attestationreport
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Fraunhofer-AISEC/cmc/internal"
+	"github.com/sirupsen/logrus"
+)
+
+type tdxQuoteParams struct {
+	nonce        []byte
+	mrtd         []byte
+	rtmrs        [][]byte
+	tdAttributes uint64
+	tamperBody   bool
+	tamperAuth   bool
+	qeIdentity   *TdxQeIdentity
+}
+
+// Captured TDX v4 quote of an Intel Sapphire Rapids platform including the PCK
+// certificate chain, taken from the github.com/google/go-tdx-guest test data. The
+// quote is followed by padding, which must be ignored
+var (
+	capturedTdxQuote = []byte{
+		0x04, 0x00, 0x02, 0x00, 0x81, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x93, 0x9a, 0x72, 0x33, 0xf7, 0x9c, 0x4c, 0xa9, 0x94, 0x0a, 0x0d, 0xb3,
+		0x95, 0x7f, 0x06, 0x07, 0x73, 0x9c, 0x3f, 0x29, 0x2a, 0x15, 0xba, 0xce,
+		0x1f, 0x72, 0x63, 0x51, 0xa7, 0x0d, 0x4b, 0x79, 0x00, 0x00, 0x00, 0x00,
+		0x03, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x2f, 0xd2, 0x79, 0xc1, 0x61, 0x64, 0xa9, 0x3d,
+		0xd5, 0xbf, 0x37, 0x3d, 0x83, 0x43, 0x28, 0xd4, 0x60, 0x08, 0xc2, 0xb6,
+		0x93, 0xaf, 0x9e, 0xbb, 0x86, 0x5b, 0x08, 0xb2, 0xce, 0xd3, 0x20, 0xc9,
+		0xa8, 0x9b, 0x48, 0x69, 0xa9, 0xfa, 0xb6, 0x0f, 0xbe, 0x9d, 0x0c, 0x5a,
+		0x53, 0x63, 0xc6, 0x56, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0xe7, 0x1a, 0x06, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x63, 0x63, 0xb8, 0x04, 0x36, 0x68, 0xa3, 0xad,
+		0x95, 0x32, 0x78, 0xe1, 0x03, 0x89, 0x57, 0x4d, 0x32, 0x6c, 0x67, 0x49,
+		0xfb, 0x78, 0xaa, 0x81, 0x0e, 0xcd, 0x93, 0x36, 0x92, 0x3d, 0xb8, 0x6f,
+		0x22, 0xfc, 0x00, 0xb8, 0xdc, 0xd4, 0x04, 0xbc, 0x10, 0xd5, 0xe1, 0x19,
+		0xd7, 0x21, 0x5c, 0xbb, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x29, 0x27, 0xda, 0x70, 0x46, 0x1c, 0xd6, 0x32,
+		0x66, 0xf4, 0x32, 0x30, 0xcc, 0x18, 0x49, 0xc0, 0x3e, 0xf2, 0x5e, 0xbe,
+		0x49, 0x00, 0x62, 0xa8, 0x01, 0xd8, 0xfc, 0xc8, 0x0a, 0xf4, 0x29, 0x76,
+		0x82, 0x3a, 0xdf, 0x08, 0xf8, 0x33, 0xc1, 0xe5, 0x0b, 0x51, 0x77, 0x9c,
+		0x65, 0x93, 0xf3, 0x2a, 0x2c, 0x70, 0x0b, 0x8b, 0xa9, 0xb8, 0x57, 0x83,
+		0xf8, 0xbe, 0x9f, 0xb9, 0x44, 0x36, 0x47, 0xbd, 0xc0, 0xbb, 0x3c, 0x50,
+		0x74, 0x7f, 0x06, 0x29, 0x7c, 0xc6, 0x53, 0x8c, 0x25, 0xa5, 0xf5, 0x89,
+		0xc4, 0xb5, 0x6d, 0x03, 0x5c, 0x59, 0x10, 0x7c, 0x6b, 0xc5, 0x80, 0x0d,
+		0xb2, 0xca, 0xcb, 0x61, 0x86, 0x52, 0xf0, 0xca, 0xab, 0xa7, 0xe2, 0x15,
+		0xea, 0x44, 0x2d, 0xc3, 0x6a, 0x44, 0x99, 0xd8, 0xfe, 0xc3, 0x36, 0x2f,
+		0x3a, 0x0b, 0x2c, 0xa1, 0x51, 0xcb, 0xe4, 0xb3, 0xe6, 0x46, 0x6f, 0xe5,
+		0x9c, 0x73, 0x68, 0xb3, 0xc2, 0x28, 0x7f, 0xc7, 0xc3, 0xbf, 0x5c, 0x92,
+		0x4e, 0xb4, 0x42, 0x4e, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x6c, 0x62, 0xde, 0xc1, 0xb8, 0x19, 0x17, 0x49,
+		0xa3, 0x1d, 0xab, 0x49, 0x0b, 0xe5, 0x32, 0xa3, 0x59, 0x44, 0xde, 0xa4,
+		0x7c, 0xae, 0xf1, 0xf9, 0x80, 0x86, 0x39, 0x93, 0xd9, 0x89, 0x95, 0x45,
+		0xeb, 0x74, 0x06, 0xa3, 0x8d, 0x1e, 0xed, 0x31, 0x3b, 0x98, 0x7a, 0x46,
+		0x7d, 0xac, 0xea, 0xd6, 0xf0, 0xc8, 0x7a, 0x6d, 0x76, 0x6c, 0x66, 0xf6,
+		0xf2, 0x9f, 0x8a, 0xcb, 0x28, 0x1f, 0x11, 0x13, 0xcb, 0x10, 0x00, 0x00,
+		0xf5, 0x16, 0x6f, 0x06, 0x98, 0x52, 0xd9, 0x6c, 0xc2, 0xcd, 0x57, 0x0a,
+		0x59, 0x73, 0xdd, 0xb2, 0xe8, 0x83, 0x2f, 0x70, 0xdc, 0xaa, 0xa9, 0x49,
+		0xa5, 0x8e, 0xbb, 0x10, 0xda, 0x2b, 0xb3, 0x55, 0x41, 0x53, 0x15, 0x4a,
+		0x36, 0x33, 0x6d, 0x34, 0xc6, 0x57, 0x05, 0x16, 0xb4, 0x78, 0xab, 0x45,
+		0xa3, 0x64, 0xdc, 0xa3, 0xbf, 0x47, 0x64, 0xec, 0x7b, 0xa4, 0x3a, 0x0b,
+		0x86, 0xbc, 0xc2, 0x7b, 0x36, 0xf3, 0x01, 0xff, 0x1d, 0xb5, 0xc2, 0x82,
+		0xf9, 0x33, 0x89, 0x66, 0xc5, 0xb8, 0xf5, 0xc7, 0x25, 0x7e, 0x75, 0xb0,
+		0x21, 0x0d, 0x0c, 0x6c, 0x8b, 0x1d, 0x4a, 0x78, 0x46, 0xe4, 0xe0, 0xe8,
+		0x4c, 0x21, 0x21, 0xd4, 0x48, 0xc7, 0x15, 0x4d, 0x37, 0xac, 0xe2, 0x10,
+		0x24, 0x7a, 0x6e, 0x17, 0x27, 0x1c, 0xd1, 0x46, 0x8d, 0xb8, 0xac, 0x7f,
+		0xcd, 0x24, 0x73, 0xb3, 0x4f, 0x7a, 0x43, 0xfc, 0x06, 0x00, 0x45, 0x10,
+		0x00, 0x00, 0x04, 0x04, 0x0d, 0x0f, 0x03, 0xff, 0x00, 0x03, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x15, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xe7, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x85, 0x3e, 0x29, 0x8f, 0x3b, 0x7c,
+		0xde, 0x28, 0xb0, 0x64, 0x93, 0xd0, 0x6f, 0xb2, 0xad, 0x6f, 0x95, 0x66,
+		0xa9, 0x7f, 0xea, 0x6d, 0x3d, 0xe6, 0x62, 0x36, 0xb2, 0xaf, 0x1a, 0x35,
+		0x15, 0x0f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xdc, 0x9e,
+		0x2a, 0x7c, 0x6f, 0x94, 0x8f, 0x17, 0x47, 0x4e, 0x34, 0xa7, 0xfc, 0x43,
+		0xed, 0x03, 0x0f, 0x7c, 0x15, 0x63, 0xf1, 0xba, 0xbd, 0xdf, 0x63, 0x40,
+		0xc8, 0x2e, 0x0e, 0x54, 0xa8, 0xc5, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x04, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xcf, 0x1a,
+		0xe2, 0xcb, 0x76, 0x9f, 0xf1, 0xf2, 0x7a, 0xc5, 0x20, 0x34, 0x4e, 0x60,
+		0x90, 0x54, 0x48, 0xa4, 0x8b, 0xca, 0x6d, 0xe8, 0xf1, 0x01, 0x4c, 0x92,
+		0xe5, 0xa4, 0x93, 0xd9, 0x41, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x08, 0xbb, 0x5c, 0x76, 0xc5, 0x61, 0xd0, 0x73, 0x72, 0x4d,
+		0x89, 0x75, 0x33, 0x8f, 0xf7, 0x25, 0xd0, 0x2f, 0xfe, 0x6c, 0x48, 0x0b,
+		0x5c, 0x67, 0x04, 0xf3, 0x7d, 0x21, 0x45, 0xde, 0x0b, 0x89, 0x5b, 0x66,
+		0x0b, 0x85, 0x33, 0x06, 0xff, 0xb7, 0x94, 0x00, 0x0c, 0x7e, 0x3e, 0x49,
+		0x95, 0x1a, 0xfc, 0xe0, 0xd5, 0x03, 0xf0, 0xa5, 0x8d, 0xf5, 0xb3, 0x45,
+		0x95, 0xec, 0x27, 0xe1, 0xa4, 0x7d, 0x20, 0x00, 0x00, 0x01, 0x02, 0x03,
+		0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b,
+		0x1c, 0x1d, 0x1e, 0x1f, 0x05, 0x00, 0x5d, 0x0e, 0x00, 0x00, 0x2d, 0x2d,
+		0x2d, 0x2d, 0x2d, 0x42, 0x45, 0x47, 0x49, 0x4e, 0x20, 0x43, 0x45, 0x52,
+		0x54, 0x49, 0x46, 0x49, 0x43, 0x41, 0x54, 0x45, 0x2d, 0x2d, 0x2d, 0x2d,
+		0x2d, 0x0a, 0x4d, 0x49, 0x49, 0x45, 0x38, 0x54, 0x43, 0x43, 0x42, 0x4a,
+		0x65, 0x67, 0x41, 0x77, 0x49, 0x42, 0x41, 0x67, 0x49, 0x56, 0x41, 0x4c,
+		0x75, 0x6d, 0x77, 0x58, 0x58, 0x59, 0x4f, 0x4c, 0x6a, 0x66, 0x4f, 0x51,
+		0x44, 0x4d, 0x4e, 0x42, 0x48, 0x79, 0x54, 0x31, 0x45, 0x74, 0x45, 0x45,
+		0x45, 0x43, 0x4d, 0x41, 0x6f, 0x47, 0x43, 0x43, 0x71, 0x47, 0x53, 0x4d,
+		0x34, 0x39, 0x42, 0x41, 0x4d, 0x43, 0x0a, 0x4d, 0x48, 0x41, 0x78, 0x49,
+		0x6a, 0x41, 0x67, 0x42, 0x67, 0x4e, 0x56, 0x42, 0x41, 0x4d, 0x4d, 0x47,
+		0x55, 0x6c, 0x75, 0x64, 0x47, 0x56, 0x73, 0x49, 0x46, 0x4e, 0x48, 0x57,
+		0x43, 0x42, 0x51, 0x51, 0x30, 0x73, 0x67, 0x55, 0x47, 0x78, 0x68, 0x64,
+		0x47, 0x5a, 0x76, 0x63, 0x6d, 0x30, 0x67, 0x51, 0x30, 0x45, 0x78, 0x47,
+		0x6a, 0x41, 0x59, 0x42, 0x67, 0x4e, 0x56, 0x42, 0x41, 0x6f, 0x4d, 0x0a,
+		0x45, 0x55, 0x6c, 0x75, 0x64, 0x47, 0x56, 0x73, 0x49, 0x45, 0x4e, 0x76,
+		0x63, 0x6e, 0x42, 0x76, 0x63, 0x6d, 0x46, 0x30, 0x61, 0x57, 0x39, 0x75,
+		0x4d, 0x52, 0x51, 0x77, 0x45, 0x67, 0x59, 0x44, 0x56, 0x51, 0x51, 0x48,
+		0x44, 0x41, 0x74, 0x54, 0x59, 0x57, 0x35, 0x30, 0x59, 0x53, 0x42, 0x44,
+		0x62, 0x47, 0x46, 0x79, 0x59, 0x54, 0x45, 0x4c, 0x4d, 0x41, 0x6b, 0x47,
+		0x41, 0x31, 0x55, 0x45, 0x0a, 0x43, 0x41, 0x77, 0x43, 0x51, 0x30, 0x45,
+		0x78, 0x43, 0x7a, 0x41, 0x4a, 0x42, 0x67, 0x4e, 0x56, 0x42, 0x41, 0x59,
+		0x54, 0x41, 0x6c, 0x56, 0x54, 0x4d, 0x42, 0x34, 0x58, 0x44, 0x54, 0x49,
+		0x79, 0x4d, 0x44, 0x6b, 0x79, 0x4d, 0x44, 0x45, 0x7a, 0x4d, 0x6a, 0x41,
+		0x7a, 0x4d, 0x56, 0x6f, 0x58, 0x44, 0x54, 0x49, 0x35, 0x4d, 0x44, 0x6b,
+		0x79, 0x4d, 0x44, 0x45, 0x7a, 0x4d, 0x6a, 0x41, 0x7a, 0x0a, 0x4d, 0x56,
+		0x6f, 0x77, 0x63, 0x44, 0x45, 0x69, 0x4d, 0x43, 0x41, 0x47, 0x41, 0x31,
+		0x55, 0x45, 0x41, 0x77, 0x77, 0x5a, 0x53, 0x57, 0x35, 0x30, 0x5a, 0x57,
+		0x77, 0x67, 0x55, 0x30, 0x64, 0x59, 0x49, 0x46, 0x42, 0x44, 0x53, 0x79,
+		0x42, 0x44, 0x5a, 0x58, 0x4a, 0x30, 0x61, 0x57, 0x5a, 0x70, 0x59, 0x32,
+		0x46, 0x30, 0x5a, 0x54, 0x45, 0x61, 0x4d, 0x42, 0x67, 0x47, 0x41, 0x31,
+		0x55, 0x45, 0x0a, 0x43, 0x67, 0x77, 0x52, 0x53, 0x57, 0x35, 0x30, 0x5a,
+		0x57, 0x77, 0x67, 0x51, 0x32, 0x39, 0x79, 0x63, 0x47, 0x39, 0x79, 0x59,
+		0x58, 0x52, 0x70, 0x62, 0x32, 0x34, 0x78, 0x46, 0x44, 0x41, 0x53, 0x42,
+		0x67, 0x4e, 0x56, 0x42, 0x41, 0x63, 0x4d, 0x43, 0x31, 0x4e, 0x68, 0x62,
+		0x6e, 0x52, 0x68, 0x49, 0x45, 0x4e, 0x73, 0x59, 0x58, 0x4a, 0x68, 0x4d,
+		0x51, 0x73, 0x77, 0x43, 0x51, 0x59, 0x44, 0x0a, 0x56, 0x51, 0x51, 0x49,
+		0x44, 0x41, 0x4a, 0x44, 0x51, 0x54, 0x45, 0x4c, 0x4d, 0x41, 0x6b, 0x47,
+		0x41, 0x31, 0x55, 0x45, 0x42, 0x68, 0x4d, 0x43, 0x56, 0x56, 0x4d, 0x77,
+		0x57, 0x54, 0x41, 0x54, 0x42, 0x67, 0x63, 0x71, 0x68, 0x6b, 0x6a, 0x4f,
+		0x50, 0x51, 0x49, 0x42, 0x42, 0x67, 0x67, 0x71, 0x68, 0x6b, 0x6a, 0x4f,
+		0x50, 0x51, 0x4d, 0x42, 0x42, 0x77, 0x4e, 0x43, 0x41, 0x41, 0x52, 0x6d,
+		0x0a, 0x6c, 0x53, 0x6e, 0x54, 0x69, 0x43, 0x38, 0x2b, 0x68, 0x74, 0x70,
+		0x4f, 0x42, 0x42, 0x38, 0x41, 0x43, 0x7a, 0x57, 0x68, 0x59, 0x79, 0x34,
+		0x5a, 0x64, 0x6f, 0x74, 0x55, 0x73, 0x67, 0x4c, 0x48, 0x69, 0x43, 0x6d,
+		0x51, 0x43, 0x62, 0x65, 0x4f, 0x53, 0x6f, 0x2b, 0x75, 0x67, 0x65, 0x54,
+		0x4c, 0x45, 0x4d, 0x55, 0x61, 0x4c, 0x35, 0x70, 0x70, 0x52, 0x42, 0x37,
+		0x42, 0x58, 0x5a, 0x33, 0x68, 0x0a, 0x63, 0x53, 0x54, 0x79, 0x6c, 0x39,
+		0x4a, 0x2b, 0x71, 0x46, 0x52, 0x6e, 0x53, 0x74, 0x49, 0x6d, 0x42, 0x31,
+		0x63, 0x61, 0x6f, 0x34, 0x49, 0x44, 0x44, 0x44, 0x43, 0x43, 0x41, 0x77,
+		0x67, 0x77, 0x48, 0x77, 0x59, 0x44, 0x56, 0x52, 0x30, 0x6a, 0x42, 0x42,
+		0x67, 0x77, 0x46, 0x6f, 0x41, 0x55, 0x6c, 0x57, 0x39, 0x64, 0x7a, 0x62,
+		0x30, 0x62, 0x34, 0x65, 0x6c, 0x41, 0x53, 0x63, 0x6e, 0x55, 0x0a, 0x39,
+		0x44, 0x50, 0x4f, 0x41, 0x56, 0x63, 0x4c, 0x33, 0x6c, 0x51, 0x77, 0x61,
+		0x77, 0x59, 0x44, 0x56, 0x52, 0x30, 0x66, 0x42, 0x47, 0x51, 0x77, 0x59,
+		0x6a, 0x42, 0x67, 0x6f, 0x46, 0x36, 0x67, 0x58, 0x49, 0x5a, 0x61, 0x61,
+		0x48, 0x52, 0x30, 0x63, 0x48, 0x4d, 0x36, 0x4c, 0x79, 0x39, 0x68, 0x63,
+		0x47, 0x6b, 0x75, 0x64, 0x48, 0x4a, 0x31, 0x63, 0x33, 0x52, 0x6c, 0x5a,
+		0x48, 0x4e, 0x6c, 0x0a, 0x63, 0x6e, 0x5a, 0x70, 0x59, 0x32, 0x56, 0x7a,
+		0x4c, 0x6d, 0x6c, 0x75, 0x64, 0x47, 0x56, 0x73, 0x4c, 0x6d, 0x4e, 0x76,
+		0x62, 0x53, 0x39, 0x7a, 0x5a, 0x33, 0x67, 0x76, 0x59, 0x32, 0x56, 0x79,
+		0x64, 0x47, 0x6c, 0x6d, 0x61, 0x57, 0x4e, 0x68, 0x64, 0x47, 0x6c, 0x76,
+		0x62, 0x69, 0x39, 0x32, 0x4e, 0x43, 0x39, 0x77, 0x59, 0x32, 0x74, 0x6a,
+		0x63, 0x6d, 0x77, 0x2f, 0x59, 0x32, 0x45, 0x39, 0x0a, 0x63, 0x47, 0x78,
+		0x68, 0x64, 0x47, 0x5a, 0x76, 0x63, 0x6d, 0x30, 0x6d, 0x5a, 0x57, 0x35,
+		0x6a, 0x62, 0x32, 0x52, 0x70, 0x62, 0x6d, 0x63, 0x39, 0x5a, 0x47, 0x56,
+		0x79, 0x4d, 0x42, 0x30, 0x47, 0x41, 0x31, 0x55, 0x64, 0x44, 0x67, 0x51,
+		0x57, 0x42, 0x42, 0x54, 0x4e, 0x55, 0x47, 0x66, 0x4b, 0x7a, 0x36, 0x46,
+		0x4e, 0x75, 0x73, 0x4f, 0x42, 0x41, 0x4f, 0x74, 0x49, 0x6c, 0x70, 0x4e,
+		0x33, 0x0a, 0x2b, 0x48, 0x51, 0x41, 0x72, 0x54, 0x41, 0x4f, 0x42, 0x67,
+		0x4e, 0x56, 0x48, 0x51, 0x38, 0x42, 0x41, 0x66, 0x38, 0x45, 0x42, 0x41,
+		0x4d, 0x43, 0x42, 0x73, 0x41, 0x77, 0x44, 0x41, 0x59, 0x44, 0x56, 0x52,
+		0x30, 0x54, 0x41, 0x51, 0x48, 0x2f, 0x42, 0x41, 0x49, 0x77, 0x41, 0x44,
+		0x43, 0x43, 0x41, 0x6a, 0x6b, 0x47, 0x43, 0x53, 0x71, 0x47, 0x53, 0x49,
+		0x62, 0x34, 0x54, 0x51, 0x45, 0x4e, 0x0a, 0x41, 0x51, 0x53, 0x43, 0x41,
+		0x69, 0x6f, 0x77, 0x67, 0x67, 0x49, 0x6d, 0x4d, 0x42, 0x34, 0x47, 0x43,
+		0x69, 0x71, 0x47, 0x53, 0x49, 0x62, 0x34, 0x54, 0x51, 0x45, 0x4e, 0x41,
+		0x51, 0x45, 0x45, 0x45, 0x41, 0x69, 0x64, 0x33, 0x39, 0x75, 0x63, 0x41,
+		0x31, 0x6e, 0x49, 0x4b, 0x6a, 0x76, 0x48, 0x63, 0x5a, 0x49, 0x35, 0x56,
+		0x30, 0x34, 0x77, 0x67, 0x67, 0x46, 0x6a, 0x42, 0x67, 0x6f, 0x71, 0x0a,
+		0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51, 0x45, 0x43,
+		0x4d, 0x49, 0x49, 0x42, 0x55, 0x7a, 0x41, 0x51, 0x42, 0x67, 0x73, 0x71,
+		0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51, 0x45, 0x43,
+		0x41, 0x51, 0x49, 0x42, 0x41, 0x7a, 0x41, 0x51, 0x42, 0x67, 0x73, 0x71,
+		0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51, 0x45, 0x43,
+		0x41, 0x67, 0x49, 0x42, 0x0a, 0x41, 0x7a, 0x41, 0x51, 0x42, 0x67, 0x73,
+		0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51, 0x45,
+		0x43, 0x41, 0x77, 0x49, 0x42, 0x41, 0x6a, 0x41, 0x51, 0x42, 0x67, 0x73,
+		0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51, 0x45,
+		0x43, 0x42, 0x41, 0x49, 0x42, 0x41, 0x6a, 0x41, 0x51, 0x42, 0x67, 0x73,
+		0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x0a, 0x44, 0x51,
+		0x45, 0x43, 0x42, 0x51, 0x49, 0x42, 0x41, 0x6a, 0x41, 0x51, 0x42, 0x67,
+		0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51,
+		0x45, 0x43, 0x42, 0x67, 0x49, 0x42, 0x41, 0x54, 0x41, 0x51, 0x42, 0x67,
+		0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51,
+		0x45, 0x43, 0x42, 0x77, 0x49, 0x42, 0x41, 0x44, 0x41, 0x51, 0x42, 0x67,
+		0x73, 0x71, 0x0a, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44,
+		0x51, 0x45, 0x43, 0x43, 0x41, 0x49, 0x42, 0x41, 0x6a, 0x41, 0x51, 0x42,
+		0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44,
+		0x51, 0x45, 0x43, 0x43, 0x51, 0x49, 0x42, 0x41, 0x44, 0x41, 0x51, 0x42,
+		0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44,
+		0x51, 0x45, 0x43, 0x43, 0x67, 0x49, 0x42, 0x0a, 0x41, 0x44, 0x41, 0x51,
+		0x42, 0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42,
+		0x44, 0x51, 0x45, 0x43, 0x43, 0x77, 0x49, 0x42, 0x41, 0x44, 0x41, 0x51,
+		0x42, 0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42,
+		0x44, 0x51, 0x45, 0x43, 0x44, 0x41, 0x49, 0x42, 0x41, 0x44, 0x41, 0x51,
+		0x42, 0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42,
+		0x0a, 0x44, 0x51, 0x45, 0x43, 0x44, 0x51, 0x49, 0x42, 0x41, 0x44, 0x41,
+		0x51, 0x42, 0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30,
+		0x42, 0x44, 0x51, 0x45, 0x43, 0x44, 0x67, 0x49, 0x42, 0x41, 0x44, 0x41,
+		0x51, 0x42, 0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30,
+		0x42, 0x44, 0x51, 0x45, 0x43, 0x44, 0x77, 0x49, 0x42, 0x41, 0x44, 0x41,
+		0x51, 0x42, 0x67, 0x73, 0x71, 0x0a, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45,
+		0x30, 0x42, 0x44, 0x51, 0x45, 0x43, 0x45, 0x41, 0x49, 0x42, 0x41, 0x44,
+		0x41, 0x51, 0x42, 0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45,
+		0x30, 0x42, 0x44, 0x51, 0x45, 0x43, 0x45, 0x51, 0x49, 0x42, 0x43, 0x7a,
+		0x41, 0x66, 0x42, 0x67, 0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45,
+		0x30, 0x42, 0x44, 0x51, 0x45, 0x43, 0x45, 0x67, 0x51, 0x51, 0x0a, 0x41,
+		0x77, 0x4d, 0x43, 0x41, 0x67, 0x49, 0x42, 0x41, 0x41, 0x49, 0x41, 0x41,
+		0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x41, 0x44, 0x41, 0x51, 0x42,
+		0x67, 0x6f, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44,
+		0x51, 0x45, 0x44, 0x42, 0x41, 0x49, 0x41, 0x41, 0x44, 0x41, 0x55, 0x42,
+		0x67, 0x6f, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44,
+		0x51, 0x45, 0x45, 0x0a, 0x42, 0x41, 0x5a, 0x51, 0x67, 0x47, 0x38, 0x41,
+		0x41, 0x41, 0x41, 0x77, 0x44, 0x77, 0x59, 0x4b, 0x4b, 0x6f, 0x5a, 0x49,
+		0x68, 0x76, 0x68, 0x4e, 0x41, 0x51, 0x30, 0x42, 0x42, 0x51, 0x6f, 0x42,
+		0x41, 0x54, 0x41, 0x65, 0x42, 0x67, 0x6f, 0x71, 0x68, 0x6b, 0x69, 0x47,
+		0x2b, 0x45, 0x30, 0x42, 0x44, 0x51, 0x45, 0x47, 0x42, 0x42, 0x43, 0x4d,
+		0x4d, 0x55, 0x30, 0x58, 0x30, 0x67, 0x58, 0x66, 0x0a, 0x72, 0x38, 0x76,
+		0x73, 0x75, 0x77, 0x44, 0x38, 0x68, 0x2b, 0x2f, 0x33, 0x4d, 0x45, 0x51,
+		0x47, 0x43, 0x69, 0x71, 0x47, 0x53, 0x49, 0x62, 0x34, 0x54, 0x51, 0x45,
+		0x4e, 0x41, 0x51, 0x63, 0x77, 0x4e, 0x6a, 0x41, 0x51, 0x42, 0x67, 0x73,
+		0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51, 0x45,
+		0x48, 0x41, 0x51, 0x45, 0x42, 0x2f, 0x7a, 0x41, 0x51, 0x42, 0x67, 0x73,
+		0x71, 0x0a, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51,
+		0x45, 0x48, 0x41, 0x67, 0x45, 0x42, 0x41, 0x44, 0x41, 0x51, 0x42, 0x67,
+		0x73, 0x71, 0x68, 0x6b, 0x69, 0x47, 0x2b, 0x45, 0x30, 0x42, 0x44, 0x51,
+		0x45, 0x48, 0x41, 0x77, 0x45, 0x42, 0x2f, 0x7a, 0x41, 0x4b, 0x42, 0x67,
+		0x67, 0x71, 0x68, 0x6b, 0x6a, 0x4f, 0x50, 0x51, 0x51, 0x44, 0x41, 0x67,
+		0x4e, 0x49, 0x41, 0x44, 0x42, 0x46, 0x0a, 0x41, 0x69, 0x45, 0x41, 0x6d,
+		0x6a, 0x4e, 0x31, 0x59, 0x4b, 0x53, 0x2b, 0x56, 0x4c, 0x72, 0x52, 0x47,
+		0x77, 0x34, 0x2f, 0x69, 0x2b, 0x46, 0x7a, 0x46, 0x37, 0x38, 0x34, 0x4c,
+		0x36, 0x53, 0x67, 0x68, 0x69, 0x44, 0x69, 0x6e, 0x50, 0x61, 0x30, 0x31,
+		0x67, 0x56, 0x76, 0x77, 0x36, 0x59, 0x43, 0x49, 0x47, 0x53, 0x55, 0x59,
+		0x35, 0x57, 0x44, 0x7a, 0x54, 0x4e, 0x49, 0x59, 0x55, 0x41, 0x68, 0x0a,
+		0x6e, 0x77, 0x31, 0x77, 0x45, 0x2b, 0x62, 0x54, 0x6c, 0x6b, 0x59, 0x31,
+		0x4d, 0x79, 0x48, 0x37, 0x71, 0x59, 0x67, 0x35, 0x78, 0x48, 0x6b, 0x48,
+		0x42, 0x36, 0x72, 0x4c, 0x0a, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d, 0x45, 0x4e,
+		0x44, 0x20, 0x43, 0x45, 0x52, 0x54, 0x49, 0x46, 0x49, 0x43, 0x41, 0x54,
+		0x45, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d, 0x0a, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d,
+		0x42, 0x45, 0x47, 0x49, 0x4e, 0x20, 0x43, 0x45, 0x52, 0x54, 0x49, 0x46,
+		0x49, 0x43, 0x41, 0x54, 0x45, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d, 0x0a, 0x4d,
+		0x49, 0x49, 0x43, 0x6c, 0x6a, 0x43, 0x43, 0x41, 0x6a, 0x32, 0x67, 0x41,
+		0x77, 0x49, 0x42, 0x41, 0x67, 0x49, 0x56, 0x41, 0x4a, 0x56, 0x76, 0x58,
+		0x63, 0x32, 0x39, 0x47, 0x2b, 0x48, 0x70, 0x51, 0x45, 0x6e, 0x4a, 0x31,
+		0x50, 0x51, 0x7a, 0x7a, 0x67, 0x46, 0x58, 0x43, 0x39, 0x35, 0x55, 0x4d,
+		0x41, 0x6f, 0x47, 0x43, 0x43, 0x71, 0x47, 0x53, 0x4d, 0x34, 0x39, 0x42,
+		0x41, 0x4d, 0x43, 0x0a, 0x4d, 0x47, 0x67, 0x78, 0x47, 0x6a, 0x41, 0x59,
+		0x42, 0x67, 0x4e, 0x56, 0x42, 0x41, 0x4d, 0x4d, 0x45, 0x55, 0x6c, 0x75,
+		0x64, 0x47, 0x56, 0x73, 0x49, 0x46, 0x4e, 0x48, 0x57, 0x43, 0x42, 0x53,
+		0x62, 0x32, 0x39, 0x30, 0x49, 0x45, 0x4e, 0x42, 0x4d, 0x52, 0x6f, 0x77,
+		0x47, 0x41, 0x59, 0x44, 0x56, 0x51, 0x51, 0x4b, 0x44, 0x42, 0x46, 0x4a,
+		0x62, 0x6e, 0x52, 0x6c, 0x62, 0x43, 0x42, 0x44, 0x0a, 0x62, 0x33, 0x4a,
+		0x77, 0x62, 0x33, 0x4a, 0x68, 0x64, 0x47, 0x6c, 0x76, 0x62, 0x6a, 0x45,
+		0x55, 0x4d, 0x42, 0x49, 0x47, 0x41, 0x31, 0x55, 0x45, 0x42, 0x77, 0x77,
+		0x4c, 0x55, 0x32, 0x46, 0x75, 0x64, 0x47, 0x45, 0x67, 0x51, 0x32, 0x78,
+		0x68, 0x63, 0x6d, 0x45, 0x78, 0x43, 0x7a, 0x41, 0x4a, 0x42, 0x67, 0x4e,
+		0x56, 0x42, 0x41, 0x67, 0x4d, 0x41, 0x6b, 0x4e, 0x42, 0x4d, 0x51, 0x73,
+		0x77, 0x0a, 0x43, 0x51, 0x59, 0x44, 0x56, 0x51, 0x51, 0x47, 0x45, 0x77,
+		0x4a, 0x56, 0x55, 0x7a, 0x41, 0x65, 0x46, 0x77, 0x30, 0x78, 0x4f, 0x44,
+		0x41, 0x31, 0x4d, 0x6a, 0x45, 0x78, 0x4d, 0x44, 0x55, 0x77, 0x4d, 0x54,
+		0x42, 0x61, 0x46, 0x77, 0x30, 0x7a, 0x4d, 0x7a, 0x41, 0x31, 0x4d, 0x6a,
+		0x45, 0x78, 0x4d, 0x44, 0x55, 0x77, 0x4d, 0x54, 0x42, 0x61, 0x4d, 0x48,
+		0x41, 0x78, 0x49, 0x6a, 0x41, 0x67, 0x0a, 0x42, 0x67, 0x4e, 0x56, 0x42,
+		0x41, 0x4d, 0x4d, 0x47, 0x55, 0x6c, 0x75, 0x64, 0x47, 0x56, 0x73, 0x49,
+		0x46, 0x4e, 0x48, 0x57, 0x43, 0x42, 0x51, 0x51, 0x30, 0x73, 0x67, 0x55,
+		0x47, 0x78, 0x68, 0x64, 0x47, 0x5a, 0x76, 0x63, 0x6d, 0x30, 0x67, 0x51,
+		0x30, 0x45, 0x78, 0x47, 0x6a, 0x41, 0x59, 0x42, 0x67, 0x4e, 0x56, 0x42,
+		0x41, 0x6f, 0x4d, 0x45, 0x55, 0x6c, 0x75, 0x64, 0x47, 0x56, 0x73, 0x0a,
+		0x49, 0x45, 0x4e, 0x76, 0x63, 0x6e, 0x42, 0x76, 0x63, 0x6d, 0x46, 0x30,
+		0x61, 0x57, 0x39, 0x75, 0x4d, 0x52, 0x51, 0x77, 0x45, 0x67, 0x59, 0x44,
+		0x56, 0x51, 0x51, 0x48, 0x44, 0x41, 0x74, 0x54, 0x59, 0x57, 0x35, 0x30,
+		0x59, 0x53, 0x42, 0x44, 0x62, 0x47, 0x46, 0x79, 0x59, 0x54, 0x45, 0x4c,
+		0x4d, 0x41, 0x6b, 0x47, 0x41, 0x31, 0x55, 0x45, 0x43, 0x41, 0x77, 0x43,
+		0x51, 0x30, 0x45, 0x78, 0x0a, 0x43, 0x7a, 0x41, 0x4a, 0x42, 0x67, 0x4e,
+		0x56, 0x42, 0x41, 0x59, 0x54, 0x41, 0x6c, 0x56, 0x54, 0x4d, 0x46, 0x6b,
+		0x77, 0x45, 0x77, 0x59, 0x48, 0x4b, 0x6f, 0x5a, 0x49, 0x7a, 0x6a, 0x30,
+		0x43, 0x41, 0x51, 0x59, 0x49, 0x4b, 0x6f, 0x5a, 0x49, 0x7a, 0x6a, 0x30,
+		0x44, 0x41, 0x51, 0x63, 0x44, 0x51, 0x67, 0x41, 0x45, 0x4e, 0x53, 0x42,
+		0x2f, 0x37, 0x74, 0x32, 0x31, 0x6c, 0x58, 0x53, 0x4f, 0x0a, 0x32, 0x43,
+		0x75, 0x7a, 0x70, 0x78, 0x77, 0x37, 0x34, 0x65, 0x4a, 0x42, 0x37, 0x32,
+		0x45, 0x79, 0x44, 0x47, 0x67, 0x57, 0x35, 0x72, 0x58, 0x43, 0x74, 0x78,
+		0x32, 0x74, 0x56, 0x54, 0x4c, 0x71, 0x36, 0x68, 0x4b, 0x6b, 0x36, 0x7a,
+		0x2b, 0x55, 0x69, 0x52, 0x5a, 0x43, 0x6e, 0x71, 0x52, 0x37, 0x70, 0x73,
+		0x4f, 0x76, 0x67, 0x71, 0x46, 0x65, 0x53, 0x78, 0x6c, 0x6d, 0x54, 0x6c,
+		0x4a, 0x6c, 0x0a, 0x65, 0x54, 0x6d, 0x69, 0x32, 0x57, 0x59, 0x7a, 0x33,
+		0x71, 0x4f, 0x42, 0x75, 0x7a, 0x43, 0x42, 0x75, 0x44, 0x41, 0x66, 0x42,
+		0x67, 0x4e, 0x56, 0x48, 0x53, 0x4d, 0x45, 0x47, 0x44, 0x41, 0x57, 0x67,
+		0x42, 0x51, 0x69, 0x5a, 0x51, 0x7a, 0x57, 0x57, 0x70, 0x30, 0x30, 0x69,
+		0x66, 0x4f, 0x44, 0x74, 0x4a, 0x56, 0x53, 0x76, 0x31, 0x41, 0x62, 0x4f,
+		0x53, 0x63, 0x47, 0x72, 0x44, 0x42, 0x53, 0x0a, 0x42, 0x67, 0x4e, 0x56,
+		0x48, 0x52, 0x38, 0x45, 0x53, 0x7a, 0x42, 0x4a, 0x4d, 0x45, 0x65, 0x67,
+		0x52, 0x61, 0x42, 0x44, 0x68, 0x6b, 0x46, 0x6f, 0x64, 0x48, 0x52, 0x77,
+		0x63, 0x7a, 0x6f, 0x76, 0x4c, 0x32, 0x4e, 0x6c, 0x63, 0x6e, 0x52, 0x70,
+		0x5a, 0x6d, 0x6c, 0x6a, 0x59, 0x58, 0x52, 0x6c, 0x63, 0x79, 0x35, 0x30,
+		0x63, 0x6e, 0x56, 0x7a, 0x64, 0x47, 0x56, 0x6b, 0x63, 0x32, 0x56, 0x79,
+		0x0a, 0x64, 0x6d, 0x6c, 0x6a, 0x5a, 0x58, 0x4d, 0x75, 0x61, 0x57, 0x35,
+		0x30, 0x5a, 0x57, 0x77, 0x75, 0x59, 0x32, 0x39, 0x74, 0x4c, 0x30, 0x6c,
+		0x75, 0x64, 0x47, 0x56, 0x73, 0x55, 0x30, 0x64, 0x59, 0x55, 0x6d, 0x39,
+		0x76, 0x64, 0x45, 0x4e, 0x42, 0x4c, 0x6d, 0x52, 0x6c, 0x63, 0x6a, 0x41,
+		0x64, 0x42, 0x67, 0x4e, 0x56, 0x48, 0x51, 0x34, 0x45, 0x46, 0x67, 0x51,
+		0x55, 0x6c, 0x57, 0x39, 0x64, 0x0a, 0x7a, 0x62, 0x30, 0x62, 0x34, 0x65,
+		0x6c, 0x41, 0x53, 0x63, 0x6e, 0x55, 0x39, 0x44, 0x50, 0x4f, 0x41, 0x56,
+		0x63, 0x4c, 0x33, 0x6c, 0x51, 0x77, 0x44, 0x67, 0x59, 0x44, 0x56, 0x52,
+		0x30, 0x50, 0x41, 0x51, 0x48, 0x2f, 0x42, 0x41, 0x51, 0x44, 0x41, 0x67,
+		0x45, 0x47, 0x4d, 0x42, 0x49, 0x47, 0x41, 0x31, 0x55, 0x64, 0x45, 0x77,
+		0x45, 0x42, 0x2f, 0x77, 0x51, 0x49, 0x4d, 0x41, 0x59, 0x42, 0x0a, 0x41,
+		0x66, 0x38, 0x43, 0x41, 0x51, 0x41, 0x77, 0x43, 0x67, 0x59, 0x49, 0x4b,
+		0x6f, 0x5a, 0x49, 0x7a, 0x6a, 0x30, 0x45, 0x41, 0x77, 0x49, 0x44, 0x52,
+		0x77, 0x41, 0x77, 0x52, 0x41, 0x49, 0x67, 0x58, 0x73, 0x56, 0x6b, 0x69,
+		0x30, 0x77, 0x2b, 0x69, 0x36, 0x56, 0x59, 0x47, 0x57, 0x33, 0x55, 0x46,
+		0x2f, 0x32, 0x32, 0x75, 0x61, 0x58, 0x65, 0x30, 0x59, 0x4a, 0x44, 0x6a,
+		0x31, 0x55, 0x65, 0x0a, 0x6e, 0x41, 0x2b, 0x54, 0x6a, 0x44, 0x31, 0x61,
+		0x69, 0x35, 0x63, 0x43, 0x49, 0x43, 0x59, 0x62, 0x31, 0x53, 0x41, 0x6d,
+		0x44, 0x35, 0x78, 0x6b, 0x66, 0x54, 0x56, 0x70, 0x76, 0x6f, 0x34, 0x55,
+		0x6f, 0x79, 0x69, 0x53, 0x59, 0x78, 0x72, 0x44, 0x57, 0x4c, 0x6d, 0x55,
+		0x52, 0x34, 0x43, 0x49, 0x39, 0x4e, 0x4b, 0x79, 0x66, 0x50, 0x4e, 0x2b,
+		0x0a, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d, 0x45, 0x4e, 0x44, 0x20, 0x43, 0x45,
+		0x52, 0x54, 0x49, 0x46, 0x49, 0x43, 0x41, 0x54, 0x45, 0x2d, 0x2d, 0x2d,
+		0x2d, 0x2d, 0x0a, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d, 0x42, 0x45, 0x47, 0x49,
+		0x4e, 0x20, 0x43, 0x45, 0x52, 0x54, 0x49, 0x46, 0x49, 0x43, 0x41, 0x54,
+		0x45, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d, 0x0a, 0x4d, 0x49, 0x49, 0x43, 0x6a,
+		0x7a, 0x43, 0x43, 0x41, 0x6a, 0x53, 0x67, 0x41, 0x77, 0x49, 0x42, 0x41,
+		0x67, 0x49, 0x55, 0x49, 0x6d, 0x55, 0x4d, 0x31, 0x6c, 0x71, 0x64, 0x4e,
+		0x49, 0x6e, 0x7a, 0x67, 0x37, 0x53, 0x56, 0x55, 0x72, 0x39, 0x51, 0x47,
+		0x7a, 0x6b, 0x6e, 0x42, 0x71, 0x77, 0x77, 0x43, 0x67, 0x59, 0x49, 0x4b,
+		0x6f, 0x5a, 0x49, 0x7a, 0x6a, 0x30, 0x45, 0x41, 0x77, 0x49, 0x77, 0x0a,
+		0x61, 0x44, 0x45, 0x61, 0x4d, 0x42, 0x67, 0x47, 0x41, 0x31, 0x55, 0x45,
+		0x41, 0x77, 0x77, 0x52, 0x53, 0x57, 0x35, 0x30, 0x5a, 0x57, 0x77, 0x67,
+		0x55, 0x30, 0x64, 0x59, 0x49, 0x46, 0x4a, 0x76, 0x62, 0x33, 0x51, 0x67,
+		0x51, 0x30, 0x45, 0x78, 0x47, 0x6a, 0x41, 0x59, 0x42, 0x67, 0x4e, 0x56,
+		0x42, 0x41, 0x6f, 0x4d, 0x45, 0x55, 0x6c, 0x75, 0x64, 0x47, 0x56, 0x73,
+		0x49, 0x45, 0x4e, 0x76, 0x0a, 0x63, 0x6e, 0x42, 0x76, 0x63, 0x6d, 0x46,
+		0x30, 0x61, 0x57, 0x39, 0x75, 0x4d, 0x52, 0x51, 0x77, 0x45, 0x67, 0x59,
+		0x44, 0x56, 0x51, 0x51, 0x48, 0x44, 0x41, 0x74, 0x54, 0x59, 0x57, 0x35,
+		0x30, 0x59, 0x53, 0x42, 0x44, 0x62, 0x47, 0x46, 0x79, 0x59, 0x54, 0x45,
+		0x4c, 0x4d, 0x41, 0x6b, 0x47, 0x41, 0x31, 0x55, 0x45, 0x43, 0x41, 0x77,
+		0x43, 0x51, 0x30, 0x45, 0x78, 0x43, 0x7a, 0x41, 0x4a, 0x0a, 0x42, 0x67,
+		0x4e, 0x56, 0x42, 0x41, 0x59, 0x54, 0x41, 0x6c, 0x56, 0x54, 0x4d, 0x42,
+		0x34, 0x58, 0x44, 0x54, 0x45, 0x34, 0x4d, 0x44, 0x55, 0x79, 0x4d, 0x54,
+		0x45, 0x77, 0x4e, 0x44, 0x55, 0x78, 0x4d, 0x46, 0x6f, 0x58, 0x44, 0x54,
+		0x51, 0x35, 0x4d, 0x54, 0x49, 0x7a, 0x4d, 0x54, 0x49, 0x7a, 0x4e, 0x54,
+		0x6b, 0x31, 0x4f, 0x56, 0x6f, 0x77, 0x61, 0x44, 0x45, 0x61, 0x4d, 0x42,
+		0x67, 0x47, 0x0a, 0x41, 0x31, 0x55, 0x45, 0x41, 0x77, 0x77, 0x52, 0x53,
+		0x57, 0x35, 0x30, 0x5a, 0x57, 0x77, 0x67, 0x55, 0x30, 0x64, 0x59, 0x49,
+		0x46, 0x4a, 0x76, 0x62, 0x33, 0x51, 0x67, 0x51, 0x30, 0x45, 0x78, 0x47,
+		0x6a, 0x41, 0x59, 0x42, 0x67, 0x4e, 0x56, 0x42, 0x41, 0x6f, 0x4d, 0x45,
+		0x55, 0x6c, 0x75, 0x64, 0x47, 0x56, 0x73, 0x49, 0x45, 0x4e, 0x76, 0x63,
+		0x6e, 0x42, 0x76, 0x63, 0x6d, 0x46, 0x30, 0x0a, 0x61, 0x57, 0x39, 0x75,
+		0x4d, 0x52, 0x51, 0x77, 0x45, 0x67, 0x59, 0x44, 0x56, 0x51, 0x51, 0x48,
+		0x44, 0x41, 0x74, 0x54, 0x59, 0x57, 0x35, 0x30, 0x59, 0x53, 0x42, 0x44,
+		0x62, 0x47, 0x46, 0x79, 0x59, 0x54, 0x45, 0x4c, 0x4d, 0x41, 0x6b, 0x47,
+		0x41, 0x31, 0x55, 0x45, 0x43, 0x41, 0x77, 0x43, 0x51, 0x30, 0x45, 0x78,
+		0x43, 0x7a, 0x41, 0x4a, 0x42, 0x67, 0x4e, 0x56, 0x42, 0x41, 0x59, 0x54,
+		0x0a, 0x41, 0x6c, 0x56, 0x54, 0x4d, 0x46, 0x6b, 0x77, 0x45, 0x77, 0x59,
+		0x48, 0x4b, 0x6f, 0x5a, 0x49, 0x7a, 0x6a, 0x30, 0x43, 0x41, 0x51, 0x59,
+		0x49, 0x4b, 0x6f, 0x5a, 0x49, 0x7a, 0x6a, 0x30, 0x44, 0x41, 0x51, 0x63,
+		0x44, 0x51, 0x67, 0x41, 0x45, 0x43, 0x36, 0x6e, 0x45, 0x77, 0x4d, 0x44,
+		0x49, 0x59, 0x5a, 0x4f, 0x6a, 0x2f, 0x69, 0x50, 0x57, 0x73, 0x43, 0x7a,
+		0x61, 0x45, 0x4b, 0x69, 0x37, 0x0a, 0x31, 0x4f, 0x69, 0x4f, 0x53, 0x4c,
+		0x52, 0x46, 0x68, 0x57, 0x47, 0x6a, 0x62, 0x6e, 0x42, 0x56, 0x4a, 0x66,
+		0x56, 0x6e, 0x6b, 0x59, 0x34, 0x75, 0x33, 0x49, 0x6a, 0x6b, 0x44, 0x59,
+		0x59, 0x4c, 0x30, 0x4d, 0x78, 0x4f, 0x34, 0x6d, 0x71, 0x73, 0x79, 0x59,
+		0x6a, 0x6c, 0x42, 0x61, 0x6c, 0x54, 0x56, 0x59, 0x78, 0x46, 0x50, 0x32,
+		0x73, 0x4a, 0x42, 0x4b, 0x35, 0x7a, 0x6c, 0x4b, 0x4f, 0x42, 0x0a, 0x75,
+		0x7a, 0x43, 0x42, 0x75, 0x44, 0x41, 0x66, 0x42, 0x67, 0x4e, 0x56, 0x48,
+		0x53, 0x4d, 0x45, 0x47, 0x44, 0x41, 0x57, 0x67, 0x42, 0x51, 0x69, 0x5a,
+		0x51, 0x7a, 0x57, 0x57, 0x70, 0x30, 0x30, 0x69, 0x66, 0x4f, 0x44, 0x74,
+		0x4a, 0x56, 0x53, 0x76, 0x31, 0x41, 0x62, 0x4f, 0x53, 0x63, 0x47, 0x72,
+		0x44, 0x42, 0x53, 0x42, 0x67, 0x4e, 0x56, 0x48, 0x52, 0x38, 0x45, 0x53,
+		0x7a, 0x42, 0x4a, 0x0a, 0x4d, 0x45, 0x65, 0x67, 0x52, 0x61, 0x42, 0x44,
+		0x68, 0x6b, 0x46, 0x6f, 0x64, 0x48, 0x52, 0x77, 0x63, 0x7a, 0x6f, 0x76,
+		0x4c, 0x32, 0x4e, 0x6c, 0x63, 0x6e, 0x52, 0x70, 0x5a, 0x6d, 0x6c, 0x6a,
+		0x59, 0x58, 0x52, 0x6c, 0x63, 0x79, 0x35, 0x30, 0x63, 0x6e, 0x56, 0x7a,
+		0x64, 0x47, 0x56, 0x6b, 0x63, 0x32, 0x56, 0x79, 0x64, 0x6d, 0x6c, 0x6a,
+		0x5a, 0x58, 0x4d, 0x75, 0x61, 0x57, 0x35, 0x30, 0x0a, 0x5a, 0x57, 0x77,
+		0x75, 0x59, 0x32, 0x39, 0x74, 0x4c, 0x30, 0x6c, 0x75, 0x64, 0x47, 0x56,
+		0x73, 0x55, 0x30, 0x64, 0x59, 0x55, 0x6d, 0x39, 0x76, 0x64, 0x45, 0x4e,
+		0x42, 0x4c, 0x6d, 0x52, 0x6c, 0x63, 0x6a, 0x41, 0x64, 0x42, 0x67, 0x4e,
+		0x56, 0x48, 0x51, 0x34, 0x45, 0x46, 0x67, 0x51, 0x55, 0x49, 0x6d, 0x55,
+		0x4d, 0x31, 0x6c, 0x71, 0x64, 0x4e, 0x49, 0x6e, 0x7a, 0x67, 0x37, 0x53,
+		0x56, 0x0a, 0x55, 0x72, 0x39, 0x51, 0x47, 0x7a, 0x6b, 0x6e, 0x42, 0x71,
+		0x77, 0x77, 0x44, 0x67, 0x59, 0x44, 0x56, 0x52, 0x30, 0x50, 0x41, 0x51,
+		0x48, 0x2f, 0x42, 0x41, 0x51, 0x44, 0x41, 0x67, 0x45, 0x47, 0x4d, 0x42,
+		0x49, 0x47, 0x41, 0x31, 0x55, 0x64, 0x45, 0x77, 0x45, 0x42, 0x2f, 0x77,
+		0x51, 0x49, 0x4d, 0x41, 0x59, 0x42, 0x41, 0x66, 0x38, 0x43, 0x41, 0x51,
+		0x45, 0x77, 0x43, 0x67, 0x59, 0x49, 0x0a, 0x4b, 0x6f, 0x5a, 0x49, 0x7a,
+		0x6a, 0x30, 0x45, 0x41, 0x77, 0x49, 0x44, 0x53, 0x51, 0x41, 0x77, 0x52,
+		0x67, 0x49, 0x68, 0x41, 0x4f, 0x57, 0x2f, 0x35, 0x51, 0x6b, 0x52, 0x2b,
+		0x53, 0x39, 0x43, 0x69, 0x53, 0x44, 0x63, 0x4e, 0x6f, 0x6f, 0x77, 0x4c,
+		0x75, 0x50, 0x52, 0x4c, 0x73, 0x57, 0x47, 0x66, 0x2f, 0x59, 0x69, 0x37,
+		0x47, 0x53, 0x58, 0x39, 0x34, 0x42, 0x67, 0x77, 0x54, 0x77, 0x67, 0x0a,
+		0x41, 0x69, 0x45, 0x41, 0x34, 0x4a, 0x30, 0x6c, 0x72, 0x48, 0x6f, 0x4d,
+		0x73, 0x2b, 0x58, 0x6f, 0x35, 0x6f, 0x2f, 0x73, 0x58, 0x36, 0x4f, 0x39,
+		0x51, 0x57, 0x78, 0x48, 0x52, 0x41, 0x76, 0x5a, 0x55, 0x47, 0x4f, 0x64,
+		0x52, 0x51, 0x37, 0x63, 0x76, 0x71, 0x52, 0x58, 0x61, 0x71, 0x49, 0x3d,
+		0x0a, 0x2d, 0x2d, 0x2d, 0x2d, 0x2d, 0x45, 0x4e, 0x44, 0x20, 0x43, 0x45,
+		0x52, 0x54, 0x49, 0x46, 0x49, 0x43, 0x41, 0x54, 0x45, 0x2d, 0x2d, 0x2d,
+		0x2d, 0x2d, 0x0a, 0x0a, 0x65, 0x78, 0x74, 0x72, 0x61, 0x20, 0x62, 0x79,
+		0x74, 0x65, 0x73, 0x28, 0x6f, 0x6e, 0x6c, 0x79, 0x20, 0x66, 0x6f, 0x72,
+		0x20, 0x74, 0x65, 0x73, 0x74, 0x69, 0x6e, 0x67, 0x20, 0x70, 0x75, 0x72,
+		0x70, 0x6f, 0x73, 0x65, 0x29, 0x0a,
+	}
+
+	intelSgxRootCa = []byte("-----BEGIN CERTIFICATE-----\nMIICjzCCAjSgAwIBAgIUImUM1lqdNInzg7SVUr9QGzknBqwwCgYIKoZIzj0EAwIw\naDEaMBgGA1UEAwwRSW50ZWwgU0dYIFJvb3QgQ0ExGjAYBgNVBAoMEUludGVsIENv\ncnBvcmF0aW9uMRQwEgYDVQQHDAtTYW50YSBDbGFyYTELMAkGA1UECAwCQ0ExCzAJ\nBgNVBAYTAlVTMB4XDTE4MDUyMTEwNDUxMFoXDTQ5MTIzMTIzNTk1OVowaDEaMBgG\nA1UEAwwRSW50ZWwgU0dYIFJvb3QgQ0ExGjAYBgNVBAoMEUludGVsIENvcnBvcmF0\naW9uMRQwEgYDVQQHDAtTYW50YSBDbGFyYTELMAkGA1UECAwCQ0ExCzAJBgNVBAYT\nAlVTMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEC6nEwMDIYZOj/iPWsCzaEKi7\n1OiOSLRFhWGjbnBVJfVnkY4u3IjkDYYL0MxO4mqsyYjlBalTVYxFP2sJBK5zlKOB\nuzCBuDAfBgNVHSMEGDAWgBQiZQzWWp00ifODtJVSv1AbOScGrDBSBgNVHR8ESzBJ\nMEegRaBDhkFodHRwczovL2NlcnRpZmljYXRlcy50cnVzdGVkc2VydmljZXMuaW50\nZWwuY29tL0ludGVsU0dYUm9vdENBLmRlcjAdBgNVHQ4EFgQUImUM1lqdNInzg7SV\nUr9QGzknBqwwDgYDVR0PAQH/BAQDAgEGMBIGA1UdEwEB/wQIMAYBAf8CAQEwCgYI\nKoZIzj0EAwIDSQAwRgIhAOW/5QkR+S9CiSDcNoowLuPRLsWGf/Yi7GSX94BgwTwg\nAiEA4J0lrHoMs+Xo5o/sX6O9QWxHRAvZUGOdRQ7cvqRXaqI=\n-----END CERTIFICATE-----\n")
+
+	capturedTdxMrtd, _ = hex.DecodeString("6363b8043668a3ad953278e10389574d326c6749fb78aa810ecd9336923db86f22fc00b8dcd404bc10d5e119d7215cbb")
+
+	capturedTdxReportData, _ = hex.DecodeString("6c62dec1b8191749a31dab490be532a35944dea47caef1f980863993d9899545eb7406a38d1eed313b987a467dacead6f0c87a6d766c66f6f29f8acb281f1113")
+
+	capturedTdxRtmr0, _ = hex.DecodeString("2927da70461cd63266f43230cc1849c03ef25ebe490062a801d8fcc80af42976823adf08f833c1e50b51779c6593f32a")
+
+	capturedTdxRtmr1, _ = hex.DecodeString("2c700b8ba9b85783f8be9fb9443647bdc0bb3c50747f06297cc6538c25a5f589c4b56d035c59107c6bc5800db2cacb61")
+
+	capturedTdxRtmr2, _ = hex.DecodeString("8652f0caaba7e215ea442dc36a4499d8fec3362f3a0b2ca151cbe4b3e6466fe59c7368b3c2287fc7c3bf5c924eb4424e")
+)
+
+func Test_verifyTdxMeasurements(t *testing.T) {
+
+	pckPriv, pckChain := createTdxPckChain(t)
+	otherPriv, otherChain := createTdxPckChain(t)
+
+	// Device certificates chaining up to a trusted CA must not be accepted as PCK
+	devPriv, devChain, err := createCertsAndKeys()
+	if err != nil {
+		t.Fatalf("failed to create device certs: %v", err)
+	}
+
+	// Accept the test PCK root in addition to the Intel SGX Root CA, but neither the
+	// other PCK root, nor the device CA
+	fingerprint := sha256.Sum256(pckChain[1].Raw)
+	defer func(fingerprints []string) { tdxRootCaFingerprints = fingerprints }(tdxRootCaFingerprints)
+	tdxRootCaFingerprints = append([]string{hex.EncodeToString(fingerprint[:])}, tdxRootCaFingerprints...)
+
+	nonce := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+	mrtd := bytes.Repeat([]byte{0x11}, 48)
+	rtmrs := [][]byte{
+		bytes.Repeat([]byte{0x20}, 48),
+		bytes.Repeat([]byte{0x21}, 48),
+		bytes.Repeat([]byte{0x22}, 48),
+		bytes.Repeat([]byte{0x00}, 48),
+	}
+
+	validParams := tdxQuoteParams{nonce: nonce, mrtd: mrtd, rtmrs: rtmrs}
+
+	validReferenceValue := ReferenceValue{
+		Type:   "TDX Reference Value",
+		Sha384: mrtd,
+		Tdx: &TdxDetails{
+			Version: 4,
+			Rtmrs:   []HexByte{rtmrs[0], rtmrs[1], rtmrs[2], rtmrs[3]},
+			Cas:     [][]byte{internal.WriteCertPem(pckChain[1])},
+		},
+	}
+
+	invalidMrtd := validReferenceValue
+	invalidMrtd.Sha384 = bytes.Repeat([]byte{0x12}, 48)
+
+	invalidRtmr := validReferenceValue
+	invalidRtmr.Tdx = &TdxDetails{
+		Version: 4,
+		Rtmrs:   []HexByte{rtmrs[0], rtmrs[1], rtmrs[1], rtmrs[3]},
+		Cas:     validReferenceValue.Tdx.Cas,
+	}
+
+	invalidCa := validReferenceValue
+	invalidCa.Tdx = &TdxDetails{
+		Version: 4,
+		Rtmrs:   validReferenceValue.Tdx.Rtmrs,
+		Cas:     [][]byte{internal.WriteCertPem(otherChain[1])},
+	}
+
+	deviceCa := validReferenceValue
+	deviceCa.Tdx = &TdxDetails{
+		Version: 4,
+		Rtmrs:   validReferenceValue.Tdx.Rtmrs,
+		Cas:     [][]byte{internal.WriteCertPem(devChain[1])},
+	}
+
+	otherQeIdentity := &TdxQeIdentity{
+		MrSigner:  bytes.Repeat([]byte{0x42}, 32),
+		IsvProdId: 3,
+		IsvSvn:    1,
+	}
+
+	customQe := validReferenceValue
+	customQe.Tdx = &TdxDetails{
+		Version:    4,
+		Rtmrs:      validReferenceValue.Tdx.Rtmrs,
+		Cas:        validReferenceValue.Tdx.Cas,
+		QeIdentity: otherQeIdentity,
+	}
+
+	minQeSvn := validReferenceValue
+	minQeSvn.Tdx = &TdxDetails{
+		Version: 4,
+		Rtmrs:   validReferenceValue.Tdx.Rtmrs,
+		Cas:     validReferenceValue.Tdx.Cas,
+		QeIdentity: &TdxQeIdentity{
+			MrSigner:  otherQeIdentity.MrSigner,
+			IsvProdId: otherQeIdentity.IsvProdId,
+			IsvSvn:    2,
+		},
+	}
+
+	capturedReferenceValue := ReferenceValue{
+		Type:   "TDX Reference Value",
+		Sha384: capturedTdxMrtd,
+		Tdx: &TdxDetails{
+			Version: 4,
+			Rtmrs:   []HexByte{capturedTdxRtmr0, capturedTdxRtmr1, capturedTdxRtmr2, make([]byte, 48)},
+			Cas:     [][]byte{intelSgxRootCa},
+		},
+	}
+
+	capturedInvalidCa := capturedReferenceValue
+	capturedInvalidCa.Tdx = &TdxDetails{
+		Version: 4,
+		Rtmrs:   capturedReferenceValue.Tdx.Rtmrs,
+		Cas:     validReferenceValue.Tdx.Cas,
+	}
+
+	capturedCustomQe := capturedReferenceValue
+	capturedCustomQe.Tdx = &TdxDetails{
+		Version:    4,
+		Rtmrs:      capturedReferenceValue.Tdx.Rtmrs,
+		Cas:        capturedReferenceValue.Tdx.Cas,
+		QeIdentity: otherQeIdentity,
+	}
+
+	tests := []struct {
+		name            string
+		params          tdxQuoteParams
+		quote           []byte
+		pckPriv         *ecdsa.PrivateKey
+		pckChain        []*x509.Certificate
+		nonce           []byte
+		referenceValues []ReferenceValue
+		want            bool
+	}{
+		{
+			name:            "Valid TDX Measurement",
+			params:          validParams,
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{validReferenceValue},
+			want:            true,
+		},
+		{
+			name:            "Invalid Nonce",
+			params:          validParams,
+			nonce:           []byte{0x08, 0x07, 0x06, 0x05, 0x04, 0x03, 0x02, 0x01},
+			referenceValues: []ReferenceValue{validReferenceValue},
+			want:            false,
+		},
+		{
+			name:            "Invalid MRTD",
+			params:          validParams,
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{invalidMrtd},
+			want:            false,
+		},
+		{
+			name:            "Invalid RTMR",
+			params:          validParams,
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{invalidRtmr},
+			want:            false,
+		},
+		{
+			name: "Debug Enabled",
+			params: tdxQuoteParams{nonce: nonce, mrtd: mrtd, rtmrs: rtmrs,
+				tdAttributes: tdxDebugAttrBit},
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{validReferenceValue},
+			want:            false,
+		},
+		{
+			name: "Invalid Quote Signature",
+			params: tdxQuoteParams{nonce: nonce, mrtd: mrtd, rtmrs: rtmrs,
+				tamperBody: true},
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{validReferenceValue},
+			want:            false,
+		},
+		{
+			name: "Invalid QE Report Data",
+			params: tdxQuoteParams{nonce: nonce, mrtd: mrtd, rtmrs: rtmrs,
+				tamperAuth: true},
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{validReferenceValue},
+			want:            false,
+		},
+		{
+			name:            "Invalid CA",
+			params:          validParams,
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{invalidCa},
+			want:            false,
+		},
+		{
+			name:            "CA Not Pinned",
+			params:          validParams,
+			pckPriv:         otherPriv,
+			pckChain:        otherChain,
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{invalidCa},
+			want:            false,
+		},
+		{
+			name:            "Device Certificate As PCK",
+			params:          validParams,
+			pckPriv:         devPriv,
+			pckChain:        devChain,
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{deviceCa},
+			want:            false,
+		},
+		{
+			name: "Invalid QE Identity",
+			params: tdxQuoteParams{nonce: nonce, mrtd: mrtd, rtmrs: rtmrs,
+				qeIdentity: otherQeIdentity},
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{validReferenceValue},
+			want:            false,
+		},
+		{
+			name: "Valid Custom QE Identity",
+			params: tdxQuoteParams{nonce: nonce, mrtd: mrtd, rtmrs: rtmrs,
+				qeIdentity: otherQeIdentity},
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{customQe},
+			want:            true,
+		},
+		{
+			name: "QE SVN Too Low",
+			params: tdxQuoteParams{nonce: nonce, mrtd: mrtd, rtmrs: rtmrs,
+				qeIdentity: otherQeIdentity},
+			nonce:           nonce,
+			referenceValues: []ReferenceValue{minQeSvn},
+			want:            false,
+		},
+		{
+			name:            "No Reference Value",
+			params:          validParams,
+			nonce:           nonce,
+			referenceValues: nil,
+			want:            false,
+		},
+		{
+			name:            "Valid Captured Quote",
+			quote:           capturedTdxQuote,
+			nonce:           capturedTdxReportData,
+			referenceValues: []ReferenceValue{capturedReferenceValue},
+			want:            true,
+		},
+		{
+			name:            "Captured Quote Invalid CA",
+			quote:           capturedTdxQuote,
+			nonce:           capturedTdxReportData,
+			referenceValues: []ReferenceValue{capturedInvalidCa},
+			want:            false,
+		},
+		{
+			name:            "Captured Quote Invalid QE Identity",
+			quote:           capturedTdxQuote,
+			nonce:           capturedTdxReportData,
+			referenceValues: []ReferenceValue{capturedCustomQe},
+			want:            false,
+		},
+	}
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := tt.quote
+			if quote == nil {
+				priv, chain := pckPriv, pckChain
+				if tt.pckPriv != nil {
+					priv, chain = tt.pckPriv, tt.pckChain
+				}
+				quote, err = createTdxQuote(priv, chain, tt.params)
+				if err != nil {
+					t.Fatalf("failed to create TDX quote: %v", err)
+				}
+			}
+			tdxM := &TdxMeasurement{
+				Type:   "TDX Measurement",
+				Report: quote,
+			}
+			got, got1 := verifyTdxMeasurements(tdxM, tt.nonce, tt.referenceValues)
+			if got1 != tt.want {
+				t.Errorf("verifyTdxMeasurements() --GOT1-- = %v, --WANT1-- %v: %+v", got1, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_decodeTdxQuote(t *testing.T) {
+	pckPriv, pckChain := createTdxPckChain(t)
+	quote, err := createTdxQuote(pckPriv, pckChain, tdxQuoteParams{
+		nonce: []byte{0x1},
+		mrtd:  bytes.Repeat([]byte{0x11}, 48),
+		rtmrs: [][]byte{{0x1}, {0x2}, {0x3}, {0x4}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create TDX quote: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		quote   []byte
+		wantErr bool
+	}{
+		{"Valid Quote", quote, false},
+		{"Valid Captured Quote", capturedTdxQuote, false},
+		{"Padded Quote", append(append([]byte{}, quote...), make([]byte, 64)...), false},
+		{"Truncated Quote", quote[:tdxHeaderSize+tdxBodySize+10], true},
+		{"Empty Quote", []byte{}, true},
+		{"Invalid Version", append([]byte{0x03, 0x00}, quote[2:]...), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeTdxQuote(tt.quote)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeTdxQuote() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// createTdxQuote creates a TDX v4 quote signed by a freshly generated attestation key,
+// which is bound to a QE report signed by the specified PCK key
+func createTdxQuote(pckPriv *ecdsa.PrivateKey, pckChain []*x509.Certificate, p tdxQuoteParams,
+) ([]byte, error) {
+
+	attPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	attKey := make([]byte, 64)
+	attPriv.X.FillBytes(attKey[:32])
+	attPriv.Y.FillBytes(attKey[32:])
+
+	header := tdxQuoteHeader{
+		Version:            tdxQuoteVersion4,
+		AttestationKeyType: tdxAttKeyTypeEcdsaP256,
+		TeeType:            tdxTeeType,
+	}
+	body := tdxReportBody{
+		TdAttributes: p.tdAttributes,
+	}
+	copy(body.MrTd[:], p.mrtd)
+	for i := range body.Rtmrs {
+		copy(body.Rtmrs[i][:], p.rtmrs[i])
+	}
+	copy(body.ReportData[:], p.nonce)
+
+	signed := new(bytes.Buffer)
+	binary.Write(signed, binary.LittleEndian, header)
+	binary.Write(signed, binary.LittleEndian, body)
+	quoteSig, err := signTdx(attPriv, signed.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if p.tamperBody {
+		signed.Bytes()[tdxHeaderSize] ^= 0xff
+	}
+
+	authData := []byte("qe auth data")
+	binding := sha256.Sum256(append(attKey, authData...))
+	if p.tamperAuth {
+		authData = []byte("other auth data")
+	}
+	qeReport := sgxReportBody{
+		IsvProdId: tdxQeIsvProdId,
+		IsvSvn:    1,
+	}
+	mrSigner, _ := hex.DecodeString(tdxQeMrSigner)
+	copy(qeReport.MrSigner[:], mrSigner)
+	if p.qeIdentity != nil {
+		copy(qeReport.MrSigner[:], p.qeIdentity.MrSigner)
+		qeReport.IsvProdId = p.qeIdentity.IsvProdId
+		qeReport.IsvSvn = p.qeIdentity.IsvSvn
+	}
+	copy(qeReport.ReportData[:], binding[:])
+	qeReportRaw := new(bytes.Buffer)
+	binary.Write(qeReportRaw, binary.LittleEndian, qeReport)
+	qeSig, err := signTdx(pckPriv, qeReportRaw.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	pckChainPem := bytes.Join(internal.WriteCertsPem(pckChain), nil)
+
+	certData := new(bytes.Buffer)
+	certData.Write(qeReportRaw.Bytes())
+	certData.Write(qeSig)
+	binary.Write(certData, binary.LittleEndian, uint16(len(authData)))
+	certData.Write(authData)
+	binary.Write(certData, binary.LittleEndian, uint16(tdxCertDataTypePckChain))
+	binary.Write(certData, binary.LittleEndian, uint32(len(pckChainPem)))
+	certData.Write(pckChainPem)
+
+	sigData := new(bytes.Buffer)
+	sigData.Write(quoteSig)
+	sigData.Write(attKey)
+	binary.Write(sigData, binary.LittleEndian, uint16(tdxCertDataTypeQeReport))
+	binary.Write(sigData, binary.LittleEndian, uint32(certData.Len()))
+	sigData.Write(certData.Bytes())
+
+	quote := new(bytes.Buffer)
+	quote.Write(signed.Bytes())
+	binary.Write(quote, binary.LittleEndian, uint32(sigData.Len()))
+	quote.Write(sigData.Bytes())
+
+	return quote.Bytes(), nil
+}
+
+// createTdxPckChain creates a P-256 root CA and a PCK certificate containing the
+// SGX PCK extension
+func createTdxPckChain(t *testing.T) (*ecdsa.PrivateKey, []*x509.Certificate) {
+
+	caPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test SGX Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caPriv.PublicKey, caPriv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	pckPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	oid := asn1.ObjectIdentifier{1, 2, 840, 113741, 1, 13, 1}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Test SGX PCK Certificate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		// Empty SEQUENCE, the PCK extension values are not evaluated
+		ExtraExtensions:       []pkix.Extension{{Id: oid, Value: []byte{0x30, 0x00}}},
+		BasicConstraintsValid: true,
+	}
+	der, err = x509.CreateCertificate(rand.Reader, tmpl, ca, &pckPriv.PublicKey, caPriv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	pck, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	return pckPriv, []*x509.Certificate{pck, ca}
+}
+
+func signTdx(priv *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	digest := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, priv, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return sig, nil
+}
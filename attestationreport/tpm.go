@@ -17,18 +17,130 @@ package attestationreport
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
+	"crypto/sha1"
 	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"sort"
 
+	"github.com/Fraunhofer-AISEC/cmc/ima"
 	"github.com/Fraunhofer-AISEC/cmc/internal"
 	"github.com/google/go-tpm/tpm2"
 )
 
-func verifyTpmMeasurements(tpmM *TpmMeasurement, nonce []byte, referenceValues []ReferenceValue, casPem []byte) (*TpmMeasurementResult, bool) {
+// AlgSM3_256 is the TPM algorithm identifier of the SM3 hash algorithm,
+// which is not defined by go-tpm
+const AlgSM3_256 tpm2.Algorithm = 0x0012
+
+// PcrBanks contains the names of the supported PCR banks
+var PcrBanks = map[string]tpm2.Algorithm{
+	"SHA1":    tpm2.AlgSHA1,
+	"SHA256":  tpm2.AlgSHA256,
+	"SHA384":  tpm2.AlgSHA384,
+	"SM3_256": AlgSM3_256,
+}
+
+// PcrBankName returns the name of the PCR bank of the specified hash algorithm
+func PcrBankName(alg tpm2.Algorithm) string {
+	for name, a := range PcrBanks {
+		if a == alg {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%04x", uint16(alg))
+}
+
+// NewPcrHash returns a new hash.Hash for the PCR bank of the specified hash algorithm
+func NewPcrHash(alg tpm2.Algorithm) (hash.Hash, error) {
+	switch alg {
+	case tpm2.AlgSHA1:
+		return sha1.New(), nil
+	case tpm2.AlgSHA256:
+		return sha256.New(), nil
+	case tpm2.AlgSHA384:
+		return sha512.New384(), nil
+	case AlgSM3_256:
+		return internal.NewSm3(), nil
+	default:
+		return nil, fmt.Errorf("PCR bank %v not supported", PcrBankName(alg))
+	}
+}
+
+// Digests returns the digests of the hash chain element for the specified PCR bank
+func (hce *HashChainElem) Digests(alg tpm2.Algorithm) []HexByte {
+	switch alg {
+	case tpm2.AlgSHA1:
+		return hce.Sha1
+	case tpm2.AlgSHA256:
+		return hce.Sha256
+	case tpm2.AlgSHA384:
+		return hce.Sha384
+	case AlgSM3_256:
+		return hce.Sm3
+	default:
+		return nil
+	}
+}
+
+// SetDigests sets the digests of the hash chain element for the specified PCR bank
+func (hce *HashChainElem) SetDigests(alg tpm2.Algorithm, digests []HexByte) error {
+	switch alg {
+	case tpm2.AlgSHA1:
+		hce.Sha1 = digests
+	case tpm2.AlgSHA256:
+		hce.Sha256 = digests
+	case tpm2.AlgSHA384:
+		hce.Sha384 = digests
+	case AlgSM3_256:
+		hce.Sm3 = digests
+	default:
+		return fmt.Errorf("PCR bank %v not supported", PcrBankName(alg))
+	}
+	return nil
+}
+
+// Digest returns the digest of the reference value for the specified PCR bank
+func (v *ReferenceValue) Digest(alg tpm2.Algorithm) HexByte {
+	switch alg {
+	case tpm2.AlgSHA1:
+		return v.Sha1
+	case tpm2.AlgSHA256:
+		return v.Sha256
+	case tpm2.AlgSHA384:
+		return v.Sha384
+	case AlgSM3_256:
+		return v.Sm3
+	default:
+		return nil
+	}
+}
+
+// SetDigest sets the digest of the reference value for the specified PCR bank
+func (v *ReferenceValue) SetDigest(alg tpm2.Algorithm, digest HexByte) error {
+	switch alg {
+	case tpm2.AlgSHA1:
+		v.Sha1 = digest
+	case tpm2.AlgSHA256:
+		v.Sha256 = digest
+	case tpm2.AlgSHA384:
+		v.Sha384 = digest
+	case AlgSM3_256:
+		v.Sm3 = digest
+	default:
+		return fmt.Errorf("PCR bank %v not supported", PcrBankName(alg))
+	}
+	return nil
+}
+
+func verifyTpmMeasurements(tpmM *TpmMeasurement, nonce []byte, referenceValues []ReferenceValue,
+	imaCertsPem [][]byte, unorderedPcrs []int, casPem []byte) (*TpmMeasurementResult, bool) {
 	result := &TpmMeasurementResult{}
 
 	log.Trace("Verifying TPM measurements")
@@ -40,63 +152,44 @@ func verifyTpmMeasurements(tpmM *TpmMeasurement, nonce []byte, referenceValues [
 		return nil, true
 	}
 
-	// If the attestationreport contains TPM Reference Values, but no TPM measurement, the
-	// attestation must fail
+	// If the attestationreport contains mandatory TPM Reference Values, but no TPM measurement,
+	// the attestation must fail
 	if tpmM == nil {
-		for _, v := range referenceValues {
-			msg := fmt.Sprintf("TPM Measurement not present. Cannot verify TPM Reference Value %v (hash: %v)",
-				v.Name, v.Sha256)
+		_, _, missing := selectReferenceValues(referenceValues, func(*ReferenceValue) bool { return false })
+		if len(missing) == 0 {
+			log.Trace("Attestation report does not contain TPM measurements and all TPM reference values are optional")
+			return nil, true
+		}
+		for _, set := range missing {
+			var msg string
+			if set.group == "" {
+				v := referenceValues[set.indices[0]]
+				msg = fmt.Sprintf("TPM Measurement not present. Cannot verify TPM Reference Value %v (hash: %v)",
+					v.Name, hex.EncodeToString(v.Sha256))
+			} else {
+				msg = fmt.Sprintf("TPM Measurement not present. Cannot verify TPM Reference Values of %v",
+					set.String(referenceValues))
+			}
 			result.ReferenceValueCheck.setFalseMulti(&msg)
 		}
 		result.Summary.Success = false
 		return result, false
 	}
 
-	// Extend the reference values to re-calculate the PCR value and evaluate it against the measured
-	// PCR value. In case of a measurement list, also extend the measured values to re-calculate
-	// the measured PCR value
-	calculatedPcrs, pcrResult, referenceValuesCheck, ok := recalculatePcrs(tpmM, referenceValues)
-	result.PcrRecalculation = pcrResult
-	result.ReferenceValueCheck = referenceValuesCheck
-
-	// Extract TPM Quote (TPMS ATTEST) and signature
-	tpmsAttest, err := tpm2.DecodeAttestationData(tpmM.Message)
-	if err != nil {
-		msg := fmt.Sprintf("Failed to decode TPM attestation data: %v", err)
-		result.Summary.setFalse(&msg)
-		return result, false
-	}
-
-	// Verify nonce with nonce from TPM Quote
-	if bytes.Equal(nonce, tpmsAttest.ExtraData) {
-		result.QuoteFreshness.Success = true
-	} else {
-		msg := fmt.Sprintf("Nonces mismatch: Supplied Nonce = %v, TPM Quote Nonce = %v)",
-			hex.EncodeToString(nonce), hex.EncodeToString(tpmsAttest.ExtraData))
-		result.QuoteFreshness.setFalse(&msg)
-		ok = false
-	}
-
-	// Verify aggregated PCR against TPM Quote PCRDigest: Hash all reference values
-	// together then compare
-	sum := make([]byte, 0)
-	for i := range tpmM.HashChain {
-		_, ok := calculatedPcrs[int(tpmM.HashChain[i].Pcr)]
-		if !ok {
-			continue
+	ok := true
+	result.ReferenceValueCheck.Success = true
+
+	// The IMA signing certificates from the OS Manifest are optional and allow accepting
+	// IMA measurements without reference values if their file signatures can be verified
+	var imaCerts []*x509.Certificate
+	if len(imaCertsPem) > 0 {
+		var err error
+		imaCerts, err = internal.ParseCerts(imaCertsPem)
+		if err != nil {
+			msg := fmt.Sprintf("Failed to parse IMA signing certificates: %v", err)
+			result.ReferenceValueCheck.setFalseMulti(&msg)
+			ok = false
 		}
-		sum = append(sum, calculatedPcrs[int(tpmM.HashChain[i].Pcr)]...)
-	}
-	verPcr := sha256.Sum256(sum)
-	if bytes.Equal(verPcr[:], tpmsAttest.AttestedQuoteInfo.PCRDigest) {
-		log.Trace("Aggregated PCR matches quote PCR")
-		result.AggPcrQuoteMatch.Success = true
-	} else {
-		msg := fmt.Sprintf("Aggregated PCR does not match Quote PCR: %v vs. %v",
-			hex.EncodeToString(verPcr[:]),
-			hex.EncodeToString(tpmsAttest.AttestedQuoteInfo.PCRDigest))
-		result.AggPcrQuoteMatch.setFalse(&msg)
-		ok = false
 	}
 
 	cas, err := internal.ParseCerts(casPem)
@@ -107,15 +200,30 @@ func verifyTpmMeasurements(tpmM *TpmMeasurement, nonce []byte, referenceValues [
 	}
 
 	mCerts, err := internal.ParseCerts(tpmM.Certs)
-	if err != nil {
+	if err != nil || len(mCerts) == 0 {
 		msg := fmt.Sprintf("Failed to load measurement certs: %v", err)
 		result.QuoteSignature.CertChainCheck.setFalse(&msg)
-		ok = false
+		result.Summary.setFalse(&msg)
+		return result, false
 	}
 
-	result.QuoteSignature.SignCheck = verifyTpmQuoteSignature(tpmM.Message, tpmM.Signature, mCerts[0])
-	if !result.QuoteSignature.SignCheck.Success {
-		ok = false
+	// Each quote covers a single PCR bank. The PCRs of each quoted bank are recalculated
+	// and verified against the reference values, the quote and its signature
+	quotes := append([]TpmQuote{{Message: tpmM.Message, Signature: tpmM.Signature}}, tpmM.Quotes...)
+	attestations, err := decodeTpmQuotes(quotes)
+	if err != nil {
+		msg := err.Error()
+		result.Summary.setFalse(&msg)
+		return result, false
+	}
+	result.AggPcrQuoteMatch.Success = true
+	result.QuoteFreshness.Success = true
+	result.QuoteSignature.SignCheck.Success = true
+	for i, quote := range quotes {
+		if !verifyTpmQuote(tpmM, &quote, attestations[i], nonce, referenceValues, imaCerts,
+			unorderedPcrs, mCerts[0], result) {
+			ok = false
+		}
 	}
 
 	x509Chains, err := internal.VerifyCertChain(mCerts, cas)
@@ -141,86 +249,389 @@ func verifyTpmMeasurements(tpmM *TpmMeasurement, nonce []byte, referenceValues [
 	return result, ok
 }
 
-func recalculatePcrs(tpmM *TpmMeasurement, referenceValues []ReferenceValue) (map[int][]byte, []PcrResult, ResultMulti, bool) {
+// decodeTpmQuotes decodes the attestation data (TPMS ATTEST) of the quotes. Each quote
+// must cover a different PCR bank, as otherwise the bank would be verified twice
+func decodeTpmQuotes(quotes []TpmQuote) ([]*tpm2.AttestationData, error) {
+	attestations := make([]*tpm2.AttestationData, 0, len(quotes))
+	banks := make(map[tpm2.Algorithm]bool)
+	for _, quote := range quotes {
+		tpmsAttest, err := tpm2.DecodeAttestationData(quote.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode TPM attestation data: %w", err)
+		}
+		if tpmsAttest.AttestedQuoteInfo == nil {
+			return nil, fmt.Errorf("TPM attestation data does not contain a quote")
+		}
+		alg := tpmsAttest.AttestedQuoteInfo.PCRSelection.Hash
+		if banks[alg] {
+			return nil, fmt.Errorf("TPM measurement contains multiple quotes of %v PCR bank",
+				PcrBankName(alg))
+		}
+		banks[alg] = true
+		attestations = append(attestations, tpmsAttest)
+	}
+	return attestations, nil
+}
+
+// verifyTpmQuote verifies a quote over a single PCR bank and adds the results to the
+// TPM measurement result
+func verifyTpmQuote(tpmM *TpmMeasurement, quote *TpmQuote, tpmsAttest *tpm2.AttestationData, nonce []byte,
+	referenceValues []ReferenceValue, imaCerts []*x509.Certificate, unorderedPcrs []int,
+	cert *x509.Certificate, result *TpmMeasurementResult) bool {
+
+	alg := tpmsAttest.AttestedQuoteInfo.PCRSelection.Hash
+	bank := PcrBankName(alg)
+
+	log.Tracef("Verifying %v PCR bank", bank)
+
+	// Extend the reference values to re-calculate the PCR value and evaluate it against the measured
+	// PCR value. In case of a measurement list, also extend the measured values to re-calculate
+	// the measured PCR value
+	pcrResult, referenceValuesCheck, ok := recalculatePcrs(tpmM, alg, referenceValues, imaCerts, unorderedPcrs)
+	result.PcrRecalculation = append(result.PcrRecalculation, pcrResult...)
+	if !referenceValuesCheck.Success {
+		result.ReferenceValueCheck.Success = false
+		result.ReferenceValueCheck.Details = append(result.ReferenceValueCheck.Details,
+			referenceValuesCheck.Details...)
+	}
+
+	// Verify nonce with nonce from TPM Quote
+	if !bytes.Equal(nonce, tpmsAttest.ExtraData) {
+		msg := fmt.Sprintf("Nonces mismatch for %v quote: Supplied Nonce = %v, TPM Quote Nonce = %v)",
+			bank, hex.EncodeToString(nonce), hex.EncodeToString(tpmsAttest.ExtraData))
+		result.QuoteFreshness.appendFalse(&msg)
+		ok = false
+	}
+
+	// Verify aggregated PCR against TPM Quote PCRDigest: Replay the measurements, hash all
+	// replayed PCR values together then compare. This binds the (unsigned) measurement lists
+	// to the signed quote. The TPM calculates the PCR digest with the hash algorithm
+	// of the signing scheme
+	_, hashAlg, err := decodeTpmSignature(quote.Signature)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to decode %v quote signature: %v", bank, err)
+		result.AggPcrQuoteMatch.appendFalse(&msg)
+		result.QuoteSignature.SignCheck.appendFalse(&msg)
+		return false
+	}
+	measuredPcrs, err := replayPcrs(tpmM, alg)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to replay %v PCRs: %v", bank, err)
+		result.AggPcrQuoteMatch.appendFalse(&msg)
+		return false
+	}
+	// The quoted PCR selection must match the measured PCRs, otherwise the measurements
+	// of a PCR could be verified against the reference values of another PCR
+	pcrs := make([]int, 0, len(measuredPcrs))
+	for pcr := range measuredPcrs {
+		pcrs = append(pcrs, pcr)
+	}
+	sort.Ints(pcrs)
+	selection := append([]int{}, tpmsAttest.AttestedQuoteInfo.PCRSelection.PCRs...)
+	sort.Ints(selection)
+	if !equalPcrs(pcrs, selection) {
+		msg := fmt.Sprintf("Measured %v PCRs %v do not match quoted PCRs %v", bank, pcrs, selection)
+		result.AggPcrQuoteMatch.appendFalse(&msg)
+		return false
+	}
+	// The TPM hashes the selected PCRs in ascending order
+	h := hashAlg.New()
+	for _, pcr := range pcrs {
+		h.Write(measuredPcrs[pcr])
+	}
+	verPcr := h.Sum(nil)
+	if bytes.Equal(verPcr, tpmsAttest.AttestedQuoteInfo.PCRDigest) {
+		log.Tracef("Aggregated %v PCR matches quote PCR", bank)
+	} else {
+		msg := fmt.Sprintf("Aggregated %v PCR does not match Quote PCR: %v vs. %v", bank,
+			hex.EncodeToString(verPcr),
+			hex.EncodeToString(tpmsAttest.AttestedQuoteInfo.PCRDigest))
+		result.AggPcrQuoteMatch.appendFalse(&msg)
+		ok = false
+	}
+
+	sigResult := verifyTpmQuoteSignature(quote.Message, quote.Signature, cert)
+	if !sigResult.Success {
+		msg := fmt.Sprintf("%v quote: %v", bank, sigResult.Details)
+		result.QuoteSignature.SignCheck.appendFalse(&msg)
+		ok = false
+	}
+
+	return ok
+}
+
+func equalPcrs(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// replayPcrs recalculates the PCR values of the specified PCR bank from the measurements.
+// Measurement lists are extended starting from the initial PCR value, otherwise the
+// final PCR value contained in the measurement is used
+func replayPcrs(tpmM *TpmMeasurement, alg tpm2.Algorithm) (map[int][]byte, error) {
+	h, err := NewPcrHash(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	measuredPcrs := make(map[int][]byte)
+	for _, hce := range tpmM.HashChain {
+		digests := hce.Digests(alg)
+		if len(digests) == 0 {
+			return nil, fmt.Errorf("TPM measurement PCR%v does not contain %v digests", hce.Pcr, PcrBankName(alg))
+		}
+		if _, ok := measuredPcrs[int(hce.Pcr)]; ok {
+			return nil, fmt.Errorf("TPM measurement contains PCR%v multiple times", hce.Pcr)
+		}
+		if err := checkStartupLocality(hce); err != nil {
+			return nil, err
+		}
+		if !isMeasurementList(hce, alg) {
+			measuredPcrs[int(hce.Pcr)] = digests[0]
+			continue
+		}
+		pcr := initialPcrValue(hce, h.Size())
+		for _, digest := range digests {
+			pcr = extendHash(h, pcr, digest)
+		}
+		measuredPcrs[int(hce.Pcr)] = pcr
+	}
+
+	return measuredPcrs, nil
+}
+
+// recalculatePcrs extends the reference values of the specified PCR bank to recalculate
+// the expected PCR values and compares them to the measured PCR values. For measurement
+// lists, every measured event is additionally checked against the reference values. For
+// measurement lists of unordered PCRs, only the set-membership of the measured events is
+// verified, as their order is not deterministic
+func recalculatePcrs(tpmM *TpmMeasurement, alg tpm2.Algorithm, referenceValues []ReferenceValue,
+	imaCerts []*x509.Certificate, unorderedPcrs []int) ([]PcrResult, ResultMulti, bool) {
 	ok := true
 	pcrResult := make([]PcrResult, 0)
 	referenceValuesCheck := ResultMulti{
 		Success: true,
 	}
 	calculatedPcrs := make(map[int][]byte)
+
+	bank := PcrBankName(alg)
+	h, err := NewPcrHash(alg)
+	if err != nil {
+		msg := fmt.Sprintf("Failed to recalculate PCRs: %v", err)
+		referenceValuesCheck.setFalseMulti(&msg)
+		return pcrResult, referenceValuesCheck, false
+	}
+	size := h.Size()
+
+	// Collect the reference values of each PCR, preserving their order
+	refPcrs := make([]int, 0)
+	pcrRefs := make(map[int][]ReferenceValue)
 	for _, v := range referenceValues {
 
 		if v.Pcr == nil {
-			msg := fmt.Sprintf("No PCR set in TPM Reference Value %v (hash: %v)", v.Name, hex.EncodeToString(v.Sha256))
+			msg := fmt.Sprintf("No PCR set in TPM Reference Value %v (hash: %v)", v.Name, hex.EncodeToString(v.Digest(alg)))
 			referenceValuesCheck.setFalseMulti(&msg)
 			ok = false
 			continue
 		}
 
-		// Initialize calculated PCR if not yet initialized, afterwards extend
-		// reference values
-		if _, ok := calculatedPcrs[*v.Pcr]; !ok {
-			calculatedPcrs[*v.Pcr] = make([]byte, 32)
+		if v.Digest(alg) == nil {
+			msg := fmt.Sprintf("No %v digest set in TPM Reference Value %v", bank, v.Name)
+			referenceValuesCheck.setFalseMulti(&msg)
+			ok = false
+			continue
 		}
-		calculatedPcrs[*v.Pcr] = extendHash(calculatedPcrs[*v.Pcr], v.Sha256)
 
-		// Only if the measurement contains the hashes of the individual software artifacts, (e.g.
-		// provided through BIOS or IMA  measurement lists), we can check the reference values directly
-		for _, hce := range tpmM.HashChain {
-			if hce.Pcr == int32(*v.Pcr) && len(hce.Sha256) > 1 {
-				found := false
-				for _, sha256 := range hce.Sha256 {
-					if bytes.Equal(sha256, v.Sha256) {
-						found = true
-						break
-					}
+		if _, ok := pcrRefs[*v.Pcr]; !ok {
+			refPcrs = append(refPcrs, *v.Pcr)
+		}
+		pcrRefs[*v.Pcr] = append(pcrRefs[*v.Pcr], v)
+	}
+
+	// Select the expected reference values of each PCR and extend them to calculate the
+	// expected PCR value. Only if the measurement contains the hashes of the individual software
+	// artifacts (e.g. provided through BIOS or IMA measurement lists), we can check the reference
+	// values directly. Otherwise, the combination of optional and alternative reference values
+	// matching the measured PCR value is selected
+	alternatives := make(map[int][]AlternativeResult)
+	missingRefs := make(map[int][]ReferenceValue)
+	for _, pcrNum := range refPcrs {
+		refs := pcrRefs[pcrNum]
+		hce := getHashChainElem(tpmM, pcrNum)
+
+		var selected []ReferenceValue
+		if hce != nil && isMeasurementList(hce, alg) {
+			digests := hce.Digests(alg)
+			var missing []referenceValueSet
+			selected, alternatives[pcrNum], missing = selectReferenceValues(refs, func(v *ReferenceValue) bool {
+				return containsDigest(digests, v.Digest(alg))
+			})
+			for _, set := range missing {
+				var msg string
+				if set.group == "" {
+					v := refs[set.indices[0]]
+					msg = fmt.Sprintf("No TPM Measurement found for TPM Reference Value %v (%v: %v)",
+						v.Name, bank, hex.EncodeToString(v.Digest(alg)))
+				} else {
+					msg = fmt.Sprintf("No TPM Measurement found for any TPM Reference Value of %v",
+						set.String(refs))
 				}
-				if !found {
-					msg := fmt.Sprintf("No TPM Measurement found for TPM Reference Value %v (hash: %v)", v.Name, v.Sha256)
-					referenceValuesCheck.setFalseMulti(&msg)
-					ok = false
+				referenceValuesCheck.setFalseMulti(&msg)
+				ok = false
+				for _, i := range set.indices {
+					missingRefs[pcrNum] = append(missingRefs[pcrNum], refs[i])
 				}
 			}
+		} else {
+			var measured []byte
+			if hce != nil && len(hce.Digests(alg)) > 0 {
+				measured = hce.Digests(alg)[0]
+			}
+			selected, alternatives[pcrNum], _, err = combineReferenceValues(refs, initialPcrValue(hce, size),
+				func(pcr []byte, v *ReferenceValue) []byte {
+					return extendHash(h, pcr, v.Digest(alg))
+				},
+				func(pcr []byte) bool {
+					return bytes.Equal(pcr, measured)
+				})
+			if err != nil {
+				msg := fmt.Sprintf("Failed to evaluate TPM Reference Values of PCR%v: %v", pcrNum, err)
+				referenceValuesCheck.setFalseMulti(&msg)
+				ok = false
+			}
+		}
+
+		calculatedPcrs[pcrNum] = extendReferenceValues(h, alg, initialPcrValue(hce, size), selected)
+	}
+
+	// Measurement lists of PCRs without reference values can still be verified if
+	// the individual measurements are signed
+	pcrNums := make([]int, 0, len(calculatedPcrs))
+	for pcrNum := range calculatedPcrs {
+		pcrNums = append(pcrNums, pcrNum)
+	}
+	if len(imaCerts) > 0 {
+		for _, hce := range tpmM.HashChain {
+			if _, ok := calculatedPcrs[int(hce.Pcr)]; !ok && isMeasurementList(hce, alg) {
+				pcrNums = append(pcrNums, int(hce.Pcr))
+			}
 		}
 	}
 
 	// Compare the calculated pcr values from the reference values with the measurement list
 	// pcr values to provide a detailed report
-	for pcrNum, calculatedHash := range calculatedPcrs {
+	for _, pcrNum := range pcrNums {
+		calculatedHash, exists := calculatedPcrs[pcrNum]
+		if !exists {
+			calculatedHash = make([]byte, size)
+		}
 		pcrRes := PcrResult{}
 		pcrRes.Pcr = int(pcrNum)
+		pcrRes.Bank = bank
 		// Find PCR in measurements
 		found := false
 		for _, hce := range tpmM.HashChain {
 			if hce.Pcr == int32(pcrNum) {
 				found = true
 
+				digests := hce.Digests(alg)
+				unordered := containsPcr(unorderedPcrs, pcrNum) && isMeasurementList(hce, alg)
 				var measurement []byte
-				if len(hce.Sha256) == 1 {
+				if len(digests) == 0 {
+					msg := fmt.Sprintf("TPM measurement PCR%v does not contain %v digests", hce.Pcr, bank)
+					pcrRes.Validation.setFalseMulti(&msg)
+					ok = false
+				} else if !isMeasurementList(hce, alg) {
 					// Measurement contains only final PCR value, so we can simply compare
-					measurement = hce.Sha256[0]
+					measurement = digests[0]
 				} else {
 					// Measurement contains individual values which must be extended to result in
 					// the final PCR value for comparison
-					measurement = make([]byte, 32)
-					for _, sha256 := range hce.Sha256 {
-						measurement = extendHash(measurement, sha256)
-
-						// Check, if a reference value exists for the measured value
-						v := getReferenceValue(sha256, referenceValues)
-						if v == nil {
-							msg := fmt.Sprintf("No TPM Reference Value found for TPM measurement: %v", sha256)
+					measurement = initialPcrValue(hce, size)
+					allVerified := true
+					signed := false
+					for i, digest := range digests {
+						measurement = extendHash(h, measurement, digest)
+						event := newEventResult(digest, hce, i)
+
+						// Check, if a reference value exists for the measured value. Only the
+						// reference values of the measured PCR are considered
+						v := getReferenceValue(alg, digest, pcrRefs[pcrNum])
+						if v != nil {
+							event.Name = v.Name
+							pcrRes.MatchedEvents = append(pcrRes.MatchedEvents, event)
+							continue
+						}
+
+						// Otherwise, check if the measurement carries a valid IMA signature
+						if len(imaCerts) > 0 && i < len(hce.Events) && len(hce.Events[i].Signature) > 0 {
+							err := verifyImaSignature(alg, digest, &hce.Events[i], imaCerts)
+							if err == nil {
+								event.Signed = true
+								pcrRes.MatchedEvents = append(pcrRes.MatchedEvents, event)
+								signed = true
+								continue
+							}
+							msg := fmt.Sprintf("No TPM Reference Value found for TPM measurement PCR%v: %v (IMA signature verification failed: %v)",
+								hce.Pcr, describeEvent(digest, hce, i), err)
+							pcrRes.Validation.setFalseMulti(&msg)
+						} else {
+							msg := fmt.Sprintf("No TPM Reference Value found for TPM measurement PCR%v: %v",
+								hce.Pcr, describeEvent(digest, hce, i))
 							pcrRes.Validation.setFalseMulti(&msg)
-							ok = false
 						}
+						pcrRes.UnknownEvents = append(pcrRes.UnknownEvents, event)
+						allVerified = false
+						ok = false
+					}
+
+					// Report the mandatory reference values of the PCR which were not measured
+					for _, v := range missingRefs[pcrNum] {
+						pcrRes.MissingEvents = append(pcrRes.MissingEvents, EventResult{
+							Digest: v.Digest(alg),
+							Name:   v.Name,
+						})
+					}
+
+					if unordered {
+						// The order of the measurements of unordered PCRs (e.g. IMA) is not
+						// deterministic, so the PCR cannot be recalculated from the reference
+						// values. Instead, every measurement must be verified individually and
+						// every mandatory reference value must have been measured. The
+						// measurement list itself is verified against the quote
+						if len(missingRefs[pcrNum]) > 0 {
+							msg := fmt.Sprintf("No TPM Measurement found for %v TPM Reference Values of unordered PCR%v",
+								len(missingRefs[pcrNum]), hce.Pcr)
+							pcrRes.Validation.setFalseMulti(&msg)
+							allVerified = false
+						}
+						if allVerified {
+							calculatedHash = measurement
+						}
+					} else if signed && allVerified {
+						// If measurements were accepted based on their signature, the PCR cannot be
+						// recalculated from the reference values alone. As all measurements were
+						// verified individually, the PCR is recalculated from the measurement list
+						calculatedHash = measurement
 					}
 				}
+				calculatedPcrs[pcrNum] = calculatedHash
+				pcrRes.Alternatives = alternatives[pcrNum]
 
-				if cmp := bytes.Compare(calculatedHash, measurement); cmp == 0 {
+				if bytes.Equal(calculatedHash, measurement) {
 					pcrRes.Validation.Success = true
-				} else {
+				} else if unordered {
+					ok = false
+				} else if len(digests) > 0 {
 					msg := fmt.Sprintf("PCR%v value did not match expectation: %v vs. %v", hce.Pcr,
-						hex.EncodeToString(hce.Sha256[0]), hex.EncodeToString(calculatedHash))
+						hex.EncodeToString(measurement), hex.EncodeToString(calculatedHash))
 					pcrRes.Validation.setFalseMulti(&msg)
 					ok = false
 				}
@@ -251,6 +662,7 @@ func recalculatePcrs(tpmM *TpmMeasurement, referenceValues []ReferenceValue) (ma
 		if !found {
 			pcrRes := PcrResult{}
 			pcrRes.Pcr = int(hce.Pcr)
+			pcrRes.Bank = bank
 			msg := fmt.Sprintf("No TPM Reference Values found for TPM measurement PCR: %v", hce.Pcr)
 			pcrRes.Validation.setFalseMulti(&msg)
 			pcrResult = append(pcrResult, pcrRes)
@@ -258,45 +670,242 @@ func recalculatePcrs(tpmM *TpmMeasurement, referenceValues []ReferenceValue) (ma
 		}
 	}
 
-	return calculatedPcrs, pcrResult, referenceValuesCheck, ok
+	return pcrResult, referenceValuesCheck, ok
 }
 
-func verifyTpmQuoteSignature(quote, sig []byte, cert *x509.Certificate) Result {
-	result := Result{}
-	result.Success = true
+// extendReferenceValues calculates the PCR value resulting from extending the digests
+// of the reference values for the specified PCR bank into the initial PCR value
+func extendReferenceValues(h hash.Hash, alg tpm2.Algorithm, pcr []byte, referenceValues []ReferenceValue) []byte {
+	for _, v := range referenceValues {
+		pcr = extendHash(h, pcr, v.Digest(alg))
+	}
+	return pcr
+}
+
+// initialPcrValue returns the value of the PCR after TPM startup. PCR0 is initialized with
+// the startup locality in its last byte, e.g. if the platform has an H-CRTM, all other
+// PCRs are initialized with zeros
+func initialPcrValue(hce *HashChainElem, size int) []byte {
+	pcr := make([]byte, size)
+	if hce != nil && hce.Pcr == 0 {
+		pcr[size-1] = hce.StartupLocality
+	}
+	return pcr
+}
+
+// checkStartupLocality checks that a startup locality is only set for PCR0 and that it
+// is one of the localities the TPM can be started from (0, 3 or 4 in case of an H-CRTM)
+func checkStartupLocality(hce *HashChainElem) error {
+	if hce.StartupLocality == 0 {
+		return nil
+	}
+	if hce.Pcr != 0 {
+		return fmt.Errorf("TPM measurement PCR%v contains startup locality", hce.Pcr)
+	}
+	if hce.StartupLocality != 3 && hce.StartupLocality != 4 {
+		return fmt.Errorf("invalid startup locality %v", hce.StartupLocality)
+	}
+	return nil
+}
+
+// getHashChainElem returns the hash chain element of the specified PCR or nil
+// if the PCR was not measured
+func getHashChainElem(tpmM *TpmMeasurement, pcr int) *HashChainElem {
+	for _, hce := range tpmM.HashChain {
+		if hce.Pcr == int32(pcr) {
+			return hce
+		}
+	}
+	return nil
+}
+
+// extendHash extends the PCR value with the data using the hash algorithm of the PCR bank
+func extendHash(h hash.Hash, pcr []byte, data []byte) []byte {
+	h.Reset()
+	h.Write(pcr)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// getReferenceValue searches for a specific hash value of the specified PCR
+// bank in the reference values for RTM and OS
+func getReferenceValue(alg tpm2.Algorithm, hash []byte, referenceValues []ReferenceValue) *ReferenceValue {
+	for _, ver := range referenceValues {
+		if bytes.Equal(ver.Digest(alg), hash) {
+			return &ver
+		}
+	}
+	return nil
+}
+
+// containsPcr returns true if the list of PCRs contains the specified PCR
+func containsPcr(pcrs []int, pcr int) bool {
+	for _, p := range pcrs {
+		if p == pcr {
+			return true
+		}
+	}
+	return false
+}
 
-	buf := new(bytes.Buffer)
-	buf.Write((sig))
-	tpmtSig, err := tpm2.DecodeSignature(buf)
+// containsDigest returns true if the list of digests contains the specified digest
+func containsDigest(digests []HexByte, digest []byte) bool {
+	for _, d := range digests {
+		if bytes.Equal(d, digest) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMeasurementList returns true if the hash chain element contains the digests
+// of the individual measured artifacts instead of only the final PCR value
+func isMeasurementList(hce *HashChainElem, alg tpm2.Algorithm) bool {
+	return len(hce.Digests(alg)) > 1 || len(hce.Events) > 0
+}
+
+// describeEvent returns a human-readable description of the i-th measurement of
+// a hash chain element, including the event information if available
+func describeEvent(digest []byte, hce *HashChainElem, i int) string {
+	desc := fmt.Sprintf("hash: %v", hex.EncodeToString(digest))
+	if i >= len(hce.Events) {
+		return desc
+	}
+	e := hce.Events[i]
+	if e.EventType != "" {
+		desc = fmt.Sprintf("%v, type: %v", desc, e.EventType)
+	}
+	if e.Description != "" {
+		desc = fmt.Sprintf("%v, description: %v", desc, e.Description)
+	}
+	return desc
+}
+
+// newEventResult returns the report entry for the i-th measurement of a hash chain
+// element, including the event information if available
+func newEventResult(digest []byte, hce *HashChainElem, i int) EventResult {
+	event := EventResult{
+		Digest: digest,
+	}
+	if i < len(hce.Events) {
+		event.EventType = hce.Events[i].EventType
+		event.Description = hce.Events[i].Description
+	}
+	return event
+}
+
+// verifyImaSignature verifies the IMA file signature of a measurement. As the
+// event information is not protected by the TPM quote, the template hash is first
+// recalculated from the event information and compared to the measured digest
+func verifyImaSignature(alg tpm2.Algorithm, digest []byte, e *EventInfo, certs []*x509.Certificate) error {
+	entry := ima.TemplateEntry{
+		TemplateName:    e.EventType,
+		FileDigestAlg:   e.FileDigestAlg,
+		FileDigest:      e.FileDigest,
+		FileName:        e.Description,
+		Signature:       e.Signature,
+		Buf:             e.Buf,
+		ModSigDigestAlg: e.ModSigDigestAlg,
+		ModSigDigest:    e.ModSigDigest,
+		ModSig:          e.ModSig,
+	}
+	data, err := entry.MarshalTemplateData()
 	if err != nil {
-		msg := fmt.Sprintf("Failed to decode TPM signature: %v", err)
-		result.setFalse(&msg)
-		return result
+		return fmt.Errorf("failed to marshal template data: %w", err)
+	}
+	h, err := NewPcrHash(alg)
+	if err != nil {
+		return err
+	}
+	h.Write(data)
+	templateHash := h.Sum(nil)
+	if !bytes.Equal(templateHash, digest) {
+		return fmt.Errorf("template hash %v does not match measurement",
+			hex.EncodeToString(templateHash))
 	}
 
-	if tpmtSig.Alg != tpm2.AlgRSASSA {
-		msg := fmt.Sprintf("Hash algorithm %v not supported", tpmtSig.Alg)
-		result.setFalse(&msg)
-		return result
+	cert, err := ima.VerifySignature(e.Signature, e.FileDigestAlg, e.FileDigest, certs)
+	if err != nil {
+		return err
 	}
+	log.Tracef("Verified IMA signature of %v with certificate %v", e.Description,
+		cert.Subject.CommonName)
 
-	// Extract public key from x509 certificate
-	pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
-	if !ok {
-		msg := "Failed to extract public key from certificate"
-		result.setFalse(&msg)
-		return result
+	return nil
+}
+
+// decodeTpmSignature decodes a TPMT_SIGNATURE and returns it together with the
+// hash algorithm of the signature scheme
+func decodeTpmSignature(sig []byte) (*tpm2.Signature, crypto.Hash, error) {
+	tpmtSig, err := tpm2.DecodeSignature(bytes.NewBuffer(sig))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode TPM signature: %w", err)
 	}
-	hashAlg, err := tpmtSig.RSA.HashAlg.Hash()
+
+	var hashAlg tpm2.Algorithm
+	switch tpmtSig.Alg {
+	case tpm2.AlgRSASSA, tpm2.AlgRSAPSS:
+		hashAlg = tpmtSig.RSA.HashAlg
+	case tpm2.AlgECDSA:
+		hashAlg = tpmtSig.ECC.HashAlg
+	default:
+		return nil, 0, fmt.Errorf("signature algorithm %v not supported", tpmtSig.Alg)
+	}
+
+	h, err := hashAlg.Hash()
+	if err != nil {
+		return nil, 0, fmt.Errorf("hash algorithm %v not supported", hashAlg)
+	}
+	if !h.Available() {
+		return nil, 0, fmt.Errorf("hash algorithm %v not available", h)
+	}
+
+	return tpmtSig, h, nil
+}
+
+// verifyTpmQuoteSignature verifies the signature of a TPM quote. RSASSA, RSAPSS and
+// ECDSA signatures are supported, the quote is hashed with the hash algorithm of the
+// signature scheme
+func verifyTpmQuoteSignature(quote, sig []byte, cert *x509.Certificate) Result {
+	result := Result{}
+	result.Success = true
+
+	tpmtSig, hashAlg, err := decodeTpmSignature(sig)
 	if err != nil {
-		msg := "Hash algorithm not supported"
+		msg := err.Error()
 		result.setFalse(&msg)
 		return result
 	}
 
 	// Hash the quote and Verify the TPM Quote signature
-	hashed := sha256.Sum256(quote)
-	err = rsa.VerifyPKCS1v15(pubKey, hashAlg, hashed[:], tpmtSig.RSA.Signature)
+	h := hashAlg.New()
+	h.Write(quote)
+	hashed := h.Sum(nil)
+
+	switch tpmtSig.Alg {
+	case tpm2.AlgRSASSA, tpm2.AlgRSAPSS:
+		pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			msg := "Failed to extract RSA public key from certificate"
+			result.setFalse(&msg)
+			return result
+		}
+		if tpmtSig.Alg == tpm2.AlgRSASSA {
+			err = rsa.VerifyPKCS1v15(pubKey, hashAlg, hashed, tpmtSig.RSA.Signature)
+		} else {
+			err = rsa.VerifyPSS(pubKey, hashAlg, hashed, tpmtSig.RSA.Signature, nil)
+		}
+	case tpm2.AlgECDSA:
+		pubKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+		if !ok {
+			msg := "Failed to extract ECDSA public key from certificate"
+			result.setFalse(&msg)
+			return result
+		}
+		if !ecdsa.Verify(pubKey, hashed, tpmtSig.ECC.R, tpmtSig.ECC.S) {
+			err = errors.New("ECDSA verification failed")
+		}
+	}
 	if err != nil {
 		msg := fmt.Sprintf("Failed to verify TPM quote signature: %v", err)
 		result.setFalse(&msg)
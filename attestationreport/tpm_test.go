@@ -16,11 +16,25 @@
 package attestationreport
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/hex"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/Fraunhofer-AISEC/cmc/ima"
+	"github.com/Fraunhofer-AISEC/cmc/internal"
+	"github.com/google/go-tpm/tpm2"
 	"github.com/sirupsen/logrus"
 )
 
@@ -162,7 +176,7 @@ func Test_verifyTpmMeasurements(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, got1 := verifyTpmMeasurements(tt.args.tpmM, tt.args.nonce, tt.args.referenceValues, tt.args.casPem)
+			got, got1 := verifyTpmMeasurements(tt.args.tpmM, tt.args.nonce, tt.args.referenceValues, nil, nil, tt.args.casPem)
 			if got1 != tt.want1 {
 				t.Errorf("verifyTpmMeasurements() --GOT1-- = %v, --WANT1-- %v", got1, tt.want1)
 			}
@@ -454,10 +468,12 @@ var (
 		PcrRecalculation: []PcrResult{
 			{
 				Pcr:        1,
+				Bank:       "SHA256",
 				Validation: validResultMulti,
 			},
 			{
 				Pcr:        4,
+				Bank:       "SHA256",
 				Validation: validResultMulti,
 			},
 		},
@@ -467,3 +483,818 @@ var (
 		ReferenceValueCheck: validResultMulti,
 	}
 )
+
+func Test_recalculatePcrsEventLog(t *testing.T) {
+	known := dec("ef5631c7bbb8d98ad220e211933fcde16aac6154cf229fea3c728fb0f2c27e39")
+	unknown := dec("2a814d03d22568e2d669595dd8be199fd7b3df2acb8caae38e24e92605e15c80")
+
+	referenceValues := []ReferenceValue{
+		{
+			Type:   "TPM Reference Value",
+			Sha256: known,
+			Name:   "EV_CPU_MICROCODE",
+			Pcr:    &pcrs[1],
+		},
+	}
+
+	tests := []struct {
+		name        string
+		elem        *HashChainElem
+		want        bool
+		wantDesc    string
+		wantMatched []HexByte
+		wantUnknown []HexByte
+		wantMissing []HexByte
+	}{
+		{
+			name: "Single Event",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    1,
+				Sha256: []HexByte{known},
+				Events: []EventInfo{{EventType: "EV_CPU_MICROCODE"}},
+			},
+			want:        true,
+			wantMatched: []HexByte{known},
+		},
+		{
+			name: "Unknown Event",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    1,
+				Sha256: []HexByte{known, unknown},
+				Events: []EventInfo{
+					{EventType: "EV_CPU_MICROCODE"},
+					{EventType: "EV_EFI_VARIABLE_BOOT", Description: "BootOrder"},
+				},
+			},
+			want:        false,
+			wantDesc:    "type: EV_EFI_VARIABLE_BOOT, description: BootOrder",
+			wantMatched: []HexByte{known},
+			wantUnknown: []HexByte{unknown},
+		},
+		{
+			name: "Missing Event",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    1,
+				Sha256: []HexByte{unknown},
+				Events: []EventInfo{{EventType: "EV_EFI_VARIABLE_BOOT", Description: "BootOrder"}},
+			},
+			want:        false,
+			wantDesc:    "type: EV_EFI_VARIABLE_BOOT, description: BootOrder",
+			wantUnknown: []HexByte{unknown},
+			wantMissing: []HexByte{known},
+		},
+	}
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpmM := &TpmMeasurement{HashChain: []*HashChainElem{tt.elem}}
+			pcrResult, _, got := recalculatePcrs(tpmM, tpm2.AlgSHA256, referenceValues, nil, nil)
+			if got != tt.want {
+				t.Errorf("recalculatePcrs() --GOT-- = %v, --WANT-- %v", got, tt.want)
+			}
+			if len(pcrResult) != 1 {
+				t.Fatalf("recalculatePcrs() returned %v PCR results, expected 1", len(pcrResult))
+			}
+			if !equalEventDigests(pcrResult[0].MatchedEvents, tt.wantMatched) {
+				t.Errorf("recalculatePcrs() matched events = %v, want %v", pcrResult[0].MatchedEvents, tt.wantMatched)
+			}
+			if !equalEventDigests(pcrResult[0].UnknownEvents, tt.wantUnknown) {
+				t.Errorf("recalculatePcrs() unknown events = %v, want %v", pcrResult[0].UnknownEvents, tt.wantUnknown)
+			}
+			if !equalEventDigests(pcrResult[0].MissingEvents, tt.wantMissing) {
+				t.Errorf("recalculatePcrs() missing events = %v, want %v", pcrResult[0].MissingEvents, tt.wantMissing)
+			}
+			if tt.wantDesc == "" {
+				return
+			}
+			found := false
+			for _, r := range pcrResult {
+				for _, d := range r.Validation.Details {
+					if strings.Contains(d, tt.wantDesc) && strings.Contains(d, hex.EncodeToString(unknown)) {
+						found = true
+					}
+				}
+			}
+			if !found {
+				t.Errorf("recalculatePcrs() did not report event '%v': %v", tt.wantDesc, pcrResult)
+			}
+		})
+	}
+}
+
+func Test_recalculatePcrsAlternatives(t *testing.T) {
+	bootV1 := sha256.Sum256([]byte("bootloader-v1"))
+	bootV2 := sha256.Sum256([]byte("bootloader-v2"))
+	module := sha256.Sum256([]byte("module"))
+	pcr := sha256.Sum256(append(make([]byte, sha256.Size), bootV2[:]...))
+
+	referenceValues := []ReferenceValue{
+		{Type: "TPM Reference Value", Sha256: bootV1[:], Name: "bootloader-v1", Pcr: &pcrs[4], AlternativeGroup: "bootloader"},
+		{Type: "TPM Reference Value", Sha256: bootV2[:], Name: "bootloader-v2", Pcr: &pcrs[4], AlternativeGroup: "bootloader"},
+		{Type: "TPM Reference Value", Sha256: module[:], Name: "module", Pcr: &pcrs[4], Optional: true},
+	}
+
+	tests := []struct {
+		name            string
+		elem            *HashChainElem
+		want            bool
+		wantAlternative string
+	}{
+		{
+			name: "Measurement List",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    4,
+				Sha256: []HexByte{bootV2[:]},
+				Events: []EventInfo{{EventType: "EV_EFI_BOOT_SERVICES_APPLICATION"}},
+			},
+			want:            true,
+			wantAlternative: "bootloader-v2",
+		},
+		{
+			name: "Final PCR Value",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    4,
+				Sha256: []HexByte{pcr[:]},
+			},
+			want:            true,
+			wantAlternative: "bootloader-v2",
+		},
+		{
+			name: "No Alternative Measured",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    4,
+				Sha256: []HexByte{module[:]},
+				Events: []EventInfo{{EventType: "EV_EFI_BOOT_SERVICES_APPLICATION"}},
+			},
+			want:            false,
+			wantAlternative: "",
+		},
+	}
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpmM := &TpmMeasurement{HashChain: []*HashChainElem{tt.elem}}
+			pcrResult, _, got := recalculatePcrs(tpmM, tpm2.AlgSHA256, referenceValues, nil, nil)
+			if got != tt.want {
+				t.Errorf("recalculatePcrs() --GOT-- = %v, --WANT-- %v: %v", got, tt.want, pcrResult)
+			}
+			if len(pcrResult) != 1 || len(pcrResult[0].Alternatives) != 1 {
+				t.Fatalf("recalculatePcrs() did not report alternatives: %v", pcrResult)
+			}
+			if pcrResult[0].Alternatives[0].Name != tt.wantAlternative {
+				t.Errorf("recalculatePcrs() matched alternative = %v, want %v",
+					pcrResult[0].Alternatives[0].Name, tt.wantAlternative)
+			}
+		})
+	}
+}
+
+func Test_recalculatePcrsUnordered(t *testing.T) {
+	first := sha256.Sum256([]byte("/usr/bin/first"))
+	second := sha256.Sum256([]byte("/usr/bin/second"))
+	unknown := sha256.Sum256([]byte("/usr/bin/unknown"))
+	bootloader := sha256.Sum256([]byte("bootloader"))
+	pcr4 := extendHash(sha256.New(), make([]byte, 32), bootloader[:])
+
+	referenceValues := []ReferenceValue{
+		{Type: "TPM Reference Value", Sha256: bootloader[:], Name: "bootloader", Pcr: &pcrs[4]},
+		{Type: "TPM Reference Value", Sha256: first[:], Name: "/usr/bin/first", Pcr: &pcrs[10]},
+		{Type: "TPM Reference Value", Sha256: second[:], Name: "/usr/bin/second", Pcr: &pcrs[10]},
+	}
+
+	tests := []struct {
+		name          string
+		digests       []HexByte
+		unorderedPcrs []int
+		want          bool
+	}{
+		{
+			name:          "Unordered Measurements",
+			digests:       []HexByte{second[:], first[:]},
+			unorderedPcrs: []int{10},
+			want:          true,
+		},
+		{
+			name:          "Unordered Measurements Ordered PCR",
+			digests:       []HexByte{second[:], first[:]},
+			unorderedPcrs: nil,
+			want:          false,
+		},
+		{
+			name:          "Unknown Measurement",
+			digests:       []HexByte{second[:], unknown[:], first[:]},
+			unorderedPcrs: []int{10},
+			want:          false,
+		},
+		{
+			name:          "Missing Measurement",
+			digests:       []HexByte{second[:], second[:]},
+			unorderedPcrs: []int{10},
+			want:          false,
+		},
+		{
+			name:          "Measurement Of Other PCR",
+			digests:       []HexByte{second[:], bootloader[:], first[:]},
+			unorderedPcrs: []int{10},
+			want:          false,
+		},
+	}
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpmM := &TpmMeasurement{
+				HashChain: []*HashChainElem{
+					{
+						Type:   "Hash Chain",
+						Pcr:    4,
+						Sha256: []HexByte{pcr4},
+					},
+					{
+						Type:   "Hash Chain",
+						Pcr:    10,
+						Sha256: tt.digests,
+					},
+				},
+			}
+			pcrResult, _, got := recalculatePcrs(tpmM, tpm2.AlgSHA256, referenceValues, nil, tt.unorderedPcrs)
+			if got != tt.want {
+				t.Errorf("recalculatePcrs() --GOT-- = %v, --WANT-- %v: %v", got, tt.want, pcrResult)
+			}
+			if len(pcrResult) != 2 || !pcrResult[0].Validation.Success ||
+				pcrResult[1].Validation.Success != tt.want {
+				t.Errorf("recalculatePcrs() PCR validation = %v, want %v", pcrResult, tt.want)
+			}
+		})
+	}
+}
+
+func equalEventDigests(events []EventResult, digests []HexByte) bool {
+	if len(events) != len(digests) {
+		return false
+	}
+	for i := range events {
+		if !bytes.Equal(events[i].Digest, digests[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func Test_replayPcrs(t *testing.T) {
+	first := sha256.Sum256([]byte("first"))
+	second := sha256.Sum256([]byte("second"))
+	pcr := sha256.Sum256(append(make([]byte, sha256.Size), first[:]...))
+	pcr = sha256.Sum256(append(pcr[:], second[:]...))
+	locality := make([]byte, sha256.Size)
+	locality[sha256.Size-1] = 3
+	pcr0 := sha256.Sum256(append(locality, first[:]...))
+
+	tests := []struct {
+		name    string
+		elem    *HashChainElem
+		want    []byte
+		wantErr bool
+	}{
+		{
+			name: "Measurement List",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    10,
+				Sha256: []HexByte{first[:], second[:]},
+			},
+			want: pcr[:],
+		},
+		{
+			name: "Final PCR Value",
+			elem: &HashChainElem{
+				Type:   "Hash Chain",
+				Pcr:    10,
+				Sha256: []HexByte{pcr[:]},
+			},
+			want: pcr[:],
+		},
+		{
+			name: "Missing PCR Bank",
+			elem: &HashChainElem{
+				Type: "Hash Chain",
+				Pcr:  10,
+				Sha1: []HexByte{make([]byte, sha1.Size)},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Startup Locality",
+			elem: &HashChainElem{
+				Type:            "Hash Chain",
+				Pcr:             0,
+				Sha256:          []HexByte{first[:]},
+				Events:          []EventInfo{{EventType: "EV_POST_CODE"}},
+				StartupLocality: 3,
+			},
+			want: pcr0[:],
+		},
+		{
+			name: "Invalid Startup Locality",
+			elem: &HashChainElem{
+				Type:            "Hash Chain",
+				Pcr:             0,
+				Sha256:          []HexByte{first[:]},
+				Events:          []EventInfo{{EventType: "EV_POST_CODE"}},
+				StartupLocality: 2,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Startup Locality Other PCR",
+			elem: &HashChainElem{
+				Type:            "Hash Chain",
+				Pcr:             10,
+				Sha256:          []HexByte{first[:], second[:]},
+				StartupLocality: 3,
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpmM := &TpmMeasurement{HashChain: []*HashChainElem{tt.elem}}
+			got, err := replayPcrs(tpmM, tpm2.AlgSHA256)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("replayPcrs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !bytes.Equal(got[int(tt.elem.Pcr)], tt.want) {
+				t.Errorf("replayPcrs() = %v, want %v", hex.EncodeToString(got[int(tt.elem.Pcr)]),
+					hex.EncodeToString(tt.want))
+			}
+		})
+	}
+}
+
+func Test_recalculatePcrsImaSignature(t *testing.T) {
+
+	vendorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	vendorCert := createImaSigningCert(t, vendorKey, []byte{0x01, 0x02, 0x03, 0x04})
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherCert := createImaSigningCert(t, otherKey, []byte{0x01, 0x02, 0x03, 0x04})
+
+	fileDigest := sha256.Sum256([]byte("signed file"))
+	s, err := ecdsa.SignASN1(rand.Reader, vendorKey, fileDigest[:])
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	// IMA signature v2 header: type, version, hash algorithm (sha256), key ID, size
+	sig := append([]byte{0x03, 0x02, 0x04, 0x01, 0x02, 0x03, 0x04, byte(len(s) >> 8), byte(len(s))}, s...)
+
+	event := EventInfo{
+		EventType:     "ima-sig",
+		Description:   "/usr/bin/signed",
+		FileDigestAlg: "sha256",
+		FileDigest:    fileDigest[:],
+		Signature:     sig,
+	}
+
+	modsigDigest := sha256.Sum256([]byte("signed module"))
+	modsigEvent := event
+	modsigEvent.EventType = "ima-modsig"
+	modsigEvent.ModSigDigestAlg = "sha256"
+	modsigEvent.ModSigDigest = modsigDigest[:]
+	modsigEvent.ModSig = []byte("appended signature")
+
+	// The signature covers a SHA256 digest, whereas the event claims an SM3 file digest
+	mismatchEvent := event
+	mismatchEvent.FileDigestAlg = "sm3"
+
+	tamperedEvent := event
+	tamperedEvent.Description = "/usr/bin/other"
+
+	tests := []struct {
+		name     string
+		event    EventInfo
+		measured EventInfo
+		certs    []*x509.Certificate
+		want     bool
+	}{
+		{
+			name:     "Valid Signature",
+			event:    event,
+			measured: event,
+			certs:    []*x509.Certificate{vendorCert},
+			want:     true,
+		},
+		{
+			name:     "Valid Signature Modsig Template",
+			event:    modsigEvent,
+			measured: modsigEvent,
+			certs:    []*x509.Certificate{vendorCert},
+			want:     true,
+		},
+		{
+			name:     "No Signing Certs",
+			event:    event,
+			measured: event,
+			certs:    nil,
+			want:     false,
+		},
+		{
+			name:     "Wrong Signing Cert",
+			event:    event,
+			measured: event,
+			certs:    []*x509.Certificate{otherCert},
+			want:     false,
+		},
+		{
+			name:     "Tampered Event Information",
+			event:    tamperedEvent,
+			measured: event,
+			certs:    []*x509.Certificate{vendorCert},
+			want:     false,
+		},
+		{
+			name:     "Signature Hash Algorithm Mismatch",
+			event:    mismatchEvent,
+			measured: mismatchEvent,
+			certs:    []*x509.Certificate{vendorCert},
+			want:     false,
+		},
+	}
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := ima.TemplateEntry{
+				TemplateName:    tt.measured.EventType,
+				FileDigestAlg:   tt.measured.FileDigestAlg,
+				FileDigest:      tt.measured.FileDigest,
+				FileName:        tt.measured.Description,
+				Signature:       tt.measured.Signature,
+				ModSigDigestAlg: tt.measured.ModSigDigestAlg,
+				ModSigDigest:    tt.measured.ModSigDigest,
+				ModSig:          tt.measured.ModSig,
+			}
+			data, err := entry.MarshalTemplateData()
+			if err != nil {
+				t.Fatalf("failed to marshal template data: %v", err)
+			}
+			templateHash := sha256.Sum256(data)
+
+			tpmM := &TpmMeasurement{
+				HashChain: []*HashChainElem{
+					{
+						Type:   "Hash Chain",
+						Pcr:    10,
+						Sha256: []HexByte{templateHash[:]},
+						Events: []EventInfo{tt.event},
+					},
+				},
+			}
+			pcrResult, _, got := recalculatePcrs(tpmM, tpm2.AlgSHA256, nil, tt.certs, nil)
+			if got != tt.want {
+				t.Errorf("recalculatePcrs() --GOT-- = %v, --WANT-- %v: %v", got, tt.want, pcrResult)
+			}
+		})
+	}
+}
+
+func Test_verifyTpmMeasurementsPcrBanks(t *testing.T) {
+
+	akKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	akCert := createImaSigningCert(t, akKey, []byte{0x01})
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	nonce := []byte{0x01, 0x02, 0x03, 0x04}
+
+	sha1Ref := sha1.Sum([]byte("kernel"))
+	sha1Pcr := sha1.Sum(append(make([]byte, sha1.Size), sha1Ref[:]...))
+	sha384Ref := sha512.Sum384([]byte("kernel"))
+	sha384Pcr := sha512.Sum384(append(make([]byte, sha512.Size384), sha384Ref[:]...))
+
+	sha1Quote := createTpmQuote(t, akKey, nonce, tpm2.AlgSHA1, sha1Pcr[:])
+	sha384Quote := createTpmQuote(t, akKey, nonce, tpm2.AlgSHA384, sha384Pcr[:])
+	invalidQuote := createTpmQuote(t, otherKey, nonce, tpm2.AlgSHA384, sha384Pcr[:])
+	invalidSha1Quote := createTpmQuote(t, otherKey, nonce, tpm2.AlgSHA1, sha1Pcr[:])
+
+	validRefVals := []ReferenceValue{
+		{
+			Type:   "TPM Reference Value",
+			Sha1:   sha1Ref[:],
+			Sha384: sha384Ref[:],
+			Name:   "Kernel",
+			Pcr:    &pcrs[4],
+		},
+	}
+	missingRefVals := []ReferenceValue{
+		{
+			Type: "TPM Reference Value",
+			Sha1: sha1Ref[:],
+			Name: "Kernel",
+			Pcr:  &pcrs[4],
+		},
+	}
+
+	validHashChain := []*HashChainElem{
+		{
+			Type:   "Hash Chain",
+			Pcr:    4,
+			Sha1:   []HexByte{sha1Pcr[:]},
+			Sha384: []HexByte{sha384Pcr[:]},
+		},
+	}
+	missingHashChain := []*HashChainElem{
+		{
+			Type: "Hash Chain",
+			Pcr:  4,
+			Sha1: []HexByte{sha1Pcr[:]},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		quotes       []TpmQuote
+		hashChain    []*HashChainElem
+		refVals      []ReferenceValue
+		want         bool
+		wantSigBanks []string
+	}{
+		{
+			name:      "Valid SHA1 Bank",
+			quotes:    []TpmQuote{sha1Quote},
+			hashChain: validHashChain,
+			refVals:   validRefVals,
+			want:      true,
+		},
+		{
+			name:      "Valid SHA1 and SHA384 Banks",
+			quotes:    []TpmQuote{sha1Quote, sha384Quote},
+			hashChain: validHashChain,
+			refVals:   validRefVals,
+			want:      true,
+		},
+		{
+			name:      "Missing SHA384 Reference Value",
+			quotes:    []TpmQuote{sha1Quote, sha384Quote},
+			hashChain: validHashChain,
+			refVals:   missingRefVals,
+			want:      false,
+		},
+		{
+			name:      "Missing SHA384 Measurement",
+			quotes:    []TpmQuote{sha1Quote, sha384Quote},
+			hashChain: missingHashChain,
+			refVals:   validRefVals,
+			want:      false,
+		},
+		{
+			name:         "Invalid SHA384 Quote Signature",
+			quotes:       []TpmQuote{sha1Quote, invalidQuote},
+			hashChain:    validHashChain,
+			refVals:      validRefVals,
+			want:         false,
+			wantSigBanks: []string{"SHA384"},
+		},
+		{
+			name:         "Invalid SHA1 and SHA384 Quote Signatures",
+			quotes:       []TpmQuote{invalidSha1Quote, invalidQuote},
+			hashChain:    validHashChain,
+			refVals:      validRefVals,
+			want:         false,
+			wantSigBanks: []string{"SHA1", "SHA384"},
+		},
+		{
+			name:      "Duplicate SHA384 Bank",
+			quotes:    []TpmQuote{sha1Quote, sha384Quote, sha384Quote},
+			hashChain: validHashChain,
+			refVals:   validRefVals,
+			want:      false,
+		},
+		{
+			name:      "Duplicate SHA1 Bank",
+			quotes:    []TpmQuote{sha1Quote, sha1Quote},
+			hashChain: validHashChain,
+			refVals:   validRefVals,
+			want:      false,
+		},
+	}
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpmM := &TpmMeasurement{
+				Type:      "TPM Measurement",
+				Message:   tt.quotes[0].Message,
+				Signature: tt.quotes[0].Signature,
+				Certs:     [][]byte{internal.WriteCertPem(akCert)},
+				HashChain: tt.hashChain,
+				Quotes:    tt.quotes[1:],
+			}
+			got, got1 := verifyTpmMeasurements(tpmM, nonce, tt.refVals, nil, nil, internal.WriteCertPem(akCert))
+			if got1 != tt.want {
+				t.Errorf("verifyTpmMeasurements() --GOT1-- = %v, --WANT1-- %v: %v", got1, tt.want, got)
+			}
+			if got1 && len(got.PcrRecalculation) != len(tt.quotes) {
+				t.Errorf("verifyTpmMeasurements() returned %v PCR results, expected %v",
+					len(got.PcrRecalculation), len(tt.quotes))
+			}
+			for _, bank := range tt.wantSigBanks {
+				if !strings.Contains(got.QuoteSignature.SignCheck.Details, bank+" quote") {
+					t.Errorf("verifyTpmMeasurements() signature details %q do not contain %v quote",
+						got.QuoteSignature.SignCheck.Details, bank)
+				}
+			}
+		})
+	}
+}
+
+func Test_verifyTpmMeasurementsPcrSelection(t *testing.T) {
+
+	akKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	akCert := createImaSigningCert(t, akKey, []byte{0x01})
+
+	nonce := []byte{0x01, 0x02, 0x03, 0x04}
+
+	kernelRef := sha256.Sum256([]byte("kernel"))
+	kernelPcr := sha256.Sum256(append(make([]byte, sha256.Size), kernelRef[:]...))
+	initrdRef := sha256.Sum256([]byte("initrd"))
+	initrdPcr := sha256.Sum256(append(make([]byte, sha256.Size), initrdRef[:]...))
+
+	// The TPM hashes the selected PCRs in ascending order
+	validQuote := createTpmQuoteSelection(t, akKey, nonce, tpm2.AlgSHA256, []int{4, 5},
+		kernelPcr[:], initrdPcr[:])
+	// Quote over PCR4 only, with a digest over the values of PCR4 and PCR5
+	partialQuote := createTpmQuoteSelection(t, akKey, nonce, tpm2.AlgSHA256, []int{4},
+		kernelPcr[:], initrdPcr[:])
+
+	refVals := []ReferenceValue{
+		{Type: "TPM Reference Value", Sha256: kernelRef[:], Name: "Kernel", Pcr: &pcrs[4]},
+		{Type: "TPM Reference Value", Sha256: initrdRef[:], Name: "Initrd", Pcr: &pcrs[5]},
+	}
+	swappedRefVals := []ReferenceValue{
+		{Type: "TPM Reference Value", Sha256: kernelRef[:], Name: "Kernel", Pcr: &pcrs[5]},
+		{Type: "TPM Reference Value", Sha256: initrdRef[:], Name: "Initrd", Pcr: &pcrs[4]},
+	}
+
+	kernelElem := &HashChainElem{Type: "Hash Chain", Pcr: 4, Sha256: []HexByte{kernelPcr[:]}}
+	initrdElem := &HashChainElem{Type: "Hash Chain", Pcr: 5, Sha256: []HexByte{initrdPcr[:]}}
+
+	tests := []struct {
+		name      string
+		quote     TpmQuote
+		hashChain []*HashChainElem
+		refVals   []ReferenceValue
+		want      bool
+	}{
+		{
+			name:      "Valid PCR Selection",
+			quote:     validQuote,
+			hashChain: []*HashChainElem{kernelElem, initrdElem},
+			refVals:   refVals,
+			want:      true,
+		},
+		{
+			name:      "Valid Unsorted Hash Chain",
+			quote:     validQuote,
+			hashChain: []*HashChainElem{initrdElem, kernelElem},
+			refVals:   refVals,
+			want:      true,
+		},
+		{
+			name:  "Swapped PCR Labels",
+			quote: validQuote,
+			hashChain: []*HashChainElem{
+				{Type: "Hash Chain", Pcr: 5, Sha256: []HexByte{kernelPcr[:]}},
+				{Type: "Hash Chain", Pcr: 4, Sha256: []HexByte{initrdPcr[:]}},
+			},
+			refVals: swappedRefVals,
+			want:    false,
+		},
+		{
+			name:  "Duplicate PCR",
+			quote: validQuote,
+			hashChain: []*HashChainElem{
+				kernelElem,
+				{Type: "Hash Chain", Pcr: 4, Sha256: []HexByte{initrdPcr[:]}},
+			},
+			refVals: refVals,
+			want:    false,
+		},
+		{
+			name:      "PCR Selection Mismatch",
+			quote:     partialQuote,
+			hashChain: []*HashChainElem{kernelElem, initrdElem},
+			refVals:   refVals,
+			want:      false,
+		},
+	}
+
+	logrus.SetLevel(logrus.InfoLevel)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpmM := &TpmMeasurement{
+				Type:      "TPM Measurement",
+				Message:   tt.quote.Message,
+				Signature: tt.quote.Signature,
+				Certs:     [][]byte{internal.WriteCertPem(akCert)},
+				HashChain: tt.hashChain,
+			}
+			got, got1 := verifyTpmMeasurements(tpmM, nonce, tt.refVals, nil, nil, internal.WriteCertPem(akCert))
+			if got1 != tt.want {
+				t.Errorf("verifyTpmMeasurements() --GOT1-- = %v, --WANT1-- %v: %v", got1, tt.want, got)
+			}
+		})
+	}
+}
+
+// createTpmQuote creates a TPM quote over PCR4 of the specified PCR bank, signed
+// with an ECDSA-SHA256 signing scheme
+func createTpmQuote(t *testing.T, key *ecdsa.PrivateKey, nonce []byte, alg tpm2.Algorithm, pcr []byte) TpmQuote {
+	return createTpmQuoteSelection(t, key, nonce, alg, []int{4}, pcr)
+}
+
+// createTpmQuoteSelection creates a TPM quote over the selected PCRs of the specified
+// PCR bank with the PCR digest calculated over the specified PCR values
+func createTpmQuoteSelection(t *testing.T, key *ecdsa.PrivateKey, nonce []byte, alg tpm2.Algorithm,
+	selection []int, pcrValues ...[]byte,
+) TpmQuote {
+	pcrDigest := sha256.Sum256(bytes.Join(pcrValues, nil))
+	ad := tpm2.AttestationData{
+		Magic:           0xff544347,
+		Type:            tpm2.TagAttestQuote,
+		QualifiedSigner: tpm2.Name{Digest: &tpm2.HashValue{Alg: tpm2.AlgSHA256, Value: make([]byte, 32)}},
+		ExtraData:       nonce,
+		AttestedQuoteInfo: &tpm2.QuoteInfo{
+			PCRSelection: tpm2.PCRSelection{Hash: alg, PCRs: selection},
+			PCRDigest:    pcrDigest[:],
+		},
+	}
+	msg, err := ad.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode attestation data: %v", err)
+	}
+	digest := sha256.Sum256(msg)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign quote: %v", err)
+	}
+	sig, err := tpm2.Signature{
+		Alg: tpm2.AlgECDSA,
+		ECC: &tpm2.SignatureECC{HashAlg: tpm2.AlgSHA256, R: r, S: s},
+	}.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode signature: %v", err)
+	}
+	return TpmQuote{Message: msg, Signature: sig}
+}
+
+func createImaSigningCert(t *testing.T, priv *ecdsa.PrivateKey, keyId []byte) *x509.Certificate {
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "IMA Signing Key"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		SubjectKeyId: append([]byte{0xaa, 0xbb}, keyId...),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
@@ -23,39 +23,44 @@ import (
 // VerificationResult represents the results of all steps taken during
 // the validation of an attestation report.
 type VerificationResult struct {
-	Type            string            `json:"type"`
-	Success         bool              `json:"raSuccessful"`         // Summarizing value illustrating whether any issues were detected during validation of the Attestation Report
-	SwCertLevel     int               `json:"swCertLevel"`          // Overall certification level for the entire software stack (the minimum of all CertificationLevels in the used manifests)
-	FreshnessCheck  Result            `json:"freshnessCheck"`       // Result for comparison of the expected nonce to the one provided in the attestation report
-	ReportSignature []SignatureResult `json:"reportSignatureCheck"` // Result for validation of the overall report signature
-	CompDescResult  *CompDescResult   `json:"companyValidation,omitempty"`
-	RtmResult       ManifestResult    `json:"rtmValidation"`
-	OsResult        ManifestResult    `json:"osValidation"`
-	AppResults      []ManifestResult  `json:"appValidation,omitempty"`
-	MeasResult      MeasurementResult `json:"measurementValidation"`
-	DevDescResult   DevDescResult     `json:"deviceDescValidation"`
-	PolicySuccess   bool              `json:"policySuccess,omitempty"`   // Result of custom policy validation (if utilized)
-	ProcessingError []string          `json:"processingError,omitempty"` // Documentation of processing errors (dependent from provided Attestation Report) which hindered a complete validation
-	InternalError   bool              `json:"internalError,omitempty"`   // Documentation of internal errors (independent from provided Attestation Report) which hindered a complete validation
+	Type                 string            `json:"type"`
+	Success              bool              `json:"raSuccessful"`         // Summarizing value illustrating whether any issues were detected during validation of the Attestation Report
+	SwCertLevel          int               `json:"swCertLevel"`          // Overall certification level for the entire software stack (the minimum of all CertificationLevels in the used manifests)
+	FreshnessCheck       Result            `json:"freshnessCheck"`       // Result for comparison of the expected nonce to the one provided in the attestation report
+	ReportSignature      []SignatureResult `json:"reportSignatureCheck"` // Result for validation of the overall report signature
+	CompDescResult       *CompDescResult   `json:"companyValidation,omitempty"`
+	RtmResult            ManifestResult    `json:"rtmValidation"`
+	OsResult             ManifestResult    `json:"osValidation"`
+	AppResults           []ManifestResult  `json:"appValidation,omitempty"`
+	MeasResult           MeasurementResult `json:"measurementValidation"`
+	DevDescResult        DevDescResult     `json:"deviceDescValidation"`
+	RevocationListResult *ManifestResult   `json:"revocationListValidation,omitempty"` // Result for the validation of the Revocation List (if provided)
+	PolicySuccess        bool              `json:"policySuccess,omitempty"`            // Result of custom policy validation (if utilized)
+	ProcessingError      []string          `json:"processingError,omitempty"`          // Documentation of processing errors (dependent from provided Attestation Report) which hindered a complete validation
+	InternalError        bool              `json:"internalError,omitempty"`            // Documentation of internal errors (independent from provided Attestation Report) which hindered a complete validation
 }
 
 // CompDescResult represents the results of the validation of the
 // Company Description and its mapping to the used device certificate.
 type CompDescResult struct {
-	Name           string            `json:"name"`
-	CompCertLevel  int               `json:"compCertLevel"`       // Certification level for the company operating the device
-	Summary        ResultMulti       `json:"resultSummary"`       // Summarizing value illustrating whether any issues were detected during validation of the Company Description
-	SignatureCheck []SignatureResult `json:"signatureValidation"` // Results for validation of the Description Signatures and the used certificates
-	ValidityCheck  Result            `json:"validityCheck"`       // Result from checking the validity of the description
+	Name            string            `json:"name"`
+	CompCertLevel   int               `json:"compCertLevel"`             // Certification level for the company operating the device
+	Summary         ResultMulti       `json:"resultSummary"`             // Summarizing value illustrating whether any issues were detected during validation of the Company Description
+	SignatureCheck  []SignatureResult `json:"signatureValidation"`       // Results for validation of the Description Signatures and the used certificates
+	ValidityCheck   Result            `json:"validityCheck"`             // Result from checking the validity of the description
+	RevocationCheck *Result           `json:"revocationCheck,omitempty"` // Result from checking the revocation status of the description (if a revocation list was provided)
+	CertLevelCheck  Result            `json:"certLevelCheck"`            // Result from checking that the company is certified for the certification level of the software stack
+	DeviceCertCheck Result            `json:"deviceCertCheck"`           // Result from checking that the device certificate was issued to the company
 }
 
 // ManifestResult represents the results of the validation of a
 // manifest provided in the Attestation Report.
 type ManifestResult struct {
-	Name           string            `json:"name"`
-	Summary        ResultMulti       `json:"resultSummary"`       // Summarizing value illustrating whether any issues were detected during validation of the Software Manifest
-	SignatureCheck []SignatureResult `json:"signatureValidation"` // Results for validation of the Manifest Signatures and the used certificates
-	ValidityCheck  Result            `json:"validityCheck"`       // Result from checking the validity of the manifest
+	Name            string            `json:"name"`
+	Summary         ResultMulti       `json:"resultSummary"`             // Summarizing value illustrating whether any issues were detected during validation of the Software Manifest
+	SignatureCheck  []SignatureResult `json:"signatureValidation"`       // Results for validation of the Manifest Signatures and the used certificates
+	ValidityCheck   Result            `json:"validityCheck"`             // Result from checking the validity of the manifest
+	RevocationCheck *Result           `json:"revocationCheck,omitempty"` // Result from checking the revocation status of the manifest (if a revocation list was provided)
 }
 
 // MeasurementResult represents the results of the comparison of
@@ -64,6 +69,7 @@ type ManifestResult struct {
 type MeasurementResult struct {
 	TpmMeasResult *TpmMeasurementResult `json:"tpm,omitempty"`
 	SnpMeasResult *SnpMeasurementResult `json:"snp,omitempty"`
+	TdxMeasResult *TdxMeasurementResult `json:"tdx,omitempty"`
 	IasMeasResult *IasMeasurementResult `json:"ias,omitempty"`
 	SwMeasResult  []SwMeasurementResult `json:"sw,omitempty"`
 }
@@ -71,13 +77,45 @@ type MeasurementResult struct {
 // DevDescResult represents the results of the validation of the
 // Device Description in the Attestation Report.
 type DevDescResult struct {
-	Summary             ResultMulti       `json:"resultSummary"`       // Summarizing value illustrating whether any issues were detected during validation of the Device Description
-	CorrectRtm          Result            `json:"correctRtm"`          // Result for comparison of RTM in the Device Description and the provided RTM Manifest
-	CorrectOs           Result            `json:"correctOs"`           // Result for comparison of OS in the Device Description and the provided OS Manifest
-	CorrectApps         ResultMulti       `json:"correctApps"`         // Result for comparison of App List in the Device Description and the provided App Manifest
-	RtmOsCompatibility  Result            `json:"rtmOsCompatibility"`  // Result for consistency check for mapping from OS Manifest to RTM Manifest
-	OsAppsCompatibility ResultMulti       `json:"osAppCompatibility"`  // Result for consistency check for mapping from App Manifests to OS Manifest
-	SignatureCheck      []SignatureResult `json:"signatureValidation"` // Results for validation of the Device Description Signature(s) and the used certificates
+	Summary             ResultMulti           `json:"resultSummary"`                // Summarizing value illustrating whether any issues were detected during validation of the Device Description
+	CorrectRtm          Result                `json:"correctRtm"`                   // Result for comparison of RTM in the Device Description and the provided RTM Manifest
+	CorrectOs           Result                `json:"correctOs"`                    // Result for comparison of OS in the Device Description and the provided OS Manifest
+	CorrectApps         ResultMulti           `json:"correctApps"`                  // Result for comparison of App List in the Device Description and the provided App Manifest
+	RtmOsCompatibility  Result                `json:"rtmOsCompatibility"`           // Result for consistency check for mapping from OS Manifest to RTM Manifest
+	OsAppsCompatibility ResultMulti           `json:"osAppCompatibility"`           // Result for consistency check for mapping from App Manifests to OS Manifest
+	SignatureCheck      []SignatureResult     `json:"signatureValidation"`          // Results for validation of the Device Description Signature(s) and the used certificates
+	RevocationCheck     *Result               `json:"revocationCheck,omitempty"`    // Result from checking the revocation status of the Device Description (if a revocation list was provided)
+	ConnectionGraph     ConnectionGraphResult `json:"connectionGraph"`              // Result for the validation of the internal connections and external interfaces
+	DeviceBindingCheck  *Result               `json:"deviceBindingCheck,omitempty"` // Result from checking the binding of the Device Description to the report-signing certificate (if specified)
+}
+
+// ConnectionGraphResult represents the results of the validation of the internal
+// connections and external interfaces specified in the Device Description.
+type ConnectionGraphResult struct {
+	Summary          ResultMulti       `json:"resultSummary"`              // Summarizing value illustrating whether any issues were detected during validation of the connection graph
+	InternalCheck    ResultMulti       `json:"internalConnections"`        // Result for checking that internal connections reference existing apps and endpoints
+	ExternalCheck    ResultMulti       `json:"externalInterfaces"`         // Result for checking that external interfaces reference existing endpoints and declared interfaces
+	ExposedEndpoints []ExposedEndpoint `json:"exposedEndpoints,omitempty"` // Successfully validated app endpoints the device may expose
+}
+
+// ExposedEndpoint describes an app endpoint the device may expose on a network interface
+type ExposedEndpoint struct {
+	App         string `json:"app"`         // Name of the App Description
+	AppEndpoint string `json:"appEndpoint"` // Name of the endpoint in the App Manifest
+	Interface   string `json:"interface"`   // Network interface the endpoint is exposed on
+	Port        int    `json:"port"`        // Port the endpoint is exposed on
+}
+
+// ExposesPort returns whether the validated connection graph allows the device
+// to expose the specified port. If iface is not empty, the port must be exposed
+// on the specified network interface
+func (r *ConnectionGraphResult) ExposesPort(iface string, port int) bool {
+	for _, e := range r.ExposedEndpoints {
+		if e.Port == port && (iface == "" || e.Interface == iface) {
+			return true
+		}
+	}
+	return false
 }
 
 // TpmMeasurementResults represents the results of the validation
@@ -91,18 +129,41 @@ type TpmMeasurementResult struct {
 	ReferenceValueCheck ResultMulti     `json:"referenceValueCheck"` // Checks that every TPM Reference Value was part of the measurements
 }
 
-// PcrResult represents the results for the recalculation of a specific PCR.
+// PcrResult represents the results for the recalculation of a specific PCR. If the
+// measurement contains the individual events of the PCR, the events are listed as well
 type PcrResult struct {
-	Pcr        int         `json:"pcr"`        // Number for the PCR which was validated
-	Validation ResultMulti `json:"validation"` // Result for the validation of the respective PCR
+	Pcr           int                 `json:"pcr"`                     // Number for the PCR which was validated
+	Bank          string              `json:"bank,omitempty"`          // PCR bank of the PCR which was validated
+	Validation    ResultMulti         `json:"validation"`              // Result for the validation of the respective PCR
+	MatchedEvents []EventResult       `json:"matchedEvents,omitempty"` // Measured events matching a reference value or carrying a valid signature
+	UnknownEvents []EventResult       `json:"unknownEvents,omitempty"` // Measured events without a matching reference value
+	MissingEvents []EventResult       `json:"missingEvents,omitempty"` // Reference values without a matching measured event
+	Alternatives  []AlternativeResult `json:"alternatives,omitempty"`  // Matched reference values of the alternative groups of the PCR
+}
+
+// AlternativeResult reports which reference value of an alternative group was matched
+type AlternativeResult struct {
+	Group   string `json:"group"`          // Name of the alternative group
+	Name    string `json:"name,omitempty"` // Name of the matched reference value, empty if none matched
+	Success bool   `json:"success"`        // A reference value of the group matched or the group is optional
+}
+
+// EventResult describes a single measured or expected event of a PCR
+type EventResult struct {
+	Digest      HexByte `json:"digest"`                // Digest of the event in the validated PCR bank
+	Name        string  `json:"name,omitempty"`        // Name of the matching reference value
+	EventType   string  `json:"eventType,omitempty"`   // Type of the measured event
+	Description string  `json:"description,omitempty"` // Description of the measured event
+	Signed      bool    `json:"signed,omitempty"`      // Event was accepted based on its signature
 }
 
 // SwMeasurementResult represents the results for the reference values of
 // a software measurement (currently only used for app reference values).
 type SwMeasurementResult struct {
-	MeasName   string `json:"measurementName"`    // Name associated with the measurement used for validation
-	VerName    string `json:"referenceValueName"` // Name of the reference value information used for validation
-	Validation Result `json:"validation"`         //Result of the validation of the software measurement
+	MeasName         string `json:"measurementName"`            // Name associated with the measurement used for validation
+	VerName          string `json:"referenceValueName"`         // Name of the reference value information used for validation
+	AlternativeGroup string `json:"alternativeGroup,omitempty"` // Alternative group of the reference value, if any
+	Validation       Result `json:"validation"`                 //Result of the validation of the software measurement
 }
 
 type VersionCheck struct {
@@ -117,6 +178,12 @@ type BooleanMatch struct {
 	Measured bool `json:"measured"`
 }
 
+type ByteMatch struct {
+	Success  bool    `json:"success"`
+	Claimed  HexByte `json:"claimed"`
+	Measured HexByte `json:"measured"`
+}
+
 type TcbCheck struct {
 	Summary Result       `json:"resultSummary"`
 	Bl      VersionCheck `json:"bl"`
@@ -134,6 +201,12 @@ type PolicyCheck struct {
 	SingleSocket BooleanMatch `json:"singleSocket"`
 }
 
+type PlatformInfoCheck struct {
+	Summary Result       `json:"resultSummary"`
+	Smt     BooleanMatch `json:"smt"`
+	Tsme    BooleanMatch `json:"tsme"`
+}
+
 // SnpMeasurementResult represents the results for the verification
 // of AMD SEV SNP measurements.
 type SnpMeasurementResult struct {
@@ -146,15 +219,40 @@ type SnpMeasurementResult struct {
 	TcbCheck            TcbCheck        `json:"tcbCheck"`
 	PolicyCheck         PolicyCheck     `json:"policyCheck"`
 	ReferenceValueCheck ResultMulti     `json:"referenceValueCheck"` // Checks that every SNP Reference Value was part of the measurements
+
+	// Results for the optional SNP Reference Value details, only present if specified
+	HostDataMatch        *ByteMatch         `json:"hostDataMatch,omitempty"`
+	IdKeyDigestMatch     *ByteMatch         `json:"idKeyDigestMatch,omitempty"`
+	AuthorKeyDigestMatch *ByteMatch         `json:"authorKeyDigestMatch,omitempty"`
+	FamilyIdMatch        *ByteMatch         `json:"familyIdMatch,omitempty"`
+	ImageIdMatch         *ByteMatch         `json:"imageIdMatch,omitempty"`
+	GuestSvnCheck        *VersionCheck      `json:"guestSvnCheck,omitempty"`
+	ReportIdMatch        *ByteMatch         `json:"reportIdMatch,omitempty"`
+	PlatformInfoCheck    *PlatformInfoCheck `json:"platformInfoCheck,omitempty"`
+}
+
+// TdxMeasurementResult represents the results for the verification
+// of Intel TDX measurements.
+type TdxMeasurementResult struct {
+	Summary             Result          `json:"resultSummary"`
+	Freshness           Result          `json:"freshness"`
+	Signature           SignatureResult `json:"signature"`           // Results for validation of the quote signature and the PCK certificate chain
+	QeReportCheck       Result          `json:"qeReportCheck"`       // Result for validation of the QE report signature and the attestation key binding
+	MeasurementMatch    Result          `json:"measurementMatch"`    // Result for comparison of the MRTD
+	RtmrMatch           ResultMulti     `json:"rtmrMatch"`           // Result for comparison of RTMR0-3
+	VersionMatch        Result          `json:"quoteVersionMatch"`   // Result for comparison of the quote version
+	DebugCheck          BooleanMatch    `json:"debugCheck"`          // Result for comparison of the TD debug attribute
+	ReferenceValueCheck ResultMulti     `json:"referenceValueCheck"` // Checks that every TDX Reference Value was part of the measurements
 }
 
 // IasMeasurementResult represents the results for the verification
 // of ARM PSA Initial Attestation Service Token measurements.
 type IasMeasurementResult struct {
-	Summary             Result          `json:"resultSummary"`
-	FreshnessCheck      Result          `json:"quoteFreshness"`
-	ReferenceValueCheck ResultMulti     `json:"referenceValueCheck"`
-	IasSignature        SignatureResult `json:"reportSignatureCheck"`
+	Summary             Result              `json:"resultSummary"`
+	FreshnessCheck      Result              `json:"quoteFreshness"`
+	ReferenceValueCheck ResultMulti         `json:"referenceValueCheck"`
+	IasSignature        SignatureResult     `json:"reportSignatureCheck"`
+	Alternatives        []AlternativeResult `json:"alternatives,omitempty"`
 }
 
 // SignatureResults represents the results for validation of
@@ -16,6 +16,10 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
 	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
 )
 
@@ -31,4 +35,29 @@ type ServerConfig struct {
 	Signer                ar.Signer
 	Serializer            ar.Serializer
 	PolicyEngineSelect    ar.PolicyEngineSelect
+	Crls                  *crlCache
+	RevocationList        *revocationListCache
+	Strict                bool
+}
+
+// marshalVerificationResult serializes the verification result in the requested format.
+// EAT Attestation Results (EAR) are signed with the key of the configured signer
+func marshalVerificationResult(result *ar.VerificationResult, nonce []byte, format ar.ResultFormat,
+	signer ar.Signer,
+) ([]byte, error) {
+	switch format {
+	case ar.ResultFormat_CmcJson:
+		return json.Marshal(result)
+	case ar.ResultFormat_EarJwt, ar.ResultFormat_EarCwt:
+		if signer == nil {
+			return nil, errors.New("no signer configured for signing the EAR")
+		}
+		verifier := ar.EarVerifierId{
+			Developer: "Fraunhofer AISEC",
+			Build:     fmt.Sprintf("cmcd %v", getVersion()),
+		}
+		return ar.SignEar(ar.NewEar(result, nonce, verifier), format, signer)
+	default:
+		return nil, fmt.Errorf("result format %v not implemented", format)
+	}
 }
@@ -24,7 +24,6 @@ import (
 	"fmt"
 
 	"encoding/hex"
-	"encoding/json"
 
 	"github.com/fxamacker/cbor/v2"
 	coap "github.com/plgd-dev/go-coap/v3"
@@ -111,7 +110,8 @@ func Attest(w mux.ResponseWriter, r *mux.Message) {
 
 	log.Debug("Prover: Generating Attestation Report with nonce: ", hex.EncodeToString(req.Nonce))
 
-	report, err := ar.Generate(req.Nonce, serverConfig.Metadata, serverConfig.MeasurementInterfaces, serverConfig.Serializer)
+	report, err := ar.Generate(req.Nonce, serverConfig.Metadata, serverConfig.MeasurementInterfaces,
+		serverConfig.Strict, serverConfig.Serializer)
 	if err != nil {
 		msg := fmt.Sprintf("failed to generate attestation report: %v", err)
 		SendCoapError(w, r, codes.InternalServerError, msg)
@@ -168,10 +168,12 @@ func Verify(w mux.ResponseWriter, r *mux.Message) {
 
 	log.Debug("Verifier: Verifying Attestation Report")
 	result := ar.Verify(string(req.AttestationReport), req.Nonce, req.Ca, req.Policies,
-		serverConfig.PolicyEngineSelect, serverConfig.Serializer)
+		serverConfig.PolicyEngineSelect, serverConfig.Crls.get(),
+		serverConfig.RevocationList.get(), serverConfig.Strict, serverConfig.Serializer)
 
 	log.Debug("Verifier: Marshaling Attestation Result")
-	data, err := json.Marshal(result)
+	data, err := marshalVerificationResult(&result, req.Nonce, req.ResultFormat,
+		serverConfig.Signer)
 	if err != nil {
 		msg := fmt.Sprintf("Verifier: failed to marshal Attestation Result: %v", err)
 		log.Warn(msg)
@@ -220,7 +222,9 @@ func TlsSign(w mux.ResponseWriter, r *mux.Message) {
 		return
 	}
 
-	// Get key handle from (hardware) interface
+	// Get key handle from (hardware) interface, which must only be used while holding the lock
+	serverConfig.Signer.Lock()
+	defer serverConfig.Signer.Unlock()
 	tlsKeyPriv, _, err := serverConfig.Signer.GetSigningKeys()
 	if err != nil {
 		msg := fmt.Sprintf("failed to get IK: %v", err)
@@ -274,7 +278,9 @@ func TlsCert(w mux.ResponseWriter, r *mux.Message) {
 	log.Tracef("Received COAP TLS cert request with ID %v", req.Id)
 
 	// Retrieve certificates
+	serverConfig.Signer.Lock()
 	certChain := serverConfig.Signer.GetCertChain()
+	serverConfig.Signer.Unlock()
 
 	// Create response
 	resp := &api.TLSCertResponse{
@@ -40,14 +40,27 @@ type config struct {
 	MetadataAddr          string   `json:"metadataAddr"`
 	LocalPath             string   `json:"localPath"`
 	FetchMetadata         bool     `json:"fetchMetadata"`
-	MeasurementInterfaces []string `json:"measurementInterfaces"`  // TPM, SNP
-	SigningInterface      string   `json:"signingInterface"`       // TPM, SW
-	UseIma                bool     `json:"useIma"`                 // TRUE, FALSE
-	ImaPcr                int32    `json:"imaPcr"`                 // 10-15
-	KeyConfig             string   `json:"keyConfig,omitempty"`    // RSA2048 RSA4096 EC256 EC384 EC521
-	Serialization         string   `json:"serialization"`          // JSON, CBOR
-	Api                   string   `json:"api"`                    // gRPC, CoAP
-	PolicyEngine          string   `json:"policyEngine,omitempty"` // JS, DUKTAPE
+	MeasurementInterfaces []string `json:"measurementInterfaces"`    // TPM, SNP, TDX
+	SigningInterface      string   `json:"signingInterface"`         // TPM, SW
+	SnpInterface          string   `json:"snpInterface,omitempty"`   // IOCTL, CONFIGFS
+	SnpCrls               []string `json:"snpCrls,omitempty"`        // AMD KDS CRL files
+	RevocationList        string   `json:"revocationList,omitempty"` // signed manifest revocation list file
+	Strict                bool     `json:"strict,omitempty"`         // strict generation and verification of attestation reports
+	UseIma                bool     `json:"useIma"`                   // TRUE, FALSE
+	ImaPcr                int32    `json:"imaPcr"`                   // 10-15
+	UseEventLog           bool     `json:"useEventLog"`              // TRUE, FALSE
+	EventLogPath          string   `json:"eventLogPath,omitempty"`   // binary_bios_measurements
+	PcrBanks              []string `json:"pcrBanks,omitempty"`       // SHA1, SHA256, SHA384, SM3_256
+	TpmTransport          string   `json:"tpmTransport,omitempty"`   // DEVICE, SIMULATOR
+	TpmAddress            string   `json:"tpmAddress,omitempty"`     // device path or simulator host:port
+	KeyConfig             string   `json:"keyConfig,omitempty"`      // RSA2048 RSA4096 EC256 EC384 EC521
+	CertRenewalDays       int      `json:"certRenewalDays,omitempty"`
+	SwKeySealing          string   `json:"swKeySealing,omitempty"`        // NONE, PASSPHRASE, TPM
+	SwKeyPassphraseFile   string   `json:"swKeyPassphraseFile,omitempty"` // file containing passphrase
+	SwKeyPcrs             []int    `json:"swKeyPcrs,omitempty"`           // PCRs the key is sealed to
+	Serialization         string   `json:"serialization"`                 // JSON, CBOR
+	Api                   string   `json:"api"`                           // gRPC, CoAP
+	PolicyEngine          string   `json:"policyEngine,omitempty"`        // JS, DUKTAPE
 	LogLevel              string   `json:"logLevel"`
 
 	serializer         ar.Serializer
@@ -88,9 +101,20 @@ const (
 	fetchMetadataFlag = "fetch"
 	measurementsFlag  = "measurements"
 	signerFlag        = "signer"
+	snpInterfaceFlag  = "snpinterface"
+	snpCrlsFlag       = "snpcrls"
+	revocationFlag    = "revocationlist"
+	strictFlag        = "strict"
 	imaFlag           = "ima"
 	imaPcrFlag        = "pcr"
+	eventLogFlag      = "eventlog"
+	eventLogPathFlag  = "eventlogpath"
+	pcrBanksFlag      = "pcrbanks"
+	tpmTransportFlag  = "tpmtransport"
+	tpmAddressFlag    = "tpmaddr"
 	keyConfigFlag     = "algo"
+	renewalFlag       = "renewaldays"
+	swSealingFlag     = "swsealing"
 	serializationFlag = "serializer"
 	apiFlag           = "api"
 	policyEngineFlag  = "policies"
@@ -117,10 +141,28 @@ func getConfig() (*config, error) {
 	measurements := flag.String(measurementsFlag, "",
 		"Measurement Interfaces (comma separated list)")
 	signer := flag.String(signerFlag, "", "Signing Interface")
+	snpInterface := flag.String(snpInterfaceFlag, "",
+		"Interface for retrieving SNP reports (ioctl or configfs)")
+	snpCrls := flag.String(snpCrlsFlag, "", "AMD SEV-SNP CRL files (comma separated list)")
+	revocationList := flag.String(revocationFlag, "", "Signed manifest revocation list file")
+	strict := flag.Bool(strictFlag, false,
+		"Reject unknown, duplicate or misplaced metadata during generation and verification")
 	ima := flag.Bool(imaFlag, false,
 		"Indicates whether to use Integrity Measurement Architecture (IMA)")
 	pcr := flag.Int(imaPcrFlag, 0, "IMA PCR")
+	eventLog := flag.Bool(eventLogFlag, false,
+		"Indicates whether to include the TPM event log into the measurements")
+	eventLogPath := flag.String(eventLogPathFlag, "", "Path of the binary TPM event log")
+	pcrBanks := flag.String(pcrBanksFlag, "",
+		"TPM PCR banks to be quoted (comma separated list of SHA1, SHA256, SHA384, SM3_256)")
+	tpmTransport := flag.String(tpmTransportFlag, "", "TPM transport (device or simulator)")
+	tpmAddress := flag.String(tpmAddressFlag, "",
+		"TPM device path or command address of the TPM simulator")
 	keyConfig := flag.String(keyConfigFlag, "", "Key configuration")
+	renewal := flag.Int(renewalFlag, 0,
+		"Days before expiry the TPM certificates are renewed (0 disables renewal)")
+	swSealing := flag.String(swSealingFlag, "",
+		"Protection of the persisted SW signing key (none, passphrase or tpm)")
 	serialization := flag.String(serializationFlag, "",
 		fmt.Sprintf("Possible serializers: %v", maps.Keys(serializers)))
 	api := flag.String(apiFlag, "", "API")
@@ -132,10 +174,12 @@ func getConfig() (*config, error) {
 
 	// Create default configuration
 	c := &config{
-		KeyConfig:     "EC256",
-		Serialization: "json",
-		Api:           "grpc",
-		LogLevel:      "trace",
+		KeyConfig:       "EC256",
+		CertRenewalDays: 30,
+		SwKeyPcrs:       []int{7},
+		Serialization:   "json",
+		Api:             "grpc",
+		LogLevel:        "trace",
 	}
 
 	// Obtain custom configuration from file if specified
@@ -174,15 +218,48 @@ func getConfig() (*config, error) {
 	if internal.FlagPassed(signerFlag) {
 		c.SigningInterface = *signer
 	}
+	if internal.FlagPassed(snpInterfaceFlag) {
+		c.SnpInterface = *snpInterface
+	}
+	if internal.FlagPassed(snpCrlsFlag) {
+		c.SnpCrls = strings.Split(*snpCrls, ",")
+	}
+	if internal.FlagPassed(revocationFlag) {
+		c.RevocationList = *revocationList
+	}
+	if internal.FlagPassed(strictFlag) {
+		c.Strict = *strict
+	}
 	if internal.FlagPassed(imaFlag) {
 		c.UseIma = *ima
 	}
 	if internal.FlagPassed(imaPcrFlag) {
 		c.ImaPcr = int32(*pcr)
 	}
+	if internal.FlagPassed(eventLogFlag) {
+		c.UseEventLog = *eventLog
+	}
+	if internal.FlagPassed(eventLogPathFlag) {
+		c.EventLogPath = *eventLogPath
+	}
+	if internal.FlagPassed(pcrBanksFlag) {
+		c.PcrBanks = strings.Split(*pcrBanks, ",")
+	}
+	if internal.FlagPassed(tpmTransportFlag) {
+		c.TpmTransport = *tpmTransport
+	}
+	if internal.FlagPassed(tpmAddressFlag) {
+		c.TpmAddress = *tpmAddress
+	}
 	if internal.FlagPassed(keyConfigFlag) {
 		c.KeyConfig = *keyConfig
 	}
+	if internal.FlagPassed(renewalFlag) {
+		c.CertRenewalDays = *renewal
+	}
+	if internal.FlagPassed(swSealingFlag) {
+		c.SwKeySealing = *swSealing
+	}
 	if internal.FlagPassed(serializationFlag) {
 		c.Serialization = *serialization
 	}
@@ -217,6 +294,30 @@ func getConfig() (*config, error) {
 		return nil, fmt.Errorf("failed to get local storage path: %w", err)
 	}
 
+	// Transform CRL file paths
+	for i := range c.SnpCrls {
+		c.SnpCrls[i], err = internal.GetFilePath(c.SnpCrls[i], &c.configDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get CRL path: %w", err)
+		}
+	}
+
+	// Transform revocation list file path
+	if c.RevocationList != "" {
+		c.RevocationList, err = internal.GetFilePath(c.RevocationList, &c.configDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get revocation list path: %w", err)
+		}
+	}
+
+	// Transform passphrase file path
+	if c.SwKeyPassphraseFile != "" {
+		c.SwKeyPassphraseFile, err = internal.GetFilePath(c.SwKeyPassphraseFile, &c.configDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get passphrase file path: %w", err)
+		}
+	}
+
 	// Get serializer
 	c.serializer, ok = serializers[strings.ToLower(c.Serialization)]
 	if !ok {
@@ -241,16 +342,29 @@ func printConfig(c *config) {
 	log.Debugf("\tFetch Metadata           : %v", c.FetchMetadata)
 	log.Debugf("\tUse IMA                  : %v", c.UseIma)
 	log.Debugf("\tIMA PCR                  : %v", c.ImaPcr)
+	log.Debugf("\tUse Event Log            : %v", c.UseEventLog)
+	log.Debugf("\tEvent Log Path           : %v", c.EventLogPath)
+	log.Debugf("\tPCR Banks                : %v", c.PcrBanks)
+	log.Debugf("\tTPM Transport            : %v", c.TpmTransport)
+	log.Debugf("\tTPM Address              : %v", c.TpmAddress)
 	log.Debugf("\tSerialization            : %v", c.Serialization)
 	log.Debugf("\tAPI                      : %v", c.Api)
 	log.Debugf("\tPolicy Engine            : %v", c.PolicyEngine)
 	log.Debugf("\tKey Config               : %v", c.KeyConfig)
+	log.Debugf("\tCert Renewal (days)      : %v", c.CertRenewalDays)
+	log.Debugf("\tSW Key Sealing           : %v", c.SwKeySealing)
+	log.Debugf("\tSW Key Passphrase File   : %v", c.SwKeyPassphraseFile)
+	log.Debugf("\tSW Key PCRs              : %v", c.SwKeyPcrs)
 	log.Debugf("\tLogging Level            : %v", c.LogLevel)
 	log.Debug("\tMeasurement Interfaces   : ")
 	for i, m := range c.MeasurementInterfaces {
 		log.Debugf("\t\t%v: %v", i, m)
 	}
 	log.Debugf("\tSigning Interface        : %v", c.SigningInterface)
+	log.Debugf("\tSNP Interface            : %v", c.SnpInterface)
+	log.Debugf("\tSNP CRLs                 : %v", c.SnpCrls)
+	log.Debugf("\tRevocation List          : %v", c.RevocationList)
+	log.Debugf("\tStrict                   : %v", c.Strict)
 }
 
 func getVersion() string {
@@ -28,7 +28,6 @@ import (
 	"net"
 
 	"encoding/hex"
-	"encoding/json"
 
 	"google.golang.org/grpc"
 
@@ -81,7 +80,8 @@ func (s *GrpcServer) Attest(ctx context.Context, in *api.AttestationRequest) (*a
 
 	log.Info("Prover: Generating Attestation Report with nonce: ", hex.EncodeToString(in.Nonce))
 
-	report, err := ar.Generate(in.Nonce, s.config.Metadata, s.config.MeasurementInterfaces, s.config.Serializer)
+	report, err := ar.Generate(in.Nonce, s.config.Metadata, s.config.MeasurementInterfaces,
+		s.config.Strict, s.config.Serializer)
 	if err != nil {
 		log.Errorf("Failed to generate attestation report: %v", err)
 		return &api.AttestationResponse{
@@ -124,10 +124,15 @@ func (s *GrpcServer) Verify(ctx context.Context, in *api.VerificationRequest) (*
 
 	log.Info("Verifier: Verifying Attestation Report")
 	result := ar.Verify(string(in.AttestationReport), in.Nonce, in.Ca, in.Policies,
-		s.config.PolicyEngineSelect, s.config.Serializer)
+		s.config.PolicyEngineSelect, s.config.Crls.get(),
+		s.config.RevocationList.get(), s.config.Strict, s.config.Serializer)
 
 	log.Info("Verifier: Marshaling Attestation Result")
-	data, err := json.Marshal(result)
+	var data []byte
+	format, err := convertResultFormat(in.ResultFormat)
+	if err == nil {
+		data, err = marshalVerificationResult(&result, in.Nonce, format, s.config.Signer)
+	}
 	if err != nil {
 		log.Errorf("Verifier: failed to marshal Attestation Result: %v", err)
 		status = api.Status_FAIL
@@ -158,7 +163,9 @@ func (s *GrpcServer) TLSSign(ctx context.Context, in *api.TLSSignRequest) (*api.
 		log.Errorf("Failed to choose requested hash function: %v", err)
 		return &api.TLSSignResponse{Status: api.Status_FAIL}, errors.New("prover: failed to find appropriate hash function")
 	}
-	// get key
+	// get key, which must only be used while holding the lock
+	s.config.Signer.Lock()
+	defer s.config.Signer.Unlock()
 	tlsKeyPriv, _, err = s.config.Signer.GetSigningKeys()
 	if err != nil {
 		log.Errorf("Failed to get IK: %v", err)
@@ -187,13 +194,29 @@ func (s *GrpcServer) TLSCert(ctx context.Context, in *api.TLSCertRequest) (*api.
 	var resp *api.TLSCertResponse = &api.TLSCertResponse{}
 
 	// provide TLS certificate chain
+	s.config.Signer.Lock()
 	certChain := s.config.Signer.GetCertChain()
+	s.config.Signer.Unlock()
 	resp.Certificate = internal.WriteCertsPem(certChain)
 	resp.Status = api.Status_OK
 	log.Info("Prover: Obtained TLS Cert.")
 	return resp, nil
 }
 
+// Converts Protobuf result format to the attestationreport result format
+func convertResultFormat(format api.ResultFormat) (ar.ResultFormat, error) {
+	switch format {
+	case api.ResultFormat_CMC_JSON:
+		return ar.ResultFormat_CmcJson, nil
+	case api.ResultFormat_EAR_JWT:
+		return ar.ResultFormat_EarJwt, nil
+	case api.ResultFormat_EAR_CWT:
+		return ar.ResultFormat_EarCwt, nil
+	default:
+		return 0, fmt.Errorf("result format not implemented: %v", format)
+	}
+}
+
 // Converts Protobuf hashtype to crypto.SignerOpts
 func convertHash(hashtype api.HashFunction, pssOpts *api.PSSOptions) (crypto.SignerOpts, error) {
 	var hash crypto.Hash
@@ -17,8 +17,14 @@ package main
 
 // Install github packages with "go get [url]"
 import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"os"
 	"path"
 	"strings"
+	"sync"
+	"time"
 
 	// local modules
 
@@ -27,9 +33,26 @@ import (
 	"github.com/Fraunhofer-AISEC/cmc/internal"
 	"github.com/Fraunhofer-AISEC/cmc/snpdriver"
 	"github.com/Fraunhofer-AISEC/cmc/swdriver"
+	"github.com/Fraunhofer-AISEC/cmc/tdxdriver"
 	"github.com/Fraunhofer-AISEC/cmc/tpmdriver"
 )
 
+// Interval in which the expiry of the TPM certificates is checked
+const renewalCheckInterval = time.Hour
+
+// Minimum interval between two attempts to reload expired CRLs
+const crlReloadInterval = time.Hour
+
+// Interval in which the signed revocation list is reloaded and minimum interval between
+// two attempts to reload an expired revocation list
+const (
+	revocationListReloadInterval = time.Hour
+	revocationListRetryInterval  = time.Minute
+)
+
+// Time format of the validity of manifests and descriptions
+const timeLayout = "20060102150405"
+
 func main() {
 
 	log.Infof("Starting cmcd %v", getVersion())
@@ -52,8 +75,21 @@ func main() {
 		return
 	}
 
+	crls, err := newCrlCache(c)
+	if err != nil {
+		log.Errorf("Failed to load CRLs: %v", err)
+		return
+	}
+
+	revocationList, err := newRevocationListCache(c)
+	if err != nil {
+		log.Errorf("Failed to load revocation list: %v", err)
+		return
+	}
+
 	var tpm *tpmdriver.Tpm
 	var snp *snpdriver.Snp
+	var tdx *tdxdriver.Tdx
 	var sw *swdriver.Sw
 
 	measurements := make([]ar.Measurement, 0)
@@ -67,7 +103,33 @@ func main() {
 			Metadata:    metadata,
 			Serializer:  c.serializer,
 		}
+		var sealer *tpmdriver.Sealer
+		switch strings.ToLower(c.SwKeySealing) {
+		case "tpm":
+			sealer, err = tpmdriver.NewSealer(c.TpmTransport, c.TpmAddress, c.SwKeyPcrs)
+			if err != nil {
+				log.Errorf("failed to create TPM sealer: %v", err)
+				return
+			}
+			swConfig.Sealer = sealer
+		case "passphrase":
+			swConfig.Passphrase, err = os.ReadFile(c.SwKeyPassphraseFile)
+			if err != nil {
+				log.Errorf("failed to read SW key passphrase: %v", err)
+				return
+			}
+			swConfig.Passphrase = bytes.TrimSpace(swConfig.Passphrase)
+		case "", "none":
+		default:
+			log.Errorf("SW key sealing %v not implemented", c.SwKeySealing)
+			return
+		}
 		sw, err = swdriver.NewSwDriver(swConfig)
+		// The sealer is only required during startup and must be closed before the
+		// TPM driver opens the TPM
+		if sealer != nil {
+			sealer.Close()
+		}
 		if err != nil {
 			log.Errorf("failed to create new SW driver: %v", err)
 			return
@@ -78,13 +140,18 @@ func main() {
 
 	if strings.EqualFold(c.SigningInterface, "TPM") || internal.Contains("TPM", c.MeasurementInterfaces) {
 		tpmConfig := &tpmdriver.Config{
-			StoragePath: path.Join(c.LocalPath, "internal"),
-			ServerAddr:  c.ProvServerAddr,
-			KeyConfig:   c.KeyConfig,
-			Metadata:    metadata,
-			UseIma:      c.UseIma,
-			ImaPcr:      c.ImaPcr,
-			Serializer:  c.serializer,
+			StoragePath:  path.Join(c.LocalPath, "internal"),
+			ServerAddr:   c.ProvServerAddr,
+			KeyConfig:    c.KeyConfig,
+			Metadata:     metadata,
+			UseIma:       c.UseIma,
+			ImaPcr:       c.ImaPcr,
+			UseEventLog:  c.UseEventLog,
+			EventLogPath: c.EventLogPath,
+			Serializer:   c.serializer,
+			PcrBanks:     c.PcrBanks,
+			Transport:    c.TpmTransport,
+			Address:      c.TpmAddress,
 		}
 
 		tpm, err = tpmdriver.NewTpm(tpmConfig)
@@ -92,7 +159,11 @@ func main() {
 			log.Errorf("Failed to create new TPM driver: %v", err)
 			return
 		}
-		defer tpmdriver.CloseTpm()
+		defer tpm.Close()
+
+		if c.CertRenewalDays > 0 {
+			go monitorTpmCerts(tpm, time.Duration(c.CertRenewalDays)*24*time.Hour)
+		}
 	}
 
 	if internal.Contains("TPM", c.MeasurementInterfaces) {
@@ -108,7 +179,8 @@ func main() {
 	if internal.Contains("SNP", c.MeasurementInterfaces) {
 		log.Info("Using SNP as Measurement Interface")
 		snpConfig := snpdriver.Config{
-			Url: c.ProvServerAddr,
+			Url:       c.ProvServerAddr,
+			Interface: c.SnpInterface,
 		}
 		snp, err = snpdriver.NewSnpDriver(snpConfig)
 		if err != nil {
@@ -119,12 +191,27 @@ func main() {
 		measurements = append(measurements, snp)
 	}
 
+	if internal.Contains("TDX", c.MeasurementInterfaces) {
+		log.Info("Using TDX as Measurement Interface")
+		tdxConfig := tdxdriver.Config{}
+		tdx, err = tdxdriver.NewTdxDriver(tdxConfig)
+		if err != nil {
+			log.Errorf("failed to create new TDX driver: %v", err)
+			return
+		}
+
+		measurements = append(measurements, tdx)
+	}
+
 	serverConfig := &ServerConfig{
 		Metadata:              metadata,
 		MeasurementInterfaces: measurements,
 		Signer:                signer,
 		Serializer:            c.serializer,
 		PolicyEngineSelect:    c.policyEngineSelect,
+		Crls:                  crls,
+		RevocationList:        revocationList,
+		Strict:                c.Strict,
 	}
 
 	server, ok := servers[strings.ToLower(c.Api)]
@@ -135,3 +222,331 @@ func main() {
 
 	server.Serve(c.Addr, serverConfig)
 }
+
+// monitorTpmCerts periodically checks the expiry of the TPM AK and IK certificates and
+// renews the certificates if they expire within the specified threshold
+func monitorTpmCerts(tpm *tpmdriver.Tpm, threshold time.Duration) {
+	for {
+		if tpm.RenewalRequired(threshold) {
+			err := tpm.RenewCerts()
+			if err != nil {
+				log.Warnf("Failed to renew TPM certificates: %v", err)
+			}
+		}
+		time.Sleep(renewalCheckInterval)
+	}
+}
+
+// crlCache holds the AMD SEV-SNP CRLs for the verification of SNP certificate chains.
+// As cmcd is a long-running service, the CRLs are reloaded as soon as one of them passed
+// its next update time, so that newly revoked certificates are taken into account
+type crlCache struct {
+	mu         sync.Mutex
+	config     *config
+	crls       []*x509.RevocationList
+	lastReload time.Time
+}
+
+func newCrlCache(c *config) (*crlCache, error) {
+	crls, err := loadCrls(c)
+	if err != nil {
+		return nil, err
+	}
+	return &crlCache{
+		config:     c,
+		crls:       crls,
+		lastReload: time.Now(),
+	}, nil
+}
+
+// get returns the current CRLs. If a CRL expired, the CRLs are reloaded. If the reload
+// fails, the previous CRLs are returned
+func (cc *crlCache) get() []*x509.RevocationList {
+	if cc == nil {
+		return nil
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if !cc.expired() || time.Since(cc.lastReload) < crlReloadInterval {
+		return cc.crls
+	}
+
+	log.Debug("CRL expired, reloading CRLs")
+	cc.lastReload = time.Now()
+	crls, err := loadCrls(cc.config)
+	if err != nil {
+		log.Warnf("Failed to reload CRLs: %v", err)
+		return cc.crls
+	}
+	cc.crls = crls
+
+	return cc.crls
+}
+
+func (cc *crlCache) expired() bool {
+	for _, crl := range cc.crls {
+		if time.Now().After(crl.NextUpdate) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCrls loads the AMD SEV-SNP CRLs for the verification of SNP certificate chains. If
+// the metadata is fetched from the provisioning server, the CRLs cached by the provisioning
+// server are fetched as well and stored in the local storage, so that the verification
+// does not require network access. Furthermore, the CRLs configured via snpCrls are loaded.
+// Files that cannot be read or parsed are skipped
+func loadCrls(c *config) ([]*x509.RevocationList, error) {
+
+	crlPath := path.Join(c.LocalPath, "crls")
+
+	if c.FetchMetadata {
+		if err := os.MkdirAll(crlPath, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create CRL directory: %w", err)
+		}
+		// TODO mandate server authentication in the future
+		estclient := client.NewClient(nil)
+		for _, product := range []string{internal.SnpProductMilan, internal.SnpProductGenoa} {
+			for _, vlek := range []bool{false, true} {
+				crl, err := estclient.GetSnpCrl(c.ProvServerAddr, product, vlek)
+				if err != nil {
+					log.Warnf("Failed to fetch SNP CRL (%v, VLEK: %v): %v", product, vlek, err)
+					continue
+				}
+				file := path.Join(crlPath, fmt.Sprintf("snp_%v_vlek_%v.crl", strings.ToLower(product), vlek))
+				if err := os.WriteFile(file, crl, 0644); err != nil {
+					return nil, fmt.Errorf("failed to store CRL: %w", err)
+				}
+			}
+		}
+	}
+
+	files := make([]string, 0, len(c.SnpCrls))
+	if entries, err := os.ReadDir(crlPath); err == nil {
+		for _, e := range entries {
+			if !e.IsDir() {
+				files = append(files, path.Join(crlPath, e.Name()))
+			}
+		}
+	}
+	files = append(files, c.SnpCrls...)
+
+	crls := make([]*x509.RevocationList, 0, len(files))
+	for _, f := range files {
+		log.Tracef("Loading CRL %v", f)
+		d, err := os.ReadFile(f)
+		if err != nil {
+			log.Warnf("Failed to read CRL %v: %v", f, err)
+			continue
+		}
+		crl, err := internal.ParseCrl(d)
+		if err != nil {
+			log.Warnf("Skipping %v: %v", f, err)
+			continue
+		}
+		crls = append(crls, crl)
+	}
+	log.Debugf("Loaded %v CRLs", len(crls))
+
+	return crls, nil
+}
+
+// revocationListCache holds the signed revocation list for manifests and descriptions. As
+// cmcd is a long-running service, the revocation list is reloaded periodically and as soon
+// as it expired, so that new revocations are taken into account. Revocation lists issued
+// before the cached revocation list are rejected to prevent rollbacks
+type revocationListCache struct {
+	mu         sync.Mutex
+	config     *config
+	raw        []byte
+	rl         *ar.RevocationList
+	lastReload time.Time
+}
+
+func newRevocationListCache(c *config) (*revocationListCache, error) {
+	rc := &revocationListCache{
+		config: c,
+	}
+
+	// The revocation list cached during previous runs serves as baseline, so that an
+	// older revocation list published by the provisioning server is rejected
+	cached := revocationListCachePath(c)
+	if c.FetchMetadata && internal.FileExists(cached) {
+		raw, err := os.ReadFile(cached)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read revocation list %v: %w", cached, err)
+		}
+		rc.raw, rc.rl, err = parseRevocationList(raw, c.serializer)
+		if err != nil {
+			log.Warnf("Ignoring cached revocation list: %v", err)
+			rc.raw, rc.rl = nil, nil
+		}
+	}
+
+	if err := rc.reload(); err != nil {
+		if rc.raw == nil {
+			return nil, err
+		}
+		log.Warnf("Failed to load revocation list, using cached revocation list: %v", err)
+	}
+
+	return rc, nil
+}
+
+// get returns the current signed revocation list. The revocation list is reloaded if the
+// reload interval elapsed or if it expired. If the reload fails, the previous revocation
+// list is returned
+func (rc *revocationListCache) get() []byte {
+	if rc == nil {
+		return nil
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	elapsed := time.Since(rc.lastReload)
+	if elapsed < revocationListReloadInterval &&
+		(!rc.expired() || elapsed < revocationListRetryInterval) {
+		return rc.raw
+	}
+
+	log.Debug("Reloading revocation list")
+	if err := rc.reload(); err != nil {
+		log.Warnf("Failed to reload revocation list: %v", err)
+	}
+
+	return rc.raw
+}
+
+// reload loads the revocation list and replaces the cached revocation list, unless it was
+// issued before the cached revocation list
+func (rc *revocationListCache) reload() error {
+	rc.lastReload = time.Now()
+
+	raw, err := loadRevocationList(rc.config)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		if rc.raw != nil {
+			log.Warn("Revocation list not present anymore, keeping cached revocation list")
+		}
+		return nil
+	}
+	if bytes.Equal(raw, rc.raw) {
+		return nil
+	}
+
+	_, rl, err := parseRevocationList(raw, rc.config.serializer)
+	if err != nil {
+		return err
+	}
+	if rc.rl != nil && isOlderRevocationList(rl, rc.rl) {
+		return fmt.Errorf("revocation list %v (version %v, issued %v) is older than cached revocation list (version %v, issued %v)",
+			rl.Name, rl.Version, rl.Validity.NotBefore, rc.rl.Version, rc.rl.Validity.NotBefore)
+	}
+
+	if rc.config.FetchMetadata {
+		cached := revocationListCachePath(rc.config)
+		if err := os.MkdirAll(path.Dir(cached), 0755); err != nil {
+			return fmt.Errorf("failed to create revocation list directory: %w", err)
+		}
+		if err := os.WriteFile(cached, raw, 0644); err != nil {
+			return fmt.Errorf("failed to store revocation list: %w", err)
+		}
+	}
+
+	log.Debugf("Loaded revocation list %v (version %v)", rl.Name, rl.Version)
+	rc.raw = raw
+	rc.rl = rl
+
+	return nil
+}
+
+func (rc *revocationListCache) expired() bool {
+	if rc.rl == nil {
+		return false
+	}
+	notAfter, err := time.Parse(timeLayout, rc.rl.Validity.NotAfter)
+	return err != nil || time.Now().After(notAfter)
+}
+
+// isOlderRevocationList returns true if the revocation list was issued before the reference
+// revocation list, i.e., it is valid from an earlier point in time or has a lower version
+// if both are valid from the same point in time. Versions are expected to be timestamps
+// like the versions of the manifests
+func isOlderRevocationList(rl, ref *ar.RevocationList) bool {
+	issued, err := time.Parse(timeLayout, rl.Validity.NotBefore)
+	if err != nil {
+		return true
+	}
+	refIssued, err := time.Parse(timeLayout, ref.Validity.NotBefore)
+	if err != nil {
+		return false
+	}
+	if !issued.Equal(refIssued) {
+		return issued.Before(refIssued)
+	}
+	return rl.Version < ref.Version
+}
+
+// parseRevocationList extracts the revocation list from the signed token. The signature is
+// verified by the verifier together with the attestation report
+func parseRevocationList(raw []byte, s ar.Serializer) ([]byte, *ar.RevocationList, error) {
+	payload, err := s.GetPayload(raw)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get revocation list payload: %w", err)
+	}
+	rl := &ar.RevocationList{}
+	if err := s.Unmarshal(payload, rl); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal revocation list: %w", err)
+	}
+	if rl.Type != "Revocation List" {
+		return nil, nil, fmt.Errorf("revocation list has invalid type %v", rl.Type)
+	}
+	return raw, rl, nil
+}
+
+func revocationListCachePath(c *config) string {
+	return path.Join(c.LocalPath, "revocation", "revocation.list")
+}
+
+// loadRevocationList loads the signed revocation list for the manifests and descriptions.
+// If the metadata is fetched from the provisioning server, the revocation list published
+// by the provisioning server is fetched. Otherwise, or if fetching fails, the cached or the
+// revocation list configured via revocationList is loaded
+func loadRevocationList(c *config) ([]byte, error) {
+
+	cached := revocationListCachePath(c)
+
+	if c.FetchMetadata {
+		// TODO mandate server authentication in the future
+		estclient := client.NewClient(nil)
+		rl, err := estclient.GetRevocationList(c.ProvServerAddr)
+		if err != nil {
+			log.Warnf("Failed to fetch revocation list: %v", err)
+		} else {
+			return rl, nil
+		}
+	}
+
+	file := c.RevocationList
+	if file == "" {
+		if !internal.FileExists(cached) {
+			log.Debug("No revocation list present")
+			return nil, nil
+		}
+		file = cached
+	}
+
+	log.Tracef("Loading revocation list %v", file)
+	rl, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read revocation list %v: %w", file, err)
+	}
+
+	return rl, nil
+}
@@ -20,6 +20,8 @@ import (
 	"crypto/rsa"
 	"errors"
 	"fmt"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
 )
 
 type AttestationRequest struct {
@@ -36,6 +38,7 @@ type VerificationRequest struct {
 	AttestationReport []byte
 	Ca                []byte
 	Policies          []byte
+	ResultFormat      ar.ResultFormat
 }
 
 type VerificationResponse struct {
@@ -17,11 +17,13 @@ package client
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"go.mozilla.org/pkcs7"
@@ -88,6 +90,34 @@ func (c *Client) SetCAs(roots []*x509.Certificate) error {
 	return nil
 }
 
+// SetClientCert configures the certificate chain and private key the client authenticates
+// with, as required for renewing certificates via simplereenroll. The private key must
+// implement crypto.Signer
+func (c *Client) SetClientCert(chain []*x509.Certificate, key crypto.PrivateKey) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("no client certificate provided")
+	}
+	if _, ok := key.(crypto.Signer); !ok {
+		return fmt.Errorf("client private key does not implement crypto.Signer")
+	}
+
+	tp, ok := c.client.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("internal error: failed to get transport")
+	}
+
+	cert := tls.Certificate{
+		PrivateKey: key,
+		Leaf:       chain[0],
+	}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	tp.TLSClientConfig.Certificates = []tls.Certificate{cert}
+
+	return nil
+}
+
 func (c *Client) GetInsecureSkipVerify() bool {
 	tp, ok := c.client.Transport.(*http.Transport)
 	if !ok {
@@ -173,6 +203,106 @@ func (c *Client) SimpleEnroll(addr string, csr *x509.CertificateRequest,
 	return certs[0], nil
 }
 
+// SimpleReenroll renews the client certificate. The client must be configured with
+// its current certificate via SetClientCert. The CSR must be created with the key of
+// the current certificate
+func (c *Client) SimpleReenroll(addr string, csr *x509.CertificateRequest,
+) (*x509.Certificate, error) {
+
+	if c.GetInsecureSkipVerify() {
+		return nil, fmt.Errorf("simple reenroll requires server CAs to be configured")
+	}
+
+	// PKCS#10 Request
+	csrbase64 := est.EncodeBase64(csr.Raw)
+
+	body := io.NopCloser(bytes.NewBuffer(csrbase64))
+
+	method := http.MethodPost
+	endpoint := strings.TrimSuffix(addr, "/") + est.EndpointPrefix + est.ReenrollEndpoint
+	accepts := est.MimeTypePKCS7
+	contentType := est.MimeTypePKCS10
+	transferEncoding := est.EncodingTypeBase64
+
+	resp, err := request(c.client, method, endpoint, accepts, contentType, transferEncoding, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+
+	certs, err := parseSimplePkiResponse(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse simple PKI response: %w", err)
+	}
+
+	return certs[0], nil
+}
+
+// TpmReenroll renews the AK and IK certificates. The client must be configured with
+// its current IK certificate via SetClientCert. The AK CSR must be created with the
+// key of the current AK certificate, whereas the IK can be a new key certified by the AK
+func (c *Client) TpmReenroll(
+	addr string,
+	akCsr, ikCsr *x509.CertificateRequest,
+	akCert *x509.Certificate,
+	akPublic []byte,
+	ikPublic, ikCreateData, ikCreateAttestation, ikCreateSignature []byte,
+) (*x509.Certificate, *x509.Certificate, error) {
+
+	if c.GetInsecureSkipVerify() {
+		return nil, nil, fmt.Errorf("reenroll requires server CAs to be configured")
+	}
+
+	buf, contentType, err := est.EncodeMultiPart(
+		[]est.MimeMultipart{
+			{ContentType: est.MimeTypePKCS10, Data: akCsr},
+			{ContentType: est.MimeTypePKCS10, Data: ikCsr},
+			{ContentType: est.MimeTypeOctetStream, Data: akCert.Raw},
+			{ContentType: est.MimeTypeOctetStream, Data: akPublic},
+			{ContentType: est.MimeTypeOctetStream, Data: ikPublic},
+			{ContentType: est.MimeTypeOctetStream, Data: ikCreateData},
+			{ContentType: est.MimeTypeOctetStream, Data: ikCreateAttestation},
+			{ContentType: est.MimeTypeOctetStream, Data: ikCreateSignature},
+		},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode multipart: %w", err)
+	}
+
+	body := io.NopCloser(buf)
+
+	method := http.MethodPost
+	endpoint := strings.TrimSuffix(addr, "/") + est.EndpointPrefix + est.ReenrollEndpoint
+	accepts := est.MimeTypePKCS7
+	transferEncoding := est.EncodingTypeBase64
+
+	resp, err := request(c.client, method, endpoint, accepts, contentType, transferEncoding, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+
+	certs, err := parseSimplePkiResponse(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse simple PKI response: %w", err)
+	}
+	if len(certs) != 2 {
+		return nil, nil, fmt.Errorf("expected AK and IK certificate, got %v certificates", len(certs))
+	}
+
+	return certs[0], certs[1], nil
+}
+
 func (c *Client) TpmActivateEnroll(
 	addr, tpmManufacturer, ekCertUrl string,
 	tpmMajor, tpmMinor int,
@@ -286,7 +416,7 @@ func (c *Client) TpmCertifyEnroll(
 	return certs[0], nil
 }
 
-func (c *Client) SnpEnroll(addr string, ChipId [64]byte, Tcb uint64,
+func (c *Client) SnpEnroll(addr string, ChipId [64]byte, Tcb uint64, product string,
 ) (*x509.Certificate, error) {
 
 	// TODO mandate server authentication in the future
@@ -295,6 +425,7 @@ func (c *Client) SnpEnroll(addr string, ChipId [64]byte, Tcb uint64,
 		[]est.MimeMultipart{
 			{ContentType: est.MimeTypeOctetStream, Data: ChipId[:]},
 			{ContentType: est.MimeTypeOctetStream, Data: Tcb},
+			{ContentType: est.MimeTypeOctetStream, Data: product},
 		},
 	)
 	if err != nil {
@@ -327,6 +458,72 @@ func (c *Client) SnpEnroll(addr string, ChipId [64]byte, Tcb uint64,
 	return certs[0], nil
 }
 
+// GetSnpCrl retrieves the AMD SEV-SNP CRL for the specified product line and signing
+// key type (VCEK or VLEK) in DER format as cached by the EST server
+func (c *Client) GetSnpCrl(addr, product string, vlek bool) ([]byte, error) {
+
+	key := "vcek"
+	if vlek {
+		key = "vlek"
+	}
+	query := url.Values{}
+	query.Set(est.SnpProductParam, product)
+	query.Set(est.SnpKeyParam, key)
+
+	method := http.MethodGet
+	endpoint := strings.TrimSuffix(addr, "/") + est.EndpointPrefix + est.SnpCrlEndpoint + "?" + query.Encode()
+	accepts := est.MimeTypePKIXCRL
+	contentType := ""
+	transferEncoding := ""
+
+	resp, err := request(c.client, method, endpoint, accepts, contentType, transferEncoding, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+
+	decoded, err := est.DecodeBase64(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 payload: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// GetRevocationList retrieves the signed revocation list for manifests and descriptions
+// published by the EST server
+func (c *Client) GetRevocationList(addr string) ([]byte, error) {
+
+	method := http.MethodGet
+	endpoint := strings.TrimSuffix(addr, "/") + est.EndpointPrefix + est.RevocationListEndpoint
+	accepts := est.MimeTypeOctetStream
+	contentType := ""
+	transferEncoding := ""
+
+	resp, err := request(c.client, method, endpoint, accepts, contentType, transferEncoding, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTTP response body: %w", err)
+	}
+
+	decoded, err := est.DecodeBase64(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 payload: %w", err)
+	}
+
+	return decoded, nil
+}
+
 func request(
 	client *http.Client,
 	method, endpoint, accepts string,
@@ -43,6 +43,14 @@ const (
 	TpmActivateEnrollEndpoint = "/tpmactivateenroll"
 	TpmCertifyEnrollEndpoint  = "/tpmcertifyenroll"
 	SnpEnrollEndpoint         = "/snpenroll"
+	SnpCrlEndpoint            = "/snpcrl"
+	RevocationListEndpoint    = "/revocationlist"
+)
+
+// URI query parameter constants
+const (
+	SnpProductParam = "product"
+	SnpKeyParam     = "key"
 )
 
 // HTTP header constants
@@ -76,6 +84,7 @@ const (
 	MimeTypePKCS7Enveloped = "application/pkcs7-mime; smime-type=enveloped-data"
 	MimeTypePKCS7GenKey    = "application/pkcs7-mime; smime-type=server-generated-key"
 	MimeTypePKCS8          = "application/pkcs8"
+	MimeTypePKIXCRL        = "application/pkix-crl"
 	MimeTypeProblemJSON    = "application/problem+json"
 	MimeTypeTextPlain      = "text/plain"
 	MimeTypeTextPlainUTF8  = "text/plain; charset=utf-8"
@@ -54,6 +54,7 @@ type config struct {
 	TpmEkCertDb        string   `json:"tpmEkCertDb,omitempty"`
 	VcekOfflineCaching bool     `json:"vcekOfflineCaching,omitempty"`
 	VcekCacheFolder    string   `json:"vcekCacheFolder,omitempty"`
+	RevocationList     string   `json:"revocationList,omitempty"`
 	LogLevel           string   `json:"logLevel"`
 
 	configDir    *string
@@ -75,6 +76,7 @@ const (
 	tpmEkCertDbFlag        = "ekdb"
 	vcekOfflineCachingFlag = "vcekcaching"
 	vcekCacheFolderFlag    = "vcekfolder"
+	revocationListFlag     = "revocationlist"
 	logFlag                = "log"
 )
 
@@ -96,6 +98,7 @@ func getConfig() (*config, error) {
 	vcekOfflineCaching := flag.Bool(vcekOfflineCachingFlag, false,
 		"Indicates whether to cache downloaded AMD SNP VCEKs")
 	vcekCacheFolder := flag.String(vcekCacheFolderFlag, "", "Folder to cache AMD SNP VCEKs")
+	revocationList := flag.String(revocationListFlag, "", "Signed manifest revocation list to be published")
 	logLevel := flag.String(logFlag, "",
 		fmt.Sprintf("Possible logging: %v", maps.Keys(logLevels)))
 	flag.Parse()
@@ -152,6 +155,9 @@ func getConfig() (*config, error) {
 	if internal.FlagPassed(vcekCacheFolderFlag) {
 		c.VcekCacheFolder = *vcekCacheFolder
 	}
+	if internal.FlagPassed(revocationListFlag) {
+		c.RevocationList = *revocationList
+	}
 	if internal.FlagPassed(logFlag) {
 		c.LogLevel = *logLevel
 	}
@@ -213,6 +219,7 @@ func printConfig(c *config) {
 	log.Debugf("\tTPM EK DB           : %v", c.TpmEkCertDb)
 	log.Debugf("\tVCEK Offline Caching: %v", c.VcekOfflineCaching)
 	log.Debugf("\tVCEK Cache Folder   : %v", c.VcekCacheFolder)
+	log.Debugf("\tRevocation List     : %v", c.RevocationList)
 	log.Debugf("\tLog Level           : %v", c.LogLevel)
 }
 
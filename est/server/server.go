@@ -17,6 +17,7 @@ package main
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/rand"
 	"crypto/sha1"
@@ -43,11 +44,12 @@ import (
 )
 
 type Server struct {
-	server       *http.Server
-	signingKey   *ecdsa.PrivateKey
-	signingCerts []*x509.Certificate
-	tpmConf      tpmConfig
-	snpConf      snpConfig
+	server         *http.Server
+	signingKey     *ecdsa.PrivateKey
+	signingCerts   []*x509.Certificate
+	tpmConf        tpmConfig
+	snpConf        snpConfig
+	revocationList string // read on every request to allow updates without restart
 }
 
 func NewServer(c *config) (*Server, error) {
@@ -94,20 +96,35 @@ func NewServer(c *config) (*Server, error) {
 			vcekOfflineCaching: c.VcekOfflineCaching,
 			vcekCacheFolder:    c.VcekCacheFolder,
 			vceks:              make(map[vcekInfo][]byte),
+			crls:               make(map[string][]byte),
 		},
 	}
 
 	cacertsEndpoint := est.EndpointPrefix + est.CacertsEndpoint
 	simpleenrollEndpoint := est.EndpointPrefix + est.EnrollEndpoint
+	simplereenrollEndpoint := est.EndpointPrefix + est.ReenrollEndpoint
 	tpmActivateEnrollEndpoint := est.EndpointPrefix + est.TpmActivateEnrollEndpoint
 	tpmCertifyEnrollEndpoint := est.EndpointPrefix + est.TpmCertifyEnrollEndpoint
 	snpEnrollEndpoint := est.EndpointPrefix + est.SnpEnrollEndpoint
+	snpCrlEndpoint := est.EndpointPrefix + est.SnpCrlEndpoint
+	revocationListEndpoint := est.EndpointPrefix + est.RevocationListEndpoint
 
 	http.HandleFunc(cacertsEndpoint, server.handleCacerts)
 	http.HandleFunc(simpleenrollEndpoint, server.handleSimpleenroll)
+	http.HandleFunc(simplereenrollEndpoint, server.handleSimplereenroll)
 	http.HandleFunc(tpmActivateEnrollEndpoint, server.handleTpmActivateEnroll)
 	http.HandleFunc(tpmCertifyEnrollEndpoint, server.handleTpmCertifyEnroll)
 	http.HandleFunc(snpEnrollEndpoint, server.handleSnpEnroll)
+	http.HandleFunc(snpCrlEndpoint, server.handleSnpCrl)
+	http.HandleFunc(revocationListEndpoint, server.handleRevocationList)
+
+	if c.RevocationList != "" {
+		rl, err := internal.GetFilePath(c.RevocationList, c.configDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get revocation list path: %w", err)
+		}
+		server.revocationList = rl
+	}
 
 	err := httpHandleMetadata(c.HttpFolder, c.configDir)
 	if err != nil {
@@ -199,6 +216,228 @@ func (s *Server) handleSimpleenroll(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// handleSimplereenroll renews certificates for clients authenticated with their current
+// certificate [RFC7030 4.2.2]. A plain PKCS#10 CSR renews the client certificate.
+// A multipart request renews the AK and IK certificates of a TPM: the CSRs are accompanied
+// by the current AK certificate and the certification parameters of the (new) IK
+func (s *Server) handleSimplereenroll(w http.ResponseWriter, req *http.Request) {
+
+	log.Tracef("Received 'simplereenroll' request from %v", req.RemoteAddr)
+
+	if strings.Compare(req.Method, "POST") != 0 {
+		writeHttpErrorf(w, "Method %v not implemented for simplereenroll request", req.Method)
+		return
+	}
+
+	clientCert, err := getClientCert(req)
+	if err != nil {
+		writeHttpErrorf(w, "Failed to authenticate client: %v", err)
+		return
+	}
+
+	t, _, err := mime.ParseMediaType(req.Header.Get(est.ContentTypeHeader))
+	if err != nil {
+		writeHttpErrorf(w, "failed to parse media type %s: %v",
+			est.ContentTypeHeader, err)
+		return
+	}
+
+	var certs []*x509.Certificate
+	if strings.HasPrefix(t, est.MimeTypePKCS10) {
+		certs, err = s.reenroll(req, clientCert)
+	} else if strings.HasPrefix(t, est.MimeTypeMultipart) {
+		certs, err = s.reenrollTpm(req, clientCert)
+	} else {
+		err = fmt.Errorf("invalid %s %s, must begin with %s or %s", est.ContentTypeHeader,
+			t, est.MimeTypePKCS10, est.MimeTypeMultipart)
+	}
+	if err != nil {
+		writeHttpErrorf(w, "Failed to reenroll certificate: %v", err)
+		return
+	}
+
+	body, err := est.EncodePkcs7CertsOnly(certs)
+	if err != nil {
+		writeHttpErrorf(w, "Failed to encode PKCS7 certs-only: %v", err)
+		return
+	}
+	encoded := est.EncodeBase64(body)
+
+	err = sendResponse(w, est.MimeTypePKCS7, est.EncodingTypeBase64, encoded)
+	if err != nil {
+		writeHttpErrorf(w, "Failed to send generated certificate: %v", err)
+		return
+	}
+}
+
+// reenroll renews the client certificate. The CSR must contain the same subject, SANs
+// and key as the current certificate. Certifying a new key would allow a client to
+// transfer the identity of a hardware-bound key (e.g. a TPM IK) to an exportable key,
+// hence new TPM keys must be renewed via reenrollTpm
+func (s *Server) reenroll(req *http.Request, clientCert *x509.Certificate,
+) ([]*x509.Certificate, error) {
+
+	csr, err := est.ParsePkcs10Csr(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSR: %w", err)
+	}
+
+	err = verifyReenrollCsr(csr, clientCert)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKeyEqual(csr.PublicKey, clientCert.PublicKey) {
+		return nil, errors.New("CSR key does not match client certificate key")
+	}
+
+	cert, err := enrollCert(csr, s.signingKey, s.signingCerts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to enroll certificate: %w", err)
+	}
+
+	return []*x509.Certificate{cert}, nil
+}
+
+// reenrollTpm renews the AK and IK certificates of a TPM. The client must be authenticated
+// with its current IK certificate and must provide its current AK certificate. The AK key
+// is re-certified, whereas the IK might be a new key certified by the AK
+func (s *Server) reenrollTpm(req *http.Request, clientCert *x509.Certificate,
+) ([]*x509.Certificate, error) {
+
+	var akCsr *x509.CertificateRequest
+	var ikCsr *x509.CertificateRequest
+	var akCertDer []byte
+	var akPublic []byte
+	var ikPublic []byte
+	var ikCreateData []byte
+	var ikCreateAttestation []byte
+	var ikCreateSignature []byte
+
+	_, err := est.DecodeMultipart(
+		req.Body,
+		[]est.MimeMultipart{
+			{ContentType: est.MimeTypePKCS10, Data: &akCsr},
+			{ContentType: est.MimeTypePKCS10, Data: &ikCsr},
+			{ContentType: est.MimeTypeOctetStream, Data: &akCertDer},
+			{ContentType: est.MimeTypeOctetStream, Data: &akPublic},
+			{ContentType: est.MimeTypeOctetStream, Data: &ikPublic},
+			{ContentType: est.MimeTypeOctetStream, Data: &ikCreateData},
+			{ContentType: est.MimeTypeOctetStream, Data: &ikCreateAttestation},
+			{ContentType: est.MimeTypeOctetStream, Data: &ikCreateSignature},
+		},
+		req.Header.Get(est.ContentTypeHeader),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode multipart: %w", err)
+	}
+	if akCsr == nil || ikCsr == nil {
+		return nil, errors.New("request does not contain AK and IK CSR")
+	}
+
+	// Verify that the AK was certified by us and that the AK CSR renews this certificate
+	akCert, err := x509.ParseCertificate(akCertDer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse AK certificate: %w", err)
+	}
+	err = s.verifyIssued(akCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify AK certificate: %w", err)
+	}
+	err = verifyReenrollCsr(akCsr, akCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify AK CSR: %w", err)
+	}
+	err = verifyTpmCsr(akPublic, akCsr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify AK: %w", err)
+	}
+	if !publicKeyEqual(akCert.PublicKey, akCsr.PublicKey) {
+		return nil, errors.New("AK CSR key does not match AK certificate key")
+	}
+
+	// Verify that the IK was certified by the AK and that the IK CSR renews the
+	// certificate the client authenticated with
+	ikParams := attest.CertificationParameters{
+		Public:            ikPublic,
+		CreateData:        ikCreateData,
+		CreateAttestation: ikCreateAttestation,
+		CreateSignature:   ikCreateSignature,
+	}
+	err = verifyIk(ikParams, akPublic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify IK: %w", err)
+	}
+	err = verifyTpmCsr(ikPublic, ikCsr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify IK: %w", err)
+	}
+	err = verifyReenrollCsr(ikCsr, clientCert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify IK CSR: %w", err)
+	}
+
+	newAkCert, err := enrollCert(akCsr, s.signingKey, s.signingCerts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to enroll AK certificate: %w", err)
+	}
+	newIkCert, err := enrollCert(ikCsr, s.signingKey, s.signingCerts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to enroll IK certificate: %w", err)
+	}
+
+	return []*x509.Certificate{newAkCert, newIkCert}, nil
+}
+
+// verifyIssued verifies that the certificate was issued by this server's CA
+func (s *Server) verifyIssued(cert *x509.Certificate) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(s.signingCerts[len(s.signingCerts)-1])
+	intermediates := x509.NewCertPool()
+	for _, c := range s.signingCerts[:len(s.signingCerts)-1] {
+		intermediates.AddCert(c)
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// getClientCert returns the certificate the client authenticated with via TLS
+func getClientCert(req *http.Request) (*x509.Certificate, error) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return nil, errors.New("client did not provide a valid certificate")
+	}
+	return req.TLS.VerifiedChains[0][0], nil
+}
+
+// verifyReenrollCsr checks that the CSR requests the renewal of the certificate, i.e.,
+// that subject and SANs are identical [RFC7030 4.2.2]
+func verifyReenrollCsr(csr *x509.CertificateRequest, cert *x509.Certificate) error {
+	if !bytes.Equal(csr.RawSubject, cert.RawSubject) {
+		return fmt.Errorf("CSR subject %v does not match certificate subject %v",
+			csr.Subject.String(), cert.Subject.String())
+	}
+	if strings.Join(csr.DNSNames, ",") != strings.Join(cert.DNSNames, ",") {
+		return fmt.Errorf("CSR SANs %v do not match certificate SANs %v",
+			csr.DNSNames, cert.DNSNames)
+	}
+	return nil
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	aPkix, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	bPkix, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aPkix, bPkix)
+}
+
 func (s *Server) handleTpmActivateEnroll(w http.ResponseWriter, req *http.Request) {
 
 	log.Tracef("Received 'tpmactivateenroll' request from %v", req.RemoteAddr)
@@ -394,12 +633,14 @@ func (s *Server) handleSnpEnroll(w http.ResponseWriter, req *http.Request) {
 
 	var chipId []byte
 	var tcb uint64
+	var product string
 
 	_, err := est.DecodeMultipart(
 		req.Body,
 		[]est.MimeMultipart{
 			{ContentType: est.MimeTypeOctetStream, Data: &chipId},
 			{ContentType: est.MimeTypeOctetStream, Data: &tcb},
+			{ContentType: est.MimeTypeOctetStream, Data: &product},
 		},
 		req.Header.Get(est.ContentTypeHeader),
 	)
@@ -408,7 +649,12 @@ func (s *Server) handleSnpEnroll(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	vcek, err := s.getVcek(chipId, tcb)
+	// Older clients do not transmit the product line, which was always Milan
+	if product == "" {
+		product = internal.SnpProductMilan
+	}
+
+	vcek, err := s.getVcek(chipId, tcb, product)
 	if err != nil {
 		writeHttpErrorf(w, "Failed to get VCEK: %v", err)
 		return
@@ -428,6 +674,61 @@ func (s *Server) handleSnpEnroll(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func (s *Server) handleSnpCrl(w http.ResponseWriter, req *http.Request) {
+
+	log.Tracef("Received 'snpcrl' request from %v", req.RemoteAddr)
+
+	if strings.Compare(req.Method, "GET") != 0 {
+		writeHttpErrorf(w, "Method %v not implemented for snpcrl request", req.Method)
+		return
+	}
+
+	product := req.URL.Query().Get(est.SnpProductParam)
+	if product == "" {
+		product = internal.SnpProductMilan
+	}
+	vlek := strings.EqualFold(req.URL.Query().Get(est.SnpKeyParam), "vlek")
+
+	crl, err := s.getSnpCrl(product, vlek)
+	if err != nil {
+		writeHttpErrorf(w, "Failed to get CRL: %v", err)
+		return
+	}
+
+	err = sendResponse(w, est.MimeTypePKIXCRL, est.EncodingTypeBase64, est.EncodeBase64(crl))
+	if err != nil {
+		writeHttpErrorf(w, "Failed to send CRL: %v", err)
+		return
+	}
+}
+
+func (s *Server) handleRevocationList(w http.ResponseWriter, req *http.Request) {
+
+	log.Tracef("Received 'revocationlist' request from %v", req.RemoteAddr)
+
+	if strings.Compare(req.Method, "GET") != 0 {
+		writeHttpErrorf(w, "Method %v not implemented for revocationlist request", req.Method)
+		return
+	}
+
+	if s.revocationList == "" {
+		http.Error(w, "No revocation list configured", http.StatusNotFound)
+		return
+	}
+
+	rl, err := os.ReadFile(s.revocationList)
+	if err != nil {
+		writeHttpErrorf(w, "Failed to read revocation list: %v", err)
+		return
+	}
+
+	err = sendResponse(w, est.MimeTypeOctetStream, est.EncodingTypeBase64, est.EncodeBase64(rl))
+	if err != nil {
+		writeHttpErrorf(w, "Failed to send revocation list: %v", err)
+		return
+	}
+}
+
 func httpHandleMetadata(httpFolder string, configPath *string) error {
 	// Retrieve the directories to be provided from config and create http
 	// directory structure
@@ -0,0 +1,226 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	est "github.com/Fraunhofer-AISEC/cmc/est/common"
+	"go.mozilla.org/pkcs7"
+)
+
+func Test_handleSimplereenroll(t *testing.T) {
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	ca, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	s := &Server{
+		signingKey:   caKey,
+		signingCerts: []*x509.Certificate{ca},
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	createCsr := func(cn string, key *ecdsa.PrivateKey) []byte {
+		tmpl := &x509.CertificateRequest{Subject: pkix.Name{CommonName: cn}, DNSNames: []string{"localhost"}}
+		der, err := x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+		if err != nil {
+			t.Fatalf("failed to create CSR: %v", err)
+		}
+		return der
+	}
+	csr, err := x509.ParseCertificateRequest(createCsr("device", key))
+	if err != nil {
+		t.Fatalf("failed to parse CSR: %v", err)
+	}
+	clientCert, err := enrollCert(csr, caKey, ca)
+	if err != nil {
+		t.Fatalf("failed to enroll client certificate: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		csr        []byte
+		tls        *tls.ConnectionState
+		wantStatus int
+	}{
+		{
+			"Success",
+			createCsr("device", key),
+			&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert, ca}}},
+			http.StatusOK,
+		},
+		{
+			"Subject Mismatch",
+			createCsr("other device", key),
+			&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert, ca}}},
+			http.StatusBadRequest,
+		},
+		{
+			"Key Mismatch",
+			createCsr("device", otherKey),
+			&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{clientCert, ca}}},
+			http.StatusBadRequest,
+		},
+		{
+			"Missing Client Certificate",
+			createCsr("device", key),
+			&tls.ConnectionState{},
+			http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, est.EndpointPrefix+est.ReenrollEndpoint,
+				bytes.NewReader(est.EncodeBase64(tt.csr)))
+			req.Header.Set(est.ContentTypeHeader, est.MimeTypePKCS10)
+			req.Header.Set(est.TransferEncodingHeader, est.EncodingTypeBase64)
+			req.TLS = tt.tls
+
+			w := httptest.NewRecorder()
+			s.handleSimplereenroll(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handleSimplereenroll() status = %v, want %v: %v", w.Code, tt.wantStatus,
+					w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			decoded, err := est.DecodeBase64(w.Body.Bytes())
+			if err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			certs, err := parsePkcs7Certs(decoded)
+			if err != nil {
+				t.Fatalf("failed to parse response: %v", err)
+			}
+			if len(certs) != 1 || !bytes.Equal(certs[0].RawSubject, clientCert.RawSubject) {
+				t.Errorf("handleSimplereenroll() returned unexpected certificates")
+			}
+			if err := certs[0].CheckSignatureFrom(ca); err != nil {
+				t.Errorf("renewed certificate not signed by CA: %v", err)
+			}
+		})
+	}
+}
+
+func Test_handleRevocationList(t *testing.T) {
+
+	rl := []byte(`{"type":"Revocation List"}`)
+	file := filepath.Join(t.TempDir(), "revocation.list")
+	if err := os.WriteFile(file, rl, 0644); err != nil {
+		t.Fatalf("failed to write revocation list: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		revocationList string
+		wantStatus     int
+	}{
+		{
+			name:           "Success",
+			method:         http.MethodGet,
+			revocationList: file,
+			wantStatus:     http.StatusOK,
+		},
+		{
+			name:           "Not Configured",
+			method:         http.MethodGet,
+			revocationList: "",
+			wantStatus:     http.StatusNotFound,
+		},
+		{
+			name:           "Invalid Method",
+			method:         http.MethodPost,
+			revocationList: file,
+			wantStatus:     http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				revocationList: tt.revocationList,
+			}
+			req := httptest.NewRequest(tt.method, est.EndpointPrefix+est.RevocationListEndpoint, nil)
+			w := httptest.NewRecorder()
+
+			s.handleRevocationList(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("handleRevocationList() status = %v, want %v: %v", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			got, err := est.DecodeBase64(w.Body.Bytes())
+			if err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !bytes.Equal(got, rl) {
+				t.Errorf("handleRevocationList() = %v, want %v", string(got), string(rl))
+			}
+		})
+	}
+}
+
+func parsePkcs7Certs(data []byte) ([]*x509.Certificate, error) {
+	p7, err := pkcs7.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return p7.Certificates, nil
+}
@@ -23,17 +23,17 @@ import (
 	"net/http"
 	"os"
 	"path"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/Fraunhofer-AISEC/cmc/internal"
 	log "github.com/sirupsen/logrus"
 )
 
 const (
-	snpVcekUrlPrefix = "https://kdsintf.amd.com/vcek/v1/Milan/"
-	snpMilanUrl      = "https://kdsintf.amd.com/vcek/v1/Milan/cert_chain"
-	snpMaxRetries    = 3
-	lenChipId        = 64
+	snpMaxRetries = 3
+	lenChipId     = 64
 )
 
 type vcekInfo struct {
@@ -46,6 +46,8 @@ type snpConfig struct {
 	vcekOfflineCaching bool
 	vcekCacheFolder    string
 	vceks              map[vcekInfo][]byte
+	crlMutex           sync.Mutex
+	crls               map[string][]byte
 }
 
 func (s *Server) lockVcekMutex() {
@@ -60,9 +62,9 @@ func (s *Server) unlockVcekMutex() {
 	log.Trace("Released Lock")
 }
 
-// Get Vcek takes the TCB and chip ID, calculates the VCEK URL and gets the certificate
-// in DER format from the cache or downloads it from the AMD server if not present
-func (s *Server) getVcek(chipId []byte, tcb uint64) (*x509.Certificate, error) {
+// Get Vcek takes the TCB, chip ID and product line, calculates the VCEK URL and gets the
+// certificate in DER format from the cache or downloads it from the AMD server if not present
+func (s *Server) getVcek(chipId []byte, tcb uint64, product string) (*x509.Certificate, error) {
 
 	// Allow only one download and caching of the VCEK certificate in parallel
 	// as the AMD KDF server allows only one request in 10s
@@ -85,14 +87,11 @@ func (s *Server) getVcek(chipId []byte, tcb uint64) (*x509.Certificate, error) {
 		return vcek, nil
 	}
 
-	ChipId := hex.EncodeToString(chipId)
-	tcbInfo := fmt.Sprintf("?blSPL=%v&teeSPL=%v&snpSPL=%v&ucodeSPL=%v",
-		tcb&0xFF,
-		(tcb>>8)&0xFF,
-		(tcb>>48)&0xFF,
-		(tcb>>56)&0xFF)
+	url, err := internal.GetSnpVcekUrl(product, chipId, tcb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VCEK URL: %w", err)
+	}
 
-	url := snpVcekUrlPrefix + ChipId + tcbInfo
 	for i := 0; i < snpMaxRetries; i++ {
 		log.Tracef("Requesting SNP VCEK certificate from: %v", url)
 		vcek, statusCode, err := downloadCert(url)
@@ -164,6 +163,110 @@ func (s *Server) cacheVcek(vcek []byte, chipId [64]byte, tcb uint64) error {
 	}
 }
 
+// getSnpCrl returns the AMD KDS CRL in DER format for the specified product line and
+// signing key type from the cache or downloads it from the AMD server if not present
+// or expired. If the download fails, an expired CRL is returned, if present
+func (s *Server) getSnpCrl(product string, vlek bool) ([]byte, error) {
+
+	s.snpConf.crlMutex.Lock()
+	defer s.snpConf.crlMutex.Unlock()
+
+	url, err := internal.GetSnpCrlUrl(product, vlek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CRL URL: %w", err)
+	}
+	name := crlName(product, vlek)
+
+	cached, ok := s.tryGetCachedCrl(name)
+	if ok {
+		crl, err := x509.ParseRevocationList(cached)
+		if err == nil && time.Now().Before(crl.NextUpdate) {
+			log.Tracef("Using cached CRL %v", name)
+			return cached, nil
+		}
+		log.Tracef("Cached CRL %v invalid or expired, will be downloaded", name)
+	}
+
+	log.Tracef("Requesting SNP CRL from: %v", url)
+	der, err := downloadCrl(url)
+	if err != nil {
+		if ok {
+			log.Warnf("Failed to download CRL: %v. Using cached CRL %v", err, name)
+			return cached, nil
+		}
+		return nil, fmt.Errorf("failed to get CRL: %w", err)
+	}
+
+	if err := s.cacheCrl(der, name); err != nil {
+		log.Warnf("Failed to cache CRL: %v", err)
+	}
+
+	return der, nil
+}
+
+func crlName(product string, vlek bool) string {
+	key := "vcek"
+	if vlek {
+		key = "vlek"
+	}
+	return fmt.Sprintf("crl_%v_%v", strings.ToLower(product), key)
+}
+
+// tryGetCachedCrl returns cached CRLs in DER format if available
+func (s *Server) tryGetCachedCrl(name string) ([]byte, bool) {
+	if der, ok := s.snpConf.crls[name]; ok {
+		return der, true
+	}
+	if s.snpConf.vcekOfflineCaching {
+		filePath := path.Join(s.snpConf.vcekCacheFolder, name+".der")
+		der, err := os.ReadFile(filePath)
+		if err != nil {
+			log.Tracef("CRL not present at %v, will be downloaded", filePath)
+			return nil, false
+		}
+		s.snpConf.crls[name] = der
+		return der, true
+	}
+	return nil, false
+}
+
+// cacheCrl caches CRLs in DER format
+func (s *Server) cacheCrl(der []byte, name string) error {
+	s.snpConf.crls[name] = der
+	if s.snpConf.vcekOfflineCaching {
+		filePath := path.Join(s.snpConf.vcekCacheFolder, name+".der")
+		err := os.WriteFile(filePath, der, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to write file %v: %w", filePath, err)
+		}
+		log.Tracef("Cached CRL at %v", filePath)
+	}
+	return nil
+}
+
+func downloadCrl(url string) ([]byte, error) {
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("error HTTP GET: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP Response Status: %v (%v)", resp.StatusCode, resp.Status)
+	}
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTTP body: %w", err)
+	}
+
+	if _, err := x509.ParseRevocationList(content); err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %w", err)
+	}
+
+	return content, nil
+}
+
 func downloadCert(url string) (*x509.Certificate, int, error) {
 
 	resp, err := http.Get(url)
@@ -1,6 +1,6 @@
 module github.com/Fraunhofer-AISEC/cmc
 
-go 1.18
+go 1.19
 
 require (
 	github.com/Fraunhofer-AISEC/go-attestation v0.3.3-0.20230306143918-e20e49317a80
@@ -13,6 +13,7 @@ require (
 	github.com/sirupsen/logrus v1.9.0
 	github.com/veraison/go-cose v1.0.0
 	go.mozilla.org/pkcs7 v0.0.0-20210826202110-33d05740a352
+	golang.org/x/crypto v0.5.0
 	golang.org/x/exp v0.0.0-20230118134722-a68e582fa157
 	google.golang.org/grpc v1.52.0
 	google.golang.org/protobuf v1.28.1
@@ -30,7 +31,6 @@ require (
 	github.com/pion/udp v0.1.4 // indirect
 	github.com/x448/float16 v0.8.4 // indirect
 	go.uber.org/atomic v1.10.0 // indirect
-	golang.org/x/crypto v0.5.0 // indirect
 	golang.org/x/net v0.7.0 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.5.0 // indirect
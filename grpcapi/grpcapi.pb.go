@@ -15,7 +15,7 @@
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.27.1
+// 	protoc-gen-go v1.28.1
 // 	protoc        v3.12.4
 // source: grpcapi.proto
 
@@ -181,6 +181,56 @@ func (HashFunction) EnumDescriptor() ([]byte, []int) {
 	return file_grpcapi_proto_rawDescGZIP(), []int{1}
 }
 
+// Values must match the ResultFormat of package attestationreport
+type ResultFormat int32
+
+const (
+	ResultFormat_CMC_JSON ResultFormat = 0
+	ResultFormat_EAR_JWT  ResultFormat = 1
+	ResultFormat_EAR_CWT  ResultFormat = 2
+)
+
+// Enum value maps for ResultFormat.
+var (
+	ResultFormat_name = map[int32]string{
+		0: "CMC_JSON",
+		1: "EAR_JWT",
+		2: "EAR_CWT",
+	}
+	ResultFormat_value = map[string]int32{
+		"CMC_JSON": 0,
+		"EAR_JWT":  1,
+		"EAR_CWT":  2,
+	}
+)
+
+func (x ResultFormat) Enum() *ResultFormat {
+	p := new(ResultFormat)
+	*p = x
+	return p
+}
+
+func (x ResultFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ResultFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_grpcapi_proto_enumTypes[2].Descriptor()
+}
+
+func (ResultFormat) Type() protoreflect.EnumType {
+	return &file_grpcapi_proto_enumTypes[2]
+}
+
+func (x ResultFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ResultFormat.Descriptor instead.
+func (ResultFormat) EnumDescriptor() ([]byte, []int) {
+	return file_grpcapi_proto_rawDescGZIP(), []int{2}
+}
+
 type PSSOptions struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -571,10 +621,11 @@ type VerificationRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Nonce             []byte `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
-	AttestationReport []byte `protobuf:"bytes,2,opt,name=attestation_report,json=attestationReport,proto3" json:"attestation_report,omitempty"`
-	Ca                []byte `protobuf:"bytes,3,opt,name=ca,proto3" json:"ca,omitempty"`
-	Policies          []byte `protobuf:"bytes,4,opt,name=policies,proto3" json:"policies,omitempty"`
+	Nonce             []byte       `protobuf:"bytes,1,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	AttestationReport []byte       `protobuf:"bytes,2,opt,name=attestation_report,json=attestationReport,proto3" json:"attestation_report,omitempty"`
+	Ca                []byte       `protobuf:"bytes,3,opt,name=ca,proto3" json:"ca,omitempty"`
+	Policies          []byte       `protobuf:"bytes,4,opt,name=policies,proto3" json:"policies,omitempty"`
+	ResultFormat      ResultFormat `protobuf:"varint,5,opt,name=result_format,json=resultFormat,proto3,enum=grpcapi.ResultFormat" json:"result_format,omitempty"`
 }
 
 func (x *VerificationRequest) Reset() {
@@ -637,6 +688,13 @@ func (x *VerificationRequest) GetPolicies() []byte {
 	return nil
 }
 
+func (x *VerificationRequest) GetResultFormat() ResultFormat {
+	if x != nil {
+		return x.ResultFormat
+	}
+	return ResultFormat_CMC_JSON
+}
+
 type VerificationResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -734,7 +792,7 @@ var file_grpcapi_proto_rawDesc = []byte{
 	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2d, 0x0a, 0x12, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61,
 	0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28,
 	0x0c, 0x52, 0x11, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
-	0x70, 0x6f, 0x72, 0x74, 0x22, 0x86, 0x01, 0x0a, 0x13, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63,
+	0x70, 0x6f, 0x72, 0x74, 0x22, 0xc2, 0x01, 0x0a, 0x13, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63,
 	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05,
 	0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x6e,
 	0x63, 0x65, 0x12, 0x2d, 0x0a, 0x12, 0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f,
@@ -742,54 +800,61 @@ var file_grpcapi_proto_rawDesc = []byte{
 	0x61, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72,
 	0x74, 0x12, 0x0e, 0x0a, 0x02, 0x63, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x02, 0x63,
 	0x61, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x0c, 0x52, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x22, 0x70, 0x0a,
-	0x14, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73,
-	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x27, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e,
-	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2f,
-	0x0a, 0x13, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x72,
-	0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x12, 0x76, 0x65, 0x72,
-	0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2a,
-	0x2f, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x4b, 0x10,
-	0x00, 0x12, 0x08, 0x0a, 0x04, 0x46, 0x41, 0x49, 0x4c, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x4e,
-	0x4f, 0x54, 0x5f, 0x49, 0x4d, 0x50, 0x4c, 0x45, 0x4d, 0x45, 0x4e, 0x54, 0x45, 0x44, 0x10, 0x02,
-	0x2a, 0x92, 0x02, 0x0a, 0x0c, 0x48, 0x61, 0x73, 0x68, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f,
-	0x6e, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x48, 0x41, 0x31, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x53,
-	0x48, 0x41, 0x32, 0x32, 0x34, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x32, 0x35,
-	0x36, 0x10, 0x02, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x33, 0x38, 0x34, 0x10, 0x03, 0x12,
-	0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x35, 0x31, 0x32, 0x10, 0x04, 0x12, 0x07, 0x0a, 0x03, 0x4d,
-	0x44, 0x34, 0x10, 0x05, 0x12, 0x07, 0x0a, 0x03, 0x4d, 0x44, 0x35, 0x10, 0x06, 0x12, 0x0b, 0x0a,
-	0x07, 0x4d, 0x44, 0x35, 0x53, 0x48, 0x41, 0x31, 0x10, 0x07, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x49,
-	0x50, 0x45, 0x4d, 0x44, 0x31, 0x36, 0x30, 0x10, 0x08, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41,
-	0x33, 0x5f, 0x32, 0x32, 0x34, 0x10, 0x09, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x33, 0x5f,
-	0x32, 0x35, 0x36, 0x10, 0x0a, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x33, 0x5f, 0x33, 0x38,
-	0x34, 0x10, 0x0b, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x33, 0x5f, 0x35, 0x31, 0x32, 0x10,
-	0x0c, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x48, 0x41, 0x35, 0x31, 0x32, 0x5f, 0x32, 0x32, 0x34, 0x10,
-	0x0d, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x48, 0x41, 0x35, 0x31, 0x32, 0x5f, 0x32, 0x35, 0x36, 0x10,
-	0x0e, 0x12, 0x0f, 0x0a, 0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x73, 0x5f, 0x32, 0x35, 0x36,
-	0x10, 0x0f, 0x12, 0x0f, 0x0a, 0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x62, 0x5f, 0x32, 0x35,
-	0x36, 0x10, 0x10, 0x12, 0x0f, 0x0a, 0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x62, 0x5f, 0x33,
-	0x38, 0x34, 0x10, 0x11, 0x12, 0x0f, 0x0a, 0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x62, 0x5f,
-	0x35, 0x31, 0x32, 0x10, 0x12, 0x32, 0x9c, 0x02, 0x0a, 0x0a, 0x43, 0x4d, 0x43, 0x53, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x12, 0x3e, 0x0a, 0x07, 0x54, 0x4c, 0x53, 0x53, 0x69, 0x67, 0x6e, 0x12,
-	0x17, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x54, 0x4c, 0x53, 0x53, 0x69, 0x67,
-	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61,
-	0x70, 0x69, 0x2e, 0x54, 0x4c, 0x53, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x12, 0x3e, 0x0a, 0x07, 0x54, 0x4c, 0x53, 0x43, 0x65, 0x72, 0x74, 0x12,
-	0x17, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x54, 0x4c, 0x53, 0x43, 0x65, 0x72,
-	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61,
-	0x70, 0x69, 0x2e, 0x54, 0x4c, 0x53, 0x43, 0x65, 0x72, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x12, 0x45, 0x0a, 0x06, 0x41, 0x74, 0x74, 0x65, 0x73, 0x74, 0x12, 0x1b,
-	0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x41, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61,
-	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x67, 0x72,
-	0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x41, 0x74, 0x74, 0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f,
-	0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x47, 0x0a, 0x06, 0x56,
-	0x65, 0x72, 0x69, 0x66, 0x79, 0x12, 0x1c, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e,
-	0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x56, 0x65,
-	0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x22, 0x00, 0x42, 0x0c, 0x5a, 0x0a, 0x2e, 0x2f, 0x3b, 0x67, 0x72, 0x70, 0x63, 0x61,
-	0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x01, 0x28, 0x0c, 0x52, 0x08, 0x70, 0x6f, 0x6c, 0x69, 0x63, 0x69, 0x65, 0x73, 0x12, 0x3a, 0x0a,
+	0x0d, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x5f, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x52, 0x0c, 0x72, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x22, 0x70, 0x0a, 0x14, 0x56, 0x65, 0x72,
+	0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x27, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x0f, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x2f, 0x0a, 0x13, 0x76, 0x65,
+	0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x72, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x12, 0x76, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2a, 0x2f, 0x0a, 0x06, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x06, 0x0a, 0x02, 0x4f, 0x4b, 0x10, 0x00, 0x12, 0x08, 0x0a,
+	0x04, 0x46, 0x41, 0x49, 0x4c, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x4e, 0x4f, 0x54, 0x5f, 0x49,
+	0x4d, 0x50, 0x4c, 0x45, 0x4d, 0x45, 0x4e, 0x54, 0x45, 0x44, 0x10, 0x02, 0x2a, 0x92, 0x02, 0x0a,
+	0x0c, 0x48, 0x61, 0x73, 0x68, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x08, 0x0a,
+	0x04, 0x53, 0x48, 0x41, 0x31, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x32, 0x32,
+	0x34, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x32, 0x35, 0x36, 0x10, 0x02, 0x12,
+	0x0a, 0x0a, 0x06, 0x53, 0x48, 0x41, 0x33, 0x38, 0x34, 0x10, 0x03, 0x12, 0x0a, 0x0a, 0x06, 0x53,
+	0x48, 0x41, 0x35, 0x31, 0x32, 0x10, 0x04, 0x12, 0x07, 0x0a, 0x03, 0x4d, 0x44, 0x34, 0x10, 0x05,
+	0x12, 0x07, 0x0a, 0x03, 0x4d, 0x44, 0x35, 0x10, 0x06, 0x12, 0x0b, 0x0a, 0x07, 0x4d, 0x44, 0x35,
+	0x53, 0x48, 0x41, 0x31, 0x10, 0x07, 0x12, 0x0d, 0x0a, 0x09, 0x52, 0x49, 0x50, 0x45, 0x4d, 0x44,
+	0x31, 0x36, 0x30, 0x10, 0x08, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x33, 0x5f, 0x32, 0x32,
+	0x34, 0x10, 0x09, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x33, 0x5f, 0x32, 0x35, 0x36, 0x10,
+	0x0a, 0x12, 0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x33, 0x5f, 0x33, 0x38, 0x34, 0x10, 0x0b, 0x12,
+	0x0c, 0x0a, 0x08, 0x53, 0x48, 0x41, 0x33, 0x5f, 0x35, 0x31, 0x32, 0x10, 0x0c, 0x12, 0x0e, 0x0a,
+	0x0a, 0x53, 0x48, 0x41, 0x35, 0x31, 0x32, 0x5f, 0x32, 0x32, 0x34, 0x10, 0x0d, 0x12, 0x0e, 0x0a,
+	0x0a, 0x53, 0x48, 0x41, 0x35, 0x31, 0x32, 0x5f, 0x32, 0x35, 0x36, 0x10, 0x0e, 0x12, 0x0f, 0x0a,
+	0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x73, 0x5f, 0x32, 0x35, 0x36, 0x10, 0x0f, 0x12, 0x0f,
+	0x0a, 0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x62, 0x5f, 0x32, 0x35, 0x36, 0x10, 0x10, 0x12,
+	0x0f, 0x0a, 0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x62, 0x5f, 0x33, 0x38, 0x34, 0x10, 0x11,
+	0x12, 0x0f, 0x0a, 0x0b, 0x42, 0x4c, 0x41, 0x4b, 0x45, 0x32, 0x62, 0x5f, 0x35, 0x31, 0x32, 0x10,
+	0x12, 0x2a, 0x36, 0x0a, 0x0c, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x46, 0x6f, 0x72, 0x6d, 0x61,
+	0x74, 0x12, 0x0c, 0x0a, 0x08, 0x43, 0x4d, 0x43, 0x5f, 0x4a, 0x53, 0x4f, 0x4e, 0x10, 0x00, 0x12,
+	0x0b, 0x0a, 0x07, 0x45, 0x41, 0x52, 0x5f, 0x4a, 0x57, 0x54, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07,
+	0x45, 0x41, 0x52, 0x5f, 0x43, 0x57, 0x54, 0x10, 0x02, 0x32, 0x9c, 0x02, 0x0a, 0x0a, 0x43, 0x4d,
+	0x43, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x3e, 0x0a, 0x07, 0x54, 0x4c, 0x53, 0x53,
+	0x69, 0x67, 0x6e, 0x12, 0x17, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x54, 0x4c,
+	0x53, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x67,
+	0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x54, 0x4c, 0x53, 0x53, 0x69, 0x67, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3e, 0x0a, 0x07, 0x54, 0x4c, 0x53, 0x43,
+	0x65, 0x72, 0x74, 0x12, 0x17, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x54, 0x4c,
+	0x53, 0x43, 0x65, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x67,
+	0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x54, 0x4c, 0x53, 0x43, 0x65, 0x72, 0x74, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x45, 0x0a, 0x06, 0x41, 0x74, 0x74, 0x65,
+	0x73, 0x74, 0x12, 0x1b, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x41, 0x74, 0x74,
+	0x65, 0x73, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1c, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2e, 0x41, 0x74, 0x74, 0x65, 0x73, 0x74,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12,
+	0x47, 0x0a, 0x06, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x12, 0x1c, 0x2e, 0x67, 0x72, 0x70, 0x63,
+	0x61, 0x70, 0x69, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x67, 0x72, 0x70, 0x63, 0x61, 0x70,
+	0x69, 0x2e, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x0c, 0x5a, 0x0a, 0x2e, 0x2f, 0x3b, 0x67,
+	0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -804,41 +869,43 @@ func file_grpcapi_proto_rawDescGZIP() []byte {
 	return file_grpcapi_proto_rawDescData
 }
 
-var file_grpcapi_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_grpcapi_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
 var file_grpcapi_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_grpcapi_proto_goTypes = []interface{}{
 	(Status)(0),                  // 0: grpcapi.Status
 	(HashFunction)(0),            // 1: grpcapi.HashFunction
-	(*PSSOptions)(nil),           // 2: grpcapi.PSSOptions
-	(*TLSSignRequest)(nil),       // 3: grpcapi.TLSSignRequest
-	(*TLSSignResponse)(nil),      // 4: grpcapi.TLSSignResponse
-	(*TLSCertRequest)(nil),       // 5: grpcapi.TLSCertRequest
-	(*TLSCertResponse)(nil),      // 6: grpcapi.TLSCertResponse
-	(*AttestationRequest)(nil),   // 7: grpcapi.AttestationRequest
-	(*AttestationResponse)(nil),  // 8: grpcapi.AttestationResponse
-	(*VerificationRequest)(nil),  // 9: grpcapi.VerificationRequest
-	(*VerificationResponse)(nil), // 10: grpcapi.VerificationResponse
+	(ResultFormat)(0),            // 2: grpcapi.ResultFormat
+	(*PSSOptions)(nil),           // 3: grpcapi.PSSOptions
+	(*TLSSignRequest)(nil),       // 4: grpcapi.TLSSignRequest
+	(*TLSSignResponse)(nil),      // 5: grpcapi.TLSSignResponse
+	(*TLSCertRequest)(nil),       // 6: grpcapi.TLSCertRequest
+	(*TLSCertResponse)(nil),      // 7: grpcapi.TLSCertResponse
+	(*AttestationRequest)(nil),   // 8: grpcapi.AttestationRequest
+	(*AttestationResponse)(nil),  // 9: grpcapi.AttestationResponse
+	(*VerificationRequest)(nil),  // 10: grpcapi.VerificationRequest
+	(*VerificationResponse)(nil), // 11: grpcapi.VerificationResponse
 }
 var file_grpcapi_proto_depIdxs = []int32{
 	1,  // 0: grpcapi.TLSSignRequest.hashtype:type_name -> grpcapi.HashFunction
-	2,  // 1: grpcapi.TLSSignRequest.pssOpts:type_name -> grpcapi.PSSOptions
+	3,  // 1: grpcapi.TLSSignRequest.pssOpts:type_name -> grpcapi.PSSOptions
 	0,  // 2: grpcapi.TLSSignResponse.status:type_name -> grpcapi.Status
 	0,  // 3: grpcapi.TLSCertResponse.status:type_name -> grpcapi.Status
 	0,  // 4: grpcapi.AttestationResponse.status:type_name -> grpcapi.Status
-	0,  // 5: grpcapi.VerificationResponse.status:type_name -> grpcapi.Status
-	3,  // 6: grpcapi.CMCService.TLSSign:input_type -> grpcapi.TLSSignRequest
-	5,  // 7: grpcapi.CMCService.TLSCert:input_type -> grpcapi.TLSCertRequest
-	7,  // 8: grpcapi.CMCService.Attest:input_type -> grpcapi.AttestationRequest
-	9,  // 9: grpcapi.CMCService.Verify:input_type -> grpcapi.VerificationRequest
-	4,  // 10: grpcapi.CMCService.TLSSign:output_type -> grpcapi.TLSSignResponse
-	6,  // 11: grpcapi.CMCService.TLSCert:output_type -> grpcapi.TLSCertResponse
-	8,  // 12: grpcapi.CMCService.Attest:output_type -> grpcapi.AttestationResponse
-	10, // 13: grpcapi.CMCService.Verify:output_type -> grpcapi.VerificationResponse
-	10, // [10:14] is the sub-list for method output_type
-	6,  // [6:10] is the sub-list for method input_type
-	6,  // [6:6] is the sub-list for extension type_name
-	6,  // [6:6] is the sub-list for extension extendee
-	0,  // [0:6] is the sub-list for field type_name
+	2,  // 5: grpcapi.VerificationRequest.result_format:type_name -> grpcapi.ResultFormat
+	0,  // 6: grpcapi.VerificationResponse.status:type_name -> grpcapi.Status
+	4,  // 7: grpcapi.CMCService.TLSSign:input_type -> grpcapi.TLSSignRequest
+	6,  // 8: grpcapi.CMCService.TLSCert:input_type -> grpcapi.TLSCertRequest
+	8,  // 9: grpcapi.CMCService.Attest:input_type -> grpcapi.AttestationRequest
+	10, // 10: grpcapi.CMCService.Verify:input_type -> grpcapi.VerificationRequest
+	5,  // 11: grpcapi.CMCService.TLSSign:output_type -> grpcapi.TLSSignResponse
+	7,  // 12: grpcapi.CMCService.TLSCert:output_type -> grpcapi.TLSCertResponse
+	9,  // 13: grpcapi.CMCService.Attest:output_type -> grpcapi.AttestationResponse
+	11, // 14: grpcapi.CMCService.Verify:output_type -> grpcapi.VerificationResponse
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_grpcapi_proto_init() }
@@ -961,7 +1028,7 @@ func file_grpcapi_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_grpcapi_proto_rawDesc,
-			NumEnums:      2,
+			NumEnums:      3,
 			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
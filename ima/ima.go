@@ -17,12 +17,19 @@ package ima
 
 import (
 	"bytes"
-	"crypto/sha256"
+	"crypto"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
+	"hash"
 	"os"
 	"strings"
 
+	// Register the hash algorithms which might be used by the IMA
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -35,102 +42,429 @@ const (
 	MAX_TCG_EVENT_LEN = 255
 )
 
+const (
+	runtimeMeasurementsPath = "/sys/kernel/security/ima/binary_runtime_measurements"
+
+	// Upper bound for a single template data field to detect corrupted logs
+	maxFieldLen = 1 << 20
+)
+
+// Template field identifiers as defined by the linux kernel IMA
+const (
+	fieldDigestNg     = "d-ng"
+	fieldNameNg       = "n-ng"
+	fieldSig          = "sig"
+	fieldBuf          = "buf"
+	fieldDigestModsig = "d-modsig"
+	fieldModsig       = "modsig"
+)
+
+// Template field descriptors of the templates supported by the kernel IMA. The
+// legacy 'ima' template has a special binary format and is handled separately
+var templateFields = map[string][]string{
+	"ima-ng":     {fieldDigestNg, fieldNameNg},
+	"ima-sig":    {fieldDigestNg, fieldNameNg, fieldSig},
+	"ima-buf":    {fieldDigestNg, fieldNameNg, fieldBuf},
+	"ima-modsig": {fieldDigestNg, fieldNameNg, fieldSig, fieldDigestModsig, fieldModsig},
+}
+
+// Mapping of the IMA hash algorithm names to go crypto hashes
+var hashAlgorithms = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha224": crypto.SHA224,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
 type header struct {
 	Pcr     int32
 	Digest  [SHA1_DIGEST_LEN]byte
 	NameLen uint32
 }
 
-type imaTemplate struct {
-	header  header
-	Name    [MAX_TCG_EVENT_LEN + 1]byte
-	Ptr     uint32
-	DataLen uint32
-	Data    []byte
+// TemplateEntry represents a single entry of the IMA runtime measurement list
+type TemplateEntry struct {
+	Pcr int32
+	// TemplateHash is the SHA-1 template hash as contained in the measurement list
+	TemplateHash [SHA1_DIGEST_LEN]byte
+	TemplateName string
+	// TemplateData contains the raw template data, from which the template hashes
+	// for the different PCR banks are calculated
+	TemplateData []byte
+
+	FileDigestAlg string
+	FileDigest    []byte
+	FileName      string
+	// Signature contains the IMA signature (security.ima extended attribute) of
+	// ima-sig and ima-modsig templates, if present
+	Signature []byte
+	// Buf contains the measured buffer of ima-buf templates
+	Buf []byte
+	// ModSigDigestAlg, ModSigDigest and ModSig contain the appended signature
+	// of ima-modsig templates, if present
+	ModSigDigestAlg string
+	ModSigDigest    []byte
+	ModSig          []byte
+}
+
+// IsViolation returns true if the entry represents a measurement violation. In this
+// case the template hash is zero and the PCR is extended with all 0xff bytes
+func (e *TemplateEntry) IsViolation() bool {
+	return e.TemplateHash == [SHA1_DIGEST_LEN]byte{}
 }
 
-// GetImaRuntimeDigests returns all hashes extended by the IMA into the TPM
-// IMA PCR as read from the sysfs
-func GetImaRuntimeDigests() ([][SHA256_DIGEST_LEN]byte, error) {
-	data, err := os.ReadFile("/sys/kernel/security/ima/binary_runtime_measurements")
+// Digest returns the template hash of the entry as extended into the PCR bank
+// of the specified hash algorithm. Even in case of non-SHA1 PCR banks, the
+// measurement list only contains the SHA1 template hash, so the template hash
+// must be calculated from the template data
+func (e *TemplateEntry) Digest(alg crypto.Hash) ([]byte, error) {
+	if !alg.Available() {
+		return nil, fmt.Errorf("hash algorithm %v not available", alg)
+	}
+	return e.HashDigest(alg.New()), nil
+}
+
+// HashDigest returns the template hash of the entry calculated with the specified
+// hash. This allows calculating the template hash for PCR banks whose hash
+// algorithm is not available as crypto.Hash, such as SM3
+func (e *TemplateEntry) HashDigest(h hash.Hash) []byte {
+	if e.IsViolation() {
+		return bytes.Repeat([]byte{0xff}, h.Size())
+	}
+
+	h.Reset()
+	if e.TemplateName == "ima" {
+		// The legacy template hash is calculated over the file digest and the file
+		// name padded to the maximum event name length
+		name := make([]byte, MAX_TCG_EVENT_LEN+1)
+		copy(name, e.FileName)
+		h.Write(e.FileDigest)
+		h.Write(name)
+	} else {
+		h.Write(e.TemplateData)
+	}
+
+	return h.Sum(nil)
+}
+
+// Sha256 returns the template hash of the entry for the SHA-256 PCR bank
+func (e *TemplateEntry) Sha256() [SHA256_DIGEST_LEN]byte {
+	var digest [SHA256_DIGEST_LEN]byte
+	d, _ := e.Digest(crypto.SHA256)
+	copy(digest[:], d)
+	return digest
+}
+
+// GetImaRuntimeEntries returns all entries of the IMA runtime measurement list
+// as read from the securityfs
+func GetImaRuntimeEntries() ([]TemplateEntry, error) {
+	data, err := os.ReadFile(runtimeMeasurementsPath)
 	if err != nil {
-		log.Error("Failed to load IMA runtime measurements from /sys/kernel/security/ima/binary_runtime_measurements - ", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to load IMA runtime measurements from %v: %w",
+			runtimeMeasurementsPath, err)
 	}
 
-	digests, err := parseImaRuntimeDigests(data)
+	entries, err := ParseImaRuntimeEntries(data)
 	if err != nil {
-		log.Error("Failed to parse IMA runtime digests - ", err)
-		return nil, err
+		return nil, fmt.Errorf("failed to parse IMA runtime measurements: %w", err)
 	}
 
-	return digests, nil
+	return entries, nil
 }
 
-func parseImaRuntimeDigests(data []byte) ([][SHA256_DIGEST_LEN]byte, error) {
+// ParseImaRuntimeEntries parses the binary IMA runtime measurement list
+// (binary_runtime_measurements)
+func ParseImaRuntimeEntries(data []byte) ([]TemplateEntry, error) {
 
 	buf := bytes.NewBuffer(data)
-	digests := make([][SHA256_DIGEST_LEN]byte, 0)
+	entries := make([]TemplateEntry, 0)
 
-	for buf.Len() > 0 {
-		header := header{}
-		err := binary.Read(buf, binary.LittleEndian, &header)
+	for i := 0; buf.Len() > 0; i++ {
+		entry, err := parseTemplateEntry(buf)
 		if err != nil {
-			log.Error("Error Reading binary data: ", err)
-			return nil, err
+			return nil, fmt.Errorf("failed to parse entry %v: %w", i, err)
 		}
 
-		name := make([]byte, header.NameLen)
-		err = binary.Read(buf, binary.LittleEndian, name)
+		log.Tracef("PCR%v %v %v:%v %v", entry.Pcr, entry.TemplateName, entry.FileDigestAlg,
+			hex.EncodeToString(entry.FileDigest), entry.FileName)
+
+		entries = append(entries, *entry)
+	}
+
+	return entries, nil
+}
+
+// ParseImaAsciiEntries parses the ASCII IMA runtime measurement list
+// (ascii_runtime_measurements). As the ASCII list does not contain the raw
+// template data, the template data is reconstructed from the template fields
+// and checked against the SHA-1 template hash of the entry
+func ParseImaAsciiEntries(data []byte) ([]TemplateEntry, error) {
+
+	entries := make([]TemplateEntry, 0)
+
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		entry, err := parseAsciiEntry(line)
 		if err != nil {
-			log.Error("Error Reading binary data: ", err)
-			return nil, err
+			return nil, fmt.Errorf("failed to parse entry %v: %w", i, err)
 		}
 
-		var template imaTemplate
-		template.header = header
-		copy(template.Name[:], name)
+		log.Tracef("PCR%v %v %v:%v %v", entry.Pcr, entry.TemplateName, entry.FileDigestAlg,
+			hex.EncodeToString(entry.FileDigest), entry.FileName)
+
+		entries = append(entries, *entry)
+	}
 
-		log.Trace("PCR: ", header.Pcr)
-		log.Trace("Name: ", string(template.Name[:]))
+	return entries, nil
+}
+
+// parseAsciiEntry parses a single line of the ASCII measurement list, which has the
+// format <pcr> <template hash> <template name> <template fields>. The template fields
+// are separated by a single space, empty fields such as a missing signature result
+// in a trailing space. File names might contain spaces themselves
+func parseAsciiEntry(line string) (*TemplateEntry, error) {
+
+	tokens := strings.Split(line, " ")
+	if len(tokens) < 5 {
+		return nil, fmt.Errorf("unexpected number of fields %v", len(tokens))
+	}
+
+	var pcr int32
+	if _, err := fmt.Sscanf(tokens[0], "%d", &pcr); err != nil {
+		return nil, fmt.Errorf("failed to parse PCR: %w", err)
+	}
+
+	templateHash, err := hex.DecodeString(tokens[1])
+	if err != nil || len(templateHash) != SHA1_DIGEST_LEN {
+		return nil, fmt.Errorf("invalid template hash %v", tokens[1])
+	}
 
-		var len uint32
-		if strings.Compare(string(template.Name[:]), "ima") == 0 {
-			template.DataLen = SHA1_DIGEST_LEN + MAX_TCG_EVENT_LEN + 1
-			len = SHA1_DIGEST_LEN
-		} else {
-			err = binary.Read(buf, binary.LittleEndian, &template.DataLen)
+	entry := &TemplateEntry{
+		Pcr:          pcr,
+		TemplateName: tokens[2],
+	}
+	copy(entry.TemplateHash[:], templateHash)
+
+	fields := tokens[3:]
+	switch entry.TemplateName {
+	case "ima":
+		entry.FileDigestAlg = "sha1"
+		entry.FileDigest, err = hex.DecodeString(fields[0])
+		if err != nil || len(entry.FileDigest) != SHA1_DIGEST_LEN {
+			return nil, fmt.Errorf("invalid file digest %v", fields[0])
+		}
+		entry.FileName = strings.Join(fields[1:], " ")
+	case "ima-ng", "ima-sig", "ima-buf":
+		entry.FileDigestAlg, entry.FileDigest, err = parseAsciiDigest(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file digest: %w", err)
+		}
+		name := fields[1:]
+		if entry.TemplateName != "ima-ng" {
+			// The last field contains the hex encoded signature or buffer
+			if len(name) < 2 {
+				return nil, fmt.Errorf("missing template field of template %v", entry.TemplateName)
+			}
+			last, err := hex.DecodeString(name[len(name)-1])
 			if err != nil {
-				log.Error("Error Reading binary data: ", err)
-				return nil, err
+				return nil, fmt.Errorf("failed to decode template field: %w", err)
+			}
+			if entry.TemplateName == "ima-sig" {
+				entry.Signature = last
+			} else {
+				entry.Buf = last
 			}
-			len = template.DataLen
+			name = name[:len(name)-1]
 		}
+		entry.FileName = strings.Join(name, " ")
+		entry.TemplateData, err = entry.MarshalTemplateData()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create template data: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("IMA template %v not supported in ASCII measurement lists",
+			entry.TemplateName)
+	}
 
-		template.Data = make([]byte, len)
-		err = binary.Read(buf, binary.LittleEndian, template.Data)
+	if !entry.IsViolation() {
+		digest, err := entry.Digest(crypto.SHA1)
 		if err != nil {
-			log.Error("Error Reading binary data: ", err)
-			return nil, err
+			return nil, fmt.Errorf("failed to calculate template hash: %w", err)
+		}
+		if !bytes.Equal(digest, entry.TemplateHash[:]) {
+			return nil, fmt.Errorf("template hash %v does not match template fields (%v)",
+				tokens[1], hex.EncodeToString(digest))
 		}
+	}
 
-		if strings.Compare(string(template.Name[:]), "ima") == 0 {
-			var fieldLen uint32
-			binary.Read(buf, binary.LittleEndian, &fieldLen)
+	return entry, nil
+}
 
-			addData := make([]byte, fieldLen)
-			binary.Read(buf, binary.LittleEndian, addData)
-			template.Data = append(template.Data, addData...)
+// parseAsciiDigest parses a digest in the format <algo>:<hex digest>
+func parseAsciiDigest(s string) (string, []byte, error) {
+	alg, digestHex, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", nil, fmt.Errorf("missing hash algorithm prefix")
+	}
+	digest, err := hex.DecodeString(digestHex)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode digest: %w", err)
+	}
+	if h, ok := hashAlgorithms[alg]; ok && h.Size() != len(digest) {
+		return "", nil, fmt.Errorf("invalid %v digest length %v", alg, len(digest))
+	}
+	return alg, digest, nil
+}
+
+func parseTemplateEntry(buf *bytes.Buffer) (*TemplateEntry, error) {
+
+	var h header
+	err := binary.Read(buf, binary.LittleEndian, &h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if h.NameLen > MAX_TCG_EVENT_LEN {
+		return nil, fmt.Errorf("invalid template name length %v", h.NameLen)
+	}
+
+	name := make([]byte, h.NameLen)
+	err = binary.Read(buf, binary.LittleEndian, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template name: %w", err)
+	}
+
+	entry := &TemplateEntry{
+		Pcr:          h.Pcr,
+		TemplateHash: h.Digest,
+		TemplateName: string(name),
+	}
+
+	if entry.TemplateName == "ima" {
+		err = parseLegacyTemplate(buf, entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %v: %w", entry.TemplateName, err)
+		}
+		return entry, nil
+	}
+
+	var dataLen uint32
+	err = binary.Read(buf, binary.LittleEndian, &dataLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template data length: %w", err)
+	}
+	if int(dataLen) > buf.Len() {
+		return nil, fmt.Errorf("template data length %v exceeds remaining size %v", dataLen, buf.Len())
+	}
+	entry.TemplateData = buf.Next(int(dataLen))
+
+	err = parseTemplateFields(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %v: %w", entry.TemplateName, err)
+	}
+
+	return entry, nil
+}
+
+// parseLegacyTemplate parses the 'ima' template, which consists of the SHA1 file
+// digest without length field, followed by the length-prefixed file name
+func parseLegacyTemplate(buf *bytes.Buffer, entry *TemplateEntry) error {
+	digest := make([]byte, SHA1_DIGEST_LEN)
+	err := binary.Read(buf, binary.LittleEndian, digest)
+	if err != nil {
+		return fmt.Errorf("failed to read file digest: %w", err)
+	}
+
+	fileName, err := readField(buf)
+	if err != nil {
+		return fmt.Errorf("failed to read file name: %w", err)
+	}
+	if len(fileName) > MAX_TCG_EVENT_LEN {
+		return fmt.Errorf("invalid file name length %v", len(fileName))
+	}
+
+	entry.FileDigestAlg = "sha1"
+	entry.FileDigest = digest
+	entry.FileName = string(fileName)
+
+	return nil
+}
+
+// parseTemplateFields parses the length-prefixed template data fields of the
+// non-legacy templates according to the template field descriptors
+func parseTemplateFields(entry *TemplateEntry) error {
+
+	fields, ok := templateFields[entry.TemplateName]
+	if !ok {
+		// Unknown templates can still be extended into the PCR, as the template
+		// hash is calculated over the raw template data
+		log.Warnf("IMA template %v not supported. Not parsing template fields", entry.TemplateName)
+		return nil
+	}
+
+	buf := bytes.NewBuffer(entry.TemplateData)
+	for _, field := range fields {
+		data, err := readField(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read field %v: %w", field, err)
 		}
 
-		// Even in case of SHA256 PCRs, the template hash from IMA is a
-		// SHA1 hash and cannot be used. Instead, the SHA256 hash of the
-		// template must be calculated manually
-		digest := sha256.Sum256(template.Data)
-		log.Trace("Sha256: ", hex.EncodeToString(digest[:]))
+		switch field {
+		case fieldDigestNg:
+			entry.FileDigestAlg, entry.FileDigest, err = parseDigestNg(data)
+		case fieldNameNg:
+			entry.FileName = strings.TrimRight(string(data), "\x00")
+		case fieldSig:
+			entry.Signature = data
+		case fieldBuf:
+			entry.Buf = data
+		case fieldDigestModsig:
+			if len(data) > 0 {
+				entry.ModSigDigestAlg, entry.ModSigDigest, err = parseDigestNg(data)
+			}
+		case fieldModsig:
+			entry.ModSig = data
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse field %v: %w", field, err)
+		}
+	}
+
+	if buf.Len() > 0 {
+		return fmt.Errorf("%v bytes of unexpected template data", buf.Len())
+	}
+
+	return nil
+}
 
-		digests = append(digests, digest)
+// parseDigestNg parses a 'd-ng' field which has the format <algo>:\0<digest>
+func parseDigestNg(data []byte) (string, []byte, error) {
+	sep := bytes.Index(data, []byte(":\x00"))
+	if sep < 0 {
+		return "", nil, fmt.Errorf("missing hash algorithm prefix")
 	}
+	alg := string(data[:sep])
+	digest := data[sep+2:]
 
-	return digests, nil
+	if h, ok := hashAlgorithms[alg]; ok && h.Size() != len(digest) {
+		return "", nil, fmt.Errorf("invalid %v digest length %v", alg, len(digest))
+	}
+
+	return alg, digest, nil
+}
+
+func readField(buf *bytes.Buffer) ([]byte, error) {
+	var fieldLen uint32
+	err := binary.Read(buf, binary.LittleEndian, &fieldLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read field length: %w", err)
+	}
+	if fieldLen > maxFieldLen || int(fieldLen) > buf.Len() {
+		return nil, fmt.Errorf("invalid field length %v", fieldLen)
+	}
+	return buf.Next(int(fieldLen)), nil
 }
@@ -0,0 +1,321 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ima
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseImaRuntimeEntries(t *testing.T) {
+
+	fileDigest := sha256.Sum256([]byte("file content"))
+	legacyDigest := sha1.Sum([]byte("file content"))
+	sig := []byte{0x03, 0x02, 0x04, 0xde, 0xad, 0xbe, 0xef}
+
+	ngData := templateData(digestNg("sha256", fileDigest[:]), nameNg("/usr/bin/bash"))
+	sigData := templateData(digestNg("sha256", fileDigest[:]), nameNg("/usr/bin/ls"), sig)
+	bufData := templateData(digestNg("sha256", fileDigest[:]), nameNg(".builtin_trusted_keys"),
+		[]byte("key"))
+
+	legacyName := make([]byte, MAX_TCG_EVENT_LEN+1)
+	copy(legacyName, "boot_aggregate")
+	legacyHash := sha256.Sum256(append(legacyDigest[:], legacyName...))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+		want    []TemplateEntry
+		wantSha [][]byte
+	}{
+		{
+			name: "Valid Templates",
+			data: concat(
+				entry(10, "ima-ng", ngData),
+				entry(10, "ima-sig", sigData),
+				entry(10, "ima-buf", bufData),
+			),
+			want: []TemplateEntry{
+				{Pcr: 10, TemplateName: "ima-ng", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/bash"},
+				{Pcr: 10, TemplateName: "ima-sig", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/ls", Signature: sig},
+				{Pcr: 10, TemplateName: "ima-buf", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: ".builtin_trusted_keys", Buf: []byte("key")},
+			},
+			wantSha: [][]byte{sha256Sum(ngData), sha256Sum(sigData), sha256Sum(bufData)},
+		},
+		{
+			name: "Legacy Template",
+			data: legacyEntry(10, legacyDigest[:], "boot_aggregate"),
+			want: []TemplateEntry{
+				{Pcr: 10, TemplateName: "ima", FileDigestAlg: "sha1",
+					FileDigest: legacyDigest[:], FileName: "boot_aggregate"},
+			},
+			wantSha: [][]byte{legacyHash[:]},
+		},
+		{
+			name: "Violation",
+			data: violationEntry(10, "ima-ng", ngData),
+			want: []TemplateEntry{
+				{Pcr: 10, TemplateName: "ima-ng", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/bash"},
+			},
+			wantSha: [][]byte{bytes.Repeat([]byte{0xff}, 32)},
+		},
+		{
+			name:    "Truncated Entry",
+			data:    entry(10, "ima-ng", ngData)[:40],
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Digest Field",
+			data:    entry(10, "ima-ng", templateData([]byte("sha256"), nameNg("/usr/bin/bash"))),
+			wantErr: true,
+		},
+		{
+			name:    "Missing Field",
+			data:    entry(10, "ima-sig", ngData),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImaRuntimeEntries(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseImaRuntimeEntries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseImaRuntimeEntries() returned %v entries, want %v", len(got), len(tt.want))
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Pcr != w.Pcr || g.TemplateName != w.TemplateName ||
+					g.FileDigestAlg != w.FileDigestAlg || !bytes.Equal(g.FileDigest, w.FileDigest) ||
+					g.FileName != w.FileName || !bytes.Equal(g.Signature, w.Signature) ||
+					!bytes.Equal(g.Buf, w.Buf) {
+					t.Errorf("entry %v:\n---GOT = %+v\n--WANT = %+v", i, g, w)
+				}
+				d, err := g.Digest(crypto.SHA256)
+				if err != nil {
+					t.Errorf("entry %v: Digest() error = %v", i, err)
+				}
+				if !bytes.Equal(d, tt.wantSha[i]) {
+					t.Errorf("entry %v: Digest() = %x, want %x", i, d, tt.wantSha[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseImaAsciiEntries(t *testing.T) {
+
+	fileDigest := sha256.Sum256([]byte("file content"))
+	legacyDigest := sha1.Sum([]byte("file content"))
+	sig := []byte{0x03, 0x02, 0x04, 0xde, 0xad, 0xbe, 0xef}
+
+	ngData := templateData(digestNg("sha256", fileDigest[:]), nameNg("/usr/bin/bash"))
+	spaceData := templateData(digestNg("sha256", fileDigest[:]), nameNg("/usr/bin/my file"))
+	sigData := templateData(digestNg("sha256", fileDigest[:]), nameNg("/usr/bin/ls"), sig)
+	noSigData := templateData(digestNg("sha256", fileDigest[:]), nameNg("/usr/bin/ls"), nil)
+	bufData := templateData(digestNg("sha256", fileDigest[:]), nameNg(".builtin_trusted_keys"),
+		[]byte("key"))
+
+	legacyName := make([]byte, MAX_TCG_EVENT_LEN+1)
+	copy(legacyName, "boot_aggregate")
+	legacyHash := sha1.Sum(append(legacyDigest[:], legacyName...))
+
+	fd := hex.EncodeToString(fileDigest[:])
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+		want    []TemplateEntry
+		wantSha [][]byte
+	}{
+		{
+			name: "Valid Templates",
+			data: asciiLine(10, ngData, "ima-ng", "sha256:"+fd, "/usr/bin/bash") +
+				asciiLine(10, spaceData, "ima-ng", "sha256:"+fd, "/usr/bin/my file") +
+				asciiLine(10, sigData, "ima-sig", "sha256:"+fd, "/usr/bin/ls", hex.EncodeToString(sig)) +
+				asciiLine(10, noSigData, "ima-sig", "sha256:"+fd, "/usr/bin/ls", "") +
+				asciiLine(10, bufData, "ima-buf", "sha256:"+fd, ".builtin_trusted_keys",
+					hex.EncodeToString([]byte("key"))),
+			want: []TemplateEntry{
+				{Pcr: 10, TemplateName: "ima-ng", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/bash"},
+				{Pcr: 10, TemplateName: "ima-ng", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/my file"},
+				{Pcr: 10, TemplateName: "ima-sig", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/ls", Signature: sig},
+				{Pcr: 10, TemplateName: "ima-sig", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/ls"},
+				{Pcr: 10, TemplateName: "ima-buf", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: ".builtin_trusted_keys", Buf: []byte("key")},
+			},
+			wantSha: [][]byte{sha256Sum(ngData), sha256Sum(spaceData), sha256Sum(sigData),
+				sha256Sum(noSigData), sha256Sum(bufData)},
+		},
+		{
+			name: "Legacy Template",
+			data: fmt.Sprintf("10 %x ima %x boot_aggregate\n", legacyHash, legacyDigest),
+			want: []TemplateEntry{
+				{Pcr: 10, TemplateName: "ima", FileDigestAlg: "sha1",
+					FileDigest: legacyDigest[:], FileName: "boot_aggregate"},
+			},
+			wantSha: [][]byte{sha256Sum(append(legacyDigest[:], legacyName...))},
+		},
+		{
+			name: "Violation",
+			data: fmt.Sprintf("10 %x ima-ng sha256:%v /usr/bin/bash\n", make([]byte, SHA1_DIGEST_LEN), fd),
+			want: []TemplateEntry{
+				{Pcr: 10, TemplateName: "ima-ng", FileDigestAlg: "sha256",
+					FileDigest: fileDigest[:], FileName: "/usr/bin/bash"},
+			},
+			wantSha: [][]byte{bytes.Repeat([]byte{0xff}, 32)},
+		},
+		{
+			name:    "Template Hash Mismatch",
+			data:    asciiLine(10, ngData, "ima-ng", "sha256:"+fd, "/usr/bin/sh"),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid Digest",
+			data:    asciiLine(10, ngData, "ima-ng", "sha256:xyz", "/usr/bin/bash"),
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported Template",
+			data:    asciiLine(10, ngData, "ima-modsig", "sha256:"+fd, "/usr/bin/bash"),
+			wantErr: true,
+		},
+		{
+			name:    "Truncated Line",
+			data:    "10 0000",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImaAsciiEntries([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseImaAsciiEntries() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseImaAsciiEntries() returned %v entries, want %v", len(got), len(tt.want))
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Pcr != w.Pcr || g.TemplateName != w.TemplateName ||
+					g.FileDigestAlg != w.FileDigestAlg || !bytes.Equal(g.FileDigest, w.FileDigest) ||
+					g.FileName != w.FileName || !bytes.Equal(g.Signature, w.Signature) ||
+					!bytes.Equal(g.Buf, w.Buf) {
+					t.Errorf("entry %v:\n---GOT = %+v\n--WANT = %+v", i, g, w)
+				}
+				d, err := g.Digest(crypto.SHA256)
+				if err != nil {
+					t.Errorf("entry %v: Digest() error = %v", i, err)
+				}
+				if !bytes.Equal(d, tt.wantSha[i]) {
+					t.Errorf("entry %v: Digest() = %x, want %x", i, d, tt.wantSha[i])
+				}
+			}
+		})
+	}
+}
+
+func concat(b ...[]byte) []byte {
+	return bytes.Join(b, nil)
+}
+
+func sha256Sum(data []byte) []byte {
+	d := sha256.Sum256(data)
+	return d[:]
+}
+
+func field(data []byte) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func digestNg(alg string, digest []byte) []byte {
+	return append([]byte(alg+":\x00"), digest...)
+}
+
+func nameNg(name string) []byte {
+	return append([]byte(name), 0)
+}
+
+func templateData(fields ...[]byte) []byte {
+	buf := new(bytes.Buffer)
+	for _, f := range fields {
+		buf.Write(field(f))
+	}
+	return buf.Bytes()
+}
+
+func entryWithHash(pcr int32, hash []byte, name string, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, pcr)
+	buf.Write(hash)
+	buf.Write(field([]byte(name)))
+	buf.Write(field(data))
+	return buf.Bytes()
+}
+
+func entry(pcr int32, name string, data []byte) []byte {
+	hash := sha1.Sum(data)
+	return entryWithHash(pcr, hash[:], name, data)
+}
+
+func violationEntry(pcr int32, name string, data []byte) []byte {
+	return entryWithHash(pcr, make([]byte, SHA1_DIGEST_LEN), name, data)
+}
+
+func asciiLine(pcr int32, data []byte, name string, fields ...string) string {
+	hash := sha1.Sum(data)
+	return fmt.Sprintf("%v %x %v %v\n", pcr, hash, name, strings.Join(fields, " "))
+}
+
+func legacyEntry(pcr int32, digest []byte, fileName string) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, pcr)
+	hash := sha1.Sum([]byte(fileName))
+	buf.Write(hash[:])
+	buf.Write(field([]byte("ima")))
+	buf.Write(digest)
+	buf.Write(field([]byte(fileName)))
+	return buf.Bytes()
+}
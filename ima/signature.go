@@ -0,0 +1,165 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ima
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+)
+
+// Constants for IMA signatures (security.ima extended attribute)
+const (
+	EVM_IMA_XATTR_DIGSIG = 0x03
+	DIGSIG_VERSION_2     = 2
+)
+
+// Mapping of the kernel hash algorithm identifiers (enum hash_algo) used in
+// IMA signatures to go crypto hashes
+var sigHashAlgorithms = map[uint8]crypto.Hash{
+	2: crypto.SHA1,
+	4: crypto.SHA256,
+	5: crypto.SHA384,
+	6: crypto.SHA512,
+	7: crypto.SHA224,
+}
+
+// Mapping of the kernel hash algorithm names used in the file digests of the
+// IMA templates to go crypto hashes
+var digestAlgorithms = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha224": crypto.SHA224,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
+
+type signatureV2Header struct {
+	Type     uint8
+	Version  uint8
+	HashAlgo uint8
+	KeyId    [4]byte
+	SigSize  uint16
+}
+
+// VerifySignature verifies an IMA file signature in the format of the
+// security.ima extended attribute (signature version 2) over the specified
+// file digest. The hash algorithm of the signature must match the algorithm of
+// the file digest. The signing certificate is selected from the provided certificates
+// through the key identifier of the signature, which must match the last four
+// bytes of the certificate's subject key identifier. On success, the
+// certificate which verified the signature is returned
+func VerifySignature(sig []byte, fileDigestAlg string, fileDigest []byte, certs []*x509.Certificate,
+) (*x509.Certificate, error) {
+
+	buf := bytes.NewBuffer(sig)
+	var h signatureV2Header
+	err := binary.Read(buf, binary.BigEndian, &h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature header: %w", err)
+	}
+	if h.Type != EVM_IMA_XATTR_DIGSIG {
+		return nil, fmt.Errorf("signature type %v not supported", h.Type)
+	}
+	if h.Version != DIGSIG_VERSION_2 {
+		return nil, fmt.Errorf("signature version %v not supported", h.Version)
+	}
+	if int(h.SigSize) != buf.Len() {
+		return nil, fmt.Errorf("signature size %v does not match remaining size %v", h.SigSize, buf.Len())
+	}
+
+	hash, ok := sigHashAlgorithms[h.HashAlgo]
+	if !ok {
+		return nil, fmt.Errorf("signature hash algorithm %v not supported", h.HashAlgo)
+	}
+	if digestHash, ok := digestAlgorithms[fileDigestAlg]; !ok || digestHash != hash {
+		return nil, fmt.Errorf("file digest algorithm %v does not match signature hash algorithm %v",
+			fileDigestAlg, hash)
+	}
+	if hash.Size() != len(fileDigest) {
+		return nil, fmt.Errorf("file digest length %v does not match signature hash algorithm %v",
+			len(fileDigest), hash)
+	}
+
+	s := buf.Bytes()
+	for _, c := range certs {
+		if !bytes.HasSuffix(c.SubjectKeyId, h.KeyId[:]) {
+			continue
+		}
+
+		switch pub := c.PublicKey.(type) {
+		case *rsa.PublicKey:
+			err = rsa.VerifyPKCS1v15(pub, hash, fileDigest, s)
+		case *ecdsa.PublicKey:
+			if !ecdsa.VerifyASN1(pub, fileDigest, s) {
+				err = fmt.Errorf("ECDSA signature verification failed")
+			}
+		default:
+			err = fmt.Errorf("public key type %T not supported", pub)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify signature with certificate %v: %w",
+				c.Subject.CommonName, err)
+		}
+		return c, nil
+	}
+
+	return nil, fmt.Errorf("no certificate found for signature key ID %v", hex.EncodeToString(h.KeyId[:]))
+}
+
+// MarshalTemplateData creates the binary template data of the entry from its
+// template fields, from which the template hash is calculated
+func (e *TemplateEntry) MarshalTemplateData() ([]byte, error) {
+
+	fields, ok := templateFields[e.TemplateName]
+	if !ok {
+		return nil, fmt.Errorf("IMA template %v not supported", e.TemplateName)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, field := range fields {
+		var data []byte
+		switch field {
+		case fieldDigestNg:
+			data = marshalDigestNg(e.FileDigestAlg, e.FileDigest)
+		case fieldNameNg:
+			data = append([]byte(e.FileName), 0)
+		case fieldSig:
+			data = e.Signature
+		case fieldBuf:
+			data = e.Buf
+		case fieldDigestModsig:
+			if len(e.ModSigDigest) > 0 {
+				data = marshalDigestNg(e.ModSigDigestAlg, e.ModSigDigest)
+			}
+		case fieldModsig:
+			data = e.ModSig
+		}
+		binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func marshalDigestNg(alg string, digest []byte) []byte {
+	return append([]byte(alg+":\x00"), digest...)
+}
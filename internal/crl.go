@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ParseCrl parses a PEM or DER encoded certificate revocation list
+func ParseCrl(data []byte) (*x509.RevocationList, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("unexpected PEM block type %v", block.Type)
+		}
+		data = block.Bytes
+	}
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CRL: %w", err)
+	}
+	return crl, nil
+}
+
+// ParseCrls parses PEM or DER encoded certificate revocation lists
+func ParseCrls(data [][]byte) ([]*x509.RevocationList, error) {
+	crls := make([]*x509.RevocationList, 0, len(data))
+	for i, d := range data {
+		crl, err := ParseCrl(d)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CRL %v: %w", i, err)
+		}
+		crls = append(crls, crl)
+	}
+	return crls, nil
+}
+
+// CheckRevocation checks whether a certificate of the chain, which must be ordered from
+// leaf to root, is revoked by one of the specified CRLs. A CRL is only taken into account
+// for a certificate if it was signed by the issuer of the certificate. In strict mode,
+// expired CRLs fail the check. Otherwise, they are still evaluated, as newer CRLs might
+// not be available in offline environments. The certificates whose revocation status
+// could not be checked, as no CRL of their issuer was present, are returned
+func CheckRevocation(chain []*x509.Certificate, crls []*x509.RevocationList, strict bool) ([]*x509.Certificate, error) {
+
+	if len(chain) == 0 {
+		return nil, errors.New("empty certificate chain")
+	}
+
+	unchecked := make([]*x509.Certificate, 0)
+	for i := 0; i < len(chain)-1; i++ {
+		cert := chain[i]
+		issuer := chain[i+1]
+
+		checked := false
+		for _, crl := range crls {
+			if err := crl.CheckSignatureFrom(issuer); err != nil {
+				// CRL not issued by the issuer of this certificate
+				continue
+			}
+			checked = true
+			if time.Now().After(crl.NextUpdate) {
+				if strict {
+					return nil, fmt.Errorf("CRL issued by %v expired at %v",
+						issuer.Subject.CommonName, crl.NextUpdate)
+				}
+				log.Warnf("CRL issued by %v expired at %v", issuer.Subject.CommonName,
+					crl.NextUpdate)
+			}
+			for _, revoked := range crl.RevokedCertificates {
+				if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return nil, fmt.Errorf("certificate %v (serial number %v) revoked at %v",
+						cert.Subject.CommonName, cert.SerialNumber, revoked.RevocationTime)
+				}
+			}
+		}
+		if !checked {
+			unchecked = append(unchecked, cert)
+		}
+	}
+
+	return unchecked, nil
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestParseCrl(t *testing.T) {
+	key, ca := createCrlTestCa(t, "CA")
+	der := createCrlTestCrl(t, ca, key, nil, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"DER", der, false},
+		{"PEM", pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), false},
+		{"Certificate PEM", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), true},
+		{"Invalid", []byte("abc"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCrl(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckRevocation(t *testing.T) {
+	caKey, ca := createCrlTestCa(t, "CA")
+	otherKey, other := createCrlTestCa(t, "Other CA")
+	leaf := createCrlTestLeaf(t, ca, caKey)
+
+	valid := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Minute)
+	crl := func(issuer *x509.Certificate, key *ecdsa.PrivateKey, revoked *x509.Certificate,
+		nextUpdate time.Time) *x509.RevocationList {
+		c, err := ParseCrl(createCrlTestCrl(t, issuer, key, revoked, nextUpdate))
+		if err != nil {
+			t.Fatalf("failed to parse CRL: %v", err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name          string
+		crls          []*x509.RevocationList
+		strict        bool
+		wantUnchecked int
+		wantErr       bool
+	}{
+		{"No CRLs", nil, false, 1, false},
+		{"Not Revoked", []*x509.RevocationList{crl(ca, caKey, nil, valid)}, false, 0, false},
+		{"Revoked", []*x509.RevocationList{crl(ca, caKey, leaf, valid)}, false, 0, true},
+		{"CRL From Other Issuer", []*x509.RevocationList{crl(other, otherKey, leaf, valid)}, false, 1, false},
+		{"Not Revoked Strict", []*x509.RevocationList{crl(ca, caKey, nil, valid)}, true, 0, false},
+		{"Expired CRL", []*x509.RevocationList{crl(ca, caKey, nil, expired)}, false, 0, false},
+		{"Expired CRL Revoked", []*x509.RevocationList{crl(ca, caKey, leaf, expired)}, false, 0, true},
+		{"Expired CRL Strict", []*x509.RevocationList{crl(ca, caKey, nil, expired)}, true, 0, true},
+		{"Expired CRL From Other Issuer Strict", []*x509.RevocationList{crl(other, otherKey, nil, expired)}, true, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unchecked, err := CheckRevocation([]*x509.Certificate{leaf, ca}, tt.crls, tt.strict)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckRevocation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(unchecked) != tt.wantUnchecked {
+				t.Errorf("CheckRevocation() unchecked = %v, want %v", len(unchecked), tt.wantUnchecked)
+			}
+		})
+	}
+}
+
+func createCrlTestCa(t *testing.T, cn string) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return key, cert
+}
+
+func createCrlTestLeaf(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func createCrlTestCrl(t *testing.T, issuer *x509.Certificate, key *ecdsa.PrivateKey, revoked *x509.Certificate,
+	nextUpdate time.Time,
+) []byte {
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	if revoked != nil {
+		tmpl.RevokedCertificates = []pkix.RevokedCertificate{
+			{SerialNumber: revoked.SerialNumber, RevocationTime: time.Now()},
+		}
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, issuer, key)
+	if err != nil {
+		t.Fatalf("failed to create CRL: %v", err)
+	}
+	return der
+}
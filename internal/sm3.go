@@ -0,0 +1,156 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package internal
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// Sm3Size is the size of an SM3 digest in bytes
+const Sm3Size = 32
+
+const sm3BlockSize = 64
+
+var sm3Iv = [8]uint32{
+	0x7380166f, 0x4914b2b9, 0x172442d7, 0xda8a0600,
+	0xa96f30bc, 0x163138aa, 0xe38dee4d, 0xb0fb0e4e,
+}
+
+// sm3 implements the SM3 cryptographic hash algorithm as specified in
+// GB/T 32905-2016, which is used by TPMs for the SM3_256 PCR bank
+type sm3 struct {
+	h   [8]uint32
+	buf [sm3BlockSize]byte
+	n   int
+	len uint64
+}
+
+// NewSm3 returns a new hash.Hash computing the SM3 digest
+func NewSm3() hash.Hash {
+	d := new(sm3)
+	d.Reset()
+	return d
+}
+
+// Sm3Sum returns the SM3 digest of the data
+func Sm3Sum(data []byte) [Sm3Size]byte {
+	var sum [Sm3Size]byte
+	d := NewSm3()
+	d.Write(data)
+	copy(sum[:], d.Sum(nil))
+	return sum
+}
+
+func (d *sm3) Size() int { return Sm3Size }
+
+func (d *sm3) BlockSize() int { return sm3BlockSize }
+
+func (d *sm3) Reset() {
+	d.h = sm3Iv
+	d.n = 0
+	d.len = 0
+}
+
+func (d *sm3) Write(p []byte) (int, error) {
+	nn := len(p)
+	d.len += uint64(nn)
+	if d.n > 0 {
+		c := copy(d.buf[d.n:], p)
+		d.n += c
+		p = p[c:]
+		if d.n == sm3BlockSize {
+			d.block(d.buf[:])
+			d.n = 0
+		}
+	}
+	for len(p) >= sm3BlockSize {
+		d.block(p[:sm3BlockSize])
+		p = p[sm3BlockSize:]
+	}
+	if len(p) > 0 {
+		d.n = copy(d.buf[:], p)
+	}
+	return nn, nil
+}
+
+func (d *sm3) Sum(in []byte) []byte {
+	// Work on a copy, so that the caller can keep writing
+	c := *d
+
+	// Padding: 0x80, zeros and the message length in bits as 64-bit big endian
+	var pad [sm3BlockSize + 8]byte
+	pad[0] = 0x80
+	padLen := sm3BlockSize - (int(c.len)+8)%sm3BlockSize
+	if padLen == 0 {
+		padLen = sm3BlockSize
+	}
+	binary.BigEndian.PutUint64(pad[padLen:], c.len<<3)
+	c.Write(pad[:padLen+8])
+
+	var digest [Sm3Size]byte
+	for i, v := range c.h {
+		binary.BigEndian.PutUint32(digest[i*4:], v)
+	}
+	return append(in, digest[:]...)
+}
+
+func (d *sm3) block(p []byte) {
+	var w [68]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[i*4:])
+	}
+	for i := 16; i < 68; i++ {
+		x := w[i-16] ^ w[i-9] ^ bits.RotateLeft32(w[i-3], 15)
+		w[i] = x ^ bits.RotateLeft32(x, 15) ^ bits.RotateLeft32(x, 23) ^
+			bits.RotateLeft32(w[i-13], 7) ^ w[i-6]
+	}
+
+	a, b, c, dd, e, f, g, h := d.h[0], d.h[1], d.h[2], d.h[3], d.h[4], d.h[5], d.h[6], d.h[7]
+	for j := 0; j < 64; j++ {
+		var t, ff, gg uint32
+		if j < 16 {
+			t = 0x79cc4519
+			ff = a ^ b ^ c
+			gg = e ^ f ^ g
+		} else {
+			t = 0x7a879d8a
+			ff = (a & b) | (a & c) | (b & c)
+			gg = (e & f) | (^e & g)
+		}
+		ss1 := bits.RotateLeft32(bits.RotateLeft32(a, 12)+e+bits.RotateLeft32(t, j%32), 7)
+		ss2 := ss1 ^ bits.RotateLeft32(a, 12)
+		tt1 := ff + dd + ss2 + (w[j] ^ w[j+4])
+		tt2 := gg + h + ss1 + w[j]
+		dd = c
+		c = bits.RotateLeft32(b, 9)
+		b = a
+		a = tt1
+		h = g
+		g = bits.RotateLeft32(f, 19)
+		f = e
+		e = tt2 ^ bits.RotateLeft32(tt2, 9) ^ bits.RotateLeft32(tt2, 17)
+	}
+
+	d.h[0] ^= a
+	d.h[1] ^= b
+	d.h[2] ^= c
+	d.h[3] ^= dd
+	d.h[4] ^= e
+	d.h[5] ^= f
+	d.h[6] ^= g
+	d.h[7] ^= h
+}
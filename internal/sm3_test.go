@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package internal
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSm3Sum(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{
+			name: "abc",
+			data: []byte("abc"),
+			want: "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0",
+		},
+		{
+			name: "64 Bytes",
+			data: bytes.Repeat([]byte("abcd"), 16),
+			want: "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sm3Sum(tt.data)
+			if hex.EncodeToString(got[:]) != tt.want {
+				t.Errorf("Sm3Sum() = %v, want %v", hex.EncodeToString(got[:]), tt.want)
+			}
+		})
+	}
+}
+
+func TestSm3Write(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 20)
+	want := Sm3Sum(data)
+
+	// Writing the data in chunks of varying size must yield the same digest
+	h := NewSm3()
+	for i := 0; i < len(data); i += 7 {
+		end := i + 7
+		if end > len(data) {
+			end = len(data)
+		}
+		h.Write(data[i:end])
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want[:]) {
+		t.Errorf("NewSm3() = %v, want %v", hex.EncodeToString(got), hex.EncodeToString(want[:]))
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// AMD SEV-SNP product lines
+const (
+	SnpProductMilan   = "Milan"
+	SnpProductGenoa   = "Genoa"
+	SnpProductBergamo = "Bergamo"
+)
+
+const (
+	snpKdsUrl       = "https://kdsintf.amd.com"
+	cpuidFamilyZen3 = 0x19
+)
+
+// GetSnpProduct returns the SEV-SNP product line based on the CPUID family
+// and model as reported in version 3 attestation reports, or an empty string
+// if the product line is unknown
+func GetSnpProduct(family, model uint8) string {
+	if family != cpuidFamilyZen3 {
+		return ""
+	}
+	switch model >> 4 {
+	case 0x0:
+		return SnpProductMilan
+	case 0x1:
+		return SnpProductGenoa
+	case 0xA:
+		return SnpProductBergamo
+	default:
+		return ""
+	}
+}
+
+// GetSnpKdsProduct returns the product name as used by the AMD Key Distribution
+// Service (KDS) for the specified product line. Bergamo and Genoa share the Genoa
+// certificate hierarchy
+func GetSnpKdsProduct(product string) (string, error) {
+	switch strings.ToLower(product) {
+	case strings.ToLower(SnpProductMilan):
+		return SnpProductMilan, nil
+	case strings.ToLower(SnpProductGenoa), strings.ToLower(SnpProductBergamo):
+		return SnpProductGenoa, nil
+	default:
+		return "", fmt.Errorf("unknown SNP product line %v", product)
+	}
+}
+
+// GetSnpCaUrl returns the AMD KDS URL of the ASK and ARK certificates for VCEKs,
+// or of the ASVK and ARK certificates for VLEKs respectively
+func GetSnpCaUrl(product string, vlek bool) (string, error) {
+	p, err := GetSnpKdsProduct(product)
+	if err != nil {
+		return "", err
+	}
+	key := "vcek"
+	if vlek {
+		key = "vlek"
+	}
+	return fmt.Sprintf("%v/%v/v1/%v/cert_chain", snpKdsUrl, key, p), nil
+}
+
+// GetSnpCrlUrl returns the AMD KDS URL of the CRL issued by the ARK for VCEKs
+// or VLEKs respectively
+func GetSnpCrlUrl(product string, vlek bool) (string, error) {
+	p, err := GetSnpKdsProduct(product)
+	if err != nil {
+		return "", err
+	}
+	key := "vcek"
+	if vlek {
+		key = "vlek"
+	}
+	return fmt.Sprintf("%v/%v/v1/%v/crl", snpKdsUrl, key, p), nil
+}
+
+// GetSnpVcekUrl returns the AMD KDS URL of the VCEK for the specified chip ID
+// and TCB
+func GetSnpVcekUrl(product string, chipId []byte, tcb uint64) (string, error) {
+	p, err := GetSnpKdsProduct(product)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v/vcek/v1/%v/%v?blSPL=%v&teeSPL=%v&snpSPL=%v&ucodeSPL=%v",
+		snpKdsUrl, p, hex.EncodeToString(chipId),
+		tcb&0xFF,
+		(tcb>>8)&0xFF,
+		(tcb>>48)&0xFF,
+		(tcb>>56)&0xFF), nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import "testing"
+
+func TestGetSnpProduct(t *testing.T) {
+	tests := []struct {
+		name   string
+		family uint8
+		model  uint8
+		want   string
+	}{
+		{"Milan", 0x19, 0x01, SnpProductMilan},
+		{"Genoa", 0x19, 0x11, SnpProductGenoa},
+		{"Bergamo", 0x19, 0xA0, SnpProductBergamo},
+		{"Unknown Model", 0x19, 0x21, ""},
+		{"Unknown Family", 0x17, 0x01, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetSnpProduct(tt.family, tt.model); got != tt.want {
+				t.Errorf("GetSnpProduct() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSnpCaUrl(t *testing.T) {
+	tests := []struct {
+		name    string
+		product string
+		vlek    bool
+		want    string
+		wantErr bool
+	}{
+		{"Milan VCEK", "Milan", false, "https://kdsintf.amd.com/vcek/v1/Milan/cert_chain", false},
+		{"Genoa VLEK", "genoa", true, "https://kdsintf.amd.com/vlek/v1/Genoa/cert_chain", false},
+		{"Bergamo VCEK", "Bergamo", false, "https://kdsintf.amd.com/vcek/v1/Genoa/cert_chain", false},
+		{"Unknown Product", "Rome", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetSnpCaUrl(tt.product, tt.vlek)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetSnpCaUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetSnpCaUrl() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
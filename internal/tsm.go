@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultTsmPath is the default mount point of the linux configfs-tsm report interface
+const DefaultTsmPath = "/sys/kernel/config/tsm/report"
+
+// TsmReport contains the outputs of a configfs-tsm report request
+type TsmReport struct {
+	Provider string
+	OutBlob  []byte
+	AuxBlob  []byte
+}
+
+// GetTsmReport requests a report with the specified input data (e.g. the nonce) via
+// the linux configfs-tsm report interface. The report entry 'entry' is created below
+// the configfs-tsm root path 'root' if not yet existing. The generation counter is
+// checked to detect concurrent modifications of the entry. If 'provider' is not
+// empty, the report must be generated by the specified provider (e.g. tdx_guest)
+func GetTsmReport(root, entry, provider string, inblob []byte, auxblob bool) (*TsmReport, error) {
+
+	if root == "" {
+		root = DefaultTsmPath
+	}
+	dir := filepath.Join(root, entry)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create configfs-tsm report entry %v: %w", dir, err)
+	}
+	defer func() {
+		if err := os.Remove(dir); err != nil {
+			log.Debugf("Failed to remove configfs-tsm report entry %v: %v", dir, err)
+		}
+	}()
+
+	if err := os.WriteFile(filepath.Join(dir, "inblob"), inblob, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write configfs-tsm inblob: %w", err)
+	}
+
+	generation, err := readTsmAttribute(dir, "generation")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TsmReport{}
+	p, err := readTsmAttribute(dir, "provider")
+	if err != nil {
+		return nil, err
+	}
+	report.Provider = p
+	if provider != "" && report.Provider != provider {
+		return nil, fmt.Errorf("unexpected configfs-tsm provider %v (expected %v)", report.Provider, provider)
+	}
+
+	report.OutBlob, err = os.ReadFile(filepath.Join(dir, "outblob"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configfs-tsm outblob: %w", err)
+	}
+
+	if auxblob {
+		report.AuxBlob, err = os.ReadFile(filepath.Join(dir, "auxblob"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read configfs-tsm auxblob: %w", err)
+		}
+	}
+
+	// The generation is incremented on every write to the inblob. If it changed, the
+	// entry was modified concurrently and the report might not contain our inblob
+	g, err := readTsmAttribute(dir, "generation")
+	if err != nil {
+		return nil, err
+	}
+	if g != generation {
+		return nil, fmt.Errorf("configfs-tsm report entry modified concurrently (generation %v vs. %v)",
+			generation, g)
+	}
+
+	return report, nil
+}
+
+func readTsmAttribute(dir, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", fmt.Errorf("failed to read configfs-tsm %v: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snpdriver
+
+import (
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+)
+
+// GUIDs of the certificate table entries provided by the hypervisor, see
+// the GHCB specification, SNP Extended Guest Request
+const (
+	vcekGuid = "63da758d-e664-4564-adc5-f4b93be8accd"
+	vlekGuid = "a8074bc2-a25a-483e-aae6-39c045a0b8a1"
+	askGuid  = "4ab7b379-bbac-4fe4-a02f-05aef327c782"
+	arkGuid  = "c0b406a4-a803-4952-9743-3fb6014cd0ae"
+)
+
+const certTableEntrySize = 24
+
+type certTableEntry struct {
+	Guid   [16]byte
+	Offset uint32
+	Length uint32
+}
+
+// snpCerts contains the certificates retrieved from the
+// certificate table of an extended report request
+type snpCerts struct {
+	Vcek *x509.Certificate
+	Vlek *x509.Certificate
+	Ask  *x509.Certificate
+	Ark  *x509.Certificate
+}
+
+// parseCertTable parses the certificate table as returned by the SNP_GET_EXT_REPORT
+// ioctl or the configfs-tsm auxblob. The table consists of entries with GUID, offset
+// and length, terminated by an all-zero entry. The certificates are DER encoded
+func parseCertTable(data []byte) (*snpCerts, error) {
+
+	certs := &snpCerts{}
+
+	for off := 0; ; off += certTableEntrySize {
+		if off+certTableEntrySize > len(data) {
+			return nil, fmt.Errorf("certificate table not terminated")
+		}
+
+		var e certTableEntry
+		copy(e.Guid[:], data[off:off+16])
+		e.Offset = binary.LittleEndian.Uint32(data[off+16 : off+20])
+		e.Length = binary.LittleEndian.Uint32(data[off+20 : off+24])
+
+		if e == (certTableEntry{}) {
+			break
+		}
+
+		if uint64(e.Offset)+uint64(e.Length) > uint64(len(data)) {
+			return nil, fmt.Errorf("certificate table entry %v exceeds table (offset %v, length %v)",
+				guidString(e.Guid), e.Offset, e.Length)
+		}
+
+		var cert **x509.Certificate
+		switch guidString(e.Guid) {
+		case vcekGuid:
+			cert = &certs.Vcek
+		case vlekGuid:
+			cert = &certs.Vlek
+		case askGuid:
+			cert = &certs.Ask
+		case arkGuid:
+			cert = &certs.Ark
+		default:
+			log.Debugf("Ignoring unknown certificate table entry %v", guidString(e.Guid))
+			continue
+		}
+
+		c, err := x509.ParseCertificate(data[e.Offset : e.Offset+e.Length])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate %v: %w", guidString(e.Guid), err)
+		}
+		*cert = c
+	}
+
+	return certs, nil
+}
+
+// guidString returns the string representation of a GUID. The hypervisor writes
+// the GUIDs of the certificate table in RFC 4122 byte order
+func guidString(g [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", g[0:4], g[4:6], g[6:8], g[8:10], g[10:16])
+}
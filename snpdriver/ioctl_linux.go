@@ -0,0 +1,182 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snpdriver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// DefaultDevicePath is the default path of the linux SEV guest device
+	DefaultDevicePath = "/dev/sev-guest"
+
+	// ioctl numbers: _IOWR('S', nr, struct snp_guest_request_ioctl), see linux/sev-guest.h
+	snpGetReport    = 0xc0205300
+	snpGetExtReport = 0xc0205302
+
+	msgVersion = 1
+
+	// The hypervisor returns SNP_GUEST_VMM_ERR_INVALID_LEN if the certificate
+	// buffer is too small and sets the required length
+	vmmErrInvalidLen = 1
+
+	pageSize        = 4096
+	defaultCertsLen = 4 * pageSize
+	maxCertsLen     = 64 * pageSize
+)
+
+type snpReportReq struct {
+	UserData [64]byte
+	Vmpl     uint32
+	Rsvd     [28]byte
+}
+
+type snpExtReportReq struct {
+	Data         snpReportReq
+	CertsAddress uint64
+	CertsLen     uint32
+	_            [4]byte
+}
+
+type snpReportResp struct {
+	Data [4000]byte
+}
+
+type snpGuestRequestIoctl struct {
+	MsgVersion uint8
+	_          [7]byte
+	ReqData    uint64
+	RespData   uint64
+	ExitInfo2  uint64 // fw_error (low 32 bit), vmm_error (high 32 bit)
+}
+
+// getIoctlReport retrieves the SNP attestation report via the SNP_GET_REPORT ioctl or,
+// if ext is set, the report and the certificate table via the SNP_GET_EXT_REPORT ioctl
+// of the SEV guest device. The returned report contains the MSG_REPORT_RSP header
+func getIoctlReport(devicePath string, reportData [64]byte, ext bool) ([]byte, []byte, error) {
+
+	f, err := os.OpenFile(devicePath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %v: %w", devicePath, err)
+	}
+	defer f.Close()
+
+	req := snpExtReportReq{
+		Data: snpReportReq{
+			UserData: reportData,
+		},
+	}
+	resp := snpReportResp{}
+
+	if !ext {
+		err := ioctl(f, snpGetReport, unsafe.Pointer(&req.Data), &resp)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ioctl SNP_GET_REPORT failed: %w", err)
+		}
+		report, err := trimReport(resp.Data[:])
+		return report, nil, err
+	}
+
+	certs := make([]byte, defaultCertsLen)
+	for {
+		req.CertsAddress = uint64(uintptr(unsafe.Pointer(&certs[0])))
+		req.CertsLen = uint32(len(certs))
+
+		err = ioctl(f, snpGetExtReport, unsafe.Pointer(&req), &resp)
+		runtime.KeepAlive(certs)
+
+		var ierr *ioctlError
+		if errors.As(err, &ierr) && ierr.vmmErr == vmmErrInvalidLen &&
+			int(req.CertsLen) > len(certs) && req.CertsLen <= maxCertsLen {
+			log.Tracef("Certificate buffer too small, retrying with %v bytes", req.CertsLen)
+			certs = make([]byte, req.CertsLen)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("ioctl SNP_GET_EXT_REPORT failed: %w", err)
+		}
+		break
+	}
+
+	report, err := trimReport(resp.Data[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The hypervisor sets the length to zero if no certificates are available
+	if req.CertsLen == 0 {
+		return report, nil, nil
+	}
+
+	return report, certs, nil
+}
+
+type ioctlError struct {
+	errno  syscall.Errno
+	fwErr  uint32
+	vmmErr uint32
+}
+
+func (e *ioctlError) Error() string {
+	return fmt.Sprintf("%v (fw_err: 0x%x, vmm_err: 0x%x)", e.errno, e.fwErr, e.vmmErr)
+}
+
+func (e *ioctlError) Unwrap() error {
+	return e.errno
+}
+
+func ioctl(f *os.File, cmd uintptr, req unsafe.Pointer, resp *snpReportResp) error {
+	data := snpGuestRequestIoctl{
+		MsgVersion: msgVersion,
+		ReqData:    uint64(uintptr(req)),
+		RespData:   uint64(uintptr(unsafe.Pointer(resp))),
+	}
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), cmd, uintptr(unsafe.Pointer(&data)))
+	runtime.KeepAlive(req)
+	runtime.KeepAlive(resp)
+	if errno != 0 {
+		return &ioctlError{
+			errno:  errno,
+			fwErr:  uint32(data.ExitInfo2),
+			vmmErr: uint32(data.ExitInfo2 >> 32),
+		}
+	}
+	return nil
+}
+
+// trimReport checks the status of the MSG_REPORT_RSP and trims the response
+// to the size of the header and the contained report
+func trimReport(resp []byte) ([]byte, error) {
+	if len(resp) < reportHeaderSize {
+		return nil, fmt.Errorf("SNP report response too short (%v bytes)", len(resp))
+	}
+	status := binary.LittleEndian.Uint32(resp[0:4])
+	if status != 0 {
+		return nil, fmt.Errorf("SNP report response returned status 0x%x", status)
+	}
+	size := binary.LittleEndian.Uint32(resp[4:8])
+	if size > uint32(len(resp)-reportHeaderSize) {
+		return nil, fmt.Errorf("invalid SNP report size %v", size)
+	}
+	return resp[:reportHeaderSize+int(size)], nil
+}
@@ -13,30 +13,19 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-#pragma once
+//go:build !linux
 
-struct snp_report_req {
-	uint8_t user_data[64];
-	uint32_t vmpl;
-	uint8_t rsvd[28];
-};
+package snpdriver
 
-struct snp_report_resp {
-	uint8_t data[4000];
-};
+import (
+	"errors"
+)
 
-struct snp_guest_req_ioctl {
-	uint8_t msg_version;
-	uint64_t req_data;
-	uint64_t resp_data;
-	uint64_t fw_err;
-};
+const (
+	// DefaultDevicePath is the default path of the linux SEV guest device
+	DefaultDevicePath = "/dev/sev-guest"
+)
 
-#define SNP_GUEST_REQ_IOC_TYPE	'S'
-
-#define SNP_GET_REPORT _IOWR(SNP_GUEST_REQ_IOC_TYPE, 0x0, struct snp_guest_req_ioctl)
-#define SNP_GET_DERIVED_KEY _IOWR(SNP_GUEST_REQ_IOC_TYPE, 0x1, struct snp_guest_req_ioctl)
-#define SNP_GET_EXT_REPORT _IOWR(SNP_GUEST_REQ_IOC_TYPE, 0x2, struct snp_guest_req_ioctl)
-
-int
-snp_dump_ar(uint8_t *buf, size_t size, uint8_t *user_data, size_t user_data_size);
\ No newline at end of file
+func getIoctlReport(devicePath string, reportData [64]byte, ext bool) ([]byte, []byte, error) {
+	return nil, nil, errors.New("SEV guest device ioctls are only supported on linux")
+}
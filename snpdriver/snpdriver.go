@@ -15,23 +15,18 @@
 
 package snpdriver
 
-/*
-#include <stdint.h>
-#include <stdlib.h>
-#include <stdbool.h>
-#include "ioctl.h"
-*/
-import "C"
 import (
 	"crypto/x509"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
-
 	"net/http"
-	"unsafe"
+	"os"
+	"strings"
+	"sync"
 
 	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
 	"github.com/Fraunhofer-AISEC/cmc/est/client"
@@ -48,66 +43,175 @@ const (
 
 type certFormat int
 
-var (
-	vcekUrlPrefix = "https://kdsintf.amd.com/vcek/v1/Milan/"
-	milanUrl      = "https://kdsintf.amd.com/vcek/v1/Milan/cert_chain"
+const (
+	// InterfaceIoctl selects the report retrieval via the SEV guest device ioctls
+	InterfaceIoctl = "ioctl"
+	// InterfaceConfigfs selects the report retrieval via the configfs-tsm report interface
+	InterfaceConfigfs = "configfs"
+)
+
+const (
+	tsmProvider    = "sev_guest"
+	reportDataSize = 64
+	// Size of the MSG_REPORT_RSP header preceding the attestation report
+	reportHeaderSize = 0x20
+	signingKeyVlek   = 1
 )
 
 // Snp is a structure required for implementing the Measure method
 // of the attestation report Measurer interface
 type Snp struct {
-	certChain []*x509.Certificate
+	mu         sync.Mutex
+	certChain  []*x509.Certificate
+	iface      string
+	devicePath string
+	tsmPath    string
+	entry      string
 }
 
+// Config is the structure for handing over the configuration
+// for an Snp object
 type Config struct {
+	// Url is the address of the provisioning server, which is used to retrieve the
+	// VCEK if the host does not provide the certificates
 	Url string
+	// Interface selects the interface for retrieving the attestation report. Possible
+	// values are 'ioctl' (default) and 'configfs'
+	Interface string
+	// DevicePath is the path of the SEV guest device. If empty, /dev/sev-guest is used
+	DevicePath string
+	// TsmPath is the root path of the configfs-tsm report interface. If empty,
+	// the default path /sys/kernel/config/tsm/report is used
+	TsmPath string
+	// Product is the SEV-SNP product line (Milan, Genoa, Bergamo). If empty, the
+	// product line is derived from the report, with Milan as fallback for reports
+	// not containing the CPUID information
+	Product string
 }
 
+// NewSnpDriver returns a new object for retrieving AMD SEV-SNP attestation reports.
+// The certificate chain is taken from the certificate table provided by the host
+// if available. Otherwise, the VCEK is retrieved via the provisioning server
 func NewSnpDriver(c Config) (*Snp, error) {
 
-	ca, _, err := getCerts(milanUrl, PEM)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get SNP certificate chain: %w", err)
+	snp := &Snp{
+		iface:      strings.ToLower(c.Interface),
+		devicePath: c.DevicePath,
+		tsmPath:    c.TsmPath,
+		entry:      fmt.Sprintf("cmc-snp-%v", os.Getpid()),
+	}
+	if snp.iface == "" {
+		snp.iface = InterfaceIoctl
+	}
+	if snp.devicePath == "" {
+		snp.devicePath = DefaultDevicePath
 	}
-	if len(ca) != 2 {
-		return nil, fmt.Errorf("failed to get SNP certificate chain. Expected 2 certificates, got %v", len(ca))
+	if snp.tsmPath == "" {
+		snp.tsmPath = internal.DefaultTsmPath
+	}
+	if snp.iface != InterfaceIoctl && snp.iface != InterfaceConfigfs {
+		return nil, fmt.Errorf("unknown SNP interface %v", c.Interface)
 	}
 
-	// Fetch the VCEK. TODO as a workaround, we get the parameters through
-	// fetching an initial attestation report and request the VCEK from the
-	// Provisioning server. As soon as we can reliably get the correct TCB,
-	// the host should provide the VCEK
-	arRaw, err := GetSnpMeasurement(make([]byte, 64))
+	// Fetch an initial attestation report together with the certificates provided
+	// by the host
+	arRaw, certTable, err := snp.getReport(make([]byte, reportDataSize), true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get SNP report: %w", err)
 	}
+
 	s, err := ar.DecodeSnpReport(arRaw)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode SNP report: %w", err)
 	}
 
-	// TODO mandate server authentication in the future, otherwise
-	// this step has to happen in a secure environment
-	log.Warn("Creating new EST client without server authentication")
-	estclient := client.NewClient(nil)
+	// The signing key selector of the report specifies whether the reports are signed
+	// with the VCEK or the VLEK (bits 4:2)
+	vlek := ((s.KeyInfo >> 2) & 0x7) == signingKeyVlek
 
-	vcek, err := estclient.SnpEnroll(c.Url, s.ChipId, s.CurrentTcb)
-	if err != nil {
-		return nil, fmt.Errorf("failed to enroll SNP: %w", err)
+	product := getProduct(s.Version, s.CpuidFamId, s.CpuidModId, c.Product)
+	log.Debugf("Using SNP product line %v (VLEK: %v)", product, vlek)
+
+	certs := &snpCerts{}
+	if len(certTable) > 0 {
+		certs, err = parseCertTable(certTable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SNP certificate table: %w", err)
+		}
 	}
 
-	snp := &Snp{
-		certChain: append([]*x509.Certificate{vcek}, ca...),
+	if certs.Ask == nil || certs.Ark == nil {
+		log.Debug("Host did not provide SNP CA certificates. Fetching certificates from AMD KDS")
+		url, err := internal.GetSnpCaUrl(product, vlek)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SNP certificate chain URL: %w", err)
+		}
+		ca, _, err := getCerts(url, PEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SNP certificate chain: %w", err)
+		}
+		if len(ca) != 2 {
+			return nil, fmt.Errorf("failed to get SNP certificate chain. Expected 2 certificates, got %v", len(ca))
+		}
+		certs.Ask = ca[0]
+		certs.Ark = ca[1]
 	}
 
+	var signingCert *x509.Certificate
+	if vlek {
+		// The VLEK is provisioned by the cloud service provider and can only be
+		// retrieved from the host
+		if certs.Vlek == nil {
+			return nil, errors.New("report is signed with VLEK, but host did not provide VLEK")
+		}
+		signingCert = certs.Vlek
+	} else if certs.Vcek != nil {
+		signingCert = certs.Vcek
+	} else {
+		// Fetch the VCEK. As the host did not provide the VCEK, we get the parameters
+		// from the initial attestation report and request the VCEK from the
+		// Provisioning server
+		log.Debug("Host did not provide VCEK. Requesting VCEK from provisioning server")
+
+		// TODO mandate server authentication in the future, otherwise
+		// this step has to happen in a secure environment
+		log.Warn("Creating new EST client without server authentication")
+		estclient := client.NewClient(nil)
+
+		signingCert, err = estclient.SnpEnroll(c.Url, s.ChipId, s.CurrentTcb, product)
+		if err != nil {
+			return nil, fmt.Errorf("failed to enroll SNP: %w", err)
+		}
+	}
+
+	snp.certChain = []*x509.Certificate{signingCert, certs.Ask, certs.Ark}
+
 	return snp, nil
 }
 
+// getProduct returns the configured product line, or the product line derived from the
+// CPUID information of version 3 and higher reports, or Milan otherwise
+func getProduct(version uint32, family, model uint8, product string) string {
+	if product != "" {
+		return product
+	}
+	if version >= 3 {
+		if p := internal.GetSnpProduct(family, model); p != "" {
+			return p
+		}
+	}
+	return internal.SnpProductMilan
+}
+
 // Measure implements the attestation reports generic Measure interface to be called
 // as a plugin during attestation report generation
-func (snp Snp) Measure(nonce []byte) (ar.Measurement, error) {
+func (snp *Snp) Measure(nonce []byte) (ar.Measurement, error) {
+
+	if snp == nil {
+		return ar.SnpMeasurement{}, errors.New("internal error: snp object not initialized")
+	}
 
-	data, err := GetSnpMeasurement(nonce)
+	data, err := snp.GetSnpMeasurement(nonce)
 	if err != nil {
 		return ar.SnpMeasurement{}, fmt.Errorf("failed to get SNP Measurement: %w", err)
 	}
@@ -123,33 +227,51 @@ func (snp Snp) Measure(nonce []byte) (ar.Measurement, error) {
 
 // GetSnpMeasurement retrieves the AMD SEV-SNP attestation report
 // and returns it as a byte array
-func GetSnpMeasurement(nonce []byte) ([]byte, error) {
-
-	if len(nonce) > 64 {
-		return nil, errors.New("user Data must be at most 64 bytes")
-	}
+func (snp *Snp) GetSnpMeasurement(nonce []byte) ([]byte, error) {
 
 	log.Tracef("Generating SNP attestation report with nonce: %v", hex.EncodeToString(nonce))
 
-	buf := make([]byte, 4000)
-	cBuf := (*C.uint8_t)(unsafe.Pointer(&buf[0]))
-	cLen := C.size_t(4000)
-	cUserData := (*C.uint8_t)(unsafe.Pointer(&nonce[0]))
-	cUserDataLen := C.size_t(len(nonce))
-
-	cRes, err := C.snp_dump_ar(cBuf, cLen, cUserData, cUserDataLen)
+	report, _, err := snp.getReport(nonce, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve SNP AR: %w", err)
 	}
 
-	res := int(cRes)
-	if res != 0 {
-		return nil, fmt.Errorf("failed to retrieve SNP AR. C.snp_dump_ar returned %v", res)
+	log.Trace("Generated SNP attestation report")
+
+	return report, nil
+}
+
+// getReport retrieves the attestation report via the configured interface. If
+// ext is set, the certificate table provided by the host is returned as well. The
+// report is always returned including the MSG_REPORT_RSP header
+func (snp *Snp) getReport(nonce []byte, ext bool) ([]byte, []byte, error) {
+
+	if len(nonce) > reportDataSize {
+		return nil, nil, fmt.Errorf("user Data must be at most %v bytes", reportDataSize)
 	}
 
-	log.Trace("Generated SNP attestation report")
+	var reportData [reportDataSize]byte
+	copy(reportData[:], nonce)
+
+	snp.mu.Lock()
+	defer snp.mu.Unlock()
+
+	if snp.iface == InterfaceIoctl {
+		return getIoctlReport(snp.devicePath, reportData, ext)
+	}
+
+	tsmReport, err := internal.GetTsmReport(snp.tsmPath, snp.entry, tsmProvider, reportData[:], ext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The configfs-tsm interface only returns the report itself. Prepend the
+	// MSG_REPORT_RSP header, which is expected by the verifier
+	report := make([]byte, reportHeaderSize, reportHeaderSize+len(tsmReport.OutBlob))
+	binary.LittleEndian.PutUint32(report[4:8], uint32(len(tsmReport.OutBlob)))
+	report = append(report, tsmReport.OutBlob...)
 
-	return buf, nil
+	return report, tsmReport.AuxBlob, nil
 }
 
 func getCerts(url string, format certFormat) ([]*x509.Certificate, int, error) {
@@ -0,0 +1,305 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package snpdriver
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+	"github.com/Fraunhofer-AISEC/cmc/internal"
+)
+
+func Test_parseCertTable(t *testing.T) {
+
+	vcek := createCert(t, "VCEK")
+	ask := createCert(t, "ASK")
+	ark := createCert(t, "ARK")
+
+	tests := []struct {
+		name     string
+		table    []byte
+		wantVcek bool
+		wantAsk  bool
+		wantArk  bool
+		wantErr  bool
+	}{
+		{
+			name:     "Complete Table",
+			table:    createCertTable(t, map[string][]byte{vcekGuid: vcek.Raw, askGuid: ask.Raw, arkGuid: ark.Raw}),
+			wantVcek: true,
+			wantAsk:  true,
+			wantArk:  true,
+		},
+		{
+			name:     "VCEK Only",
+			table:    createCertTable(t, map[string][]byte{vcekGuid: vcek.Raw}),
+			wantVcek: true,
+		},
+		{
+			name:  "Unknown Entry",
+			table: createCertTable(t, map[string][]byte{"00000000-0000-0000-0000-000000000001": {0x1}}),
+		},
+		{
+			name:    "Invalid Certificate",
+			table:   createCertTable(t, map[string][]byte{vcekGuid: {0x1, 0x2, 0x3}}),
+			wantErr: true,
+		},
+		{
+			name:    "Not Terminated",
+			table:   createCertTable(t, map[string][]byte{vcekGuid: vcek.Raw})[:certTableEntrySize],
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			certs, err := parseCertTable(tt.table)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCertTable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (certs.Vcek != nil) != tt.wantVcek {
+				t.Errorf("parseCertTable() VCEK = %v, want %v", certs.Vcek != nil, tt.wantVcek)
+			}
+			if (certs.Ask != nil) != tt.wantAsk {
+				t.Errorf("parseCertTable() ASK = %v, want %v", certs.Ask != nil, tt.wantAsk)
+			}
+			if (certs.Ark != nil) != tt.wantArk {
+				t.Errorf("parseCertTable() ARK = %v, want %v", certs.Ark != nil, tt.wantArk)
+			}
+		})
+	}
+}
+
+func Test_guidString(t *testing.T) {
+	tests := []struct {
+		name string
+		guid [16]byte
+		want string
+	}{
+		{
+			name: "VCEK",
+			guid: [16]byte{0x63, 0xda, 0x75, 0x8d, 0xe6, 0x64, 0x45, 0x64,
+				0xad, 0xc5, 0xf4, 0xb9, 0x3b, 0xe8, 0xac, 0xcd},
+			want: vcekGuid,
+		},
+		{
+			name: "VLEK",
+			guid: [16]byte{0xa8, 0x07, 0x4b, 0xc2, 0xa2, 0x5a, 0x48, 0x3e,
+				0xaa, 0xe6, 0x39, 0xc0, 0x45, 0xa0, 0xb8, 0xa1},
+			want: vlekGuid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guidString(tt.guid); got != tt.want {
+				t.Errorf("guidString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasureConfigfs(t *testing.T) {
+
+	vcek := createCert(t, "VCEK")
+	ask := createCert(t, "ASK")
+	ark := createCert(t, "ARK")
+	table := createCertTable(t, map[string][]byte{vcekGuid: vcek.Raw, askGuid: ask.Raw, arkGuid: ark.Raw})
+
+	report := make([]byte, 1184)
+	report[0] = 0x2
+
+	root := createFakeTsm(t, tsmProvider, report, table)
+
+	snp, err := NewSnpDriver(Config{Interface: "configfs", TsmPath: root})
+	if err != nil {
+		t.Fatalf("NewSnpDriver() error = %v", err)
+	}
+
+	nonce := []byte{0x1, 0x2, 0x3}
+	m, err := snp.Measure(nonce)
+	if err != nil {
+		t.Fatalf("Measure() error = %v", err)
+	}
+	snpM, ok := m.(ar.SnpMeasurement)
+	if !ok {
+		t.Fatalf("Measure() returned %T, want ar.SnpMeasurement", m)
+	}
+
+	if len(snpM.Report) != reportHeaderSize+len(report) {
+		t.Fatalf("Measure() report length = %v, want %v", len(snpM.Report), reportHeaderSize+len(report))
+	}
+	if size := binary.LittleEndian.Uint32(snpM.Report[4:8]); size != uint32(len(report)) {
+		t.Errorf("Measure() report size = %v, want %v", size, len(report))
+	}
+	if !bytes.Equal(snpM.Report[reportHeaderSize:], report) {
+		t.Errorf("Measure() report does not match outblob")
+	}
+
+	if len(snpM.Certs) != 3 {
+		t.Fatalf("Measure() returned %v certs, want 3", len(snpM.Certs))
+	}
+	for i, c := range []*x509.Certificate{vcek, ask, ark} {
+		if !bytes.Equal(snpM.Certs[i], internal.WriteCertPem(c)) {
+			t.Errorf("Measure() cert %v does not match %v", i, c.Subject.CommonName)
+		}
+	}
+
+	inblob, err := os.ReadFile(filepath.Join(root, snp.entry, "inblob"))
+	if err != nil {
+		t.Fatalf("failed to read inblob: %v", err)
+	}
+	want := make([]byte, reportDataSize)
+	copy(want, nonce)
+	if !bytes.Equal(inblob, want) {
+		t.Errorf("inblob = %v, want %v", inblob, want)
+	}
+
+	if _, err := snp.Measure(make([]byte, 65)); err == nil {
+		t.Errorf("Measure() expected error for too long nonce")
+	}
+}
+
+func TestNewSnpDriver(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"Unknown Interface", Config{Interface: "unknown"}},
+		{"Missing Device", Config{Interface: "ioctl", DevicePath: filepath.Join(t.TempDir(), "sev-guest")}},
+		{"Wrong Provider", Config{Interface: "configfs", TsmPath: createFakeTsm(t, "tdx_guest", nil, nil)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSnpDriver(tt.config); err == nil {
+				t.Errorf("NewSnpDriver() expected error")
+			}
+		})
+	}
+}
+
+func Test_trimReport(t *testing.T) {
+	resp := make([]byte, 4000)
+	binary.LittleEndian.PutUint32(resp[4:8], 1184)
+
+	report, err := trimReport(resp)
+	if err != nil {
+		t.Fatalf("trimReport() error = %v", err)
+	}
+	if len(report) != reportHeaderSize+1184 {
+		t.Errorf("trimReport() length = %v, want %v", len(report), reportHeaderSize+1184)
+	}
+
+	binary.LittleEndian.PutUint32(resp[0:4], 0x16)
+	if _, err := trimReport(resp); err == nil {
+		t.Errorf("trimReport() expected error for status != 0")
+	}
+
+	binary.LittleEndian.PutUint32(resp[0:4], 0)
+	binary.LittleEndian.PutUint32(resp[4:8], 4000)
+	if _, err := trimReport(resp); err == nil {
+		t.Errorf("trimReport() expected error for invalid size")
+	}
+}
+
+// createFakeTsm creates a fake configfs-tsm report entry as the kernel would
+// create it when the report entry directory is created
+func createFakeTsm(t *testing.T, provider string, outblob, auxblob []byte) string {
+	root := t.TempDir()
+	entry := filepath.Join(root, fmt.Sprintf("cmc-snp-%v", os.Getpid()))
+	if err := os.MkdirAll(entry, 0755); err != nil {
+		t.Fatalf("failed to create fake tsm entry: %v", err)
+	}
+	files := map[string][]byte{
+		"provider":   []byte(provider + "\n"),
+		"generation": []byte("1\n"),
+		"outblob":    outblob,
+		"auxblob":    auxblob,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(entry, name), data, 0644); err != nil {
+			t.Fatalf("failed to create fake tsm attribute %v: %v", name, err)
+		}
+	}
+	return root
+}
+
+func createCertTable(t *testing.T, certs map[string][]byte) []byte {
+	guids := make([]string, 0, len(certs))
+	for g := range certs {
+		guids = append(guids, g)
+	}
+
+	table := new(bytes.Buffer)
+	data := new(bytes.Buffer)
+	offset := (len(guids) + 1) * certTableEntrySize
+	for _, g := range guids {
+		raw, err := hex.DecodeString(strings.ReplaceAll(g, "-", ""))
+		if err != nil || len(raw) != 16 {
+			t.Fatalf("failed to decode GUID %v: %v", g, err)
+		}
+		var guid [16]byte
+		copy(guid[:], raw)
+
+		binary.Write(table, binary.LittleEndian, certTableEntry{
+			Guid:   guid,
+			Offset: uint32(offset + data.Len()),
+			Length: uint32(len(certs[g])),
+		})
+		data.Write(certs[g])
+	}
+	table.Write(make([]byte, certTableEntrySize))
+	table.Write(data.Bytes())
+	return table.Bytes()
+}
+
+func createCert(t *testing.T, cn string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
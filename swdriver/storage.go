@@ -0,0 +1,268 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package swdriver
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/Fraunhofer-AISEC/cmc/internal"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	keyFile   = "sw_key.json"
+	chainFile = "sw_chain.pem"
+)
+
+// Key protection mechanisms of the persisted private key
+const (
+	protectionNone       = "none"
+	protectionPassphrase = "passphrase"
+	protectionTpm        = "tpm"
+)
+
+const (
+	aesKeySize = 32
+	saltSize   = 16
+	// scrypt parameters as recommended for interactive logins
+	scryptN = 32768
+	scryptR = 8
+	scryptP = 1
+)
+
+// protectedKey is the persisted private key. Unless the protection is 'none', the
+// key is encrypted with AES-GCM. The AES key is either sealed to the TPM or derived
+// from the passphrase
+type protectedKey struct {
+	Protection string `json:"protection"`
+	SealedKey  []byte `json:"sealedKey,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce,omitempty"`
+	Key        []byte `json:"key"`
+}
+
+// loadIdentity loads the persisted private key and certificate chain. If no identity
+// was persisted or the certificate expired, nil is returned
+func loadIdentity(c *Config) (*ecdsa.PrivateKey, []*x509.Certificate, error) {
+
+	data, err := os.ReadFile(path.Join(c.StoragePath, chainFile))
+	if errors.Is(err, os.ErrNotExist) {
+		log.Debug("No software identity persisted")
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("failed to read certificate chain: %w", err)
+	}
+	certChain, err := internal.ParseCerts(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate chain: %w", err)
+	}
+	if len(certChain) == 0 {
+		return nil, nil, errors.New("persisted certificate chain is empty")
+	}
+	if time.Now().After(certChain[0].NotAfter) {
+		log.Infof("Software certificate expired %v", certChain[0].NotAfter)
+		return nil, nil, nil
+	}
+
+	data, err = os.ReadFile(path.Join(c.StoragePath, keyFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+	pk := new(protectedKey)
+	err = json.Unmarshal(data, pk)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal private key: %w", err)
+	}
+
+	// Do not accept keys with a different protection, e.g. a plain key if sealing
+	// is configured
+	if pk.Protection != getProtection(c) {
+		return nil, nil, fmt.Errorf("persisted key protection %v does not match configured protection %v",
+			pk.Protection, getProtection(c))
+	}
+
+	der := pk.Key
+	if pk.Protection != protectionNone {
+		aesKey, err := getAesKey(c, pk)
+		if err != nil {
+			return nil, nil, err
+		}
+		der, err = decrypt(aesKey, pk.Nonce, pk.Key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+	}
+
+	priv, err := x509.ParseECPrivateKey(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	if !publicKeyEqual(&priv.PublicKey, certChain[0].PublicKey) {
+		return nil, nil, errors.New("private key does not match certificate")
+	}
+
+	return priv, certChain, nil
+}
+
+// storeIdentity persists the private key, protected as configured, and the
+// certificate chain
+func storeIdentity(c *Config, priv *ecdsa.PrivateKey, certChain []*x509.Certificate) error {
+
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %w", err)
+	}
+
+	pk := &protectedKey{
+		Protection: getProtection(c),
+		Key:        der,
+	}
+
+	switch pk.Protection {
+	case protectionTpm:
+		aesKey := make([]byte, aesKeySize)
+		if _, err := rand.Read(aesKey); err != nil {
+			return fmt.Errorf("failed to generate key: %w", err)
+		}
+		pk.SealedKey, err = c.Sealer.Seal(aesKey)
+		if err != nil {
+			return fmt.Errorf("failed to seal key: %w", err)
+		}
+		pk.Nonce, pk.Key, err = encrypt(aesKey, der)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+	case protectionPassphrase:
+		pk.Salt = make([]byte, saltSize)
+		if _, err := rand.Read(pk.Salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		aesKey, err := getAesKey(c, pk)
+		if err != nil {
+			return err
+		}
+		pk.Nonce, pk.Key, err = encrypt(aesKey, der)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt private key: %w", err)
+		}
+	default:
+		log.Warn("Storing unprotected software private key")
+	}
+
+	data, err := json.Marshal(pk)
+	if err != nil {
+		return fmt.Errorf("failed to marshal private key: %w", err)
+	}
+	keyPath := path.Join(c.StoragePath, keyFile)
+	if err := os.WriteFile(keyPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %v: %w", keyPath, err)
+	}
+
+	chainPath := path.Join(c.StoragePath, chainFile)
+	if err := os.WriteFile(chainPath, bytes.Join(internal.WriteCertsPem(certChain), nil), 0644); err != nil {
+		return fmt.Errorf("failed to write %v: %w", chainPath, err)
+	}
+
+	return nil
+}
+
+func getProtection(c *Config) string {
+	if c.Sealer != nil {
+		return protectionTpm
+	}
+	if len(c.Passphrase) > 0 {
+		return protectionPassphrase
+	}
+	return protectionNone
+}
+
+// getAesKey unseals the AES key or derives the AES key from the passphrase
+func getAesKey(c *Config, pk *protectedKey) ([]byte, error) {
+	switch pk.Protection {
+	case protectionTpm:
+		aesKey, err := c.Sealer.Unseal(pk.SealedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unseal key: %w", err)
+		}
+		return aesKey, nil
+	case protectionPassphrase:
+		aesKey, err := scrypt.Key(c.Passphrase, pk.Salt, scryptN, scryptR, scryptP, aesKeySize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to derive key: %w", err)
+		}
+		return aesKey, nil
+	default:
+		return nil, fmt.Errorf("unknown key protection %v", pk.Protection)
+	}
+}
+
+func encrypt(key, plaintext []byte) ([]byte, []byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+func decrypt(key, nonce, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGcm(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce size %v", len(nonce))
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func newGcm(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+func publicKeyEqual(a, b interface{}) bool {
+	aPkix, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	bPkix, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aPkix, bPkix)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package swdriver
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// testSealer simulates a TPM whose PCRs can be changed
+type testSealer struct {
+	pcrs []byte
+}
+
+func (s *testSealer) Seal(data []byte) ([]byte, error) {
+	return append(append([]byte{}, s.pcrs...), data...), nil
+}
+
+func (s *testSealer) Unseal(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, s.pcrs) {
+		return nil, errors.New("PCR policy check failed")
+	}
+	return data[len(s.pcrs):], nil
+}
+
+func Test_storeIdentity(t *testing.T) {
+	tests := []struct {
+		name     string
+		store    Config
+		load     Config
+		notAfter time.Time
+		wantErr  bool
+		wantNil  bool
+	}{
+		{
+			name:     "Unprotected",
+			notAfter: time.Now().Add(time.Hour),
+		},
+		{
+			name:     "Passphrase",
+			store:    Config{Passphrase: []byte("secret")},
+			load:     Config{Passphrase: []byte("secret")},
+			notAfter: time.Now().Add(time.Hour),
+		},
+		{
+			name:     "Wrong Passphrase",
+			store:    Config{Passphrase: []byte("secret")},
+			load:     Config{Passphrase: []byte("wrong")},
+			notAfter: time.Now().Add(time.Hour),
+			wantErr:  true,
+		},
+		{
+			name:     "TPM",
+			store:    Config{Sealer: &testSealer{pcrs: []byte{1}}},
+			load:     Config{Sealer: &testSealer{pcrs: []byte{1}}},
+			notAfter: time.Now().Add(time.Hour),
+		},
+		{
+			name:     "TPM PCRs Changed",
+			store:    Config{Sealer: &testSealer{pcrs: []byte{1}}},
+			load:     Config{Sealer: &testSealer{pcrs: []byte{2}}},
+			notAfter: time.Now().Add(time.Hour),
+			wantErr:  true,
+		},
+		{
+			name:     "Protection Downgrade",
+			load:     Config{Sealer: &testSealer{pcrs: []byte{1}}},
+			notAfter: time.Now().Add(time.Hour),
+			wantErr:  true,
+		},
+		{
+			name:     "Certificate Expired",
+			notAfter: time.Now().Add(-time.Hour),
+			wantNil:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.store.StoragePath = dir
+			tt.load.StoragePath = dir
+
+			priv, chain := createIdentity(t, tt.notAfter)
+
+			err := storeIdentity(&tt.store, priv, chain)
+			if err != nil {
+				t.Fatalf("storeIdentity() error = %v", err)
+			}
+
+			if tt.store.Sealer != nil || len(tt.store.Passphrase) > 0 {
+				data, err := os.ReadFile(path.Join(dir, keyFile))
+				if err != nil {
+					t.Fatalf("failed to read key file: %v", err)
+				}
+				der, _ := x509.MarshalECPrivateKey(priv)
+				if bytes.Contains(data, der) {
+					t.Errorf("storeIdentity() stored unprotected key")
+				}
+			}
+
+			gotPriv, gotChain, err := loadIdentity(&tt.load)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadIdentity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tt.wantNil {
+				if gotPriv != nil || gotChain != nil {
+					t.Errorf("loadIdentity() returned expired identity")
+				}
+				return
+			}
+			if !priv.Equal(gotPriv) {
+				t.Errorf("loadIdentity() returned different key")
+			}
+			if len(gotChain) != 1 || !gotChain[0].Equal(chain[0]) {
+				t.Errorf("loadIdentity() returned different certificate chain")
+			}
+		})
+	}
+}
+
+func Test_loadIdentityNotPersisted(t *testing.T) {
+	priv, chain, err := loadIdentity(&Config{StoragePath: t.TempDir()})
+	if err != nil || priv != nil || chain != nil {
+		t.Errorf("loadIdentity() = %v, %v, %v, expected empty identity", priv, chain, err)
+	}
+}
+
+func createIdentity(t *testing.T, notAfter time.Time) (*ecdsa.PrivateKey, []*x509.Certificate) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "device"},
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return priv, []*x509.Certificate{cert}
+}
@@ -40,6 +40,16 @@ type Config struct {
 	Url         string
 	Metadata    [][]byte
 	Serializer  ar.Serializer
+	// Sealer optionally seals the persisted private key to a TPM
+	Sealer Sealer
+	// Passphrase optionally protects the persisted private key, if no Sealer is configured
+	Passphrase []byte
+}
+
+// Sealer seals and unseals data, e.g., to the PCRs of a TPM
+type Sealer interface {
+	Seal(data []byte) ([]byte, error)
+	Unseal(data []byte) ([]byte, error)
 }
 
 // Sw is a struct required for implementing the signer and measurer interfaces
@@ -69,14 +79,47 @@ func NewSwDriver(c Config) (*Sw, error) {
 		}
 	}
 
+	// Reuse the persisted key and certificate chain until the certificate expires
+	priv, certChain, err := loadIdentity(&c)
+	if err != nil {
+		log.Warnf("Failed to load software identity: %v. Enrolling new identity", err)
+	} else if certChain != nil {
+		log.Debugf("Loaded software identity %v valid until %v",
+			certChain[0].Subject.CommonName, certChain[0].NotAfter)
+		sw.certChain = certChain
+		sw.priv = priv
+		return sw, nil
+	}
+
+	priv, certChain, err = enroll(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = storeIdentity(&c, priv, certChain)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store software identity: %w", err)
+	}
+
+	sw.certChain = certChain
+	sw.priv = priv
+
+	return sw, nil
+}
+
+// enroll creates a new private key and enrolls a certificate for the key
+func enroll(c *Config) (*ecdsa.PrivateKey, []*x509.Certificate, error) {
+
+	log.Info("Enrolling new software identity")
+
 	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate private key: %w", err)
+		return nil, nil, fmt.Errorf("failed to generate private key: %w", err)
 	}
 
-	csr, err := createCsr(&c, priv)
+	csr, err := createCsr(c, priv)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create CSRs: %w", err)
+		return nil, nil, fmt.Errorf("failed to create CSRs: %w", err)
 	}
 
 	// Get CA certificates and enroll newly created CSR
@@ -89,31 +132,28 @@ func NewSwDriver(c Config) (*Sw, error) {
 	log.Info("Retrieving CA certs")
 	caCerts, err := estclient.CaCerts(c.Url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve certs: %w", err)
+		return nil, nil, fmt.Errorf("failed to retrieve certs: %w", err)
 	}
 	log.Debug("Received certs:")
 	for _, c := range caCerts {
 		log.Debugf("\t%v", c.Subject.CommonName)
 	}
 	if len(caCerts) == 0 {
-		return nil, fmt.Errorf("no certs provided")
+		return nil, nil, fmt.Errorf("no certs provided")
 	}
 
 	log.Warn("Setting retrieved cert for future authentication")
 	err = estclient.SetCAs([]*x509.Certificate{caCerts[len(caCerts)-1]})
 	if err != nil {
-		return nil, fmt.Errorf("failed to set EST CA: %w", err)
+		return nil, nil, fmt.Errorf("failed to set EST CA: %w", err)
 	}
 
 	cert, err := estclient.SimpleEnroll(c.Url, csr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to enroll cert: %w", err)
+		return nil, nil, fmt.Errorf("failed to enroll cert: %w", err)
 	}
 
-	sw.certChain = append([]*x509.Certificate{cert}, caCerts...)
-	sw.priv = priv
-
-	return sw, nil
+	return priv, append([]*x509.Certificate{cert}, caCerts...), nil
 }
 
 // Lock implements the locking method for the attestation report signer interface
@@ -0,0 +1,120 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdxdriver
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+	"github.com/Fraunhofer-AISEC/cmc/internal"
+	"github.com/sirupsen/logrus"
+)
+
+var log = logrus.WithField("service", "tdxdriver")
+
+const (
+	tsmProvider    = "tdx_guest"
+	reportDataSize = 64
+)
+
+// Tdx is a structure required for implementing the Measure method
+// of the attestation report Measurer interface
+type Tdx struct {
+	mu      sync.Mutex
+	tsmPath string
+	entry   string
+}
+
+// Config is the structure for handing over the configuration
+// for a Tdx object
+type Config struct {
+	// TsmPath is the root path of the configfs-tsm report interface. If empty,
+	// the default path /sys/kernel/config/tsm/report is used
+	TsmPath string
+}
+
+// NewTdxDriver returns a new object for retrieving Intel TDX quotes
+func NewTdxDriver(c Config) (*Tdx, error) {
+
+	tsmPath := c.TsmPath
+	if tsmPath == "" {
+		tsmPath = internal.DefaultTsmPath
+	}
+
+	if _, err := os.Stat(tsmPath); err != nil {
+		return nil, fmt.Errorf("configfs-tsm report interface %v not available: %w", tsmPath, err)
+	}
+
+	tdx := &Tdx{
+		tsmPath: tsmPath,
+		entry:   fmt.Sprintf("cmc-tdx-%v", os.Getpid()),
+	}
+
+	return tdx, nil
+}
+
+// Measure implements the attestation reports generic Measure interface to be called
+// as a plugin during attestation report generation
+func (tdx *Tdx) Measure(nonce []byte) (ar.Measurement, error) {
+
+	if tdx == nil {
+		return ar.TdxMeasurement{}, errors.New("internal error: tdx object not initialized")
+	}
+
+	data, err := tdx.GetTdxMeasurement(nonce)
+	if err != nil {
+		return ar.TdxMeasurement{}, fmt.Errorf("failed to get TDX Measurement: %w", err)
+	}
+
+	measurement := ar.TdxMeasurement{
+		Type:   "TDX Measurement",
+		Report: data,
+	}
+
+	return measurement, nil
+}
+
+// GetTdxMeasurement retrieves the Intel TDX quote via the configfs-tsm
+// report interface and returns it as a byte array
+func (tdx *Tdx) GetTdxMeasurement(nonce []byte) ([]byte, error) {
+
+	if len(nonce) > reportDataSize {
+		return nil, fmt.Errorf("user Data must be at most %v bytes", reportDataSize)
+	}
+
+	log.Tracef("Generating TDX quote with nonce: %v", hex.EncodeToString(nonce))
+
+	// The report data must be exactly 64 bytes
+	reportData := make([]byte, reportDataSize)
+	copy(reportData, nonce)
+
+	// The configfs-tsm report entry is shared, requests must therefore be serialized
+	tdx.mu.Lock()
+	defer tdx.mu.Unlock()
+
+	report, err := internal.GetTsmReport(tdx.tsmPath, tdx.entry, tsmProvider, reportData, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get TDX quote: %w", err)
+	}
+
+	log.Trace("Generated TDX quote")
+
+	return report.OutBlob, nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tdxdriver
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+)
+
+// createFakeTsm creates a fake configfs-tsm report entry as the kernel would
+// create it when the report entry directory is created
+func createFakeTsm(t *testing.T, provider string, outblob []byte) string {
+	root := t.TempDir()
+	entry := filepath.Join(root, fmt.Sprintf("cmc-tdx-%v", os.Getpid()))
+	if err := os.MkdirAll(entry, 0755); err != nil {
+		t.Fatalf("failed to create fake tsm entry: %v", err)
+	}
+	files := map[string][]byte{
+		"provider":   []byte(provider + "\n"),
+		"generation": []byte("1\n"),
+		"outblob":    outblob,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(entry, name), data, 0644); err != nil {
+			t.Fatalf("failed to create fake tsm attribute %v: %v", name, err)
+		}
+	}
+	return root
+}
+
+func TestMeasure(t *testing.T) {
+
+	quote := []byte{0x04, 0x00, 0x02, 0x00, 0x81, 0x00, 0x00, 0x00}
+
+	tests := []struct {
+		name     string
+		provider string
+		nonce    []byte
+		wantErr  bool
+	}{
+		{"Valid", "tdx_guest", []byte{0x1, 0x2, 0x3}, false},
+		{"Wrong Provider", "sev_guest", []byte{0x1, 0x2, 0x3}, true},
+		{"Nonce Too Long", "tdx_guest", make([]byte, 65), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := createFakeTsm(t, tt.provider, quote)
+
+			tdx, err := NewTdxDriver(Config{TsmPath: root})
+			if err != nil {
+				t.Fatalf("NewTdxDriver() error = %v", err)
+			}
+
+			m, err := tdx.Measure(tt.nonce)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Measure() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			tdxM, ok := m.(ar.TdxMeasurement)
+			if !ok {
+				t.Fatalf("Measure() returned %T, want ar.TdxMeasurement", m)
+			}
+			if !bytes.Equal(tdxM.Report, quote) {
+				t.Errorf("Measure() report = %v, want %v", tdxM.Report, quote)
+			}
+
+			inblob, err := os.ReadFile(filepath.Join(root, tdx.entry, "inblob"))
+			if err != nil {
+				t.Fatalf("failed to read inblob: %v", err)
+			}
+			want := make([]byte, reportDataSize)
+			copy(want, tt.nonce)
+			if !bytes.Equal(inblob, want) {
+				t.Errorf("inblob = %v, want %v", inblob, want)
+			}
+		})
+	}
+}
+
+func TestNewTdxDriver(t *testing.T) {
+	_, err := NewTdxDriver(Config{TsmPath: filepath.Join(t.TempDir(), "nonexistent")})
+	if err == nil {
+		t.Errorf("NewTdxDriver() expected error for missing configfs-tsm path")
+	}
+}
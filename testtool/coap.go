@@ -22,7 +22,6 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
-	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -127,6 +126,7 @@ func (a CoapApi) verify(c *config) {
 		AttestationReport: data,
 		Ca:                c.ca,
 		Policies:          c.policies,
+		ResultFormat:      c.resultFormat,
 	}
 
 	resp, err := verifyInternal(c.CmcAddr, req)
@@ -134,12 +134,8 @@ func (a CoapApi) verify(c *config) {
 		log.Fatalf("Failed to verify: %v", err)
 	}
 
-	var out bytes.Buffer
-	json.Indent(&out, resp.VerificationResult, "", "    ")
-
 	// Save the Attestation Result
-	os.WriteFile(c.ResultFile, out.Bytes(), 0644)
-	fmt.Println("Wrote file ", c.ResultFile)
+	saveResult(c, resp.VerificationResult)
 }
 
 func (a CoapApi) dial(c *config) {
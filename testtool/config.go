@@ -23,6 +23,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
 	"github.com/Fraunhofer-AISEC/cmc/internal"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/exp/maps"
@@ -45,6 +46,12 @@ var (
 		"trace": logrus.TraceLevel,
 	}
 
+	resultFormats = map[string]ar.ResultFormat{
+		"cmc":     ar.ResultFormat_CmcJson,
+		"ear-jwt": ar.ResultFormat_EarJwt,
+		"ear-cwt": ar.ResultFormat_EarCwt,
+	}
+
 	log = logrus.WithField("service", "testtool")
 )
 
@@ -69,11 +76,13 @@ type config struct {
 	PoliciesFile string `json:"policies"`
 	ApiFlag      string `json:"api"`
 	LogLevel     string `json:"logLevel"`
+	ResultFormat string `json:"resultFormat"`
 
-	ca        []byte
-	policies  []byte
-	api       Api
-	configDir *string
+	ca           []byte
+	policies     []byte
+	api          Api
+	resultFormat ar.ResultFormat
+	configDir    *string
 }
 
 const (
@@ -89,6 +98,7 @@ const (
 	apiFlag      = "api"
 	mtlsFlag     = "mtls"
 	logFlag      = "log"
+	formatFlag   = "resultformat"
 )
 
 func getConfig() *config {
@@ -109,17 +119,20 @@ func getConfig() *config {
 	mtls := flag.Bool(mtlsFlag, false, "Performs mutual TLS with remote attestation on both sides.")
 	logLevel := flag.String(logFlag, "",
 		fmt.Sprintf("Possible logging: %v", maps.Keys(logLevels)))
+	resultFormat := flag.String(formatFlag, "",
+		fmt.Sprintf("Format of the attestation result. Possible: %v", maps.Keys(resultFormats)))
 	flag.Parse()
 
 	// Create default configuration
 	c := &config{
-		Addr:       "0.0.0.0:4443",
-		CmcAddr:    "127.0.0.1:9955",
-		ReportFile: "attestation-report",
-		ResultFile: "attestation-result.json",
-		NonceFile:  "nonce",
-		ApiFlag:    "grpc",
-		LogLevel:   "info",
+		Addr:         "0.0.0.0:4443",
+		CmcAddr:      "127.0.0.1:9955",
+		ReportFile:   "attestation-report",
+		ResultFile:   "attestation-result.json",
+		NonceFile:    "nonce",
+		ApiFlag:      "grpc",
+		LogLevel:     "info",
+		ResultFormat: "cmc",
 	}
 
 	// Obtain custom configuration from file if specified
@@ -171,6 +184,9 @@ func getConfig() *config {
 	if internal.FlagPassed(logFlag) {
 		c.LogLevel = *logLevel
 	}
+	if internal.FlagPassed(formatFlag) {
+		c.ResultFormat = *resultFormat
+	}
 
 	// Configure the logger
 	l, ok := logLevels[strings.ToLower(c.LogLevel)]
@@ -207,6 +223,13 @@ func getConfig() *config {
 		log.Fatalf("API %v is not implemented\n", c.ApiFlag)
 	}
 
+	// Get result format
+	c.resultFormat, ok = resultFormats[strings.ToLower(c.ResultFormat)]
+	if !ok {
+		flag.Usage()
+		log.Fatalf("Result format %v is not implemented\n", c.ResultFormat)
+	}
+
 	return c
 }
 
@@ -223,4 +246,5 @@ func printConfig(c *config) {
 	log.Debugf("\tPoliciesFile : %v", c.PoliciesFile)
 	log.Debugf("\tApiFlag      : %v", c.ApiFlag)
 	log.Debugf("\tLogLevel     : %v", c.LogLevel)
+	log.Debugf("\tResultFormat : %v", c.ResultFormat)
 }
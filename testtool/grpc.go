@@ -19,10 +19,8 @@ package main
 
 // Install github packages with "go get [url]"
 import (
-	"bytes"
 	"context"
 	"crypto/rand"
-	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -32,6 +30,7 @@ import (
 
 	// local modules
 
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
 	"github.com/Fraunhofer-AISEC/cmc/attestedtls"
 	api "github.com/Fraunhofer-AISEC/cmc/grpcapi"
 	"github.com/Fraunhofer-AISEC/cmc/internal"
@@ -119,6 +118,7 @@ func (a GrpcApi) verify(c *config) {
 		AttestationReport: data,
 		Ca:                c.ca,
 		Policies:          c.policies,
+		ResultFormat:      convertResultFormat(c.resultFormat),
 	}
 
 	response, err := client.Verify(ctx, &request)
@@ -129,12 +129,8 @@ func (a GrpcApi) verify(c *config) {
 		log.Warnf("Failed to verify attestation report. Status %v", response.GetStatus())
 	}
 
-	var out bytes.Buffer
-	json.Indent(&out, response.GetVerificationResult(), "", "    ")
-
 	// Save the Attestation Result
-	os.WriteFile(c.ResultFile, out.Bytes(), 0644)
-	fmt.Println("Wrote file ", c.ResultFile)
+	saveResult(c, response.GetVerificationResult())
 }
 
 func (a GrpcApi) dial(c *config) {
@@ -152,3 +148,15 @@ func (a GrpcApi) cacerts(c *config) {
 func (a GrpcApi) iothub(c *config) {
 	log.Fatalf("IoT hub not implemented for gRPC")
 }
+
+// Converts the attestationreport result format to the Protobuf result format
+func convertResultFormat(format ar.ResultFormat) api.ResultFormat {
+	switch format {
+	case ar.ResultFormat_EarJwt:
+		return api.ResultFormat_EAR_JWT
+	case ar.ResultFormat_EarCwt:
+		return api.ResultFormat_EAR_CWT
+	default:
+		return api.ResultFormat_CMC_JSON
+	}
+}
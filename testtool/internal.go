@@ -18,15 +18,18 @@ package main
 // Install github packages with "go get [url]"
 import (
 	"bufio"
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net"
 	"os"
 	"time"
 
 	// local modules
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
 	atls "github.com/Fraunhofer-AISEC/cmc/attestedtls"
 	"github.com/Fraunhofer-AISEC/cmc/est/client"
 	"github.com/Fraunhofer-AISEC/cmc/internal"
@@ -209,3 +212,17 @@ func handleConnection(conn net.Conn) {
 		log.Errorf("Failed to write: %v", err)
 	}
 }
+
+// saveResult stores the attestation result in the result file. JSON verification
+// results are indented, signed EAR tokens are stored as received
+func saveResult(c *config, result []byte) {
+	data := result
+	if c.resultFormat == ar.ResultFormat_CmcJson {
+		var out bytes.Buffer
+		json.Indent(&out, result, "", "    ")
+		data = out.Bytes()
+	}
+
+	os.WriteFile(c.ResultFile, data, 0644)
+	fmt.Println("Wrote file ", c.ResultFile)
+}
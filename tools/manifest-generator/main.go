@@ -0,0 +1,329 @@
+// Copyright(c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the License); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/go-tpm/tpm2"
+	log "github.com/sirupsen/logrus"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+	"github.com/Fraunhofer-AISEC/cmc/ima"
+	"github.com/Fraunhofer-AISEC/cmc/tpmdriver"
+)
+
+const timeLayout = "20060102150405"
+
+func main() {
+	log.SetLevel(log.TraceLevel)
+
+	eventLogFile := flag.String("eventlog", "", "Path to the TPM binary event log (default: securityfs binary_bios_measurements)")
+	imaFile := flag.String("ima", "", "Path to the IMA runtime measurement list in binary or ASCII format (optional)")
+	imaPcr := flag.Int("imapcr", 10, "PCR the IMA measurements are extended into")
+	rtmPcrs := flag.String("rtmpcrs", "0,1,2,3,4,5,6,7", "PCRs to be included in the RTM Manifest, as a comma-separated list")
+	osPcrs := flag.String("ospcrs", "8,9", "PCRs to be included in the OS Manifest, as a comma-separated list. The IMA PCR is always included if -ima is specified")
+	rtmIn := flag.String("rtmin", "", "Path to an RTM Manifest as JSON or CBOR used as template (optional)")
+	osIn := flag.String("osin", "", "Path to an OS Manifest as JSON or CBOR used as template (optional)")
+	rtmName := flag.String("rtmname", "de.test.rtm", "Name of the RTM Manifest, if no template is used")
+	osName := flag.String("osname", "de.test.os", "Name of the OS Manifest, if no template is used")
+	developer := flag.String("developer", "Test Developer", "Developer common name of the manifests, if no template is used")
+	validity := flag.Int("validity", 365, "Validity of the manifests in days, if no template is used")
+	rtmOut := flag.String("rtmout", "", "Path to the output file to save the RTM Manifest")
+	osOut := flag.String("osout", "", "Path to the output file to save the OS Manifest")
+	outForm := flag.String("outform", "json", "Output format (JSON or CBOR)")
+	pcrBanks := flag.String("banks", "SHA256", "PCR banks to create reference values for, as a comma-separated list (SHA1, SHA256, SHA384, SM3_256)")
+	flag.Parse()
+
+	if *rtmOut == "" && *osOut == "" {
+		log.Error("output file(s) not specified (-rtmout, -osout)")
+		flag.Usage()
+		return
+	}
+
+	var so ar.Serializer
+	if strings.EqualFold(*outForm, "json") {
+		so = ar.JsonSerializer{}
+	} else if strings.EqualFold(*outForm, "cbor") {
+		so = ar.CborSerializer{}
+	} else {
+		log.Fatalf("Output format %v not supported (only JSON and CBOR are supported)", *outForm)
+	}
+
+	banks, err := parseBanks(*pcrBanks)
+	if err != nil {
+		log.Fatalf("Failed to parse PCR banks: %v", err)
+	}
+
+	events, err := tpmdriver.GetBiosMeasurements(*eventLogFile)
+	if err != nil {
+		log.Fatalf("Failed to read event log: %v", err)
+	}
+
+	var entries []ima.TemplateEntry
+	if *imaFile != "" {
+		entries, err = readImaEntries(*imaFile)
+		if err != nil {
+			log.Fatalf("Failed to read IMA measurement list: %v", err)
+		}
+	}
+
+	now := time.Now()
+	defaultValidity := ar.Validity{
+		NotBefore: now.Format(timeLayout),
+		NotAfter:  now.AddDate(0, 0, *validity).Format(timeLayout),
+	}
+
+	if *rtmOut != "" {
+		pcrs, err := parsePcrs(*rtmPcrs)
+		if err != nil {
+			log.Fatalf("Failed to parse RTM PCRs: %v", err)
+		}
+		refVals, err := createReferenceValues(events, nil, pcrs, *imaPcr, banks)
+		if err != nil {
+			log.Fatalf("Failed to create RTM reference values: %v", err)
+		}
+
+		m := &ar.RtmManifest{
+			Type:               "RTM Manifest",
+			Name:               *rtmName,
+			DevCommonName:      *developer,
+			Version:            now.Format(timeLayout),
+			Description:        "RTM Manifest",
+			CertificationLevel: 1,
+			Validity:           defaultValidity,
+		}
+		if *rtmIn != "" {
+			if err := readTemplate(*rtmIn, m); err != nil {
+				log.Fatalf("Failed to read RTM Manifest template: %v", err)
+			}
+		}
+		m.ReferenceValues = refVals
+
+		if err := writeManifest(*rtmOut, m, so); err != nil {
+			log.Fatalf("Failed to write RTM Manifest: %v", err)
+		}
+	}
+
+	if *osOut != "" {
+		pcrs, err := parsePcrs(*osPcrs)
+		if err != nil {
+			log.Fatalf("Failed to parse OS PCRs: %v", err)
+		}
+		if *imaFile != "" && !contains(pcrs, *imaPcr) {
+			pcrs = append(pcrs, *imaPcr)
+		}
+		refVals, err := createReferenceValues(events, entries, pcrs, *imaPcr, banks)
+		if err != nil {
+			log.Fatalf("Failed to create OS reference values: %v", err)
+		}
+
+		m := &ar.OsManifest{
+			Type:               "OS Manifest",
+			Name:               *osName,
+			DevCommonName:      *developer,
+			Version:            now.Format(timeLayout),
+			Rtms:               []string{*rtmName},
+			Description:        "OS Manifest",
+			CertificationLevel: 1,
+			Validity:           defaultValidity,
+		}
+		if *osIn != "" {
+			if err := readTemplate(*osIn, m); err != nil {
+				log.Fatalf("Failed to read OS Manifest template: %v", err)
+			}
+		}
+		m.ReferenceValues = refVals
+		// The order of the IMA measurements is not deterministic
+		if *imaFile != "" && !contains(m.UnorderedPcrs, *imaPcr) {
+			m.UnorderedPcrs = append(m.UnorderedPcrs, *imaPcr)
+		}
+
+		if err := writeManifest(*osOut, m, so); err != nil {
+			log.Fatalf("Failed to write OS Manifest: %v", err)
+		}
+	}
+}
+
+// createReferenceValues creates a TPM reference value for each event of the event log
+// and each IMA measurement list entry extended into one of the specified PCRs. The
+// reference values are created in the order of the measurements, which is the order
+// they are extended in during verification. Each reference value contains the digests
+// of all specified PCR banks
+func createReferenceValues(events []tpmdriver.Event, entries []ima.TemplateEntry, pcrs []int,
+	imaPcr int, banks []tpm2.Algorithm,
+) ([]ar.ReferenceValue, error) {
+
+	refVals := make([]ar.ReferenceValue, 0)
+
+	for _, e := range events {
+		if !contains(pcrs, e.Pcr) || e.Type == tpmdriver.EV_NO_ACTION {
+			continue
+		}
+		refVal := newReferenceValue(e.Pcr, eventName(&e))
+		for _, alg := range banks {
+			digest, ok := e.Digests[alg]
+			if !ok {
+				return nil, fmt.Errorf("event %v of PCR%v does not contain %v digest",
+					e.TypeName(), e.Pcr, ar.PcrBankName(alg))
+			}
+			refVal.SetDigest(alg, digest)
+		}
+		refVals = append(refVals, refVal)
+	}
+
+	if contains(pcrs, imaPcr) {
+		for _, e := range entries {
+			if int(e.Pcr) != imaPcr {
+				continue
+			}
+			name := e.FileName
+			if e.IsViolation() {
+				name = "IMA violation: " + e.FileName
+			}
+			refVal := newReferenceValue(imaPcr, name)
+			for _, alg := range banks {
+				h, err := ar.NewPcrHash(alg)
+				if err != nil {
+					return nil, err
+				}
+				refVal.SetDigest(alg, e.HashDigest(h))
+			}
+			refVals = append(refVals, refVal)
+		}
+	}
+
+	log.Debugf("Created %v reference values for PCRs %v", len(refVals), pcrs)
+
+	return refVals, nil
+}
+
+func newReferenceValue(pcr int, name string) ar.ReferenceValue {
+	return ar.ReferenceValue{
+		Type: "TPM Reference Value",
+		Name: name,
+		Pcr:  &pcr,
+	}
+}
+
+// eventName returns a human-readable name of an event consisting of the event
+// type and, if available, a description extracted from the event data
+func eventName(e *tpmdriver.Event) string {
+	if desc := e.Description(); desc != "" {
+		return fmt.Sprintf("%v: %v", e.TypeName(), desc)
+	}
+	return e.TypeName()
+}
+
+// readImaEntries reads an IMA runtime measurement list. The ASCII format is detected
+// by the leading decimal PCR number, whereas the binary format starts with the
+// little endian PCR number
+func readImaEntries(file string) ([]ima.TemplateEntry, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %v: %w", file, err)
+	}
+	if len(data) > 0 && data[0] >= '0' && data[0] <= '9' {
+		log.Debugf("Parsing ASCII IMA measurement list %v", file)
+		return ima.ParseImaAsciiEntries(data)
+	}
+	log.Debugf("Parsing binary IMA measurement list %v", file)
+	return ima.ParseImaRuntimeEntries(data)
+}
+
+// readTemplate reads a manifest in JSON or CBOR format used as template for the
+// generated manifest
+func readTemplate(file string, m any) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %w", file, err)
+	}
+
+	var si ar.Serializer
+	if json.Valid(data) {
+		si = ar.JsonSerializer{}
+	} else if err := cbor.Valid(data); err == nil {
+		si = ar.CborSerializer{}
+	} else {
+		return errors.New("failed to detect serialization (only JSON and CBOR are supported)")
+	}
+
+	if err := si.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("failed to unmarshal: %w", err)
+	}
+	return nil
+}
+
+func writeManifest(file string, m any, s ar.Serializer) error {
+	raw, err := s.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	log.Tracef("Writing output file %v", file)
+	if err := os.WriteFile(file, raw, 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func parsePcrs(s string) ([]int, error) {
+	pcrs := make([]int, 0)
+	if s == "" {
+		return pcrs, nil
+	}
+	for _, p := range strings.Split(s, ",") {
+		pcr, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PCR %v: %w", p, err)
+		}
+		if pcr < 0 || pcr > 23 {
+			return nil, fmt.Errorf("invalid PCR %v", pcr)
+		}
+		pcrs = append(pcrs, pcr)
+	}
+	return pcrs, nil
+}
+
+// parseBanks parses a comma-separated list of PCR bank names
+func parseBanks(s string) ([]tpm2.Algorithm, error) {
+	banks := make([]tpm2.Algorithm, 0)
+	for _, b := range strings.Split(s, ",") {
+		alg, ok := ar.PcrBanks[strings.ToUpper(strings.TrimSpace(b))]
+		if !ok {
+			return nil, fmt.Errorf("PCR bank %v not supported", b)
+		}
+		banks = append(banks, alg)
+	}
+	return banks, nil
+}
+
+func contains(pcrs []int, pcr int) bool {
+	for _, p := range pcrs {
+		if p == pcr {
+			return true
+		}
+	}
+	return false
+}
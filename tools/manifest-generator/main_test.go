@@ -0,0 +1,225 @@
+// Copyright(c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the License); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/go-tpm/tpm2"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+	"github.com/Fraunhofer-AISEC/cmc/ima"
+	"github.com/Fraunhofer-AISEC/cmc/tpmdriver"
+)
+
+func Test_createReferenceValues(t *testing.T) {
+
+	crtm := sha256.Sum256([]byte("crtm"))
+	sb := sha256.Sum256([]byte("secureboot"))
+	sep := sha256.Sum256([]byte{0, 0, 0, 0})
+	grub := sha256.Sum256([]byte("grub"))
+
+	events := []tpmdriver.Event{
+		{Pcr: 0, Type: tpmdriver.EV_NO_ACTION, Data: []byte("Spec ID Event03")},
+		{Pcr: 0, Type: tpmdriver.EV_S_CRTM_VERSION, Digests: sha256Digest(crtm[:]),
+			Data: []byte("1.0\x00")},
+		{Pcr: 7, Type: tpmdriver.EV_EFI_VARIABLE_DRIVER_CONFIG, Digests: sha256Digest(sb[:]),
+			Data: efiVariableData("SecureBoot")},
+		{Pcr: 0, Type: tpmdriver.EV_SEPARATOR, Digests: sha256Digest(sep[:]),
+			Data: []byte{0xff, 0x00, 0x01, 0x02}},
+		{Pcr: 8, Type: tpmdriver.EV_IPL, Digests: sha256Digest(grub[:]),
+			Data: []byte("grub_cmd: linux /vmlinuz")},
+	}
+
+	ngData := []byte("template data")
+	ngHash := sha1.Sum(ngData)
+	entries := []ima.TemplateEntry{
+		{Pcr: 10, TemplateName: "ima-ng", TemplateHash: ngHash, TemplateData: ngData,
+			FileName: "/usr/bin/bash"},
+		{Pcr: 10, TemplateName: "ima-ng", TemplateData: ngData, FileName: "/usr/bin/ls"},
+		{Pcr: 11, TemplateName: "ima-ng", TemplateHash: ngHash, TemplateData: ngData,
+			FileName: "/usr/bin/sh"},
+	}
+	ngSha := sha256.Sum256(ngData)
+	sepSha1 := sha1.Sum([]byte{0, 0, 0, 0})
+	pcrs := []int{0}
+
+	tests := []struct {
+		name    string
+		events  []tpmdriver.Event
+		entries []ima.TemplateEntry
+		pcrs    []int
+		banks   []tpm2.Algorithm
+		want    []ar.ReferenceValue
+		wantErr bool
+	}{
+		{
+			name:   "RTM PCRs",
+			events: events,
+			pcrs:   []int{0, 1, 2, 3, 4, 5, 6, 7},
+			want: []ar.ReferenceValue{
+				refVal(0, crtm[:], "EV_S_CRTM_VERSION: 1.0"),
+				refVal(7, sb[:], "EV_EFI_VARIABLE_DRIVER_CONFIG: SecureBoot"),
+				refVal(0, sep[:], "EV_SEPARATOR"),
+			},
+		},
+		{
+			name:    "OS PCRs with IMA",
+			events:  events,
+			entries: entries,
+			pcrs:    []int{8, 9, 10},
+			want: []ar.ReferenceValue{
+				refVal(8, grub[:], "EV_IPL: grub_cmd: linux /vmlinuz"),
+				refVal(10, ngSha[:], "/usr/bin/bash"),
+				refVal(10, bytes.Repeat([]byte{0xff}, 32), "IMA violation: /usr/bin/ls"),
+			},
+		},
+		{
+			name:    "IMA PCR not selected",
+			events:  events,
+			entries: entries,
+			pcrs:    []int{8, 9},
+			want: []ar.ReferenceValue{
+				refVal(8, grub[:], "EV_IPL: grub_cmd: linux /vmlinuz"),
+			},
+		},
+		{
+			name: "SHA1 and SHA256 Banks",
+			events: []tpmdriver.Event{
+				{Pcr: 0, Type: tpmdriver.EV_SEPARATOR, Digests: map[tpm2.Algorithm][]byte{
+					tpm2.AlgSHA1: sepSha1[:], tpm2.AlgSHA256: sep[:]}},
+			},
+			pcrs:  []int{0},
+			banks: []tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA256},
+			want: []ar.ReferenceValue{
+				{
+					Type:   "TPM Reference Value",
+					Sha1:   sepSha1[:],
+					Sha256: sep[:],
+					Name:   "EV_SEPARATOR",
+					Pcr:    &pcrs[0],
+				},
+			},
+		},
+		{
+			name: "Missing SHA-256 Digest",
+			events: []tpmdriver.Event{
+				{Pcr: 0, Type: tpmdriver.EV_SEPARATOR, Digests: map[tpm2.Algorithm][]byte{
+					tpm2.AlgSHA1: make([]byte, 20)}},
+			},
+			pcrs:    []int{0},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			banks := tt.banks
+			if banks == nil {
+				banks = []tpm2.Algorithm{tpm2.AlgSHA256}
+			}
+			got, err := createReferenceValues(tt.events, tt.entries, tt.pcrs, 10, banks)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createReferenceValues() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("createReferenceValues()\n---GOT = %+v\n--WANT = %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parsePcrs(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []int
+		wantErr bool
+	}{
+		{"Valid", "0, 1,7", []int{0, 1, 7}, false},
+		{"Empty", "", []int{}, false},
+		{"Invalid Number", "0,a", nil, true},
+		{"Invalid PCR", "24", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePcrs(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parsePcrs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePcrs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readTemplate(t *testing.T) {
+	dir := t.TempDir()
+	file := path.Join(dir, "rtm.manifest.json")
+	err := os.WriteFile(file, []byte(`{"type":"RTM Manifest","name":"de.test.rtm","referenceValues":[]}`), 0644)
+	if err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	m := &ar.RtmManifest{Version: "20230101000000"}
+	if err := readTemplate(file, m); err != nil {
+		t.Fatalf("readTemplate() error = %v", err)
+	}
+	if m.Name != "de.test.rtm" || m.Version != "20230101000000" {
+		t.Errorf("readTemplate() unexpected manifest %+v", m)
+	}
+
+	if err := readTemplate(path.Join(dir, "missing.json"), m); err == nil {
+		t.Errorf("readTemplate() expected error for missing file")
+	}
+}
+
+func refVal(pcr int, digest []byte, name string) ar.ReferenceValue {
+	return ar.ReferenceValue{
+		Type:   "TPM Reference Value",
+		Sha256: digest,
+		Name:   name,
+		Pcr:    &pcr,
+	}
+}
+
+func sha256Digest(d []byte) map[tpm2.Algorithm][]byte {
+	return map[tpm2.Algorithm][]byte{tpm2.AlgSHA256: d}
+}
+
+func efiVariableData(name string) []byte {
+	n := utf16.Encode([]rune(name))
+	buf := new(bytes.Buffer)
+	buf.Write(make([]byte, 16))
+	binary.Write(buf, binary.LittleEndian, uint64(len(n)))
+	binary.Write(buf, binary.LittleEndian, uint64(0))
+	binary.Write(buf, binary.LittleEndian, n)
+	return buf.Bytes()
+}
@@ -0,0 +1,202 @@
+// Copyright(c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the License); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+)
+
+func main() {
+	log.SetLevel(log.TraceLevel)
+
+	ovmfFile := flag.String("ovmf", "", "Path to the OVMF firmware image")
+	kernelFile := flag.String("kernel", "", "Path to the kernel image for measured direct kernel boot (optional)")
+	initrdFile := flag.String("initrd", "", "Path to the initrd for measured direct kernel boot (optional)")
+	cmdline := flag.String("append", "", "Kernel command line for measured direct kernel boot (optional)")
+	vcpus := flag.Int("vcpus", 1, "Number of vCPUs")
+	vcpuType := flag.String("vcputype", "", "QEMU vCPU model, e.g. EPYC-v4, EPYC-Milan or EPYC-Genoa")
+	vcpuSig := flag.Uint("vcpusig", 0, "vCPU signature (CPUID Fn0000_0001_EAX), overrides -vcputype")
+	guestFeatures := flag.Uint64("guestfeatures", defaultGuestFeatures, "Guest features (SEV_FEATURES) of the VMSA")
+	policy := flag.Uint64("policy", 0x30000, "Guest policy as specified in the SNP_LAUNCH_START command")
+	version := flag.Uint("version", 2, "Expected SNP attestation report version")
+	product := flag.String("product", "", "Expected SNP product line (Milan, Genoa, Bergamo), optional")
+	fwMajor := flag.Uint("fwmajor", 0, "Minimum SNP firmware major version")
+	fwMinor := flag.Uint("fwminor", 0, "Minimum SNP firmware minor version")
+	fwBuild := flag.Uint("fwbuild", 0, "Minimum SNP firmware build")
+	tcbBl := flag.Uint("tcbbl", 0, "Minimum bootloader SVN")
+	tcbTee := flag.Uint("tcbtee", 0, "Minimum TEE SVN")
+	tcbSnp := flag.Uint("tcbsnp", 0, "Minimum SNP SVN")
+	tcbUcode := flag.Uint("tcbucode", 0, "Minimum microcode SVN")
+	caFiles := flag.String("cas", "", "Paths to the AMD SNP CA certificates (ARK, ASK) in PEM or DER format, as a comma-separated list")
+	name := flag.String("name", "OVMF", "Name of the reference value")
+	outputFile := flag.String("out", "", "Path to the output file to save the reference value")
+	outForm := flag.String("outform", "json", "Output format (JSON or CBOR)")
+	flag.Parse()
+
+	if *ovmfFile == "" {
+		log.Error("OVMF file not specified (-ovmf)")
+		flag.Usage()
+		return
+	}
+	if *outputFile == "" {
+		log.Error("output file not specified (-out)")
+		flag.Usage()
+		return
+	}
+	if *kernelFile == "" && (*initrdFile != "" || *cmdline != "") {
+		log.Error("initrd and kernel command line require a kernel (-kernel)")
+		flag.Usage()
+		return
+	}
+
+	sig := uint32(*vcpuSig)
+	if sig == 0 {
+		if *vcpuType == "" {
+			log.Error("vCPU type (-vcputype) or signature (-vcpusig) not specified")
+			flag.Usage()
+			return
+		}
+		var err error
+		sig, err = getVcpuSig(*vcpuType)
+		if err != nil {
+			log.Fatalf("Failed to get vCPU signature: %v", err)
+		}
+	}
+
+	c := &launchConfig{
+		Cmdline:       *cmdline,
+		Vcpus:         *vcpus,
+		VcpuSig:       sig,
+		GuestFeatures: *guestFeatures,
+	}
+
+	var err error
+	c.Ovmf, err = os.ReadFile(*ovmfFile)
+	if err != nil {
+		log.Fatalf("Failed to read OVMF file: %v", err)
+	}
+	if *kernelFile != "" {
+		c.Kernel, err = os.ReadFile(*kernelFile)
+		if err != nil {
+			log.Fatalf("Failed to read kernel: %v", err)
+		}
+	}
+	if *initrdFile != "" {
+		c.Initrd, err = os.ReadFile(*initrdFile)
+		if err != nil {
+			log.Fatalf("Failed to read initrd: %v", err)
+		}
+	}
+
+	details := &ar.SnpDetails{
+		Version: uint32(*version),
+		Policy:  getPolicy(*policy),
+		Fw: ar.SnpFw{
+			Build: uint8(*fwBuild),
+			Major: uint8(*fwMajor),
+			Minor: uint8(*fwMinor),
+		},
+		Tcb: ar.SnpTcb{
+			Bl:    uint8(*tcbBl),
+			Tee:   uint8(*tcbTee),
+			Snp:   uint8(*tcbSnp),
+			Ucode: uint8(*tcbUcode),
+		},
+		Product: *product,
+	}
+	if *caFiles != "" {
+		for _, f := range strings.Split(*caFiles, ",") {
+			ca, err := os.ReadFile(f)
+			if err != nil {
+				log.Fatalf("Failed to read CA certificate %v: %v", f, err)
+			}
+			details.Cas = append(details.Cas, ca)
+		}
+	}
+
+	raw, err := createReferenceValue(c, details, *name, *outForm)
+	if err != nil {
+		log.Fatalf("Failed to create reference value: %v", err)
+	}
+
+	log.Tracef("Writing output file %v", *outputFile)
+	err = os.WriteFile(*outputFile, raw, 0644)
+	if err != nil {
+		log.Fatalf("Failed to write file: %v", err)
+	}
+}
+
+// createReferenceValue calculates the SNP launch digest and returns the serialized
+// SNP reference value with the given details
+func createReferenceValue(c *launchConfig, details *ar.SnpDetails, name, outform string) ([]byte, error) {
+
+	var s ar.Serializer
+	if strings.EqualFold(outform, "json") {
+		s = ar.JsonSerializer{}
+	} else if strings.EqualFold(outform, "cbor") {
+		s = ar.CborSerializer{}
+	} else {
+		return nil, fmt.Errorf("output format %v not supported (only JSON and CBOR are supported)",
+			outform)
+	}
+
+	if details == nil {
+		return nil, errors.New("internal error: SNP details not specified")
+	}
+
+	digest, err := calculateLaunchDigest(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate launch digest: %w", err)
+	}
+	log.Debugf("Calculated SNP launch digest: %x", digest)
+
+	r := ar.ReferenceValue{
+		Type:   "SNP Reference Value",
+		Sha384: digest,
+		Name:   name,
+		Snp:    details,
+	}
+
+	raw, err := s.Marshal(&r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal reference value: %w", err)
+	}
+
+	return raw, nil
+}
+
+// getPolicy converts the guest policy into the expected policy of the reference
+// value, using the same bit layout as the verifier
+func getPolicy(policy uint64) ar.SnpPolicy {
+	return ar.SnpPolicy{
+		Type:         "SNP Policy",
+		AbiMajor:     uint8(policy & 0xFF),
+		AbiMinor:     uint8((policy >> 8) & 0xFF),
+		Smt:          (policy & (1 << 16)) != 0,
+		Migration:    (policy & (1 << 18)) != 0,
+		Debug:        (policy & (1 << 19)) != 0,
+		SingleSocket: (policy & (1 << 20)) != 0,
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright(c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the License); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	pageSize = 4096
+	// Size of the PAGE_INFO structure, SEV-SNP Firmware ABI Specification, Table 67
+	pageInfoSize = 0x70
+	// Guest physical address of the VMSA pages
+	vmsaGpa = 0xfffffffff000
+)
+
+// Page types of the SNP_LAUNCH_UPDATE command
+const (
+	pageTypeNormal     = 0x01
+	pageTypeVmsa       = 0x02
+	pageTypeZero       = 0x03
+	pageTypeUnmeasured = 0x04
+	pageTypeSecrets    = 0x05
+	pageTypeCpuid      = 0x06
+)
+
+// GUIDs of the SEV hash table for measured direct kernel boot, as specified in
+// QEMU (target/i386/sev.c)
+const (
+	sevHashTableHeaderGuid = "9438d606-4f22-4cc9-b479-a793d411fd21"
+	sevKernelEntryGuid     = "4de79437-abd2-427f-b835-d5b172d2045b"
+	sevInitrdEntryGuid     = "44baf731-3a2f-4bd7-9af1-41e29169781d"
+	sevCmdlineEntryGuid    = "97d02dd8-bd20-4c94-aa78-e7714d36ab2a"
+)
+
+const (
+	// Size of a SEV hash table entry: GUID, uint16 length, SHA256 hash
+	sevHashTableEntrySize = 16 + 2 + sha256.Size
+	// Size of the SEV hash table: GUID, uint16 length, cmdline, initrd and kernel entries
+	sevHashTableSize = 16 + 2 + 3*sevHashTableEntrySize
+)
+
+// launchConfig contains the inputs for the calculation of the SNP launch digest
+type launchConfig struct {
+	Ovmf          []byte
+	Kernel        []byte
+	Initrd        []byte
+	Cmdline       string
+	Vcpus         int
+	VcpuSig       uint32
+	GuestFeatures uint64
+}
+
+// gctx represents the guest context, which holds the launch digest during the
+// SNP_LAUNCH_UPDATE commands
+type gctx struct {
+	ld [sha512.Size384]byte
+}
+
+// update extends the launch digest with a page, SEV-SNP Firmware ABI
+// Specification, Section 8.17.2
+func (g *gctx) update(pageType uint8, gpa uint64, contents [sha512.Size384]byte) {
+	pageInfo := make([]byte, pageInfoSize)
+	copy(pageInfo[0:48], g.ld[:])
+	copy(pageInfo[48:96], contents[:])
+	binary.LittleEndian.PutUint16(pageInfo[96:98], pageInfoSize)
+	// Page type, followed by IMI page, VMPL3, VMPL2 and VMPL1 permissions and
+	// a reserved byte, which are all zero
+	pageInfo[98] = pageType
+	binary.LittleEndian.PutUint64(pageInfo[104:112], gpa)
+
+	g.ld = sha512.Sum384(pageInfo)
+}
+
+func (g *gctx) updateNormalPages(gpa uint64, data []byte) {
+	for offset := 0; offset < len(data); offset += pageSize {
+		end := offset + pageSize
+		if end > len(data) {
+			end = len(data)
+		}
+		g.update(pageTypeNormal, gpa+uint64(offset), sha512.Sum384(data[offset:end]))
+	}
+}
+
+func (g *gctx) updateZeroPages(gpa uint64, size uint32) {
+	for offset := uint64(0); offset < uint64(size); offset += pageSize {
+		g.update(pageTypeZero, gpa+offset, [sha512.Size384]byte{})
+	}
+}
+
+func (g *gctx) updateVmsaPage(vmsa []byte) {
+	g.update(pageTypeVmsa, vmsaGpa, sha512.Sum384(vmsa))
+}
+
+func (g *gctx) updateSecretsPage(gpa uint64) {
+	g.update(pageTypeSecrets, gpa, [sha512.Size384]byte{})
+}
+
+func (g *gctx) updateCpuidPage(gpa uint64) {
+	g.update(pageTypeCpuid, gpa, [sha512.Size384]byte{})
+}
+
+// calculateLaunchDigest calculates the SNP launch digest (MEASUREMENT field of the
+// attestation report) of a QEMU/KVM virtual machine booting the given OVMF firmware
+func calculateLaunchDigest(c *launchConfig) ([]byte, error) {
+
+	if c.Vcpus < 1 {
+		return nil, fmt.Errorf("invalid number of vCPUs %v", c.Vcpus)
+	}
+
+	o, err := parseOvmf(c.Ovmf)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &gctx{}
+	g.updateNormalPages(o.gpa(), o.data)
+
+	// The kernel hashes are only measured in case of a direct kernel boot
+	var hashPage []byte
+	if c.Kernel != nil {
+		if !o.hasSection(sectionSnpKernelHashes) {
+			return nil, errors.New("kernel specified, but OVMF does not contain SNP kernel hashes section")
+		}
+		gpa, err := o.sevHashTableGpa()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SEV hash table address: %w", err)
+		}
+		offset := int(gpa & (pageSize - 1))
+		if offset+sevHashTableSize > pageSize {
+			return nil, fmt.Errorf("SEV hash table at %x exceeds page", gpa)
+		}
+		hashPage = sevHashPage(c.Kernel, c.Initrd, c.Cmdline, offset)
+	}
+
+	for _, s := range o.sections {
+		switch s.SectionType {
+		case sectionSnpSecMem:
+			g.updateZeroPages(uint64(s.Gpa), s.Size)
+		case sectionSnpSecrets:
+			g.updateSecretsPage(uint64(s.Gpa))
+		case sectionCpuid:
+			g.updateCpuidPage(uint64(s.Gpa))
+		case sectionSvsmCaa:
+			g.updateZeroPages(uint64(s.Gpa), s.Size)
+		case sectionSnpKernelHashes:
+			if hashPage != nil {
+				if s.Size != pageSize {
+					return nil, fmt.Errorf("unexpected SNP kernel hashes section size %v", s.Size)
+				}
+				g.updateNormalPages(uint64(s.Gpa), hashPage)
+			} else {
+				g.updateZeroPages(uint64(s.Gpa), s.Size)
+			}
+		default:
+			return nil, fmt.Errorf("unknown OVMF SEV metadata section type %v", s.SectionType)
+		}
+	}
+
+	apEip, err := o.sevEsResetEip()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SEV-ES reset EIP: %w", err)
+	}
+
+	bspVmsa := buildVmsa(bspEip, c.GuestFeatures, c.VcpuSig)
+	apVmsa := buildVmsa(apEip, c.GuestFeatures, c.VcpuSig)
+	for i := 0; i < c.Vcpus; i++ {
+		if i == 0 {
+			g.updateVmsaPage(bspVmsa)
+		} else {
+			g.updateVmsaPage(apVmsa)
+		}
+	}
+
+	return g.ld[:], nil
+}
+
+// sevHashPage creates the page containing the SEV hash table with the hashes of
+// the kernel, the initrd and the kernel command line at the given offset
+func sevHashPage(kernel, initrd []byte, cmdline string, offset int) []byte {
+
+	// QEMU hashes the NUL-terminated command line
+	cmdlineData := append([]byte(cmdline), 0)
+
+	page := make([]byte, pageSize)
+	table := page[offset : offset+sevHashTableSize]
+
+	copy(table[0:16], guidBytes(sevHashTableHeaderGuid))
+	binary.LittleEndian.PutUint16(table[16:18], sevHashTableSize)
+	putHashTableEntry(table[18:], sevCmdlineEntryGuid, cmdlineData)
+	putHashTableEntry(table[18+sevHashTableEntrySize:], sevInitrdEntryGuid, initrd)
+	putHashTableEntry(table[18+2*sevHashTableEntrySize:], sevKernelEntryGuid, kernel)
+
+	return page
+}
+
+func putHashTableEntry(entry []byte, guid string, data []byte) {
+	hash := sha256.Sum256(data)
+	copy(entry[0:16], guidBytes(guid))
+	binary.LittleEndian.PutUint16(entry[16:18], sevHashTableEntrySize)
+	copy(entry[18:18+sha256.Size], hash[:])
+}
@@ -0,0 +1,289 @@
+// Copyright(c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the License); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+)
+
+const (
+	testOvmfSize        = 16 * pageSize
+	testMetadataOffset  = 0x2000
+	testHashTableGpa    = 0x80fc00
+	testApResetEip      = 0xffffb004
+	testSecMemGpa       = 0x800000
+	testSecMemSize      = 0x2000
+	testSecretsGpa      = 0x802000
+	testCpuidGpa        = 0x803000
+	testKernelHashesGpa = 0x80f000
+)
+
+func Test_parseOvmf(t *testing.T) {
+	tests := []struct {
+		name     string
+		ovmf     []byte
+		sections int
+		wantErr  bool
+	}{
+		{"Valid", createOvmf(true, false, false), 4, false},
+		{"No Kernel Hashes Section", createOvmf(false, false, false), 3, false},
+		{"Invalid Footer GUID", createOvmf(true, true, false), 0, true},
+		{"Invalid Metadata Signature", createOvmf(true, false, true), 0, true},
+		{"Invalid Size", createOvmf(true, false, false)[1:], 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := parseOvmf(tt.ovmf)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseOvmf() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if len(o.sections) != tt.sections {
+				t.Errorf("parseOvmf() sections = %v, want %v", len(o.sections), tt.sections)
+			}
+			if o.gpa() != fourGb-testOvmfSize {
+				t.Errorf("parseOvmf() gpa = %x, want %x", o.gpa(), fourGb-testOvmfSize)
+			}
+			eip, err := o.sevEsResetEip()
+			if err != nil || eip != testApResetEip {
+				t.Errorf("sevEsResetEip() = %x (%v), want %x", eip, err, testApResetEip)
+			}
+			gpa, err := o.sevHashTableGpa()
+			if err != nil || gpa != testHashTableGpa {
+				t.Errorf("sevHashTableGpa() = %x (%v), want %x", gpa, err, testHashTableGpa)
+			}
+		})
+	}
+}
+
+func Test_calculateLaunchDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       *launchConfig
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "Firmware Only",
+			c: &launchConfig{
+				Ovmf:          createOvmf(true, false, false),
+				Vcpus:         1,
+				VcpuSig:       cpuSig(25, 1, 1),
+				GuestFeatures: defaultGuestFeatures,
+			},
+			want:    "b599ba43f12ec7597776a24e7410f1a9bf675847aed9fe20eaf2cf035eb1d508f3549771e91867f3372192aa06ec3999",
+			wantErr: false,
+		},
+		{
+			name: "Direct Kernel Boot",
+			c: &launchConfig{
+				Ovmf:          createOvmf(true, false, false),
+				Kernel:        []byte("kernel"),
+				Initrd:        []byte("initrd"),
+				Cmdline:       "console=ttyS0",
+				Vcpus:         4,
+				VcpuSig:       cpuSig(25, 1, 1),
+				GuestFeatures: defaultGuestFeatures,
+			},
+			want:    "1d198aeaaac77d33ce9a941e5ec74b7122bd530d691b8a17bd7cf530d3b55b4a808e9075b1536988e88ce0dfc7e00c50",
+			wantErr: false,
+		},
+		{
+			name: "Kernel Without Hashes Section",
+			c: &launchConfig{
+				Ovmf:          createOvmf(false, false, false),
+				Kernel:        []byte("kernel"),
+				Vcpus:         1,
+				VcpuSig:       cpuSig(25, 1, 1),
+				GuestFeatures: defaultGuestFeatures,
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid vCPUs",
+			c: &launchConfig{
+				Ovmf:          createOvmf(true, false, false),
+				Vcpus:         0,
+				VcpuSig:       cpuSig(25, 1, 1),
+				GuestFeatures: defaultGuestFeatures,
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calculateLaunchDigest(tt.c)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("calculateLaunchDigest() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("calculateLaunchDigest() = %x, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sevHashPage(t *testing.T) {
+	page := sevHashPage([]byte("kernel"), nil, "", 0xc00)
+
+	if len(page) != pageSize {
+		t.Fatalf("sevHashPage() len = %v, want %v", len(page), pageSize)
+	}
+	if !bytes.Equal(page[:0xc00], make([]byte, 0xc00)) {
+		t.Errorf("sevHashPage() data before table not zero")
+	}
+	table := page[0xc00:]
+	if guidString(table[0:16]) != sevHashTableHeaderGuid {
+		t.Errorf("sevHashPage() header GUID = %v, want %v", guidString(table[0:16]), sevHashTableHeaderGuid)
+	}
+	if l := binary.LittleEndian.Uint16(table[16:18]); l != sevHashTableSize {
+		t.Errorf("sevHashPage() table length = %v, want %v", l, sevHashTableSize)
+	}
+	kernel := table[18+2*sevHashTableEntrySize:]
+	if guidString(kernel[0:16]) != sevKernelEntryGuid {
+		t.Errorf("sevHashPage() kernel GUID = %v, want %v", guidString(kernel[0:16]), sevKernelEntryGuid)
+	}
+	// SHA256 of "kernel"
+	want := "6923dd1bc0460082c5d55a831908c24a282860b7f1cd6c2b79cf1bc8857c639c"
+	if hex.EncodeToString(kernel[18:50]) != want {
+		t.Errorf("sevHashPage() kernel hash = %x, want %v", kernel[18:50], want)
+	}
+}
+
+func Test_createReferenceValue(t *testing.T) {
+	c := &launchConfig{
+		Ovmf:          createOvmf(true, false, false),
+		Vcpus:         1,
+		VcpuSig:       cpuSig(25, 1, 1),
+		GuestFeatures: defaultGuestFeatures,
+	}
+	details := &ar.SnpDetails{
+		Version: 2,
+		Policy:  getPolicy(0x30000),
+	}
+
+	tests := []struct {
+		name    string
+		outform string
+		s       ar.Serializer
+		wantErr bool
+	}{
+		{"JSON", "json", ar.JsonSerializer{}, false},
+		{"CBOR", "CBOR", ar.CborSerializer{}, false},
+		{"Unsupported Format", "xml", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := createReferenceValue(c, details, "OVMF", tt.outform)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("createReferenceValue() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			var r ar.ReferenceValue
+			if err := tt.s.Unmarshal(raw, &r); err != nil {
+				t.Fatalf("failed to unmarshal reference value: %v", err)
+			}
+			if r.Type != "SNP Reference Value" || r.Name != "OVMF" || len(r.Sha384) != 48 {
+				t.Errorf("createReferenceValue() unexpected reference value %+v", r)
+			}
+			if r.Snp == nil || !r.Snp.Policy.Smt || r.Snp.Policy.Debug || r.Snp.Version != 2 {
+				t.Errorf("createReferenceValue() unexpected SNP details %+v", r.Snp)
+			}
+		})
+	}
+}
+
+// createOvmf creates a minimal OVMF image containing the footer table with the
+// SEV-ES reset block, the SEV hash table and the SEV metadata
+func createOvmf(kernelHashes, invalidFooter, invalidMetadata bool) []byte {
+	data := make([]byte, testOvmfSize)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	// SEV metadata
+	sections := []metadataSection{
+		{testSecMemGpa, testSecMemSize, sectionSnpSecMem},
+		{testSecretsGpa, pageSize, sectionSnpSecrets},
+		{testCpuidGpa, pageSize, sectionCpuid},
+	}
+	if kernelHashes {
+		sections = append(sections, metadataSection{testKernelHashesGpa, pageSize, sectionSnpKernelHashes})
+	}
+	metadata := data[testMetadataOffset:]
+	copy(metadata[0:4], []byte(ovmfSevMetadataMagic))
+	if invalidMetadata {
+		metadata[0] = 'X'
+	}
+	binary.LittleEndian.PutUint32(metadata[4:8], uint32(sevMetadataHeaderSize+len(sections)*sevMetadataSectionSize))
+	binary.LittleEndian.PutUint32(metadata[8:12], 1)
+	binary.LittleEndian.PutUint32(metadata[12:16], uint32(len(sections)))
+	for i, s := range sections {
+		off := sevMetadataHeaderSize + i*sevMetadataSectionSize
+		binary.LittleEndian.PutUint32(metadata[off:], s.Gpa)
+		binary.LittleEndian.PutUint32(metadata[off+4:], s.Size)
+		binary.LittleEndian.PutUint32(metadata[off+8:], s.SectionType)
+	}
+
+	// Footer table entries, ordered as in the OVMF reset vector
+	entry := func(guid string, payload []byte) []byte {
+		e := append([]byte{}, payload...)
+		e = append(e, 0, 0)
+		binary.LittleEndian.PutUint16(e[len(e)-2:], uint16(len(payload)+footerEntrySize))
+		return append(e, guidBytes(guid)...)
+	}
+	u32 := func(vals ...uint32) []byte {
+		b := make([]byte, 4*len(vals))
+		for i, v := range vals {
+			binary.LittleEndian.PutUint32(b[4*i:], v)
+		}
+		return b
+	}
+	var table []byte
+	table = append(table, entry(ovmfSevMetadataGuid, u32(testOvmfSize-testMetadataOffset))...)
+	table = append(table, entry(sevHashTableRvGuid, u32(testHashTableGpa, 0x400))...)
+	table = append(table, entry(sevEsResetBlockGuid, u32(testApResetEip))...)
+
+	// The footer entry only contains the size of the overall table
+	footerGuid := ovmfTableFooterGuid
+	if invalidFooter {
+		footerGuid = sevEsResetBlockGuid
+	}
+	footer := make([]byte, 2, footerEntrySize)
+	binary.LittleEndian.PutUint16(footer, uint16(len(table)+footerEntrySize))
+	footer = append(footer, guidBytes(footerGuid)...)
+	table = append(table, footer...)
+
+	copy(data[testOvmfSize-footerTableOffset-len(table):], table)
+
+	return data
+}
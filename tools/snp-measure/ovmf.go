@@ -0,0 +1,238 @@
+// Copyright(c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the License); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GUIDs of the OVMF footer table entries as specified in the OVMF reset vector
+// (OvmfPkg/ResetVector/Ia16/ResetVectorVtf0.asm)
+const (
+	ovmfTableFooterGuid  = "96b582de-1fb2-45f7-baea-a366c55a082d"
+	sevHashTableRvGuid   = "7255371f-3a3b-4b04-927b-1da6efa8d454"
+	sevEsResetBlockGuid  = "00f771de-1a7e-4fcb-890e-68c77e2fb44e"
+	ovmfSevMetadataGuid  = "dc886566-984a-4798-a75e-5585a7bf67cc"
+	ovmfSevMetadataMagic = "ASEV"
+)
+
+// Section types of the OVMF SEV metadata
+const (
+	sectionSnpSecMem       = 1
+	sectionSnpSecrets      = 2
+	sectionCpuid           = 3
+	sectionSvsmCaa         = 4
+	sectionSnpKernelHashes = 0x10
+)
+
+const (
+	fourGb = 0x100000000
+	// The OVMF footer table ends 32 bytes before the end of the firmware image
+	footerTableOffset = 32
+	// Size of an OVMF footer table entry header: uint16 size + GUID
+	footerEntrySize = 18
+	// Size of the OVMF SEV metadata header: signature, size, version, number of items
+	sevMetadataHeaderSize = 16
+	// Size of an OVMF SEV metadata section descriptor: gpa, size, section type
+	sevMetadataSectionSize = 12
+)
+
+type metadataSection struct {
+	Gpa         uint32
+	Size        uint32
+	SectionType uint32
+}
+
+type ovmf struct {
+	data     []byte
+	table    map[string][]byte
+	sections []metadataSection
+}
+
+// parseOvmf parses the footer table and the SEV metadata of an OVMF firmware image
+func parseOvmf(data []byte) (*ovmf, error) {
+
+	if len(data) == 0 || len(data)%pageSize != 0 {
+		return nil, fmt.Errorf("invalid OVMF size %v: must be a multiple of the page size", len(data))
+	}
+
+	o := &ovmf{
+		data: data,
+	}
+
+	var err error
+	o.table, err = parseFooterTable(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OVMF footer table: %w", err)
+	}
+
+	o.sections, err = o.parseSevMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OVMF SEV metadata: %w", err)
+	}
+
+	return o, nil
+}
+
+// gpa returns the guest physical address the OVMF image is mapped to. The
+// firmware is mapped directly below 4 GB
+func (o *ovmf) gpa() uint64 {
+	return fourGb - uint64(len(o.data))
+}
+
+// hasSection returns true if the SEV metadata contain a section of the given type
+func (o *ovmf) hasSection(sectionType uint32) bool {
+	for _, s := range o.sections {
+		if s.SectionType == sectionType {
+			return true
+		}
+	}
+	return false
+}
+
+// sevHashTableGpa returns the guest physical address of the SEV hash table
+// used for measured direct kernel boot
+func (o *ovmf) sevHashTableGpa() (uint32, error) {
+	return o.tableUint32(sevHashTableRvGuid)
+}
+
+// sevEsResetEip returns the reset EIP of the application processors
+func (o *ovmf) sevEsResetEip() (uint32, error) {
+	return o.tableUint32(sevEsResetBlockGuid)
+}
+
+func (o *ovmf) tableUint32(guid string) (uint32, error) {
+	entry, ok := o.table[guid]
+	if !ok {
+		return 0, fmt.Errorf("OVMF footer table does not contain entry %v", guid)
+	}
+	if len(entry) < 4 {
+		return 0, fmt.Errorf("OVMF footer table entry %v too short (%v bytes)", guid, len(entry))
+	}
+	return binary.LittleEndian.Uint32(entry), nil
+}
+
+func (o *ovmf) parseSevMetadata() ([]metadataSection, error) {
+
+	entry, ok := o.table[ovmfSevMetadataGuid]
+	if !ok {
+		// Firmware without SEV metadata
+		return nil, nil
+	}
+	if len(entry) < 4 {
+		return nil, errors.New("SEV metadata entry too short")
+	}
+
+	offsetFromEnd := binary.LittleEndian.Uint32(entry)
+	if offsetFromEnd > uint32(len(o.data)) {
+		return nil, fmt.Errorf("invalid SEV metadata offset %v", offsetFromEnd)
+	}
+	start := len(o.data) - int(offsetFromEnd)
+	if start+sevMetadataHeaderSize > len(o.data) {
+		return nil, errors.New("SEV metadata header exceeds firmware image")
+	}
+
+	header := o.data[start : start+sevMetadataHeaderSize]
+	if !bytes.Equal(header[0:4], []byte(ovmfSevMetadataMagic)) {
+		return nil, fmt.Errorf("invalid SEV metadata signature %q", header[0:4])
+	}
+	size := binary.LittleEndian.Uint32(header[4:8])
+	numItems := binary.LittleEndian.Uint32(header[12:16])
+
+	if size < sevMetadataHeaderSize || start+int(size) > len(o.data) {
+		return nil, fmt.Errorf("invalid SEV metadata size %v", size)
+	}
+	if uint64(numItems)*sevMetadataSectionSize > uint64(size-sevMetadataHeaderSize) {
+		return nil, fmt.Errorf("SEV metadata with %v items exceeds metadata size %v", numItems, size)
+	}
+
+	items := o.data[start+sevMetadataHeaderSize : start+int(size)]
+	sections := make([]metadataSection, 0, numItems)
+	for i := 0; i < int(numItems); i++ {
+		off := i * sevMetadataSectionSize
+		sections = append(sections, metadataSection{
+			Gpa:         binary.LittleEndian.Uint32(items[off : off+4]),
+			Size:        binary.LittleEndian.Uint32(items[off+4 : off+8]),
+			SectionType: binary.LittleEndian.Uint32(items[off+8 : off+12]),
+		})
+	}
+
+	return sections, nil
+}
+
+// parseFooterTable parses the OVMF footer table. The table is located at the end of
+// the firmware image and is parsed backwards, starting with the footer entry, which
+// contains the size of the overall table
+func parseFooterTable(data []byte) (map[string][]byte, error) {
+
+	if len(data) < footerTableOffset+footerEntrySize {
+		return nil, errors.New("firmware image too small")
+	}
+
+	footerStart := len(data) - footerTableOffset - footerEntrySize
+	footer := data[footerStart : footerStart+footerEntrySize]
+	if guid := guidString(footer[2:18]); guid != ovmfTableFooterGuid {
+		return nil, fmt.Errorf("unexpected footer table GUID %v", guid)
+	}
+	footerSize := int(binary.LittleEndian.Uint16(footer[0:2]))
+	if footerSize < footerEntrySize || footerSize-footerEntrySize > footerStart {
+		return nil, fmt.Errorf("invalid footer table size %v", footerSize)
+	}
+
+	table := make(map[string][]byte)
+	tableBytes := data[footerStart-(footerSize-footerEntrySize) : footerStart]
+	for len(tableBytes) >= footerEntrySize {
+		entry := tableBytes[len(tableBytes)-footerEntrySize:]
+		entrySize := int(binary.LittleEndian.Uint16(entry[0:2]))
+		if entrySize < footerEntrySize || entrySize > len(tableBytes) {
+			return nil, fmt.Errorf("invalid footer table entry size %v", entrySize)
+		}
+		guid := guidString(entry[2:18])
+		table[guid] = tableBytes[len(tableBytes)-entrySize : len(tableBytes)-footerEntrySize]
+		tableBytes = tableBytes[:len(tableBytes)-entrySize]
+	}
+
+	return table, nil
+}
+
+// guidString returns the string representation of a GUID in its mixed-endian
+// binary representation
+func guidString(b []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(b[0:4]),
+		binary.LittleEndian.Uint16(b[4:6]),
+		binary.LittleEndian.Uint16(b[6:8]),
+		b[8:10], b[10:16])
+}
+
+// guidBytes returns the mixed-endian binary representation of a GUID string
+func guidBytes(guid string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(guid, "-", ""))
+	if err != nil || len(b) != 16 {
+		return make([]byte, 16)
+	}
+	// The first three fields are stored in little endian byte order
+	b[0], b[1], b[2], b[3] = b[3], b[2], b[1], b[0]
+	b[4], b[5] = b[5], b[4]
+	b[6], b[7] = b[7], b[6]
+	return b
+}
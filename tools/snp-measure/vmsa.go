@@ -0,0 +1,147 @@
+// Copyright(c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Licensed under the Apache License, Version 2.0 (the License); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an AS IS BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Initial EIP of the bootstrap processor
+	bspEip = 0xfffffff0
+	// Guest features (SEV_FEATURES) with only SNPActive set
+	defaultGuestFeatures = 0x1
+)
+
+// Offsets of the fields of the VMSA (struct sev_es_save_area, AMD APM Vol. 2,
+// Table B-4) set by QEMU/KVM during the launch
+const (
+	vmsaEs          = 0x000
+	vmsaCs          = 0x010
+	vmsaSs          = 0x020
+	vmsaDs          = 0x030
+	vmsaFs          = 0x040
+	vmsaGs          = 0x050
+	vmsaGdtr        = 0x060
+	vmsaLdtr        = 0x070
+	vmsaIdtr        = 0x080
+	vmsaTr          = 0x090
+	vmsaEfer        = 0x0d0
+	vmsaCr4         = 0x148
+	vmsaCr0         = 0x158
+	vmsaDr7         = 0x160
+	vmsaDr6         = 0x168
+	vmsaRflags      = 0x170
+	vmsaRip         = 0x178
+	vmsaGPat        = 0x268
+	vmsaRdx         = 0x310
+	vmsaSevFeatures = 0x3b0
+	vmsaXcr0        = 0x3e8
+	vmsaMxcsr       = 0x408
+	vmsaX87Fcw      = 0x410
+)
+
+// vcpuSigs contains the CPUID signatures of the QEMU CPU models supporting SEV-SNP
+var vcpuSigs = map[string]uint32{
+	"epyc":          cpuSig(23, 1, 2),
+	"epyc-v1":       cpuSig(23, 1, 2),
+	"epyc-v2":       cpuSig(23, 1, 2),
+	"epyc-ibpb":     cpuSig(23, 1, 2),
+	"epyc-v3":       cpuSig(23, 1, 2),
+	"epyc-v4":       cpuSig(23, 1, 2),
+	"epyc-rome":     cpuSig(23, 49, 0),
+	"epyc-rome-v1":  cpuSig(23, 49, 0),
+	"epyc-rome-v2":  cpuSig(23, 49, 0),
+	"epyc-rome-v3":  cpuSig(23, 49, 0),
+	"epyc-milan":    cpuSig(25, 1, 1),
+	"epyc-milan-v1": cpuSig(25, 1, 1),
+	"epyc-milan-v2": cpuSig(25, 1, 1),
+	"epyc-genoa":    cpuSig(25, 17, 0),
+	"epyc-genoa-v1": cpuSig(25, 17, 0),
+}
+
+// cpuSig returns the CPUID signature (CPUID Fn0000_0001_EAX) for the given family,
+// model and stepping
+func cpuSig(family, model, stepping uint32) uint32 {
+	var familyLow, familyHigh uint32
+	if family > 0xf {
+		familyLow = 0xf
+		familyHigh = (family - 0xf) & 0xff
+	} else {
+		familyLow = family
+	}
+	modelLow := model & 0xf
+	modelHigh := (model >> 4) & 0xf
+	steppingLow := stepping & 0xf
+
+	return (familyHigh << 20) | (modelHigh << 16) | (familyLow << 8) | (modelLow << 4) | steppingLow
+}
+
+// getVcpuSig returns the CPUID signature of the QEMU CPU model
+func getVcpuSig(vcpuType string) (uint32, error) {
+	sig, ok := vcpuSigs[strings.ToLower(vcpuType)]
+	if !ok {
+		return 0, fmt.Errorf("unknown vCPU type %v", vcpuType)
+	}
+	return sig, nil
+}
+
+// vmcbSeg writes a segment register (selector, attributes, limit, base) to the VMSA
+func vmcbSeg(vmsa []byte, offset int, selector, attrib uint16, limit uint32, base uint64) {
+	binary.LittleEndian.PutUint16(vmsa[offset:], selector)
+	binary.LittleEndian.PutUint16(vmsa[offset+2:], attrib)
+	binary.LittleEndian.PutUint32(vmsa[offset+4:], limit)
+	binary.LittleEndian.PutUint64(vmsa[offset+8:], base)
+}
+
+// buildVmsa creates the initial VMSA page of a vCPU as created by QEMU/KVM for
+// the given EIP
+func buildVmsa(eip uint32, guestFeatures uint64, vcpuSig uint32) []byte {
+
+	vmsa := make([]byte, pageSize)
+
+	vmcbSeg(vmsa, vmsaEs, 0, 0x93, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaCs, 0xf000, 0x9b, 0xffff, uint64(eip&0xffff0000))
+	vmcbSeg(vmsa, vmsaSs, 0, 0x93, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaDs, 0, 0x93, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaFs, 0, 0x93, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaGs, 0, 0x93, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaGdtr, 0, 0, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaLdtr, 0, 0x82, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaIdtr, 0, 0, 0xffff, 0)
+	vmcbSeg(vmsa, vmsaTr, 0, 0x8b, 0xffff, 0)
+
+	// KVM enables EFER.SVME and CR4.MCE
+	binary.LittleEndian.PutUint64(vmsa[vmsaEfer:], 0x1000)
+	binary.LittleEndian.PutUint64(vmsa[vmsaCr4:], 0x40)
+	binary.LittleEndian.PutUint64(vmsa[vmsaCr0:], 0x10)
+	binary.LittleEndian.PutUint64(vmsa[vmsaDr7:], 0x400)
+	binary.LittleEndian.PutUint64(vmsa[vmsaDr6:], 0xffff0ff0)
+	binary.LittleEndian.PutUint64(vmsa[vmsaRflags:], 0x2)
+	binary.LittleEndian.PutUint64(vmsa[vmsaRip:], uint64(eip&0xffff))
+	// PAT MSR reset value, see AMD APM Vol. 2, Section A.3
+	binary.LittleEndian.PutUint64(vmsa[vmsaGPat:], 0x7040600070406)
+	binary.LittleEndian.PutUint64(vmsa[vmsaRdx:], uint64(vcpuSig))
+	binary.LittleEndian.PutUint64(vmsa[vmsaSevFeatures:], guestFeatures)
+	binary.LittleEndian.PutUint64(vmsa[vmsaXcr0:], 0x1)
+	binary.LittleEndian.PutUint32(vmsa[vmsaMxcsr:], 0x1f80)
+	binary.LittleEndian.PutUint16(vmsa[vmsaX87Fcw:], 0x37f)
+
+	return vmsa
+}
@@ -0,0 +1,419 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tpmdriver
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+// Event types as defined in the TCG PC Client Platform Firmware Profile Specification
+const (
+	EV_PREBOOT_CERT                  = 0x00000000
+	EV_POST_CODE                     = 0x00000001
+	EV_UNUSED                        = 0x00000002
+	EV_NO_ACTION                     = 0x00000003
+	EV_SEPARATOR                     = 0x00000004
+	EV_ACTION                        = 0x00000005
+	EV_EVENT_TAG                     = 0x00000006
+	EV_S_CRTM_CONTENTS               = 0x00000007
+	EV_S_CRTM_VERSION                = 0x00000008
+	EV_CPU_MICROCODE                 = 0x00000009
+	EV_PLATFORM_CONFIG_FLAGS         = 0x0000000A
+	EV_TABLE_OF_DEVICES              = 0x0000000B
+	EV_COMPACT_HASH                  = 0x0000000C
+	EV_IPL                           = 0x0000000D
+	EV_IPL_PARTITION_DATA            = 0x0000000E
+	EV_NONHOST_CODE                  = 0x0000000F
+	EV_NONHOST_CONFIG                = 0x00000010
+	EV_NONHOST_INFO                  = 0x00000011
+	EV_OMIT_BOOT_DEVICE_EVENTS       = 0x00000012
+	EV_EFI_EVENT_BASE                = 0x80000000
+	EV_EFI_VARIABLE_DRIVER_CONFIG    = 0x80000001
+	EV_EFI_VARIABLE_BOOT             = 0x80000002
+	EV_EFI_BOOT_SERVICES_APPLICATION = 0x80000003
+	EV_EFI_BOOT_SERVICES_DRIVER      = 0x80000004
+	EV_EFI_RUNTIME_SERVICES_DRIVER   = 0x80000005
+	EV_EFI_GPT_EVENT                 = 0x80000006
+	EV_EFI_ACTION                    = 0x80000007
+	EV_EFI_PLATFORM_FIRMWARE_BLOB    = 0x80000008
+	EV_EFI_HANDOFF_TABLES            = 0x80000009
+	EV_EFI_PLATFORM_FIRMWARE_BLOB2   = 0x8000000A
+	EV_EFI_HANDOFF_TABLES2           = 0x8000000B
+	EV_EFI_VARIABLE_BOOT2            = 0x8000000C
+	EV_EFI_HCRTM_EVENT               = 0x80000010
+	EV_EFI_VARIABLE_AUTHORITY        = 0x800000E0
+	EV_EFI_SPDM_FIRMWARE_BLOB        = 0x800000E1
+	EV_EFI_SPDM_FIRMWARE_CONFIG      = 0x800000E2
+)
+
+var eventTypeNames = map[uint32]string{
+	EV_PREBOOT_CERT:                  "EV_PREBOOT_CERT",
+	EV_POST_CODE:                     "EV_POST_CODE",
+	EV_UNUSED:                        "EV_UNUSED",
+	EV_NO_ACTION:                     "EV_NO_ACTION",
+	EV_SEPARATOR:                     "EV_SEPARATOR",
+	EV_ACTION:                        "EV_ACTION",
+	EV_EVENT_TAG:                     "EV_EVENT_TAG",
+	EV_S_CRTM_CONTENTS:               "EV_S_CRTM_CONTENTS",
+	EV_S_CRTM_VERSION:                "EV_S_CRTM_VERSION",
+	EV_CPU_MICROCODE:                 "EV_CPU_MICROCODE",
+	EV_PLATFORM_CONFIG_FLAGS:         "EV_PLATFORM_CONFIG_FLAGS",
+	EV_TABLE_OF_DEVICES:              "EV_TABLE_OF_DEVICES",
+	EV_COMPACT_HASH:                  "EV_COMPACT_HASH",
+	EV_IPL:                           "EV_IPL",
+	EV_IPL_PARTITION_DATA:            "EV_IPL_PARTITION_DATA",
+	EV_NONHOST_CODE:                  "EV_NONHOST_CODE",
+	EV_NONHOST_CONFIG:                "EV_NONHOST_CONFIG",
+	EV_NONHOST_INFO:                  "EV_NONHOST_INFO",
+	EV_OMIT_BOOT_DEVICE_EVENTS:       "EV_OMIT_BOOT_DEVICE_EVENTS",
+	EV_EFI_EVENT_BASE:                "EV_EFI_EVENT_BASE",
+	EV_EFI_VARIABLE_DRIVER_CONFIG:    "EV_EFI_VARIABLE_DRIVER_CONFIG",
+	EV_EFI_VARIABLE_BOOT:             "EV_EFI_VARIABLE_BOOT",
+	EV_EFI_BOOT_SERVICES_APPLICATION: "EV_EFI_BOOT_SERVICES_APPLICATION",
+	EV_EFI_BOOT_SERVICES_DRIVER:      "EV_EFI_BOOT_SERVICES_DRIVER",
+	EV_EFI_RUNTIME_SERVICES_DRIVER:   "EV_EFI_RUNTIME_SERVICES_DRIVER",
+	EV_EFI_GPT_EVENT:                 "EV_EFI_GPT_EVENT",
+	EV_EFI_ACTION:                    "EV_EFI_ACTION",
+	EV_EFI_PLATFORM_FIRMWARE_BLOB:    "EV_EFI_PLATFORM_FIRMWARE_BLOB",
+	EV_EFI_HANDOFF_TABLES:            "EV_EFI_HANDOFF_TABLES",
+	EV_EFI_PLATFORM_FIRMWARE_BLOB2:   "EV_EFI_PLATFORM_FIRMWARE_BLOB2",
+	EV_EFI_HANDOFF_TABLES2:           "EV_EFI_HANDOFF_TABLES2",
+	EV_EFI_VARIABLE_BOOT2:            "EV_EFI_VARIABLE_BOOT2",
+	EV_EFI_HCRTM_EVENT:               "EV_EFI_HCRTM_EVENT",
+	EV_EFI_VARIABLE_AUTHORITY:        "EV_EFI_VARIABLE_AUTHORITY",
+	EV_EFI_SPDM_FIRMWARE_BLOB:        "EV_EFI_SPDM_FIRMWARE_BLOB",
+	EV_EFI_SPDM_FIRMWARE_CONFIG:      "EV_EFI_SPDM_FIRMWARE_CONFIG",
+}
+
+const (
+	// Default location of the binary event log in the securityfs
+	defaultEventLogPath = "/sys/kernel/security/tpm0/binary_bios_measurements"
+
+	specIdSignature          = "Spec ID Event03\x00"
+	startupLocalitySignature = "StartupLocality\x00"
+	sha1DigestLen            = 20
+)
+
+// Event represents a single event of the TCG PC Client binary event log
+// (binary_bios_measurements). An event can contain digests of multiple
+// hash algorithms, indexed by the TPM algorithm identifier
+type Event struct {
+	Pcr     int
+	Type    uint32
+	Digests map[tpm2.Algorithm][]byte
+	Data    []byte
+}
+
+// TypeName returns the name of the event type as defined in the TCG specification
+func (e *Event) TypeName() string {
+	if name, ok := eventTypeNames[e.Type]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown Event Type 0x%x", e.Type)
+}
+
+// Description tries to extract a human-readable description of the event from
+// the event data, such as the name of an EFI variable or an ASCII action string
+func (e *Event) Description() string {
+	switch e.Type {
+	case EV_EFI_VARIABLE_DRIVER_CONFIG, EV_EFI_VARIABLE_BOOT, EV_EFI_VARIABLE_BOOT2,
+		EV_EFI_VARIABLE_AUTHORITY:
+		return efiVariableName(e.Data)
+	case EV_S_CRTM_VERSION:
+		// The version may be encoded as ASCII or as UCS-2 string
+		if s := printableString(e.Data); s != "" {
+			return s
+		}
+		return utf16String(e.Data)
+	default:
+		return printableString(e.Data)
+	}
+}
+
+type eventHeader struct {
+	Pcr  uint32
+	Type uint32
+}
+
+type specIdHeader struct {
+	Signature        [16]byte
+	PlatformClass    uint32
+	SpecVersionMinor uint8
+	SpecVersionMajor uint8
+	SpecErrata       uint8
+	UintnSize        uint8
+	NumAlgorithms    uint32
+}
+
+type specIdAlg struct {
+	Id         uint16
+	DigestSize uint16
+}
+
+// GetBiosMeasurements reads and parses the TCG binary event log from the
+// specified path. If the path is empty, the default securityfs path is used
+func GetBiosMeasurements(path string) ([]Event, error) {
+	if path == "" {
+		path = defaultEventLogPath
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log %v: %w", path, err)
+	}
+
+	events, err := ParseEventLog(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse event log %v: %w", path, err)
+	}
+
+	return events, nil
+}
+
+// ParseEventLog parses a TCG binary event log. Both the legacy SHA-1 format and
+// the crypto agile format (introduced by the Spec ID Event03) are supported
+func ParseEventLog(data []byte) ([]Event, error) {
+
+	buf := bytes.NewBuffer(data)
+	events := make([]Event, 0)
+
+	// The first event is always in the legacy SHA-1 format
+	first, err := parseSha1Event(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse first event: %w", err)
+	}
+
+	var algs []specIdAlg
+	if first.Type == EV_NO_ACTION && bytes.HasPrefix(first.Data, []byte(specIdSignature)) {
+		algs, err = parseSpecIdEvent(first.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Spec ID event: %w", err)
+		}
+		log.Tracef("Found crypto agile event log with %v algorithms", len(algs))
+	} else {
+		log.Trace("Found legacy SHA-1 event log")
+		events = append(events, *first)
+	}
+
+	for i := 1; buf.Len() > 0; i++ {
+		var e *Event
+		if algs != nil {
+			e, err = parseCryptoAgileEvent(buf, algs)
+		} else {
+			e, err = parseSha1Event(buf)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse event %v: %w", i, err)
+		}
+		events = append(events, *e)
+	}
+
+	return events, nil
+}
+
+func parseSha1Event(buf *bytes.Buffer) (*Event, error) {
+
+	var h eventHeader
+	if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
+		return nil, fmt.Errorf("failed to read event header: %w", err)
+	}
+
+	digest := make([]byte, sha1DigestLen)
+	if err := binary.Read(buf, binary.LittleEndian, digest); err != nil {
+		return nil, fmt.Errorf("failed to read event digest: %w", err)
+	}
+
+	data, err := readEventData(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Event{
+		Pcr:     int(h.Pcr),
+		Type:    h.Type,
+		Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA1: digest},
+		Data:    data,
+	}
+
+	return e, nil
+}
+
+func parseCryptoAgileEvent(buf *bytes.Buffer, algs []specIdAlg) (*Event, error) {
+
+	var h eventHeader
+	if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
+		return nil, fmt.Errorf("failed to read event header: %w", err)
+	}
+
+	var count uint32
+	if err := binary.Read(buf, binary.LittleEndian, &count); err != nil {
+		return nil, fmt.Errorf("failed to read digest count: %w", err)
+	}
+	if int(count) > len(algs) {
+		return nil, fmt.Errorf("event contains %v digests, but only %v algorithms are specified",
+			count, len(algs))
+	}
+
+	digests := make(map[tpm2.Algorithm][]byte)
+	for i := uint32(0); i < count; i++ {
+		var id uint16
+		if err := binary.Read(buf, binary.LittleEndian, &id); err != nil {
+			return nil, fmt.Errorf("failed to read digest algorithm: %w", err)
+		}
+		size := -1
+		for _, a := range algs {
+			if a.Id == id {
+				size = int(a.DigestSize)
+				break
+			}
+		}
+		if size < 0 {
+			return nil, fmt.Errorf("digest algorithm 0x%x not specified in Spec ID event", id)
+		}
+		digest := make([]byte, size)
+		if err := binary.Read(buf, binary.LittleEndian, digest); err != nil {
+			return nil, fmt.Errorf("failed to read event digest: %w", err)
+		}
+		digests[tpm2.Algorithm(id)] = digest
+	}
+
+	data, err := readEventData(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Event{
+		Pcr:     int(h.Pcr),
+		Type:    h.Type,
+		Digests: digests,
+		Data:    data,
+	}
+
+	return e, nil
+}
+
+// getStartupLocality returns the locality the TPM was started from as recorded in the
+// StartupLocality event (EV_NO_ACTION). If the event log does not contain this event,
+// the TPM was started from locality 0
+func getStartupLocality(events []Event) (uint8, error) {
+	for _, e := range events {
+		if e.Pcr != 0 || e.Type != EV_NO_ACTION ||
+			!bytes.HasPrefix(e.Data, []byte(startupLocalitySignature)) {
+			continue
+		}
+		if len(e.Data) != len(startupLocalitySignature)+1 {
+			return 0, fmt.Errorf("invalid StartupLocality event size %v", len(e.Data))
+		}
+		return e.Data[len(startupLocalitySignature)], nil
+	}
+	return 0, nil
+}
+
+func parseSpecIdEvent(data []byte) ([]specIdAlg, error) {
+
+	buf := bytes.NewBuffer(data)
+
+	var h specIdHeader
+	if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if h.NumAlgorithms == 0 {
+		return nil, fmt.Errorf("no algorithms specified")
+	}
+	if int(h.NumAlgorithms)*binary.Size(specIdAlg{}) > buf.Len() {
+		return nil, fmt.Errorf("invalid number of algorithms %v", h.NumAlgorithms)
+	}
+
+	algs := make([]specIdAlg, h.NumAlgorithms)
+	if err := binary.Read(buf, binary.LittleEndian, algs); err != nil {
+		return nil, fmt.Errorf("failed to read algorithms: %w", err)
+	}
+
+	return algs, nil
+}
+
+func readEventData(buf *bytes.Buffer) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(buf, binary.LittleEndian, &size); err != nil {
+		return nil, fmt.Errorf("failed to read event size: %w", err)
+	}
+	if int(size) > buf.Len() {
+		return nil, fmt.Errorf("event size %v exceeds remaining log size %v", size, buf.Len())
+	}
+	data := make([]byte, size)
+	if err := binary.Read(buf, binary.LittleEndian, data); err != nil {
+		return nil, fmt.Errorf("failed to read event data: %w", err)
+	}
+	return data, nil
+}
+
+// efiVariableName extracts the variable name from a UEFI_VARIABLE_DATA structure
+func efiVariableName(data []byte) string {
+	buf := bytes.NewBuffer(data)
+	var h struct {
+		Guid       [16]byte
+		NameLength uint64
+		DataLength uint64
+	}
+	if err := binary.Read(buf, binary.LittleEndian, &h); err != nil {
+		return ""
+	}
+	if h.NameLength*2 > uint64(buf.Len()) {
+		return ""
+	}
+	return utf16String(buf.Next(int(h.NameLength * 2)))
+}
+
+func utf16String(data []byte) string {
+	if len(data)%2 != 0 {
+		return ""
+	}
+	u := make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		c := binary.LittleEndian.Uint16(data[i:])
+		if c == 0 {
+			break
+		}
+		u = append(u, c)
+	}
+	s := string(utf16.Decode(u))
+	for _, r := range s {
+		if !unicode.IsPrint(r) {
+			return ""
+		}
+	}
+	return s
+}
+
+func printableString(data []byte) string {
+	s := strings.TrimRight(string(data), "\x00")
+	if s == "" {
+		return ""
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsPrint(r) {
+			return ""
+		}
+	}
+	return s
+}
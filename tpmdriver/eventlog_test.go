@@ -0,0 +1,248 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tpmdriver
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/go-tpm/tpm2"
+)
+
+func Test_ParseEventLog(t *testing.T) {
+
+	sep := []byte{0, 0, 0, 0}
+	sepSha1 := sha1.Sum(sep)
+	sepSha256 := sha256.Sum256(sep)
+
+	varData := efiVariableData("BootOrder", []byte{0x01, 0x00})
+	varSha256 := sha256.Sum256(varData)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		wantErr   bool
+		wantTypes []string
+		wantDescs []string
+		wantPcrs  []int
+		wantSha   [][]byte
+	}{
+		{
+			name: "Crypto Agile Log",
+			data: concat(
+				specIdEvent(),
+				agileEvent(1, EV_EFI_VARIABLE_BOOT, varSha256[:], varData),
+				agileEvent(0, EV_NO_ACTION, make([]byte, 32), []byte("StartupLocality\x00")),
+				agileEvent(7, EV_SEPARATOR, sepSha256[:], sep),
+			),
+			wantTypes: []string{"EV_EFI_VARIABLE_BOOT", "EV_NO_ACTION", "EV_SEPARATOR"},
+			wantDescs: []string{"BootOrder", "StartupLocality", ""},
+			wantPcrs:  []int{1, 0, 7},
+			wantSha:   [][]byte{varSha256[:], make([]byte, 32), sepSha256[:]},
+		},
+		{
+			name: "Legacy SHA-1 Log",
+			data: concat(
+				sha1Event(0, EV_S_CRTM_VERSION, make([]byte, 20), []byte("1.0\x00")),
+				sha1Event(7, EV_SEPARATOR, sepSha1[:], sep),
+			),
+			wantTypes: []string{"EV_S_CRTM_VERSION", "EV_SEPARATOR"},
+			wantDescs: []string{"1.0", ""},
+			wantPcrs:  []int{0, 7},
+		},
+		{
+			name: "Truncated Log",
+			data: concat(
+				specIdEvent(),
+				agileEvent(7, EV_SEPARATOR, sepSha256[:], sep)[:20],
+			),
+			wantErr: true,
+		},
+		{
+			name:    "Empty Log",
+			data:    []byte{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := ParseEventLog(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEventLog() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(events) != len(tt.wantTypes) {
+				t.Fatalf("ParseEventLog() returned %v events, want %v", len(events), len(tt.wantTypes))
+			}
+			for i, e := range events {
+				if e.TypeName() != tt.wantTypes[i] {
+					t.Errorf("event %v: type = %v, want %v", i, e.TypeName(), tt.wantTypes[i])
+				}
+				if e.Description() != tt.wantDescs[i] {
+					t.Errorf("event %v: description = %q, want %q", i, e.Description(), tt.wantDescs[i])
+				}
+				if e.Pcr != tt.wantPcrs[i] {
+					t.Errorf("event %v: pcr = %v, want %v", i, e.Pcr, tt.wantPcrs[i])
+				}
+				if tt.wantSha != nil && !bytes.Equal(e.Digests[tpm2.AlgSHA256], tt.wantSha[i]) {
+					t.Errorf("event %v: sha256 = %x, want %x", i, e.Digests[tpm2.AlgSHA256], tt.wantSha[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_getEventDigests(t *testing.T) {
+	events := []Event{
+		{Pcr: 0, Type: EV_NO_ACTION, Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA256: {0x0}}},
+		{Pcr: 0, Type: EV_POST_CODE, Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA256: {0x1}}},
+		{Pcr: 1, Type: EV_POST_CODE, Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA256: {0x2}}},
+		{Pcr: 0, Type: EV_SEPARATOR, Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA256: {0x3}}},
+		{Pcr: 2, Type: EV_SEPARATOR, Digests: map[tpm2.Algorithm][]byte{tpm2.AlgSHA1: {0x4}}},
+	}
+
+	digests, infos, err := getEventDigests(events, 0, tpm2.AlgSHA256)
+	if err != nil {
+		t.Fatalf("getEventDigests() error = %v", err)
+	}
+	if len(digests) != 2 || digests[0][0] != 0x1 || digests[1][0] != 0x3 {
+		t.Errorf("getEventDigests() digests = %v", digests)
+	}
+	if len(infos) != 2 || infos[0].EventType != "EV_POST_CODE" || infos[1].EventType != "EV_SEPARATOR" {
+		t.Errorf("getEventDigests() infos = %v", infos)
+	}
+
+	_, _, err = getEventDigests(events, 2, tpm2.AlgSHA256)
+	if err == nil {
+		t.Errorf("getEventDigests() expected error for event without SHA-256 digest")
+	}
+
+	digests, _, err = getEventDigests(events, 2, tpm2.AlgSHA1)
+	if err != nil {
+		t.Fatalf("getEventDigests() error = %v", err)
+	}
+	if len(digests) != 1 || digests[0][0] != 0x4 {
+		t.Errorf("getEventDigests() SHA-1 digests = %v", digests)
+	}
+}
+
+func Test_getStartupLocality(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []Event
+		want    uint8
+		wantErr bool
+	}{
+		{
+			name: "Startup Locality 3",
+			events: []Event{
+				{Pcr: 0, Type: EV_NO_ACTION, Data: []byte("StartupLocality\x00\x03")},
+				{Pcr: 0, Type: EV_S_CRTM_VERSION, Data: []byte("1.0")},
+			},
+			want: 3,
+		},
+		{
+			name: "No Startup Locality Event",
+			events: []Event{
+				{Pcr: 0, Type: EV_NO_ACTION, Data: []byte("Spec ID Event03\x00")},
+				{Pcr: 0, Type: EV_S_CRTM_VERSION, Data: []byte("1.0")},
+			},
+			want: 0,
+		},
+		{
+			name: "Invalid Event Size",
+			events: []Event{
+				{Pcr: 0, Type: EV_NO_ACTION, Data: []byte("StartupLocality\x00")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getStartupLocality(tt.events)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getStartupLocality() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("getStartupLocality() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func concat(b ...[]byte) []byte {
+	return bytes.Join(b, nil)
+}
+
+func sha1Event(pcr, typ uint32, digest, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, pcr)
+	binary.Write(buf, binary.LittleEndian, typ)
+	buf.Write(digest)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func agileEvent(pcr, typ uint32, sha256Digest, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, pcr)
+	binary.Write(buf, binary.LittleEndian, typ)
+	binary.Write(buf, binary.LittleEndian, uint32(2))
+	binary.Write(buf, binary.LittleEndian, uint16(tpm2.AlgSHA1))
+	buf.Write(make([]byte, 20))
+	binary.Write(buf, binary.LittleEndian, uint16(tpm2.AlgSHA256))
+	buf.Write(sha256Digest)
+	binary.Write(buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func specIdEvent() []byte {
+	buf := new(bytes.Buffer)
+	h := specIdHeader{
+		PlatformClass:    0,
+		SpecVersionMajor: 2,
+		UintnSize:        2,
+		NumAlgorithms:    2,
+	}
+	copy(h.Signature[:], specIdSignature)
+	binary.Write(buf, binary.LittleEndian, h)
+	binary.Write(buf, binary.LittleEndian, []specIdAlg{
+		{Id: uint16(tpm2.AlgSHA1), DigestSize: 20},
+		{Id: uint16(tpm2.AlgSHA256), DigestSize: 32},
+	})
+	buf.WriteByte(0) // vendorInfoSize
+	return sha1Event(0, EV_NO_ACTION, make([]byte, 20), buf.Bytes())
+}
+
+func efiVariableData(name string, data []byte) []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(make([]byte, 16))
+	u := utf16.Encode([]rune(name))
+	binary.Write(buf, binary.LittleEndian, uint64(len(u)))
+	binary.Write(buf, binary.LittleEndian, uint64(len(data)))
+	binary.Write(buf, binary.LittleEndian, u)
+	buf.Write(data)
+	return buf.Bytes()
+}
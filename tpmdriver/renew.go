@@ -0,0 +1,218 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tpmdriver
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/Fraunhofer-AISEC/cmc/est/client"
+)
+
+// Suffix of the files staged during certificate renewal
+const renewalSuffix = ".new"
+
+// RenewalRequired returns true if the AK or the IK certificate expires within the
+// specified duration
+func (t *Tpm) RenewalRequired(threshold time.Duration) bool {
+	t.Lock()
+	defer t.Unlock()
+
+	return expires(t.MeasuringCerts, threshold) || expires(t.SigningCerts, threshold)
+}
+
+// RenewCerts renews the AK and IK certificates without re-provisioning the TPM. A new IK
+// certified by the AK is created and the AK and new IK certificates are requested via the
+// EST simplereenroll endpoint, authenticated with the current IK certificate. The new IK
+// and the certificates are then swapped on disk and in the running instance
+func (t *Tpm) RenewCerts() error {
+	t.Lock()
+	defer t.Unlock()
+
+	if t.tpm == nil || t.ak == nil || t.ik == nil {
+		return errors.New("failed to renew certificates: TPM keys not loaded")
+	}
+	if len(t.MeasuringCerts) == 0 || len(t.SigningCerts) == 0 {
+		return errors.New("failed to renew certificates: TPM certificates not loaded")
+	}
+
+	log.Info("Renewing TPM AK and IK certificates")
+
+	ik, err := createIk(t.tpm, t.ak, t.keyConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create IK: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			ik.Close()
+		}
+	}()
+
+	// The CSRs renew the current certificates and therefore must contain the same
+	// subject and SANs
+	akCsr, err := signAkCsr(t.ak, renewalTemplate(t.MeasuringCerts[0]))
+	if err != nil {
+		return fmt.Errorf("failed to create AK CSR: %w", err)
+	}
+	ikCsr, err := signIkCsr(ik, renewalTemplate(t.SigningCerts[0]))
+	if err != nil {
+		return fmt.Errorf("failed to create IK CSR: %w", err)
+	}
+
+	// The EST server is authenticated with the CA the certificates were issued by,
+	// the client authenticates with the current IK certificate
+	priv, err := t.ik.Private(t.ik.Public())
+	if err != nil {
+		return fmt.Errorf("failed to get IK private key: %w", err)
+	}
+	estclient := client.NewClient([]*x509.Certificate{t.SigningCerts[len(t.SigningCerts)-1]})
+	err = estclient.SetClientCert(t.SigningCerts, priv)
+	if err != nil {
+		return fmt.Errorf("failed to set EST client certificate: %w", err)
+	}
+
+	akParams := t.ak.AttestationParameters()
+	ikParams := ik.CertificationParameters()
+	akCert, ikCert, err := estclient.TpmReenroll(
+		t.serverAddr,
+		akCsr, ikCsr,
+		t.MeasuringCerts[0],
+		akParams.Public,
+		ikParams.Public, ikParams.CreateData, ikParams.CreateAttestation, ikParams.CreateSignature,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to reenroll: %w", err)
+	}
+	if !publicKeyEqual(akCert.PublicKey, akCsr.PublicKey) {
+		err = errors.New("received AK certificate does not match AK")
+		return err
+	}
+	if !publicKeyEqual(ikCert.PublicKey, ikCsr.PublicKey) {
+		err = errors.New("received IK certificate does not match IK")
+		return err
+	}
+
+	akchain := append([]*x509.Certificate{akCert}, t.MeasuringCerts[1:]...)
+	ikchain := append([]*x509.Certificate{ikCert}, t.SigningCerts[1:]...)
+
+	ikBytes, err := ik.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal IK: %w", err)
+	}
+	err = storeRenewal(t.storagePath, ikBytes, akchain, ikchain)
+	if err != nil {
+		return fmt.Errorf("failed to store renewed keys: %w", err)
+	}
+
+	// The old IK can be closed, as callers only use the IK while holding the lock
+	old := t.ik
+	t.ik = ik
+	t.MeasuringCerts = akchain
+	t.SigningCerts = ikchain
+	old.Close()
+
+	log.Infof("Renewed TPM certificates, valid until %v", ikCert.NotAfter)
+
+	return nil
+}
+
+// storeRenewal swaps the IK and the certificate chains on disk. All files are first staged
+// and then renamed. The rename of the IK marks the commit point: if the renewal is
+// interrupted, completeRenewal either discards or finishes the renewal
+func storeRenewal(storagePath string, ikBytes []byte, akchain, ikchain []*x509.Certificate) error {
+
+	akchainPath := path.Join(storagePath, akchainFile)
+	ikchainPath := path.Join(storagePath, ikchainFile)
+	ikPath := path.Join(storagePath, ikFile)
+
+	if err := writeChain(akchainPath+renewalSuffix, akchain); err != nil {
+		return err
+	}
+	if err := writeChain(ikchainPath+renewalSuffix, ikchain); err != nil {
+		return err
+	}
+	if err := os.WriteFile(ikPath+renewalSuffix, ikBytes, 0644); err != nil {
+		return fmt.Errorf("failed to write file %v: %v", ikPath+renewalSuffix, err)
+	}
+
+	if err := os.Rename(ikPath+renewalSuffix, ikPath); err != nil {
+		return fmt.Errorf("failed to rename %v: %w", ikPath+renewalSuffix, err)
+	}
+
+	return completeRenewal(storagePath)
+}
+
+// completeRenewal finishes an interrupted renewal. If the IK has not been renamed, the
+// renewal was not committed and the staged files are removed. Otherwise, the staged
+// certificate chains are renamed
+func completeRenewal(storagePath string) error {
+
+	ikStaged := path.Join(storagePath, ikFile+renewalSuffix)
+	chains := []string{
+		path.Join(storagePath, akchainFile),
+		path.Join(storagePath, ikchainFile),
+	}
+
+	if _, err := os.Stat(ikStaged); err == nil {
+		log.Warn("Discarding uncommitted certificate renewal")
+		for _, f := range append(chains, path.Join(storagePath, ikFile)) {
+			if err := os.Remove(f + renewalSuffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove %v: %w", f+renewalSuffix, err)
+			}
+		}
+		return nil
+	}
+
+	for _, f := range chains {
+		if _, err := os.Stat(f + renewalSuffix); err != nil {
+			continue
+		}
+		if err := os.Rename(f+renewalSuffix, f); err != nil {
+			return fmt.Errorf("failed to rename %v: %w", f+renewalSuffix, err)
+		}
+	}
+
+	return nil
+}
+
+// renewalTemplate returns a CSR template with the same subject and SANs as the certificate
+func renewalTemplate(cert *x509.Certificate) *x509.CertificateRequest {
+	return &x509.CertificateRequest{
+		RawSubject: cert.RawSubject,
+		DNSNames:   cert.DNSNames,
+	}
+}
+
+func expires(chain []*x509.Certificate, threshold time.Duration) bool {
+	return len(chain) == 0 || time.Now().Add(threshold).After(chain[0].NotAfter)
+}
+
+func publicKeyEqual(a, b crypto.PublicKey) bool {
+	aPkix, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	bPkix, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(aPkix, bPkix)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tpmdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Sealer seals data to the TPM. The sealed data can only be unsealed with the same TPM
+// as long as the selected PCRs contain the same values as during sealing
+type Sealer struct {
+	rwc  io.ReadWriteCloser
+	pcrs []int
+}
+
+// sealedBlob is the serialized sealed data object together with the PCRs of its policy
+type sealedBlob struct {
+	Pcrs    []int  `json:"pcrs"`
+	Public  []byte `json:"public"`
+	Private []byte `json:"private"`
+}
+
+// srkTemplate is the template of the storage key the data is sealed to. As the key is
+// derived from the TPM's storage primary seed, the same key is created on each start
+var srkTemplate = tpm2.Public{
+	Type:    tpm2.AlgECC,
+	NameAlg: tpm2.AlgSHA256,
+	Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin |
+		tpm2.FlagUserWithAuth | tpm2.FlagRestricted | tpm2.FlagDecrypt | tpm2.FlagNoDA,
+	ECCParameters: &tpm2.ECCParams{
+		Symmetric: &tpm2.SymScheme{
+			Alg:     tpm2.AlgAES,
+			KeyBits: 128,
+			Mode:    tpm2.AlgCFB,
+		},
+		CurveID: tpm2.CurveNISTP256,
+	},
+}
+
+// NewSealer opens the TPM via the specified transport for sealing data to the
+// specified PCRs of the SHA256 bank
+func NewSealer(transport, address string, pcrs []int) (*Sealer, error) {
+	if len(pcrs) == 0 {
+		return nil, errors.New("sealing requires at least one PCR")
+	}
+
+	rwc, err := openTransport(transport, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open TPM: %w", err)
+	}
+
+	return &Sealer{
+		rwc:  rwc,
+		pcrs: pcrs,
+	}, nil
+}
+
+// Close closes the TPM
+func (s *Sealer) Close() error {
+	return s.rwc.Close()
+}
+
+// Seal seals the data to the current values of the configured PCRs. The data must not
+// exceed 128 bytes
+func (s *Sealer) Seal(data []byte) ([]byte, error) {
+
+	srk, _, err := tpm2.CreatePrimary(s.rwc, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SRK: %w", err)
+	}
+	defer tpm2.FlushContext(s.rwc, srk)
+
+	// Compute the policy digest with a trial session
+	session, err := startPcrSession(s.rwc, tpm2.SessionTrial, s.pcrs)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := tpm2.PolicyGetDigest(s.rwc, session)
+	tpm2.FlushContext(s.rwc, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policy digest: %w", err)
+	}
+
+	priv, pub, err := tpm2.Seal(s.rwc, srk, "", "", policy, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to seal data: %w", err)
+	}
+
+	blob, err := json.Marshal(&sealedBlob{
+		Pcrs:    s.pcrs,
+		Public:  pub,
+		Private: priv,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sealed data: %w", err)
+	}
+
+	return blob, nil
+}
+
+// Unseal unseals the data sealed with Seal. Unsealing fails, if the PCRs the data was
+// sealed to changed
+func (s *Sealer) Unseal(data []byte) ([]byte, error) {
+
+	blob := new(sealedBlob)
+	err := json.Unmarshal(data, blob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal sealed data: %w", err)
+	}
+
+	srk, _, err := tpm2.CreatePrimary(s.rwc, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SRK: %w", err)
+	}
+	defer tpm2.FlushContext(s.rwc, srk)
+
+	handle, _, err := tpm2.Load(s.rwc, srk, "", blob.Public, blob.Private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sealed data: %w", err)
+	}
+	defer tpm2.FlushContext(s.rwc, handle)
+
+	session, err := startPcrSession(s.rwc, tpm2.SessionPolicy, blob.Pcrs)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(s.rwc, session)
+
+	unsealed, err := tpm2.UnsealWithSession(s.rwc, session, handle, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal data: %w", err)
+	}
+
+	return unsealed, nil
+}
+
+// startPcrSession starts a session with a policy requiring the current values of the PCRs
+func startPcrSession(rw io.ReadWriter, se tpm2.SessionType, pcrs []int) (tpmutil.Handle, error) {
+	session, _, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull,
+		make([]byte, 16), nil, se, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		return tpm2.HandleNull, fmt.Errorf("failed to start session: %w", err)
+	}
+
+	sel := tpm2.PCRSelection{
+		Hash: tpm2.AlgSHA256,
+		PCRs: pcrs,
+	}
+	err = tpm2.PolicyPCR(rw, session, nil, sel)
+	if err != nil {
+		tpm2.FlushContext(rw, session)
+		return tpm2.HandleNull, fmt.Errorf("failed to set PCR policy: %w", err)
+	}
+
+	return session, nil
+}
@@ -25,9 +25,11 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/Fraunhofer-AISEC/go-attestation/attest"
@@ -53,20 +55,47 @@ type Tpm struct {
 	MeasuringCerts []*x509.Certificate
 	UseIma         bool
 	ImaPcr         int32
+	UseEventLog    bool
+	EventLogPath   string
+	PcrBanks       []tpm2.Algorithm
+
+	tpm *attest.TPM
+	rwc io.ReadWriteCloser
+	ak  *attest.AK
+	ik  *attest.Key
+	ek  []attest.EK
+
+	storagePath string
+	serverAddr  string
+	keyConfig   string
 }
 
 // Config is the structure for handing over the configuration
 // for a Tpm object
 type Config struct {
-	StoragePath string
-	ServerAddr  string
-	KeyConfig   string
-	Metadata    [][]byte
-	UseIma      bool
-	ImaPcr      int32
-	Serializer  ar.Serializer
+	StoragePath  string
+	ServerAddr   string
+	KeyConfig    string
+	Metadata     [][]byte
+	UseIma       bool
+	ImaPcr       int32
+	UseEventLog  bool
+	EventLogPath string
+	Serializer   ar.Serializer
+	// PcrBanks specifies the PCR banks to be quoted (SHA1, SHA256, SHA384, SM3_256).
+	// If empty, only the SHA256 bank is quoted
+	PcrBanks []string
+	// Transport selects the connection to the TPM ('device' (default) or 'simulator')
+	Transport string
+	// Address is the path of the TPM device or the command address (host:port) of
+	// the TPM simulator. If empty, the respective default is used
+	Address string
 }
 
+const (
+	biosMeasurements = "/sys/kernel/security/tpm0/binary_bios_measurements"
+)
+
 const (
 	akchainFile = "akchain.pem"
 	ikchainFile = "ikchain.pem"
@@ -74,17 +103,11 @@ const (
 	ikFile      = "ik_encrypted.json"
 )
 
-var (
-	TPM *attest.TPM = nil
-	ak  *attest.AK  = nil
-	ik  *attest.Key = nil
-	ek  []attest.EK
-)
-
 var log = logrus.WithField("service", "tpmdriver")
 
 // NewTpm creates a new TPM object, opens and initializes the TPM object,
-// checks if provosioning is required and if so, provisions the TPM
+// checks if provosioning is required and if so, provisions the TPM. The returned
+// object holds its own TPM handle and keys and must be closed with Close
 func NewTpm(c *Config) (*Tpm, error) {
 
 	// Check if serializer is initialized
@@ -109,16 +132,44 @@ func NewTpm(c *Config) (*Tpm, error) {
 		return nil, fmt.Errorf("failed retrieve TPM PCRs: %v", err)
 	}
 
-	// Check if the TPM is provisioned. If provisioned, load the AK and IK key.
-	// Otherwise perform credential activation with provisioning server and then load the keys
-	provisioningRequired, err := IsTpmProvisioningRequired(c.StoragePath)
+	banks, err := getPcrBanks(c.PcrBanks)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if TPM is provisioned: %v", err)
+		return nil, fmt.Errorf("failed to get PCR banks: %w", err)
+	}
+
+	tpm := &Tpm{
+		Pcrs:         pcrs,
+		UseIma:       c.UseIma,
+		ImaPcr:       c.ImaPcr,
+		UseEventLog:  c.UseEventLog,
+		EventLogPath: c.EventLogPath,
+		PcrBanks:     banks,
+		storagePath:  c.StoragePath,
+		serverAddr:   c.ServerAddr,
+		keyConfig:    c.KeyConfig,
+	}
+
+	err = tpm.open(c.Transport, c.Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the TPM. Check if you have privileges to open the TPM: %v", err)
+	}
+	defer func() {
+		if err != nil {
+			tpm.Close()
+		}
+	}()
+
+	// Finish or discard a certificate renewal that was interrupted
+	err = completeRenewal(c.StoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete interrupted certificate renewal: %w", err)
 	}
 
-	err = OpenTpm()
+	// Check if the TPM is provisioned. If provisioned, load the AK and IK key.
+	// Otherwise perform credential activation with provisioning server and then load the keys
+	provisioningRequired, err := tpm.IsTpmProvisioningRequired(c.StoragePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open the TPM. Check if you have privileges to open /dev/tpm0: %v", err)
+		return nil, fmt.Errorf("failed to check if TPM is provisioned: %v", err)
 	}
 
 	var akchain []*x509.Certificate
@@ -126,13 +177,14 @@ func NewTpm(c *Config) (*Tpm, error) {
 	if provisioningRequired {
 
 		log.Info("Provisioning TPM (might take a while)..")
-		ek, ak, ik, err = createKeys(TPM, c.KeyConfig)
+		tpm.ek, tpm.ak, tpm.ik, err = createKeys(tpm.tpm, c.KeyConfig)
 		if err != nil {
 			return nil, fmt.Errorf("activate credential failed: createKeys returned %v", err)
 		}
 
 		// Load relevant parameters from the metadata files
-		akCsr, ikCsr, err := createCsrs(c, ak, ik)
+		var akCsr, ikCsr *x509.CertificateRequest
+		akCsr, ikCsr, err = createCsrs(c, tpm.ak, tpm.ik)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create CSRs: %v", err)
 		}
@@ -140,7 +192,7 @@ func NewTpm(c *Config) (*Tpm, error) {
 		log.Tracef("Created AK CSR: %v", akCsr.Subject.CommonName)
 		log.Tracef("Created IK CSR: %v", ikCsr.Subject.CommonName)
 
-		akchain, ikchain, err = provisionTpm(c.ServerAddr, akCsr, ikCsr)
+		akchain, ikchain, err = tpm.provision(c.ServerAddr, akCsr, ikCsr)
 		if err != nil {
 			return nil, fmt.Errorf("failed to provision TPM: %v", err)
 		}
@@ -150,13 +202,13 @@ func NewTpm(c *Config) (*Tpm, error) {
 			return nil, fmt.Errorf("failed to save TPM data: %v", err)
 		}
 
-		err = saveKeys(c.StoragePath)
+		err = tpm.saveKeys(c.StoragePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to save keys: %w", err)
 		}
 
 	} else {
-		err = loadTpmKeys(c.StoragePath)
+		err = tpm.loadKeys(c.StoragePath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load TPM keys: %v", err)
 		}
@@ -166,13 +218,8 @@ func NewTpm(c *Config) (*Tpm, error) {
 		}
 	}
 
-	tpm := &Tpm{
-		Pcrs:           pcrs,
-		UseIma:         c.UseIma,
-		ImaPcr:         c.ImaPcr,
-		SigningCerts:   ikchain,
-		MeasuringCerts: akchain,
-	}
+	tpm.SigningCerts = ikchain
+	tpm.MeasuringCerts = akchain
 
 	return tpm, nil
 }
@@ -190,7 +237,7 @@ func (t *Tpm) Measure(nonce []byte) (ar.Measurement, error) {
 
 	log.Trace("Collecting TPM Quote")
 
-	pcrValues, quote, err := GetTpmMeasurement(t, nonce, t.Pcrs)
+	banks, akchain, err := t.GetTpmMeasurement(nonce, t.Pcrs)
 	if err != nil {
 		return ar.TpmMeasurement{}, fmt.Errorf("failed to get TPM Measurement: %v", err)
 	}
@@ -199,31 +246,84 @@ func (t *Tpm) Measure(nonce []byte) (ar.Measurement, error) {
 
 	hashChain := make([]*ar.HashChainElem, len(t.Pcrs))
 	for i, num := range t.Pcrs {
-
 		hashChain[i] = &ar.HashChainElem{
-			Type:   "Hash Chain",
-			Pcr:    int32(num),
-			Sha256: []ar.HexByte{pcrValues[num].Digest}}
+			Type: "Hash Chain",
+			Pcr:  int32(num),
+		}
+		for _, bank := range banks {
+			hashChain[i].SetDigests(bank.Alg, []ar.HexByte{bank.Pcrs[num]})
+		}
 	}
 
-	if t.UseIma {
-		// If the IMA is used, not the final PCR value is sent but instead
-		// a list of the kernel modules which are extended during verification
-		// to result in the final value
-		imaDigests, err := ima.GetImaRuntimeDigests()
+	if t.UseEventLog {
+		// If the event log is used, not the final PCR values are sent but instead
+		// the digests of the individual events, which are extended during verification
+		// to result in the final values. The event information allows the verifier
+		// to report which event did not match
+		events, err := GetBiosMeasurements(t.EventLogPath)
 		if err != nil {
-			log.Error("failed to get IMA runtime digests. Ignoring..")
+			log.Errorf("failed to get event log: %v. Ignoring..", err)
 		}
 
-		imaDigestsHex := make([]ar.HexByte, 0)
-		for _, elem := range imaDigests {
-			imaDigestsHex = append(imaDigestsHex, elem[:])
+		for _, elem := range hashChain {
+			if t.UseIma && elem.Pcr == t.ImaPcr {
+				continue
+			}
+			bankDigests := make(map[tpm2.Algorithm][]ar.HexByte)
+			var infos []ar.EventInfo
+			for _, bank := range banks {
+				bankDigests[bank.Alg], infos, err = getEventDigests(events, int(elem.Pcr), bank.Alg)
+				if err != nil {
+					break
+				}
+			}
+			if err != nil {
+				log.Warnf("Failed to get event log digests for PCR%v: %v. Using final PCR value",
+					elem.Pcr, err)
+				continue
+			}
+			// PCR0 does not start at zero if the TPM was started from a locality other
+			// than 0, e.g. on platforms with an H-CRTM
+			var locality uint8
+			if elem.Pcr == 0 {
+				locality, err = getStartupLocality(events)
+				if err != nil {
+					log.Warnf("Failed to get startup locality: %v. Using final PCR value", err)
+					continue
+				}
+			}
+			if len(infos) > 0 {
+				for alg, digests := range bankDigests {
+					elem.SetDigests(alg, digests)
+				}
+				elem.Events = infos
+				elem.StartupLocality = locality
+			}
 		}
+	}
 
-		// Find the IMA PCR in the TPM Measurement
-		for _, elem := range hashChain {
-			if elem.Pcr == t.ImaPcr {
-				elem.Sha256 = imaDigestsHex
+	if t.UseIma {
+		// If the IMA is used, not the final PCR value is sent but instead
+		// a list of the kernel modules which are extended during verification
+		// to result in the final value. The template information allows the
+		// verifier to report which file did not match
+		entries, err := ima.GetImaRuntimeEntries()
+		if err != nil {
+			log.Errorf("failed to get IMA runtime digests: %v. Ignoring..", err)
+		} else {
+			// Find the IMA PCR in the TPM Measurement
+			for _, elem := range hashChain {
+				if elem.Pcr != t.ImaPcr {
+					continue
+				}
+				for _, bank := range banks {
+					imaDigests, imaInfos, err := getImaDigests(entries, t.ImaPcr, bank.Alg)
+					if err != nil {
+						return ar.TpmMeasurement{}, fmt.Errorf("failed to get IMA digests: %w", err)
+					}
+					elem.SetDigests(bank.Alg, imaDigests)
+					elem.Events = imaInfos
+				}
 			}
 		}
 	}
@@ -231,14 +331,23 @@ func (t *Tpm) Measure(nonce []byte) (ar.Measurement, error) {
 	tm := ar.TpmMeasurement{
 		Type:      "TPM Measurement",
 		HashChain: hashChain,
-		Message:   quote.Quote,
-		Signature: quote.Signature,
-		Certs:     internal.WriteCertsPem(t.MeasuringCerts),
+		Message:   banks[0].Quote.Quote,
+		Signature: banks[0].Quote.Signature,
+		Certs:     internal.WriteCertsPem(akchain),
+	}
+	for _, bank := range banks[1:] {
+		tm.Quotes = append(tm.Quotes, ar.TpmQuote{
+			Message:   bank.Quote.Quote,
+			Signature: bank.Quote.Signature,
+		})
 	}
 
 	for _, elem := range tm.HashChain {
-		for _, sha := range elem.Sha256 {
-			log.Tracef("PCR%v: %v\n", elem.Pcr, hex.EncodeToString(sha))
+		for _, bank := range banks {
+			for _, digest := range elem.Digests(bank.Alg) {
+				log.Tracef("PCR%v (%v): %v\n", elem.Pcr, ar.PcrBankName(bank.Alg),
+					hex.EncodeToString(digest))
+			}
 		}
 	}
 	log.Trace("Quote: ", hex.EncodeToString(tm.Message))
@@ -247,6 +356,65 @@ func (t *Tpm) Measure(nonce []byte) (ar.Measurement, error) {
 	return tm, nil
 }
 
+// getEventDigests returns the digests of the specified PCR bank and the event information
+// of all events of the event log that were extended into the specified PCR
+func getEventDigests(events []Event, pcr int, alg tpm2.Algorithm) ([]ar.HexByte, []ar.EventInfo, error) {
+	digests := make([]ar.HexByte, 0)
+	infos := make([]ar.EventInfo, 0)
+	for _, e := range events {
+		if e.Pcr != pcr || e.Type == EV_NO_ACTION {
+			continue
+		}
+		digest, ok := e.Digests[alg]
+		if !ok {
+			return nil, nil, fmt.Errorf("event of type %v does not contain %v digest",
+				e.TypeName(), ar.PcrBankName(alg))
+		}
+		digests = append(digests, digest)
+		infos = append(infos, ar.EventInfo{
+			EventType:   e.TypeName(),
+			Description: e.Description(),
+		})
+	}
+	return digests, infos, nil
+}
+
+// getImaDigests returns the template hashes for the specified PCR bank and the template
+// information of all IMA runtime measurement list entries that were extended into the
+// specified PCR
+func getImaDigests(entries []ima.TemplateEntry, pcr int32, alg tpm2.Algorithm) ([]ar.HexByte, []ar.EventInfo, error) {
+	h, err := ar.NewPcrHash(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+	digests := make([]ar.HexByte, 0)
+	infos := make([]ar.EventInfo, 0)
+	for _, e := range entries {
+		if e.Pcr != pcr {
+			continue
+		}
+		digests = append(digests, e.HashDigest(h))
+		info := ar.EventInfo{
+			EventType:   e.TemplateName,
+			Description: e.FileName,
+		}
+		// Signed measurements can be verified against the IMA signing certificates
+		// of the OS Manifest, which requires all template fields to recalculate the
+		// template hash
+		if len(e.Signature) > 0 {
+			info.FileDigestAlg = e.FileDigestAlg
+			info.FileDigest = e.FileDigest
+			info.Signature = e.Signature
+			info.Buf = e.Buf
+			info.ModSigDigestAlg = e.ModSigDigestAlg
+			info.ModSigDigest = e.ModSigDigest
+			info.ModSig = e.ModSig
+		}
+		infos = append(infos, info)
+	}
+	return digests, infos, nil
+}
+
 func (t *Tpm) Lock() {
 	log.Trace("Trying to get lock for TPM")
 	t.Mu.Lock()
@@ -260,20 +428,23 @@ func (t *Tpm) Unlock() {
 }
 
 // GetSigningKeys returns the IK private and public key as a generic
-// crypto interface
+// crypto interface. Must be called while holding the lock, as the IK is
+// swapped during certificate renewal
 func (t *Tpm) GetSigningKeys() (crypto.PrivateKey, crypto.PublicKey, error) {
 
-	if ik == nil {
+	if t.ik == nil {
 		return nil, nil, fmt.Errorf("failed to get IK Signer: not initialized")
 	}
-	priv, err := ik.Private(ik.Public())
+	priv, err := t.ik.Private(t.ik.Public())
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to get IK Private")
 	}
 
-	return priv, ik.Public(), nil
+	return priv, t.ik.Public(), nil
 }
 
+// GetCertChain returns the IK certificate chain. Must be called while holding
+// the lock, as the chain is swapped during certificate renewal
 func (t *Tpm) GetCertChain() []*x509.Certificate {
 	return t.SigningCerts
 }
@@ -282,8 +453,8 @@ func (t *Tpm) GetCertChain() []*x509.Certificate {
 // at 0x810000001 and the encrypted AK blob is present, which is used as an
 // indicator that the TPM is provisioned and the AK can directly be loaded.
 // This function uses the low-level go-tpm library directly as go-attestation
-// does not provide such a functionality.
-func IsTpmProvisioningRequired(storagePath string) (bool, error) {
+// does not provide such a functionality. The TPM must be opened.
+func (t *Tpm) IsTpmProvisioningRequired(storagePath string) (bool, error) {
 
 	if _, err := os.Stat(path.Join(storagePath, akchainFile)); err != nil {
 		log.Info("TPM Provisioning (Credential Activation) REQUIRED")
@@ -295,14 +466,12 @@ func IsTpmProvisioningRequired(storagePath string) (bool, error) {
 		return true, nil
 	}
 
-	rwc, err := tpm2.OpenTPM("/dev/tpm0")
-	if err != nil {
-		return true, fmt.Errorf("failed to Open TPM. Check access rights to /dev/tpm0")
+	if t.rwc == nil {
+		return true, fmt.Errorf("TPM is not opened")
 	}
-	defer rwc.Close()
 
 	srkHandle := tpmutil.Handle(0x81000001)
-	_, _, _, err = tpm2.ReadPublic(rwc, srkHandle)
+	_, _, _, err := tpm2.ReadPublic(t.rwc, srkHandle)
 	if err == nil {
 		log.Info("TPM Provisioning (Credential Activation) NOT REQUIRED")
 		return false, nil
@@ -312,43 +481,76 @@ func IsTpmProvisioningRequired(storagePath string) (bool, error) {
 	return true, nil
 }
 
-// OpenTpm opens the TPM and stores the handle internally
-func OpenTpm() error {
+// cmdChannel provides the opened TPM device as command channel to go-attestation
+type cmdChannel struct {
+	io.ReadWriteCloser
+}
+
+// MeasurementLog implements the go-attestation CommandChannelTPM20 interface
+func (c *cmdChannel) MeasurementLog() ([]byte, error) {
+	return os.ReadFile(biosMeasurements)
+}
+
+// open opens the TPM via the specified transport and stores the handle in the object.
+// The connection is opened directly, as go-attestation does neither support TPM
+// simulators, nor provide access to PCR banks other than SHA1 and SHA256
+func (t *Tpm) open(transport, address string) error {
 	log.Debug("Opening TPM")
 
-	if TPM != nil {
+	if t.tpm != nil {
 		return fmt.Errorf("failed to open TPM - already open")
 	}
 
-	var err error
-	config := &attest.OpenConfig{}
-	TPM, err = attest.OpenTPM(config)
+	rwc, err := openTransport(transport, address)
 	if err != nil {
-		TPM = nil
+		return err
+	}
+
+	config := &attest.OpenConfig{
+		CommandChannel: &cmdChannel{rwc},
+	}
+	tpm, err := attest.OpenTPM(config)
+	if err != nil {
+		rwc.Close()
 		return fmt.Errorf("activate credential failed: OpenTPM returned %v", err)
 	}
 
+	t.tpm = tpm
+	t.rwc = rwc
+
 	return nil
 }
 
-// CloseTpm closes the TPM
-func CloseTpm() error {
-	if TPM == nil {
+// Close releases the keys and closes the TPM
+func (t *Tpm) Close() error {
+	if t.tpm == nil {
 		return fmt.Errorf("failed to close TPM - TPM is not openend")
 	}
-	TPM.Close()
-	TPM = nil
+	if t.ak != nil {
+		t.ak.Close(t.tpm)
+		t.ak = nil
+	}
+	if t.ik != nil {
+		t.ik.Close()
+		t.ik = nil
+	}
+	err := t.tpm.Close()
+	t.tpm = nil
+	t.rwc = nil
+	if err != nil {
+		return fmt.Errorf("failed to close TPM: %w", err)
+	}
 	return nil
 }
 
 // GetTpmInfo retrieves general TPM infos
-func GetTpmInfo() (*attest.TPMInfo, error) {
+func (t *Tpm) GetTpmInfo() (*attest.TPMInfo, error) {
 
-	if TPM == nil {
+	if t.tpm == nil {
 		return nil, fmt.Errorf("failed to Get TPM info - TPM is not openend")
 	}
 
-	tpmInfo, err := TPM.Info()
+	tpmInfo, err := t.tpm.Info()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get TPM info - %v", err)
 	}
@@ -367,17 +569,17 @@ func GetTpmInfo() (*attest.TPMInfo, error) {
 //
 //	Name = nameAlg || HASH (TPMS_NV_PUBLIC)
 //	QName = HASH(QName_parent || Name)
-func GetAkQualifiedName() ([]byte, error) {
+func (t *Tpm) GetAkQualifiedName() ([]byte, error) {
 
-	if TPM == nil {
+	if t.tpm == nil {
 		return nil, errors.New("failed to get AK Qualified Name: TPM is not opened")
 	}
-	if ak == nil {
+	if t.ak == nil {
 		return nil, errors.New("failed to get AK Qualified Name: AK does not exist")
 	}
 
 	// This is a TPMT_PUBLIC structure
-	pub := ak.AttestationParameters().Public
+	pub := t.ak.AttestationParameters().Public
 
 	// TPMT_PUBLIC Contains algorithm used for hashing the public area to get
 	// the name (nameAlg)
@@ -397,7 +599,7 @@ func GetAkQualifiedName() ([]byte, error) {
 	name := append(alg, digestPub[:]...)
 
 	// TPMS_CREATION_DATA contains parentQualifiedName
-	createData := ak.AttestationParameters().CreateData
+	createData := t.ak.AttestationParameters().CreateData
 	tpm2CreateData, err := tpm2.DecodeCreationData(createData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to Decode Creation Data: %v", err)
@@ -418,51 +620,122 @@ func GetAkQualifiedName() ([]byte, error) {
 	return qualifiedName, nil
 }
 
+// BankMeasurement contains the PCR values of a single PCR bank and the quote over them
+type BankMeasurement struct {
+	Alg   tpm2.Algorithm
+	Pcrs  map[int][]byte
+	Quote *attest.Quote
+}
+
 // GetTpmMeasurement retrieves the specified PCRs as well as a Quote over the PCRs
-// and returns the TPM quote as well as the single PCR values
-func GetTpmMeasurement(t *Tpm, nonce []byte, pcrs []int) ([]attest.PCR, *attest.Quote, error) {
+// for each configured PCR bank and returns the TPM quotes as well as the single PCR values
+// and the AK certificate chain at the time of quoting
+func (t *Tpm) GetTpmMeasurement(nonce []byte, pcrs []int) ([]BankMeasurement, []*x509.Certificate, error) {
 
-	if TPM == nil {
+	if t.tpm == nil {
 		return nil, nil, fmt.Errorf("TPM is not opened")
 	}
-	if ak == nil {
+	if t.ak == nil {
 		return nil, nil, fmt.Errorf("AK does not exist")
 	}
+	if len(t.PcrBanks) == 0 {
+		return nil, nil, fmt.Errorf("no PCR banks configured")
+	}
 
 	// Read and Store PCRs into TPM Measurement structure. Lock this access, as only
-	// one instance can have write access at the same time
+	// one instance can have write access at the same time and the AK certificate
+	// chain might be renewed in the meantime
 	t.Lock()
 	defer t.Unlock()
 
-	pcrValues, err := TPM.PCRs(attest.HashSHA256)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get TPM PCRs: %v", err)
+	banks := make([]BankMeasurement, 0, len(t.PcrBanks))
+	for _, alg := range t.PcrBanks {
+		pcrValues, err := t.readPcrs(alg, pcrs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get TPM %v PCRs: %v", ar.PcrBankName(alg), err)
+		}
+		log.Tracef("Finished reading %v PCRs from TPM", ar.PcrBankName(alg))
+
+		// Retrieve quote and store quote data and signature in TPM measurement object.
+		// The go-attestation hash algorithm is passed to the TPM as is
+		quote, err := t.ak.QuotePCRs(t.tpm, nonce, attest.HashAlg(alg), pcrs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get TPM %v quote - %v", ar.PcrBankName(alg), err)
+		}
+		log.Tracef("Finished getting %v Quote from TPM", ar.PcrBankName(alg))
+
+		banks = append(banks, BankMeasurement{
+			Alg:   alg,
+			Pcrs:  pcrValues,
+			Quote: quote,
+		})
 	}
-	log.Trace("Finished reading PCRs from TPM")
 
-	// Retrieve quote and store quote data and signature in TPM measurement object
-	quote, err := ak.QuotePCRs(TPM, nonce, attest.HashSHA256, pcrs)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get TPM quote - %v", err)
+	return banks, t.MeasuringCerts, nil
+}
+
+// readPcrs reads the specified PCRs of a PCR bank from the TPM
+func (t *Tpm) readPcrs(alg tpm2.Algorithm, pcrs []int) (map[int][]byte, error) {
+	values := make(map[int][]byte)
+
+	// The TPM might only return a subset of the selected PCRs, so the command
+	// is repeated until all PCRs were read
+	for i := 0; i < len(pcrs) && len(values) < len(pcrs); i++ {
+		sel := tpm2.PCRSelection{Hash: alg}
+		for _, pcr := range pcrs {
+			if _, ok := values[pcr]; !ok {
+				sel.PCRs = append(sel.PCRs, pcr)
+			}
+		}
+		ret, err := tpm2.ReadPCRs(t.rwc, sel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PCRs: %w", err)
+		}
+		if len(ret) == 0 {
+			break
+		}
+		for pcr, digest := range ret {
+			values[pcr] = digest
+		}
+	}
+
+	if len(values) < len(pcrs) {
+		return nil, fmt.Errorf("PCR bank %v not active", ar.PcrBankName(alg))
 	}
-	log.Trace("Finished getting Quote from TPM")
 
-	return pcrValues, quote, nil
+	return values, nil
 }
 
-func provisionTpm(
+// getPcrBanks returns the hash algorithms of the configured PCR banks. If no PCR
+// banks are configured, the SHA256 bank is used
+func getPcrBanks(names []string) ([]tpm2.Algorithm, error) {
+	if len(names) == 0 {
+		return []tpm2.Algorithm{tpm2.AlgSHA256}, nil
+	}
+	banks := make([]tpm2.Algorithm, 0, len(names))
+	for _, name := range names {
+		alg, ok := ar.PcrBanks[strings.ToUpper(name)]
+		if !ok {
+			return nil, fmt.Errorf("PCR bank %v not supported", name)
+		}
+		banks = append(banks, alg)
+	}
+	return banks, nil
+}
+
+func (t *Tpm) provision(
 	provServerURL string, akCsr, ikCsr *x509.CertificateRequest,
 ) ([]*x509.Certificate, []*x509.Certificate, error) {
 	log.Debug("Performing TPM credential activation..")
 
-	if TPM == nil {
+	if t.tpm == nil {
 		return nil, nil, errors.New("TPM is not openend")
 	}
-	if len(ek) == 0 || ak == nil || ik == nil {
+	if len(t.ek) == 0 || t.ak == nil || t.ik == nil {
 		return nil, nil, errors.New("keys not created")
 	}
 
-	tpmInfo, err := GetTpmInfo()
+	tpmInfo, err := t.GetTpmInfo()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to retrieve TPM Info: %w", err)
 	}
@@ -489,25 +762,25 @@ func provisionTpm(
 		return nil, nil, fmt.Errorf("failed to set EST CA: %w", err)
 	}
 
-	akParams := ak.AttestationParameters()
+	akParams := t.ak.AttestationParameters()
 
 	// Encode EK public key
-	ekPub, err := x509.MarshalPKIXPublicKey(ek[0].Public)
+	ekPub, err := x509.MarshalPKIXPublicKey(t.ek[0].Public)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal EK public key: %w", err)
 	}
 
 	var ekRaw []byte
-	if ek[0].Certificate != nil {
-		ekRaw = ek[0].Certificate.Raw
+	if t.ek[0].Certificate != nil {
+		ekRaw = t.ek[0].Certificate.Raw
 	} else {
 		ekRaw = nil
-		log.Tracef("EK not present. Using EK URL %v", ek[0].CertificateURL)
+		log.Tracef("EK not present. Using EK URL %v", t.ek[0].CertificateURL)
 	}
 
 	log.Info("Performing TPM AK Enroll")
 	encCredential, encSecret, pkcs7Cert, err := estclient.TpmActivateEnroll(
-		provServerURL, tpmInfo.Manufacturer.String(), ek[0].CertificateURL,
+		provServerURL, tpmInfo.Manufacturer.String(), t.ek[0].CertificateURL,
 		tpmInfo.FirmwareVersionMajor, tpmInfo.FirmwareVersionMinor,
 		akCsr,
 		akParams.Public, akParams.CreateData, akParams.CreateAttestation, akParams.CreateSignature,
@@ -517,7 +790,7 @@ func provisionTpm(
 		return nil, nil, fmt.Errorf("failed to enroll AK: %w", err)
 	}
 
-	secret, err := ActivateCredential(TPM, ak, encCredential, encSecret)
+	secret, err := ActivateCredential(t.tpm, t.ak, encCredential, encSecret)
 	if err != nil {
 		return nil, nil, fmt.Errorf("request activate credential failed: %w", err)
 	}
@@ -541,7 +814,7 @@ func provisionTpm(
 	log.Tracef("Created new AK Cert: %v", akCert.Subject.CommonName)
 
 	log.Info("Performing TPM IK Enroll")
-	ikParams := ik.CertificationParameters()
+	ikParams := t.ik.CertificationParameters()
 
 	ikCert, err := estclient.TpmCertifyEnroll(
 		provServerURL,
@@ -562,31 +835,27 @@ func provisionTpm(
 }
 
 func saveCerts(storagePath string, akchain, ikchain []*x509.Certificate) error {
-
-	akchainPem := make([]byte, 0)
-	for _, cert := range akchain {
-		c := internal.WriteCertPem(cert)
-		akchainPem = append(akchainPem, c...)
-	}
-	if err := os.WriteFile(path.Join(storagePath, akchainFile), akchainPem, 0644); err != nil {
-		return fmt.Errorf("failed to write  %v: %v", path.Join(storagePath, akchainFile), err)
+	if err := writeChain(path.Join(storagePath, akchainFile), akchain); err != nil {
+		return err
 	}
+	return writeChain(path.Join(storagePath, ikchainFile), ikchain)
+}
 
-	ikchainPem := make([]byte, 0)
-	for _, cert := range ikchain {
+func writeChain(file string, chain []*x509.Certificate) error {
+	chainPem := make([]byte, 0)
+	for _, cert := range chain {
 		c := internal.WriteCertPem(cert)
-		ikchainPem = append(ikchainPem, c...)
+		chainPem = append(chainPem, c...)
 	}
-	if err := os.WriteFile(path.Join(storagePath, ikchainFile), ikchainPem, 0644); err != nil {
-		return fmt.Errorf("failed to write  %v: %v", path.Join(storagePath, ikchainFile), err)
+	if err := os.WriteFile(file, chainPem, 0644); err != nil {
+		return fmt.Errorf("failed to write  %v: %v", file, err)
 	}
-
 	return nil
 }
 
-func saveKeys(storagePath string) error {
+func (t *Tpm) saveKeys(storagePath string) error {
 	// Store the encrypted AK blob on disk
-	akBytes, err := ak.Marshal()
+	akBytes, err := t.ak.Marshal()
 	if err != nil {
 		return fmt.Errorf("activate credential failed: Marshal AK returned %v", err)
 	}
@@ -596,7 +865,7 @@ func saveKeys(storagePath string) error {
 	}
 
 	// Store the encrypted IK blob on disk
-	ikBytes, err := ik.Marshal()
+	ikBytes, err := t.ik.Marshal()
 	if err != nil {
 		return fmt.Errorf("activate credential failed: Marshal IK returned %v", err)
 	}
@@ -608,9 +877,9 @@ func saveKeys(storagePath string) error {
 	return nil
 }
 
-func loadTpmKeys(storagePath string) error {
+func (t *Tpm) loadKeys(storagePath string) error {
 
-	if TPM == nil {
+	if t.tpm == nil {
 		return errors.New("tpm is not opened")
 	}
 
@@ -621,7 +890,7 @@ func loadTpmKeys(storagePath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read file %v: %v", akPath, err)
 	}
-	ak, err = TPM.LoadAK(akBytes)
+	t.ak, err = t.tpm.LoadAK(akBytes)
 	if err != nil {
 		return fmt.Errorf("LoadAK failed: %v", err)
 	}
@@ -633,7 +902,7 @@ func loadTpmKeys(storagePath string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read file %v: %v", ikPath, err)
 	}
-	ik, err = TPM.LoadKey(ikBytes)
+	t.ik, err = t.tpm.LoadKey(ikBytes)
 	if err != nil {
 		return fmt.Errorf("failed to load key: %v", err)
 	}
@@ -685,6 +954,17 @@ func createKeys(tpm *attest.TPM, keyConfig string) ([]attest.EK, *attest.AK, *at
 		return nil, nil, nil, fmt.Errorf("failed to create new AK - %v", err)
 	}
 
+	ik, err := createIk(tpm, ak, keyConfig)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return eks, ak, ik, nil
+}
+
+// createIk creates a new IK with the specified key configuration certified by the AK
+func createIk(tpm *attest.TPM, ak *attest.AK, keyConfig string) (*attest.Key, error) {
+
 	log.Debug("Creating new IK")
 
 	// Create key as specified in the config file
@@ -706,15 +986,15 @@ func createKeys(tpm *attest.TPM, keyConfig string) ([]attest.EK, *attest.AK, *at
 		ikConfig.Algorithm = attest.RSA
 		ikConfig.Size = 4096
 	default:
-		return nil, nil, nil, fmt.Errorf("failed to create new IK Key, unknown key configuration: %v", keyConfig)
+		return nil, fmt.Errorf("failed to create new IK Key, unknown key configuration: %v", keyConfig)
 	}
 
 	ik, err := tpm.NewKey(ak, ikConfig)
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("failed to create new IK key - %v", err)
+		return nil, fmt.Errorf("failed to create new IK key - %v", err)
 	}
 
-	return eks, ak, ik, nil
+	return ik, nil
 }
 
 func ActivateCredential(
@@ -868,7 +1148,14 @@ func createAkCsr(ak *attest.AK, params ar.CsrParams) (*x509.CertificateRequest,
 		},
 	}
 
-	der, err := CreateCertificateRequest(rand.Reader, &tmpl, ak.Private())
+	return signAkCsr(ak, &tmpl)
+}
+
+// signAkCsr creates a CSR from the template signed with the AK. As the AK is a restricted
+// signing key, the CSR is created with a custom implementation
+func signAkCsr(ak *attest.AK, tmpl *x509.CertificateRequest) (*x509.CertificateRequest, error) {
+
+	der, err := CreateCertificateRequest(rand.Reader, tmpl, ak.Private())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create certificate request: %v", err)
 	}
@@ -903,12 +1190,18 @@ func createIkCsr(ik *attest.Key, params ar.CsrParams) (*x509.CertificateRequest,
 		DNSNames: params.SANs,
 	}
 
+	return signIkCsr(ik, &tmpl)
+}
+
+// signIkCsr creates a CSR from the template signed with the IK
+func signIkCsr(ik *attest.Key, tmpl *x509.CertificateRequest) (*x509.CertificateRequest, error) {
+
 	priv, err := ik.Private(ik.Public())
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve IK private key: %w", err)
 	}
 
-	der, err := x509.CreateCertificateRequest(rand.Reader, &tmpl, priv)
+	der, err := x509.CreateCertificateRequest(rand.Reader, tmpl, priv)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create certificate request: %v", err)
 	}
@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tpmdriver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	ar "github.com/Fraunhofer-AISEC/cmc/attestationreport"
+	"github.com/google/go-tpm/tpm2"
+)
+
+func Test_getPcrBanks(t *testing.T) {
+	tests := []struct {
+		name    string
+		banks   []string
+		want    []tpm2.Algorithm
+		wantErr bool
+	}{
+		{"Default", nil, []tpm2.Algorithm{tpm2.AlgSHA256}, false},
+		{"Multiple Banks", []string{"sha1", "SHA384", "sm3_256"},
+			[]tpm2.Algorithm{tpm2.AlgSHA1, tpm2.AlgSHA384, ar.AlgSM3_256}, false},
+		{"Unknown Bank", []string{"SHA512"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getPcrBanks(tt.banks)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getPcrBanks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getPcrBanks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTpmMultipleInstances(t *testing.T) {
+	sim1 := newFakeSimulator(t, 0)
+	sim2 := newFakeSimulator(t, 0)
+
+	first := &Tpm{}
+	if err := first.open(TransportSimulator, sim1.cmd.Addr().String()); err != nil {
+		t.Fatalf("failed to open first TPM: %v", err)
+	}
+	second := &Tpm{}
+	if err := second.open(TransportSimulator, sim2.cmd.Addr().String()); err != nil {
+		t.Fatalf("failed to open second TPM: %v", err)
+	}
+
+	if err := first.open(TransportSimulator, sim1.cmd.Addr().String()); err == nil {
+		t.Errorf("open() expected error for already opened TPM")
+	}
+
+	if err := first.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if err := first.Close(); err == nil {
+		t.Errorf("Close() expected error for already closed TPM")
+	}
+
+	// Closing the first TPM must not affect the second TPM
+	if second.tpm == nil || second.rwc == nil {
+		t.Fatalf("second TPM was closed")
+	}
+	if err := tpm2.Startup(second.rwc, tpm2.StartupClear); err != nil {
+		t.Errorf("failed to send command to second TPM: %v", err)
+	}
+	if err := second.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func Test_storeRenewal(t *testing.T) {
+	akchain := []*x509.Certificate{createCert(t, "ak", time.Now().Add(time.Hour))}
+	ikchain := []*x509.Certificate{createCert(t, "ik", time.Now().Add(time.Hour))}
+
+	tests := []struct {
+		name      string
+		staged    []string
+		committed bool
+	}{
+		{"Renewal", nil, true},
+		{"Interrupted Before Commit", []string{akchainFile, ikchainFile, ikFile}, false},
+		{"Interrupted After Commit", []string{akchainFile, ikchainFile}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, f := range []string{akchainFile, ikchainFile, ikFile} {
+				if err := os.WriteFile(path.Join(dir, f), []byte("old"), 0644); err != nil {
+					t.Fatalf("failed to write file: %v", err)
+				}
+			}
+
+			if tt.staged == nil {
+				if err := storeRenewal(dir, []byte("new"), akchain, ikchain); err != nil {
+					t.Fatalf("storeRenewal() error = %v", err)
+				}
+			} else {
+				for _, f := range tt.staged {
+					if err := os.WriteFile(path.Join(dir, f+renewalSuffix), []byte("new"), 0644); err != nil {
+						t.Fatalf("failed to write file: %v", err)
+					}
+				}
+				if err := completeRenewal(dir); err != nil {
+					t.Fatalf("completeRenewal() error = %v", err)
+				}
+			}
+
+			for _, f := range []string{akchainFile, ikchainFile} {
+				data, err := os.ReadFile(path.Join(dir, f))
+				if err != nil {
+					t.Fatalf("failed to read file: %v", err)
+				}
+				if renewed := string(data) != "old"; renewed != tt.committed {
+					t.Errorf("%v renewed = %v, want %v", f, renewed, tt.committed)
+				}
+				if _, err := os.Stat(path.Join(dir, f+renewalSuffix)); err == nil {
+					t.Errorf("staged file %v was not removed", f)
+				}
+			}
+		})
+	}
+}
+
+func Test_expires(t *testing.T) {
+	tests := []struct {
+		name     string
+		notAfter time.Time
+		want     bool
+	}{
+		{"Valid", time.Now().Add(60 * 24 * time.Hour), false},
+		{"Within Threshold", time.Now().Add(10 * 24 * time.Hour), true},
+		{"Expired", time.Now().Add(-time.Hour), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain := []*x509.Certificate{createCert(t, "ik", tt.notAfter)}
+			if got := expires(chain, 30*24*time.Hour); got != tt.want {
+				t.Errorf("expires() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func createCert(t *testing.T, cn string, notAfter time.Time) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
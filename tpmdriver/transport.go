@@ -0,0 +1,105 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tpmdriver
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpmutil/mssim"
+)
+
+const (
+	// TransportDevice selects the TPM character device (default)
+	TransportDevice = "device"
+	// TransportSimulator selects a TCP connection to a TPM simulator implementing
+	// the Microsoft simulator interface, such as ms-tpm-20-ref or swtpm
+	TransportSimulator = "simulator"
+)
+
+const (
+	tpmDevice               = "/dev/tpm0"
+	tpmResourceManager      = "/dev/tpmrm0"
+	defaultSimulatorAddress = "127.0.0.1:2321"
+)
+
+// openTransport opens the connection to the TPM via the specified transport. For
+// the device transport, the address is the path of the TPM device. If empty, the
+// kernel resource manager is used if available. For the simulator transport, the
+// address is the command address of the simulator
+func openTransport(transport, address string) (io.ReadWriteCloser, error) {
+	switch strings.ToLower(transport) {
+	case "", TransportDevice:
+		device := address
+		if device == "" {
+			device = tpmResourceManager
+			if _, err := os.Stat(device); err != nil {
+				device = tpmDevice
+			}
+		}
+		log.Debugf("Opening TPM device %v", device)
+		rwc, err := tpm2.OpenTPM(device)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %v: %w", device, err)
+		}
+		return rwc, nil
+	case TransportSimulator:
+		return openSimulator(address)
+	default:
+		return nil, fmt.Errorf("TPM transport %v not supported", transport)
+	}
+}
+
+// openSimulator connects to a TPM simulator. The simulator is expected to serve
+// the platform interface at the port following the command port, which is the
+// default for ms-tpm-20-ref and swtpm (--server port=2321 --ctrl type=tcp,port=2322)
+func openSimulator(address string) (io.ReadWriteCloser, error) {
+	if address == "" {
+		address = defaultSimulatorAddress
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulator address %v: %w", address, err)
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid simulator port %v: %w", port, err)
+	}
+
+	log.Debugf("Connecting to TPM simulator %v", address)
+	log.Warn("Using TPM simulator. The measurements are not backed by a hardware TPM")
+
+	conn, err := mssim.Open(mssim.Config{
+		CommandAddress:  address,
+		PlatformAddress: net.JoinHostPort(host, strconv.Itoa(p+1)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to TPM simulator: %w", err)
+	}
+
+	// The simulator is power cycled on connecting and must be started up. As no
+	// firmware measures the boot chain, all PCRs are in their initial state
+	if err := tpm2.Startup(conn, tpm2.StartupClear); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start up TPM simulator: %w", err)
+	}
+
+	return conn, nil
+}
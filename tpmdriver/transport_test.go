@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Fraunhofer AISEC
+// Fraunhofer-Gesellschaft zur Foerderung der angewandten Forschung e.V.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tpmdriver
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// fakeSimulator implements the Microsoft simulator interface and answers each TPM
+// command with the configured response code
+type fakeSimulator struct {
+	mu       sync.Mutex
+	cmd      net.Listener
+	platform net.Listener
+	rc       uint32
+	commands []uint32
+}
+
+func newFakeSimulator(t *testing.T, rc uint32) *fakeSimulator {
+	// The platform interface must be served at the port following the command port
+	for i := 0; i < 10; i++ {
+		cmd, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		port := cmd.Addr().(*net.TCPAddr).Port
+		platform, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port+1)))
+		if err != nil {
+			cmd.Close()
+			continue
+		}
+		s := &fakeSimulator{cmd: cmd, platform: platform, rc: rc}
+		go s.servePlatform()
+		go s.serveCommands()
+		t.Cleanup(func() {
+			cmd.Close()
+			platform.Close()
+		})
+		return s
+	}
+	t.Fatalf("failed to find free ports for simulator")
+	return nil
+}
+
+func (s *fakeSimulator) servePlatform() {
+	for {
+		conn, err := s.platform.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				var signal uint32
+				if err := binary.Read(conn, binary.BigEndian, &signal); err != nil || signal == 20 {
+					return
+				}
+				binary.Write(conn, binary.BigEndian, uint32(0))
+			}
+		}()
+	}
+}
+
+func (s *fakeSimulator) serveCommands() {
+	for {
+		conn, err := s.cmd.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			for {
+				var header struct {
+					Signal   uint32
+					Locality uint8
+					Size     uint32
+				}
+				if err := binary.Read(conn, binary.BigEndian, &header); err != nil || header.Signal != 8 {
+					return
+				}
+				cmd := make([]byte, header.Size)
+				if _, err := io.ReadFull(conn, cmd); err != nil {
+					return
+				}
+				s.mu.Lock()
+				s.commands = append(s.commands, binary.BigEndian.Uint32(cmd[6:10]))
+				s.mu.Unlock()
+
+				// Response: TPM_ST_NO_SESSIONS, size, response code
+				resp := make([]byte, 10)
+				binary.BigEndian.PutUint16(resp[0:], 0x8001)
+				binary.BigEndian.PutUint32(resp[2:], 10)
+				binary.BigEndian.PutUint32(resp[6:], s.rc)
+				binary.Write(conn, binary.BigEndian, uint32(len(resp)))
+				conn.Write(resp)
+				binary.Write(conn, binary.BigEndian, uint32(0))
+			}
+		}()
+	}
+}
+
+func Test_openSimulator(t *testing.T) {
+	tests := []struct {
+		name    string
+		rc      uint32
+		wantErr bool
+	}{
+		{"Success", 0, false},
+		{"Startup Failure", 0x101, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim := newFakeSimulator(t, tt.rc)
+
+			rwc, err := openTransport(TransportSimulator, sim.cmd.Addr().String())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("openTransport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			rwc.Close()
+
+			sim.mu.Lock()
+			defer sim.mu.Unlock()
+			// TPM2_Startup must be sent after the simulator was power cycled
+			if len(sim.commands) != 1 || sim.commands[0] != 0x144 {
+				t.Errorf("openTransport() sent commands %x, expected TPM2_Startup", sim.commands)
+			}
+		})
+	}
+}
+
+func Test_openTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport string
+		address   string
+	}{
+		{"Unknown Transport", "socket", ""},
+		{"Invalid Simulator Address", TransportSimulator, "localhost"},
+		{"Missing Device", TransportDevice, "/nonexistent/tpm0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := openTransport(tt.transport, tt.address); err == nil {
+				t.Errorf("openTransport() expected error")
+			}
+		})
+	}
+}